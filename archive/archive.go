@@ -0,0 +1,93 @@
+// Package archive keeps the exact payload and Signature header of every
+// verified webhook delivery in object storage, under keys derived from their
+// content, so deliveries can be verified again or replayed later.
+//
+//	a := &archive.Archive{Objects: &archive.S3{Client: s3.NewFromConfig(cfg), Bucket: "webhooks"}}
+//	h := &travis.Handler{OnDelivery: a.Record}
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// Entry is an archived delivery
+type Entry struct {
+	Received  time.Time `json:"received"`
+	Payload   string    `json:"payload"`
+	Signature string    `json:"signature"`
+}
+
+// Key returns the content address of e: the hex SHA-256 of the payload and
+// signature. Travis redelivering a webhook produces the same key.
+func (e *Entry) Key() string {
+	h := sha256.New()
+	h.Write([]byte(e.Payload))
+	h.Write([]byte{0})
+	h.Write([]byte(e.Signature))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Objects stores archived entries
+type Objects interface {
+	Put(ctx context.Context, key string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// Archive writes deliveries to Objects as <Prefix>/<key[:2]>/<key>.json
+type Archive struct {
+	Objects Objects
+	Prefix  string
+	// Rejected also archives the deliveries that failed verification
+	Rejected bool
+	// OnError, if set, is called with the errors of Objects
+	OnError func(err error)
+}
+
+// Record archives d, it is meant to be used as Handler.OnDelivery
+func (a *Archive) Record(d *travis.Delivery) {
+	if d.RawPayload == "" || (d.Disposition == travis.DispositionRejected && !a.Rejected) {
+		return
+	}
+	_, err := a.Write(d.Context(), &Entry{
+		Received:  d.Received.UTC(),
+		Payload:   d.RawPayload,
+		Signature: d.Signature,
+	})
+	if err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
+
+// Write stores e and returns its key
+func (a *Archive) Write(ctx context.Context, e *Entry) (string, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	key := e.Key()
+	return key, a.Objects.Put(ctx, a.path(key), body)
+}
+
+// Read returns the entry stored under key
+func (a *Archive) Read(ctx context.Context, key string) (*Entry, error) {
+	body, err := a.Objects.Get(ctx, a.path(key))
+	if err != nil {
+		return nil, err
+	}
+	e := new(Entry)
+	if err := json.Unmarshal(body, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (a *Archive) path(key string) string {
+	return path.Join(a.Prefix, key[:2], key+".json")
+}
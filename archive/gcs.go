@@ -0,0 +1,34 @@
+package archive
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// GCS is Objects in a Google Cloud Storage bucket
+type GCS struct {
+	Bucket *storage.BucketHandle
+}
+
+// Put uploads body to key
+func (g *GCS) Put(ctx context.Context, key string, body []byte) error {
+	w := g.Bucket.Object(key).NewWriter(ctx)
+	w.ContentType = "application/json"
+	if _, err := w.Write(body); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Get downloads key
+func (g *GCS) Get(ctx context.Context, key string) ([]byte, error) {
+	r, err := g.Bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
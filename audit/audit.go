@@ -0,0 +1,128 @@
+// Package audit records every webhook delivery received by a travis.Handler
+// to a pluggable backend, as evidence of what triggered builds and deploys.
+//
+//	rec := &audit.Recorder{Backend: &audit.File{Dir: "/var/log/travis"}, MaxAge: 90 * 24 * time.Hour}
+//	h := &travis.Handler{OnDelivery: rec.Record}
+//	go rec.Run(ctx)
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// Record is the audit entry of one delivery
+type Record struct {
+	Time        time.Time `json:"time"`
+	Repo        string    `json:"repo,omitempty"`
+	BuildID     int64     `json:"build_id,omitempty"`
+	State       string    `json:"state,omitempty"`
+	Disposition string    `json:"disposition"`
+	Verified    bool      `json:"verified"`
+	Error       string    `json:"error,omitempty"`
+	// Payload and Signature are kept exactly as received so the delivery
+	// can be verified again later
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// NewRecord returns the audit entry of d
+func NewRecord(d *travis.Delivery) *Record {
+	r := &Record{
+		Time:        d.Received.UTC(),
+		Disposition: d.Disposition,
+		Verified:    d.Disposition != travis.DispositionRejected,
+		Payload:     d.RawPayload,
+		Signature:   d.Signature,
+	}
+	if d.Err != nil {
+		r.Error = d.Err.Error()
+	}
+	if p := d.Payload; p != nil {
+		r.Repo = p.Slug()
+		r.BuildID = p.ID
+		r.State = travis.Outcome(p)
+	}
+	return r
+}
+
+// Backend stores audit records
+type Backend interface {
+	Write(ctx context.Context, r *Record) error
+	// Prune deletes the records older than before
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// DefaultPruneInterval is how often Recorder.Run prunes if PruneInterval
+// isn't set
+const DefaultPruneInterval = time.Hour
+
+// Recorder writes deliveries to a Backend and enforces the retention policy
+// in the background with Run, so pruning never delays a delivery
+type Recorder struct {
+	Backend Backend
+	// MaxAge is how long records are kept, forever if zero
+	MaxAge time.Duration
+	// PruneInterval is how often Run prunes, DefaultPruneInterval if zero
+	PruneInterval time.Duration
+	// OnError, if set, is called with the errors of the backend
+	OnError func(err error)
+
+	// Clock tells the time, travis.SystemClock if nil
+	Clock travis.Clock
+}
+
+// Record writes d to the backend with the context of its request. It is
+// meant to be used as Handler.OnDelivery.
+func (rec *Recorder) Record(d *travis.Delivery) {
+	if err := rec.Backend.Write(d.Context(), NewRecord(d)); err != nil {
+		rec.error(err)
+	}
+}
+
+// Run prunes the records older than MaxAge at once and then every
+// PruneInterval until ctx is done. It returns at once if MaxAge is zero.
+func (rec *Recorder) Run(ctx context.Context) {
+	if rec.MaxAge <= 0 {
+		return
+	}
+	interval := rec.PruneInterval
+	if interval <= 0 {
+		interval = DefaultPruneInterval
+	}
+	ticker := rec.clock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := rec.Prune(ctx); err != nil {
+			rec.error(err)
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Prune deletes the records older than MaxAge
+func (rec *Recorder) Prune(ctx context.Context) error {
+	if rec.MaxAge <= 0 {
+		return nil
+	}
+	return rec.Backend.Prune(ctx, rec.clock().Now().Add(-rec.MaxAge))
+}
+
+func (rec *Recorder) clock() travis.Clock {
+	if rec.Clock == nil {
+		return travis.SystemClock
+	}
+	return rec.Clock
+}
+
+func (rec *Recorder) error(err error) {
+	if rec.OnError != nil {
+		rec.OnError(err)
+	}
+}
@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is a Backend appending records as JSON lines to one file per day,
+// named audit-YYYY-MM-DD.jsonl, in Dir
+type File struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+const fileDateLayout = "2006-01-02"
+
+// Write appends r to the file of its day
+func (f *File) Write(ctx context.Context, r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(f.Dir, "audit-"+r.Time.UTC().Format(fileDateLayout)+".jsonl")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Prune deletes the files of the days before before
+func (f *File) Prune(ctx context.Context, before time.Time) error {
+	names, err := filepath.Glob(filepath.Join(f.Dir, "audit-*.jsonl"))
+	if err != nil {
+		return err
+	}
+	cutoff := before.UTC().Truncate(24 * time.Hour)
+	for _, name := range names {
+		date := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), "audit-"), ".jsonl")
+		day, err := time.Parse(fileDateLayout, date)
+		if err != nil || !day.Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3API is the part of *s3.Client used by S3
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// S3 is a Backend storing each record as a JSON object under
+// <Prefix>/YYYY/MM/DD/ in Bucket. Consider a bucket lifecycle rule instead
+// of Prune for large archives.
+type S3 struct {
+	Client S3API
+	Bucket string
+	Prefix string
+}
+
+// Write uploads r
+func (s *S3) Write(ctx context.Context, r *Record) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	t := r.Time.UTC()
+	key := path.Join(s.Prefix, t.Format("2006/01/02"),
+		fmt.Sprintf("%s-%d-%s.json", t.Format("150405.000000000"), r.BuildID, r.Disposition))
+	_, err = s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// Prune deletes the objects under Prefix last modified before before
+func (s *S3) Prune(ctx context.Context, before time.Time) error {
+	in := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(s.Prefix),
+	}
+	for {
+		out, err := s.Client.ListObjectsV2(ctx, in)
+		if err != nil {
+			return err
+		}
+		for _, o := range out.Contents {
+			if o.LastModified == nil || !o.LastModified.Before(before) {
+				continue
+			}
+			_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket: aws.String(s.Bucket),
+				Key:    o.Key,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			return nil
+		}
+		in.ContinuationToken = out.NextContinuationToken
+	}
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQL is a Backend storing records in a table of a database/sql database,
+// such as SQLite. The driver has to be imported by the program.
+type SQL struct {
+	DB *sql.DB
+	// Table is the name of the table, "travis_audit" if empty
+	Table string
+	// Postgres switches the query placeholders from ? to $n
+	Postgres bool
+}
+
+func (s *SQL) table() string {
+	if s.Table == "" {
+		return "travis_audit"
+	}
+	return s.Table
+}
+
+func (s *SQL) placeholders(n int) []interface{} {
+	p := make([]interface{}, n)
+	for i := range p {
+		if s.Postgres {
+			p[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			p[i] = "?"
+		}
+	}
+	return p
+}
+
+// CreateTable creates the table if it doesn't exist
+func (s *SQL) CreateTable(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time TIMESTAMP NOT NULL,
+	repo TEXT NOT NULL,
+	build_id BIGINT NOT NULL,
+	state TEXT NOT NULL,
+	disposition TEXT NOT NULL,
+	verified BOOLEAN NOT NULL,
+	error TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	signature TEXT NOT NULL
+)`, s.table()))
+	return err
+}
+
+// Write inserts r
+func (s *SQL) Write(ctx context.Context, r *Record) error {
+	query := fmt.Sprintf("INSERT INTO %s (time, repo, build_id, state, disposition, verified, error, payload, signature) VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s)",
+		append([]interface{}{s.table()}, s.placeholders(9)...)...)
+	_, err := s.DB.ExecContext(ctx, query, r.Time, r.Repo, r.BuildID, r.State, r.Disposition, r.Verified, r.Error, r.Payload, r.Signature)
+	return err
+}
+
+// Prune deletes the records older than before
+func (s *SQL) Prune(ctx context.Context, before time.Time) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE time < %s", s.table(), s.placeholders(1)[0])
+	_, err := s.DB.ExecContext(ctx, query, before.UTC())
+	return err
+}
@@ -0,0 +1,130 @@
+// Package awssink publishes travis payloads to AWS SNS topics and SQS queues.
+//
+// Payloads are sent as JSON with the repo, branch, state and type message
+// attributes so subscriptions can filter on them.
+package awssink
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/jacksgt/travis"
+)
+
+// SNSPublisher is the part of *sns.Client used by SNS
+type SNSPublisher interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// SQSSender is the part of *sqs.Client used by SQS
+type SQSSender interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SNS is a travis.Sink publishing payloads to an SNS topic
+type SNS struct {
+	Client   SNSPublisher
+	TopicARN string
+}
+
+// Send publishes p to the topic
+func (s *SNS) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *SNS) SendContext(ctx context.Context, p *travis.Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	attrs := make(map[string]snstypes.MessageAttributeValue)
+	for k, v := range attributes(p) {
+		attrs[k] = snstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	in := &sns.PublishInput{
+		TopicArn:          aws.String(s.TopicARN),
+		Message:           aws.String(string(body)),
+		MessageAttributes: attrs,
+	}
+	if strings.HasSuffix(s.TopicARN, ".fifo") {
+		in.MessageGroupId = aws.String(p.Slug())
+		in.MessageDeduplicationId = aws.String(deduplicationID(p))
+	}
+	_, err = s.Client.Publish(ctx, in)
+	return err
+}
+
+// SQS is a travis.Sink sending payloads to an SQS queue
+type SQS struct {
+	Client   SQSSender
+	QueueURL string
+}
+
+// Send sends p to the queue
+func (s *SQS) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *SQS) SendContext(ctx context.Context, p *travis.Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	attrs := make(map[string]sqstypes.MessageAttributeValue)
+	for k, v := range attributes(p) {
+		attrs[k] = sqstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(v),
+		}
+	}
+
+	in := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.QueueURL),
+		MessageBody:       aws.String(string(body)),
+		MessageAttributes: attrs,
+	}
+	if strings.HasSuffix(s.QueueURL, ".fifo") {
+		in.MessageGroupId = aws.String(p.Slug())
+		in.MessageDeduplicationId = aws.String(deduplicationID(p))
+	}
+	_, err = s.Client.SendMessage(ctx, in)
+	return err
+}
+
+// attributes returns the non-empty filtering attributes of p, AWS rejects
+// attributes with an empty value
+func attributes(p *travis.Payload) map[string]string {
+	attrs := make(map[string]string)
+	if slug := p.Slug(); slug != "" {
+		attrs["repo"] = slug
+	}
+	if p.Branch != "" {
+		attrs["branch"] = p.Branch
+	}
+	attrs["state"] = travis.Outcome(p)
+	if p.Type != "" {
+		attrs["type"] = p.Type
+	}
+	return attrs
+}
+
+// deduplicationID identifies a delivery in FIFO topics and queues, Travis
+// sends one webhook per build and state
+func deduplicationID(p *travis.Payload) string {
+	return strconv.FormatInt(p.ID, 10) + "-" + travis.Outcome(p)
+}
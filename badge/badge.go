@@ -0,0 +1,174 @@
+// Package badge renders shields.io style SVG badges of build statuses and
+// serves them from a store.
+//
+//	http.Handle("/badge/", http.StripPrefix("/badge/", &badge.Handler{Store: s}))
+//
+// serves the status of the main branch of acme/api at /badge/acme/api/main.svg,
+// and at /badge/acme/api/main.json in the JSON of shields.io endpoint badges,
+// for https://img.shields.io/endpoint?url=... to render.
+package badge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+)
+
+// Status returns the badge message and color of p, "unknown" if p is nil
+func Status(p *travis.Payload) (string, travis.Color) {
+	if p == nil {
+		return "unknown", travis.Cancel
+	}
+	switch travis.Outcome(p) {
+	case travis.OutcomePassed:
+		return "passing", travis.Passed
+	case travis.OutcomeFailed:
+		return "failing", travis.Fail
+	case travis.OutcomeErrored:
+		return "error", travis.Fail
+	case travis.OutcomeCanceled:
+		return "canceled", travis.Cancel
+	}
+	return "pending", travis.InProgress
+}
+
+// Render writes a flat badge with label on the left and message on a
+// background of color on the right
+func Render(w io.Writer, label, message string, color travis.Color) error {
+	lw, mw := textWidth(label), textWidth(message)
+	label, message = html.EscapeString(label), html.EscapeString(message)
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>
+`, lw+mw, lw, mw, label, message, color.Hex(), lw/2, lw+mw/2)
+	return err
+}
+
+// Endpoint is a badge in the schema of shields.io endpoint badges
+type Endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	// Color is the color of the message, in hex without #
+	Color string `json:"color"`
+	// CacheSeconds is how long shields.io caches the badge, its default
+	// of 300 if zero
+	CacheSeconds int `json:"cacheSeconds,omitempty"`
+}
+
+// NewEndpoint returns the endpoint badge of p with label, "unknown" if p is
+// nil
+func NewEndpoint(label string, p *travis.Payload) *Endpoint {
+	message, color := Status(p)
+	return &Endpoint{
+		SchemaVersion: 1,
+		Label:         label,
+		Message:       message,
+		Color:         strings.TrimPrefix(color.Hex(), "#"),
+	}
+}
+
+// textWidth approximates the width in pixels of s in 11px Verdana plus padding
+func textWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iljtf.,:;!|' ", r):
+			w += 4
+		case strings.ContainsRune("mwMW", r):
+			w += 10
+		default:
+			w += 7
+		}
+	}
+	return w + 10
+}
+
+// Handler serves badges at <owner>/<repo>/<branch>.svg, and their shields.io
+// endpoint JSON at <owner>/<repo>/<branch>.json, relative to where it is
+// mounted with http.StripPrefix. Branches may contain slashes.
+type Handler struct {
+	Store store.Store
+	// Label is the left part of badges, "build" if empty
+	Label string
+	// CacheSeconds is the cacheSeconds of endpoint badges, the default of
+	// shields.io if zero
+	CacheSeconds int
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	repo, branch, ok := ParsePath(r.URL.Path)
+	endpoint := false
+	if !ok {
+		repo, branch, ok = parsePath(r.URL.Path, ".json")
+		endpoint = true
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	p, err := h.latest(r.Context(), repo, branch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	label := h.Label
+	if label == "" {
+		label = "build"
+	}
+	if endpoint {
+		e := NewEndpoint(label, p)
+		e.CacheSeconds = h.CacheSeconds
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache, max-age=0")
+		json.NewEncoder(w).Encode(e)
+		return
+	}
+	message, color := Status(p)
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache, max-age=0")
+	Render(w, label, message, color)
+}
+
+func (h *Handler) latest(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+	p, err := h.Store.Latest(ctx, repo, branch)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ParsePath splits <owner>/<repo>/<branch>.svg into the repository slug
+// and branch
+func ParsePath(path string) (repo, branch string, ok bool) {
+	return parsePath(path, ".svg")
+}
+
+// parsePath splits <owner>/<repo>/<branch><ext>
+func parsePath(path, ext string) (repo, branch string, ok bool) {
+	path = strings.TrimPrefix(path, "/")
+	if !strings.HasSuffix(path, ext) {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimSuffix(path, ext), "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[0] + "/" + parts[1], parts[2], true
+}
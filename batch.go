@@ -0,0 +1,60 @@
+package travis
+
+import (
+	"context"
+	"crypto/rsa"
+	"runtime"
+	"sync"
+)
+
+// BatchResult is the outcome of verifying a delivery with VerifyBatch
+type BatchResult struct {
+	// Payload is the decoded payload, nil if Err is set
+	Payload *Payload
+	// Err is ErrUnauthorized for a signature not matching key, the
+	// decoding error or the error of ctx for the deliveries not verified
+	// before it was done
+	Err error
+}
+
+// VerifyBatch verifies the RawPayload and Signature of deliveries against
+// key and decodes them, on concurrency goroutines or GOMAXPROCS if zero,
+// e.g. to replay an archive. The result of deliveries[i] is results[i].
+func VerifyBatch(ctx context.Context, key *rsa.PublicKey, deliveries []*Delivery, concurrency int) []BatchResult {
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	results := make([]BatchResult, len(deliveries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				d := deliveries[i]
+				if err := VerifySignature(d.RawPayload, d.Signature, key); err != nil {
+					results[i].Err = err
+					continue
+				}
+				results[i].Payload, results[i].Err = decodePayload(d.RawPayload)
+			}
+		}()
+	}
+
+	i := 0
+feed:
+	for ; i < len(deliveries); i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	for ; i < len(deliveries); i++ {
+		results[i].Err = ctx.Err()
+	}
+	return results
+}
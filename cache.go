@@ -0,0 +1,111 @@
+package travis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultDedupeTTL is how long a Handler remembers the deliveries it
+// handled to drop their duplicates if DedupeTTL isn't set
+const DefaultDedupeTTL = 24 * time.Hour
+
+// CacheBackend is a key-value store with expiration backing the caching
+// features: the public key of a Handler and the deduplication of its
+// deliveries. MemoryCache keeps them in the process, a backend shared by
+// the replicas of a receiver, such as the rediscache package, keeps them
+// from fetching the key each and from handling the deliveries sent again
+// to another replica.
+type CacheBackend interface {
+	// Get returns the value of key, false if it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set sets the value of key, expiring after ttl unless it is zero
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Add sets the value of key like Set if it is missing or expired and
+	// returns whether it did, atomically
+	Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryCache is a CacheBackend in the memory of the process
+type MemoryCache struct {
+	// MaxEntries is the number of keys kept, DefaultMaxEntries if zero.
+	// The least recently used are evicted first.
+	MaxEntries int
+
+	// Clock expires the keys, SystemClock if nil
+	Clock Clock
+
+	mu      sync.Mutex
+	entries lru[string, memoryEntry]
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Get returns the value of key
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	return e.value, ok, nil
+}
+
+// Set sets the value of key
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+	return nil
+}
+
+// Add sets the value of key if it is missing
+func (c *MemoryCache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.lookup(key); ok {
+		return false, nil
+	}
+	c.set(key, value, ttl)
+	return true, nil
+}
+
+// Delete removes key
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.Remove(key)
+	return nil
+}
+
+// Memory reports the keys cached
+func (c *MemoryCache) Memory() MemoryStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.stats()
+}
+
+// lookup returns the entry of key, removing it if it expired
+func (c *MemoryCache) lookup(key string) (memoryEntry, bool) {
+	e, ok := c.entries.Get(key)
+	if ok && !e.expires.IsZero() && !clockOr(c.Clock).Now().Before(e.expires) {
+		c.entries.Remove(key)
+		return memoryEntry{}, false
+	}
+	return e, ok
+}
+
+func (c *MemoryCache) set(key string, value []byte, ttl time.Duration) {
+	if c.entries.Size == nil {
+		c.entries.Size = func(k string, e memoryEntry) int { return len(k) + len(e.value) }
+	}
+	c.entries.limit(c.MaxEntries, 0)
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expires = clockOr(c.Clock).Now().Add(ttl)
+	}
+	c.entries.Put(key, e)
+}
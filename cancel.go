@@ -0,0 +1,91 @@
+package travis
+
+import (
+	"context"
+	"errors"
+)
+
+// BuildCanceler lists and cancels builds, it is implemented by Client
+type BuildCanceler interface {
+	Builds(ctx context.Context, slug string, opts BuildsOptions) ([]*Build, Pagination, error)
+	CancelBuild(ctx context.Context, id int64) error
+}
+
+// AutoCancel is a Sink canceling the builds superseded by a build starting:
+// the older builds of the same event type and branch still created or
+// running, in the repository of the build and the Related ones. Unlike the
+// auto cancellation setting of Travis, it spans repositories, e.g. the
+// builds of a branch of a library and of the services depending on it.
+// Older pull request builds are only canceled in the same repository and
+// pull request.
+type AutoCancel struct {
+	Canceler BuildCanceler
+
+	// Repos and Branches are path.Match patterns of the builds superseding
+	// the older ones, all if empty
+	Repos    []string
+	Branches []string
+	// Events are the event types whose builds supersede the older ones,
+	// push if empty
+	Events []string
+	// Related returns the other repositories whose builds p supersedes
+	Related func(p *Payload) []string
+
+	// OnCancel, if set, is called with each build canceled and the
+	// payload superseding it
+	OnCancel func(b *Build, by *Payload)
+}
+
+// Send cancels the builds superseded by p
+func (a *AutoCancel) Send(p *Payload) error {
+	return a.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (a *AutoCancel) SendContext(ctx context.Context, p *Payload) error {
+	events := a.Events
+	if len(events) == 0 {
+		events = []string{"push"}
+	}
+	if Outcome(p) != OutcomePending || !MatchAny(events, p.Type) ||
+		!MatchAny(a.Repos, p.Slug()) || !MatchAny(a.Branches, p.Branch) {
+		return nil
+	}
+	repos := []string{p.Slug()}
+	if a.Related != nil && !p.IsPullRequest() {
+		repos = append(repos, a.Related(p)...)
+	}
+
+	var errs []error
+	for _, slug := range repos {
+		builds, _, err := a.Canceler.Builds(ctx, slug, BuildsOptions{
+			Branch: p.Branch,
+			States: []string{"created", "received", "started"},
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, b := range builds {
+			if !a.supersedes(p, b) {
+				continue
+			}
+			if err := a.Canceler.CancelBuild(ctx, b.ID); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if a.OnCancel != nil {
+				a.OnCancel(b, p)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// supersedes returns true if p supersedes b
+func (a *AutoCancel) supersedes(p *Payload, b *Build) bool {
+	if b.ID >= p.ID || b.Finished() || b.EventType != p.Type || b.Branch.Name != p.Branch {
+		return false
+	}
+	return !p.IsPullRequest() || b.PullRequestNumber == p.PullRequestNumber
+}
@@ -0,0 +1,259 @@
+// Package changelog renders changelog fragments of releases: when the build
+// of a tag passes, the commits since the previous tag are listed with the
+// GitHub API and rendered with a template, the first step of release notes.
+//
+//	h := &travis.Handler{Sinks: []travis.Sink{&changelog.Generator{
+//		Token: os.Getenv("GITHUB_TOKEN"),
+//		OnFragment: func(f *changelog.Fragment) {
+//			os.WriteFile("changes/"+f.Tag+".md", []byte(f.Text), 0o644)
+//		},
+//	}}}
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// DefaultAPI is the API a Generator calls if BaseURL isn't set
+const DefaultAPI = "https://api.github.com"
+
+// DefaultTemplate is the template of fragments if Template isn't set, in
+// markdown
+const DefaultTemplate = `## {{.Tag}} ({{.Date.Format "2006-01-02"}})
+{{range .Commits}}
+- {{.Subject}} ({{.Short}}){{end}}
+`
+
+// Commit is a commit of a release
+type Commit struct {
+	SHA string
+	// Subject is the first line of the message and Body the rest
+	Subject, Body string
+	Author        string
+	URL           string
+}
+
+// Short returns the abbreviated SHA of c
+func (c Commit) Short() string {
+	if len(c.SHA) > 7 {
+		return c.SHA[:7]
+	}
+	return c.SHA
+}
+
+// Fragment is the changelog of a release, the data of the template
+type Fragment struct {
+	Payload *travis.Payload
+	Tag     string
+	// Previous is the previous tag, empty for the first release
+	Previous string
+	// Date is the end of the build
+	Date time.Time
+	// Commits are the commits since Previous, the newest first
+	Commits []Commit
+	// Text is the rendered fragment
+	Text string
+}
+
+// Generator is a travis.Sink calling OnFragment with the changelog fragment
+// of each tag whose build passed
+type Generator struct {
+	// BaseURL of the API, DefaultAPI if empty, e.g. the /api/v3 URL of a
+	// GitHub Enterprise server
+	BaseURL string
+	// Token authenticates the requests, it is required for private
+	// repositories
+	Token string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	// Template is a text/template template of Fragment, DefaultTemplate
+	// if empty
+	Template string
+	// Merges, if true, keeps the merge commits
+	Merges bool
+
+	OnFragment func(f *Fragment)
+
+	once sync.Once
+	tmpl *template.Template
+	err  error
+}
+
+// Send generates the fragment of p if it is a passed tag build
+func (g *Generator) Send(p *travis.Payload) error {
+	return g.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (g *Generator) SendContext(ctx context.Context, p *travis.Payload) error {
+	if p.Tag == "" || travis.Outcome(p) != travis.OutcomePassed {
+		return nil
+	}
+	f, err := g.Generate(ctx, p)
+	if err != nil {
+		return err
+	}
+	if g.OnFragment != nil {
+		g.OnFragment(f)
+	}
+	return nil
+}
+
+// Generate returns the fragment of the tag of p. The commits are the range
+// of the compare URL of p, if it is one, else the ones between the tag
+// listed before it by GitHub and the tag, or the latest 100 commits of the
+// tag if it is the first one.
+func (g *Generator) Generate(ctx context.Context, p *travis.Payload) (*Fragment, error) {
+	g.once.Do(func() {
+		text := g.Template
+		if text == "" {
+			text = DefaultTemplate
+		}
+		g.tmpl, g.err = template.New("changelog").Parse(text)
+	})
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	f := &Fragment{Payload: p, Tag: p.Tag, Date: p.FinishedAt}
+	if f.Date.IsZero() {
+		f.Date = time.Now()
+	}
+	repo := "/repos/" + p.Slug()
+	var commits []githubCommit
+	base, head, ok := compareRange(p.CompareURL)
+	if !ok {
+		var err error
+		if base, err = g.previousTag(ctx, repo, p.Tag); err != nil {
+			return nil, err
+		}
+		head = p.Tag
+	}
+	f.Previous = base
+	if base == "" {
+		if err := g.get(ctx, repo+"/commits?per_page=100&sha="+url.QueryEscape(head), &commits); err != nil {
+			return nil, err
+		}
+	} else {
+		var res struct {
+			Commits []githubCommit `json:"commits"`
+		}
+		if err := g.get(ctx, repo+"/compare/"+url.PathEscape(base)+"..."+url.PathEscape(head), &res); err != nil {
+			return nil, err
+		}
+		// compare lists the oldest first
+		for i := len(res.Commits) - 1; i >= 0; i-- {
+			commits = append(commits, res.Commits[i])
+		}
+	}
+
+	for _, c := range commits {
+		if len(c.Parents) > 1 && !g.Merges {
+			continue
+		}
+		subject, body, _ := strings.Cut(c.Commit.Message, "\n")
+		f.Commits = append(f.Commits, Commit{
+			SHA:     c.SHA,
+			Subject: subject,
+			Body:    strings.TrimSpace(body),
+			Author:  c.Commit.Author.Name,
+			URL:     c.HTMLURL,
+		})
+	}
+
+	var b bytes.Buffer
+	if err := g.tmpl.Execute(&b, f); err != nil {
+		return nil, err
+	}
+	f.Text = b.String()
+	return f, nil
+}
+
+type githubCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commit"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+// compareRange returns the range of a GitHub compare URL such as
+// https://github.com/acme/api/compare/v1.0.0...v1.1.0
+func compareRange(compareURL string) (base, head string, ok bool) {
+	_, r, found := strings.Cut(compareURL, "/compare/")
+	if !found {
+		return "", "", false
+	}
+	base, head, ok = strings.Cut(r, "...")
+	if !ok || base == "" || head == "" || strings.Trim(base, "0") == "" {
+		return "", "", false
+	}
+	base, _ = url.PathUnescape(base)
+	head, _ = url.PathUnescape(head)
+	return base, head, true
+}
+
+// previousTag returns the tag following tag in the tags of the repository,
+// which GitHub sorts by version, newest first, "" if tag is the oldest
+func (g *Generator) previousTag(ctx context.Context, repo, tag string) (string, error) {
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if err := g.get(ctx, repo+"/tags?per_page=100", &tags); err != nil {
+		return "", err
+	}
+	for i, t := range tags {
+		if t.Name == tag && i+1 < len(tags) {
+			return tags[i+1].Name, nil
+		}
+	}
+	return "", nil
+}
+
+func (g *Generator) get(ctx context.Context, path string, out any) error {
+	base := g.BaseURL
+	if base == "" {
+		base = DefaultAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(base, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("changelog: github: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
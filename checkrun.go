@@ -0,0 +1,107 @@
+package travis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckRun is the body of a request creating or updating a GitHub check
+// run, POST /repos/{owner}/{repo}/check-runs
+type CheckRun struct {
+	Name        string     `json:"name"`
+	HeadSHA     string     `json:"head_sha"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DetailsURL  string     `json:"details_url,omitempty"`
+	ExternalID  string     `json:"external_id,omitempty"`
+	Output      struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	} `json:"output"`
+}
+
+// ToCheckRun returns the check run mirroring the build of p, named like
+// the ones of the Travis GitHub App, e.g. "Travis CI - Pull Request". Its
+// status is queued until the build starts, in_progress while it runs and
+// completed with a success, failure or cancelled conclusion when it ends.
+func ToCheckRun(p *Payload) *CheckRun {
+	run := &CheckRun{
+		Name:       "Travis CI - " + eventLabel(p),
+		HeadSHA:    p.Commit,
+		DetailsURL: p.BuildURL,
+		ExternalID: strconv.FormatInt(p.ID, 10),
+	}
+	if p.IsPullRequest() && p.HeadCommit != "" {
+		run.HeadSHA = p.HeadCommit
+	}
+	if !p.StartedAt.IsZero() {
+		t := p.StartedAt
+		run.StartedAt = &t
+	}
+
+	switch outcome := Outcome(p); outcome {
+	case OutcomePending:
+		run.Status = "queued"
+		if p.State == "started" {
+			run.Status = "in_progress"
+		}
+	default:
+		run.Status = "completed"
+		switch outcome {
+		case OutcomePassed:
+			run.Conclusion = "success"
+		case OutcomeCanceled:
+			run.Conclusion = "cancelled"
+		default:
+			run.Conclusion = "failure"
+		}
+		if !p.FinishedAt.IsZero() {
+			t := p.FinishedAt
+			run.CompletedAt = &t
+		}
+	}
+
+	run.Output.Title = "Build " + StageStatus(p)
+	if p.StatusMessage == "" {
+		run.Output.Title = "Build " + Outcome(p)
+	}
+	run.Output.Summary = checkRunSummary(p)
+	return run
+}
+
+// eventLabel returns the event type of p as the Travis GitHub App names it
+func eventLabel(p *Payload) string {
+	switch {
+	case p.IsPullRequest():
+		return "Pull Request"
+	case p.IsCron():
+		return "Cron"
+	case p.IsAPI():
+		return "API"
+	}
+	return "Branch"
+}
+
+// checkRunSummary lists the jobs of p as a markdown table
+func checkRunSummary(p *Payload) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Build #%s](%s) of %s", p.Number, p.BuildURL, p.Ref())
+	if len(p.Matrix) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n\n| Job | Stage | State |\n| --- | --- | --- |\n")
+	for _, s := range Stages(p) {
+		for _, j := range s.Jobs {
+			state := j.Outcome()
+			if j.AllowFailure {
+				state += " (allowed to fail)"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", j.Number, s.Name, state)
+		}
+	}
+	return b.String()
+}
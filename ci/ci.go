@@ -0,0 +1,67 @@
+// Package ci is a provider-agnostic view of CI webhooks, so routing and
+// notification code works the same for every CI service receiving them.
+// The travis package implements Event with its Payload:
+//
+//	var s ci.Sink = ci.SinkFunc(func(e ci.Event) error {
+//		log.Printf("%s %s@%s %s", e.Provider(), e.Slug(), e.Ref(), e.EventState())
+//		return nil
+//	})
+//	h := &travis.Handler{Sinks: []travis.Sink{travis.CISink(s)}}
+package ci
+
+import "time"
+
+// State is the state of the build of an Event
+type State string
+
+// States of an Event
+const (
+	StatePending  State = "pending"
+	StateRunning  State = "running"
+	StatePassed   State = "passed"
+	StateFailed   State = "failed"
+	StateErrored  State = "errored"
+	StateCanceled State = "canceled"
+)
+
+// Finished returns true for the states of finished builds
+func (s State) Finished() bool {
+	switch s {
+	case StatePassed, StateFailed, StateErrored, StateCanceled:
+		return true
+	}
+	return false
+}
+
+// Event is a webhook of a CI service about a build
+type Event interface {
+	// Provider is the CI service, e.g. "travis"
+	Provider() string
+	// EventID identifies the build across providers and their instances
+	EventID() string
+	EventState() State
+	// Slug is the repository as "owner/name"
+	Slug() string
+	// Ref is the branch or tag built
+	Ref() string
+	// SHA is the commit built
+	SHA() string
+	// WebURL is the page of the build
+	WebURL() string
+	// StartTime and EndTime are zero until the build starts and ends
+	StartTime() time.Time
+	EndTime() time.Time
+}
+
+// Sink receives events, e.g. to notify or route them
+type Sink interface {
+	Send(e Event) error
+}
+
+// SinkFunc is an adapter to allow the use of ordinary functions as a Sink
+type SinkFunc func(e Event) error
+
+// Send calls f(e)
+func (f SinkFunc) Send(e Event) error {
+	return f(e)
+}
@@ -0,0 +1,520 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL is the Travis API used by a Client without BaseURL
+const DefaultAPIURL = "https://api.travis-ci.com"
+
+// DefaultAPICacheTTL is how long a Client keeps the responses it cached if
+// CacheTTL isn't set
+const DefaultAPICacheTTL = 24 * time.Hour
+
+// Client calls the Travis API v3
+type Client struct {
+	// BaseURL of the API, DefaultAPIURL if empty
+	BaseURL string
+	// Token authenticates the requests, it is optional for public repositories
+	Token string
+	// HTTPClient sends the requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	// Logger, if set, logs every request at debug level
+	Logger *slog.Logger
+
+	// Cache, if set, keeps the responses to GET requests with their ETag,
+	// to send them again with If-None-Match and reuse them when the API
+	// responds 304 Not Modified
+	Cache CacheBackend
+	// CacheTTL is how long the responses are cached, DefaultAPICacheTTL if
+	// zero
+	CacheTTL time.Duration
+}
+
+// APIError is returned when the API responds with an error
+type APIError struct {
+	StatusCode int
+	Type       string `json:"error_type"`
+	Message    string `json:"error_message"`
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("travis api: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("travis api: %s (%s)", e.Message, e.Type)
+}
+
+// Pagination is the @pagination field of API collections
+type Pagination struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Count   int  `json:"count"`
+	IsFirst bool `json:"is_first"`
+	IsLast  bool `json:"is_last"`
+}
+
+// Build is a build as returned by the API
+type Build struct {
+	ID                int64     `json:"id"`
+	Number            string    `json:"number"`
+	State             string    `json:"state"`
+	PreviousState     string    `json:"previous_state"`
+	Duration          int       `json:"duration"`
+	EventType         string    `json:"event_type"`
+	PullRequestNumber int       `json:"pull_request_number"`
+	PullRequestTitle  string    `json:"pull_request_title"`
+	StartedAt         time.Time `json:"started_at"`
+	FinishedAt        time.Time `json:"finished_at"`
+	Repository        struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"repository"`
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Tag *struct {
+		Name string `json:"name"`
+	} `json:"tag"`
+	Commit struct {
+		ID          int64     `json:"id"`
+		SHA         string    `json:"sha"`
+		Message     string    `json:"message"`
+		CompareURL  string    `json:"compare_url"`
+		CommittedAt time.Time `json:"committed_at"`
+		Author      struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commit"`
+	Jobs []struct {
+		ID int64 `json:"id"`
+	} `json:"jobs"`
+}
+
+// Payload converts b to the webhook payload Travis would have sent for it.
+// Fields the API doesn't return, like emails, are left empty.
+func (b *Build) Payload() *Payload {
+	p := &Payload{
+		ID:                b.ID,
+		Number:            b.Number,
+		Type:              b.EventType,
+		State:             b.State,
+		StatusMessage:     statusMessage(b.State, b.PreviousState),
+		StartedAt:         b.StartedAt,
+		FinishedAt:        b.FinishedAt,
+		Duration:          b.Duration,
+		CommitID:          int(b.Commit.ID),
+		Commit:            b.Commit.SHA,
+		Branch:            b.Branch.Name,
+		Message:           b.Commit.Message,
+		CompareURL:        b.Commit.CompareURL,
+		CommitedAt:        b.Commit.CommittedAt,
+		AuthorName:        b.Commit.Author.Name,
+		PullRequestNumber: b.PullRequestNumber,
+		PullRequestTitle:  b.PullRequestTitle,
+		Repository: &Repository{
+			ID:   b.Repository.ID,
+			Name: b.Repository.Name,
+		},
+	}
+	p.ResultMessage = p.StatusMessage
+	if p.IsPullRequest() {
+		p.PullRequest = 1
+	}
+	if b.Tag != nil {
+		p.Tag = b.Tag.Name
+	}
+	if i := strings.Index(b.Repository.Slug, "/"); i >= 0 {
+		p.Repository.OwnerName = b.Repository.Slug[:i]
+	}
+	return p
+}
+
+// statusMessage derives the status message of a webhook from the state of
+// a build and of the previous one
+func statusMessage(state, previous string) string {
+	switch state {
+	case "passed":
+		if previous == "failed" || previous == "errored" {
+			return "Fixed"
+		}
+		return "Passed"
+	case "failed":
+		switch previous {
+		case "passed":
+			return "Broken"
+		case "failed", "errored":
+			return "Still Failing"
+		}
+		return "Failed"
+	case "errored":
+		return "Errored"
+	case "canceled":
+		return "Canceled"
+	}
+	return "Pending"
+}
+
+// BuildsOptions select the builds listed by Builds
+type BuildsOptions struct {
+	Limit  int
+	Offset int
+	// SortBy is e.g. "id:desc", the API default if empty
+	SortBy string
+	// Branch restricts the builds to a branch
+	Branch string
+	// States restricts the builds to these states, e.g. created and started
+	States []string
+}
+
+// Builds lists a page of the builds of the repository slug ("owner/name")
+func (c *Client) Builds(ctx context.Context, slug string, opts BuildsOptions) ([]*Build, Pagination, error) {
+	q := url.Values{}
+	if opts.Limit > 0 {
+		q.Set("limit", fmt.Sprint(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", fmt.Sprint(opts.Offset))
+	}
+	if opts.SortBy != "" {
+		q.Set("sort_by", opts.SortBy)
+	}
+	if opts.Branch != "" {
+		q.Set("branch.name", opts.Branch)
+	}
+	if len(opts.States) > 0 {
+		q.Set("build.state", strings.Join(opts.States, ","))
+	}
+
+	var res struct {
+		Pagination Pagination `json:"@pagination"`
+		Builds     []*Build   `json:"builds"`
+	}
+	path := "/repo/" + url.PathEscape(slug) + "/builds"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	err := c.do(ctx, "GET", path, nil, &res)
+	return res.Builds, res.Pagination, err
+}
+
+// Build returns the build with id
+func (c *Client) Build(ctx context.Context, id int64) (*Build, error) {
+	b := new(Build)
+	err := c.do(ctx, "GET", fmt.Sprintf("/build/%d", id), nil, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// APIJob is a job as returned by the API
+type APIJob struct {
+	ID           int64     `json:"id"`
+	Number       string    `json:"number"`
+	State        string    `json:"state"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	AllowFailure bool      `json:"allow_failure"`
+	Stage        *struct {
+		Name string `json:"name"`
+	} `json:"stage"`
+}
+
+// Finished returns true if the job is done running
+func (j *APIJob) Finished() bool {
+	return finishedState(j.State)
+}
+
+// Finished returns true if the build is done running
+func (b *Build) Finished() bool {
+	return finishedState(b.State)
+}
+
+func finishedState(state string) bool {
+	switch state {
+	case "passed", "failed", "errored", "canceled":
+		return true
+	}
+	return false
+}
+
+// Jobs returns the jobs of the build with id
+func (c *Client) Jobs(ctx context.Context, buildID int64) ([]*APIJob, error) {
+	var res struct {
+		Jobs []*APIJob `json:"jobs"`
+	}
+	err := c.do(ctx, "GET", fmt.Sprintf("/build/%d/jobs", buildID), nil, &res)
+	return res.Jobs, err
+}
+
+// Log returns the log of the job with id, as much as is available while it runs
+func (c *Client) Log(ctx context.Context, jobID int64) (string, error) {
+	var res struct {
+		Content string `json:"content"`
+	}
+	err := c.do(ctx, "GET", fmt.Sprintf("/job/%d/log", jobID), nil, &res)
+	return res.Content, err
+}
+
+// TailLog writes the log of the job with id to w as it grows, polling every
+// interval until the job is finished or ctx is done
+func (c *Client) TailLog(ctx context.Context, jobID int64, interval time.Duration, w io.Writer) error {
+	written := 0
+	for {
+		var job APIJob
+		if err := c.do(ctx, "GET", fmt.Sprintf("/job/%d", jobID), nil, &job); err != nil {
+			return err
+		}
+		log, err := c.Log(ctx, jobID)
+		if err != nil {
+			return err
+		}
+		if len(log) > written {
+			if _, err := io.WriteString(w, log[written:]); err != nil {
+				return err
+			}
+			written = len(log)
+		}
+		if job.Finished() {
+			return nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// TriggerOptions describe a build requested with TriggerBuild
+type TriggerOptions struct {
+	Branch  string `json:"branch,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Config is merged with the .travis.yml of the branch according to
+	// MergeMode: deep_merge (the API default), merge or replace
+	Config    map[string]interface{} `json:"config,omitempty"`
+	MergeMode string                 `json:"merge_mode,omitempty"`
+}
+
+// BuildRequest is a request for builds, as created by TriggerBuild
+type BuildRequest struct {
+	ID int64 `json:"id"`
+	// Result is pending until the request is processed, then approved or rejected
+	Result  string `json:"result"`
+	Message string `json:"message"`
+	Builds  []struct {
+		ID int64 `json:"id"`
+	} `json:"builds"`
+}
+
+// TriggerBuild requests a build of the repository slug
+func (c *Client) TriggerBuild(ctx context.Context, slug string, opts TriggerOptions) (*BuildRequest, error) {
+	body, err := json.Marshal(map[string]interface{}{"request": opts})
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Request BuildRequest `json:"request"`
+	}
+	err = c.do(ctx, "POST", "/repo/"+url.PathEscape(slug)+"/requests", bytes.NewReader(body), &res)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Request, nil
+}
+
+// Request returns the build request with id of the repository slug
+func (c *Client) Request(ctx context.Context, slug string, id int64) (*BuildRequest, error) {
+	r := new(BuildRequest)
+	err := c.do(ctx, "GET", fmt.Sprintf("/repo/%s/request/%d", url.PathEscape(slug), id), nil, r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// WaitForRequest polls the build request with id every interval until it
+// is processed and returns the id of its build. It returns an error if the
+// request was rejected.
+func (c *Client) WaitForRequest(ctx context.Context, slug string, id int64, interval time.Duration) (int64, error) {
+	for {
+		r, err := c.Request(ctx, slug, id)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case r.Result == "rejected":
+			return 0, &RequestRejectedError{Request: r}
+		case len(r.Builds) > 0:
+			return r.Builds[0].ID, nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// RequestRejectedError is returned when Travis refuses to run a build request
+type RequestRejectedError struct {
+	Request *BuildRequest
+}
+
+func (e *RequestRejectedError) Error() string {
+	if e.Request.Message == "" {
+		return fmt.Sprintf("travis api: build request %d rejected", e.Request.ID)
+	}
+	return fmt.Sprintf("travis api: build request %d rejected: %s", e.Request.ID, e.Request.Message)
+}
+
+// RestartBuild restarts every job of the build with id
+func (c *Client) RestartBuild(ctx context.Context, id int64) error {
+	return c.do(ctx, "POST", fmt.Sprintf("/build/%d/restart", id), nil, nil)
+}
+
+// CancelBuild cancels every job of the build with id
+func (c *Client) CancelBuild(ctx context.Context, id int64) error {
+	return c.do(ctx, "POST", fmt.Sprintf("/build/%d/cancel", id), nil, nil)
+}
+
+// WaitForBuild polls the build with id every interval until it is finished
+func (c *Client) WaitForBuild(ctx context.Context, id int64, interval time.Duration) (*Build, error) {
+	for {
+		b, err := c.Build(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if b.Finished() {
+			return b, nil
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do sends a request to path and decodes the response into out if not nil
+func (c *Client) do(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultAPIURL
+	}
+	u := strings.TrimSuffix(base, "/") + path
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Travis-API-Version", "3")
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	log := c.Logger
+	if log == nil {
+		log = discardLogger
+	}
+
+	var cacheKey string
+	var cached []byte
+	if c.Cache != nil && method == http.MethodGet {
+		cacheKey = apiCacheKey(c.Token, u)
+		if etag, b, ok := c.cached(ctx, log, cacheKey); ok {
+			req.Header.Set("If-None-Match", etag)
+			cached = b
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Debug("travis api request failed", "method", method, "path", path, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+	log.Debug("travis api request", "method", method, "path", path, "status", resp.StatusCode, "duration", time.Since(start))
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(cached, out)
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(apiErr)
+		return apiErr
+	}
+	if etag := resp.Header.Get("ETag"); cacheKey != "" && etag != "" && !strings.ContainsAny(etag, "\r\n") {
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		ttl := c.CacheTTL
+		if ttl <= 0 {
+			ttl = DefaultAPICacheTTL
+		}
+		if err := c.Cache.Set(ctx, cacheKey, append([]byte(etag+"\n"), b...), ttl); err != nil {
+			log.Warn("writing the travis api cache", "error", err)
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(b, out)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// cached returns the ETag and the body of the response cached under key
+func (c *Client) cached(ctx context.Context, log *slog.Logger, key string) (string, []byte, bool) {
+	v, ok, err := c.Cache.Get(ctx, key)
+	if err != nil {
+		log.Warn("reading the travis api cache", "error", err)
+		return "", nil, false
+	}
+	if !ok {
+		return "", nil, false
+	}
+	etag, b, ok := bytes.Cut(v, []byte("\n"))
+	return string(etag), b, ok
+}
+
+// apiCacheKey is the CacheBackend key of the response to a GET of u with
+// token, which only appears hashed
+func apiCacheKey(token, u string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "travis:api:" + hex.EncodeToString(sum[:8]) + ":" + u
+}
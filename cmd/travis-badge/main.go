@@ -0,0 +1,180 @@
+// Command travis-badge writes SVG status badges of a list of repositories
+// to a directory, for teams serving them from a static host or a bucket
+// instead of running a badge server.
+//
+//	travis-badge -out public/badges octocat/hello-world octocat/spoon-knife@develop
+//	travis-badge -out public/badges -repos repos.txt -bolt /var/lib/travis/builds.db
+//
+// Repositories are given as owner/name, with @branch for another branch
+// than -branch, as arguments or one per line in the -repos file. The
+// badges are written to <out>/<owner>/<name>/<branch>.svg, the layout of
+// badge.Handler. The latest builds are read from the Travis API (token in
+// TRAVIS_TOKEN, URL in TRAVIS_API_URL) or from a boltstore file with -bolt.
+// With -output json it prints a JSON array of
+// {"repo": "owner/name", "branch": "main", "status": "passing", "file": "..."}
+// with an "error" instead of the status for the failed repositories.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/badge"
+	"github.com/jacksgt/travis/internal/cli"
+	"github.com/jacksgt/travis/store"
+	"github.com/jacksgt/travis/store/boltstore"
+)
+
+// result is the JSON output of a repository
+type result struct {
+	Repo   string `json:"repo"`
+	Branch string `json:"branch"`
+	Status string `json:"status,omitempty"`
+	File   string `json:"file,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// latestFunc returns the latest build of branch, nil if there is none
+type latestFunc func(ctx context.Context, repo, branch string) (*travis.Payload, error)
+
+func main() {
+	dir := flag.String("out", "badges", "output `directory`")
+	reposFile := flag.String("repos", "", "`file` listing the repositories, one per line")
+	defaultBranch := flag.String("branch", "master", "`branch` of the repositories without @branch")
+	label := flag.String("label", "build", "left part of the badges")
+	boltPath := flag.String("bolt", "", "read the builds from this boltstore `file` instead of the API")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	repos := flag.Args()
+	if *reposFile != "" {
+		lines, err := readLines(*reposFile)
+		if err != nil {
+			fatal(err)
+		}
+		repos = append(repos, lines...)
+	}
+	if len(repos) == 0 {
+		fatal(errors.New("no repositories given"))
+	}
+
+	latest := apiLatest(&travis.Client{BaseURL: os.Getenv("TRAVIS_API_URL"), Token: os.Getenv("TRAVIS_TOKEN")})
+	if *boltPath != "" {
+		s, err := boltstore.Open(*boltPath, nil)
+		if err != nil {
+			fatal(err)
+		}
+		defer s.Close()
+		latest = storeLatest(s)
+	}
+
+	ctx := context.Background()
+	failed := false
+	results := []result{}
+	var rows []string
+	for _, r := range repos {
+		repo, branch, _ := strings.Cut(r, "@")
+		if branch == "" {
+			branch = *defaultBranch
+		}
+		res := result{Repo: repo, Branch: branch, File: filepath.Join(*dir, repo, branch+".svg")}
+		p, err := latest(ctx, repo, branch)
+		if err == nil {
+			err = write(res.File, *label, p)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "travis-badge: %s@%s: %v\n", repo, branch, err)
+			res.File, res.Error, failed = "", err.Error(), true
+		} else {
+			res.Status, _ = badge.Status(p)
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s", repo, branch, res.Status))
+		}
+		results = append(results, res)
+	}
+	out.Print(results, rows...)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// apiLatest returns the latest push, cron or api build of a branch from the API
+func apiLatest(c *travis.Client) latestFunc {
+	return func(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+		builds, _, err := c.Builds(ctx, repo, travis.BuildsOptions{Branch: branch, Limit: 25, SortBy: "id:desc"})
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range builds {
+			if b.EventType != "pull_request" {
+				return b.Payload(), nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// storeLatest returns the latest build of a branch from s
+func storeLatest(s store.Store) latestFunc {
+	return func(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+		p, err := s.Latest(ctx, repo, branch)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil
+		}
+		return p, err
+	}
+}
+
+// write renders the badge of p to path, atomically so a static host never
+// serves a partial file
+func write(path, label string, p *travis.Payload) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".badge-*")
+	if err != nil {
+		return err
+	}
+	message, color := badge.Status(p)
+	if err := badge.Render(f, label, message, color); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-badge:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,113 @@
+// Command travis-bench runs the benchmarks of travistest.BenchmarkSuite on
+// the current machine, to size webhook receivers and to catch performance
+// regressions in CI.
+//
+//	travis-bench
+//	travis-bench -run 'verify|decode' -test.benchtime 2s
+//	travis-bench -output json > baseline.json
+//	travis-bench -baseline baseline.json -max-regression 0.2
+//
+// It prints the time, throughput and allocations per operation of each
+// benchmark. With -baseline, a previous -output json run, it exits with 1
+// if the time or the allocations per operation of a benchmark grew by
+// more than -max-regression. With -output json it prints a JSON array of
+// {"name": "verify/jobs=8", "n": 1000, "ns_per_op": 1, "mb_per_s": 1,
+// "bytes_per_op": 1, "allocs_per_op": 1}.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/jacksgt/travis/internal/cli"
+	"github.com/jacksgt/travis/travistest"
+)
+
+// result is the JSON output of a benchmark
+type result struct {
+	Name        string  `json:"name"`
+	N           int     `json:"n"`
+	NsPerOp     int64   `json:"ns_per_op"`
+	MBPerS      float64 `json:"mb_per_s"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+}
+
+func main() {
+	testing.Init()
+	run := flag.String("run", "", "run the benchmarks matching this `regexp` only")
+	baselinePath := flag.String("baseline", "", "compare with the results of this JSON `file`")
+	maxRegression := flag.Float64("max-regression", 0.1, "growth of ns/op or allocs/op over the baseline failing the run, as a `fraction`")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	match, err := regexp.Compile(*run)
+	if err != nil {
+		fatal(err)
+	}
+	baseline := map[string]result{}
+	if *baselinePath != "" {
+		b, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			fatal(err)
+		}
+		var results []result
+		if err := json.Unmarshal(b, &results); err != nil {
+			fatal(fmt.Errorf("%s: %v", *baselinePath, err))
+		}
+		for _, r := range results {
+			baseline[r.Name] = r
+		}
+	}
+
+	results := []result{}
+	rows := []string{"NAME\tN\tNS/OP\tMB/S\tB/OP\tALLOCS/OP"}
+	regressed := false
+	for _, bm := range travistest.BenchmarkSuite() {
+		if !match.MatchString(bm.Name) {
+			continue
+		}
+		br := testing.Benchmark(bm.F)
+		if br.N == 0 {
+			fatal(fmt.Errorf("%s failed", bm.Name))
+		}
+		r := result{
+			Name:        bm.Name,
+			N:           br.N,
+			NsPerOp:     br.NsPerOp(),
+			BytesPerOp:  br.AllocedBytesPerOp(),
+			AllocsPerOp: br.AllocsPerOp(),
+		}
+		if br.Bytes > 0 && br.T > 0 {
+			r.MBPerS = float64(br.Bytes) * float64(br.N) / 1e6 / br.T.Seconds()
+		}
+		results = append(results, r)
+		rows = append(rows, fmt.Sprintf("%s\t%d\t%d\t%.2f\t%d\t%d", r.Name, r.N, r.NsPerOp, r.MBPerS, r.BytesPerOp, r.AllocsPerOp))
+
+		if base, ok := baseline[r.Name]; ok {
+			if grew(base.NsPerOp, r.NsPerOp, *maxRegression) || grew(base.AllocsPerOp, r.AllocsPerOp, *maxRegression) {
+				fmt.Fprintf(os.Stderr, "travis-bench: %s regressed: %d ns/op, %d allocs/op, baseline %d ns/op, %d allocs/op\n",
+					r.Name, r.NsPerOp, r.AllocsPerOp, base.NsPerOp, base.AllocsPerOp)
+				regressed = true
+			}
+		}
+	}
+	out.Print(results, rows...)
+	if regressed {
+		os.Exit(1)
+	}
+}
+
+// grew returns true if v is over base by more than the fraction max
+func grew(base, v int64, max float64) bool {
+	return base > 0 && float64(v) > float64(base)*(1+max)
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-bench:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,151 @@
+// Command travis-calendar exports the cron schedules and the recent failure
+// windows of repositories as an iCalendar file, for release managers to
+// overlay on their planning calendars.
+//
+//	travis-calendar -out ci.ics octocat/hello-world octocat/spoon-knife
+//	travis-calendar -since 168h -repos repos.txt > ci.ics
+//
+// Repositories are given as owner/name, as arguments or one per line in the
+// -repos file. The crons and the builds of the last -since are read from the
+// Travis API (token in TRAVIS_TOKEN, URL in TRAVIS_API_URL). The calendar is
+// written to -out, stdout by default, and the -output format of the summary
+// goes to stderr then. With -output json it prints a JSON array of
+// {"repo": "owner/name", "crons": 1, "failures": 2} with an "error" instead
+// for the failed repositories.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/ical"
+	"github.com/jacksgt/travis/internal/cli"
+	"github.com/jacksgt/travis/store"
+)
+
+// result is the JSON output of a repository
+type result struct {
+	Repo     string `json:"repo"`
+	Crons    int    `json:"crons"`
+	Failures int    `json:"failures"`
+	Error    string `json:"error,omitempty"`
+}
+
+func main() {
+	outPath := flag.String("out", "", "write the calendar to `file` instead of stdout")
+	reposFile := flag.String("repos", "", "`file` listing the repositories, one per line")
+	since := flag.Duration("since", 30*24*time.Hour, "export the failure windows of this `duration`")
+	name := flag.String("name", "Travis CI", "calendar `name`")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	repos := flag.Args()
+	if *reposFile != "" {
+		lines, err := readLines(*reposFile)
+		if err != nil {
+			fatal(err)
+		}
+		repos = append(repos, lines...)
+	}
+	if len(repos) == 0 {
+		fatal(errors.New("no repositories given"))
+	}
+
+	c := &travis.Client{BaseURL: os.Getenv("TRAVIS_API_URL"), Token: os.Getenv("TRAVIS_TOKEN")}
+	ctx := context.Background()
+	now := time.Now()
+	cal := &ical.Calendar{Name: *name}
+	failed := false
+	results := []result{}
+	var rows []string
+	for _, repo := range repos {
+		res := result{Repo: repo}
+		crons, builds, err := fetch(ctx, c, repo, now.Add(-*since))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "travis-calendar: %s: %v\n", repo, err)
+			res.Error, failed = err.Error(), true
+		} else {
+			cronEvents := ical.CronEvents(repo, crons)
+			failureEvents := ical.FailureEvents(builds, now)
+			cal.Events = append(cal.Events, cronEvents...)
+			cal.Events = append(cal.Events, failureEvents...)
+			res.Crons, res.Failures = len(cronEvents), len(failureEvents)
+			rows = append(rows, fmt.Sprintf("%s\t%d crons\t%d failures", repo, res.Crons, res.Failures))
+		}
+		results = append(results, res)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		w = f
+	} else {
+		out.W = os.Stderr
+	}
+	if err := cal.Write(w); err != nil {
+		fatal(err)
+	}
+	out.Print(results, rows...)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// fetch returns the crons of repo and its builds finished after since
+func fetch(ctx context.Context, c *travis.Client, repo string, since time.Time) ([]*travis.Cron, []*travis.Payload, error) {
+	crons, err := c.Crons(ctx, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+	var builds []*travis.Payload
+	for offset := 0; ; {
+		page, pagination, err := c.Builds(ctx, repo, travis.BuildsOptions{Limit: 100, Offset: offset, SortBy: "id:desc"})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, b := range page {
+			p := b.Payload()
+			if t := store.BuildTime(p); !t.IsZero() && t.Before(since) {
+				return crons, builds, nil
+			}
+			builds = append(builds, p)
+		}
+		if pagination.IsLast || len(page) == 0 {
+			return crons, builds, nil
+		}
+		offset += len(page)
+	}
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-calendar:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,298 @@
+// Command travis-env syncs the environment variables of many repositories
+// from a YAML file, e.g. to rotate a credential shared by every project of
+// an organization.
+//
+//	travis-env -dry-run env.yml
+//	SENTRY_DSN=... travis-env -rotate -prune env.yml
+//
+// The file maps repositories, or owner/glob patterns matched against the
+// repositories of the owner, to variables:
+//
+//	octocat/*:
+//	  SENTRY_DSN: $SENTRY_DSN
+//	  LOG_LEVEL:
+//	    value: info
+//	    public: true
+//	octocat/hello-world:
+//	  DEPLOY_KEY:
+//	    value: $HELLO_DEPLOY_KEY
+//	    branch: main
+//
+// Entries apply in order, later ones overriding the variables of earlier
+// ones, and globs only match the active repositories. $VAR references in
+// values are expanded from the environment, so secrets stay out of the
+// file; an unset variable is an error. Variables missing from a repository
+// are created and public ones with another value updated. The API doesn't
+// return the values of private variables so they are only rewritten with
+// -rotate. Variables absent from the file are deleted with -prune. With
+// -dry-run the changes are printed but not applied.
+//
+// The token is read from TRAVIS_TOKEN and the API URL from TRAVIS_API_URL.
+// With -output json it prints a JSON array of
+// {"repo": "owner/name", "name": "LOG_LEVEL", "branch": "", "action": "update"}
+// with an "error" for the changes that failed. Values are never printed.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/cli"
+)
+
+// Actions of a change
+const (
+	actionCreate = "create"
+	actionUpdate = "update"
+	actionDelete = "delete"
+)
+
+// change is the JSON output of a variable to create, update or delete
+type change struct {
+	Repo   string `json:"repo"`
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+
+	v *travis.EnvVar
+}
+
+// entry is the variables of a repository or pattern of the file
+type entry struct {
+	pattern string
+	vars    []*travis.EnvVar
+}
+
+// varKey identifies a variable of a repository
+type varKey struct{ name, branch string }
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the changes without applying them")
+	rotate := flag.Bool("rotate", false, "rewrite private variables, whose values can't be compared")
+	prune := flag.Bool("prune", false, "delete the variables absent from the file")
+	out := cli.OutputFlag()
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: travis-env [flags] file.yml")
+		os.Exit(2)
+	}
+
+	entries, err := readFile(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	c := &travis.Client{BaseURL: os.Getenv("TRAVIS_API_URL"), Token: os.Getenv("TRAVIS_TOKEN")}
+	ctx := context.Background()
+	desired, err := resolve(ctx, c, entries)
+	if err != nil {
+		fatal(err)
+	}
+
+	repos := make([]string, 0, len(desired))
+	for repo := range desired {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	failed := false
+	changes := []change{}
+	var rows []string
+	for _, repo := range repos {
+		current, err := c.EnvVars(ctx, repo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "travis-env: %s: %v\n", repo, err)
+			failed = true
+			continue
+		}
+		for _, ch := range diff(repo, current, desired[repo], *rotate, *prune) {
+			if !*dryRun {
+				if err := apply(ctx, c, ch); err != nil {
+					fmt.Fprintf(os.Stderr, "travis-env: %s: %s: %v\n", repo, ch.Name, err)
+					ch.Error, failed = err.Error(), true
+				}
+			}
+			changes = append(changes, ch)
+			rows = append(rows, fmt.Sprintf("%s\t%s\t%s\t%s", ch.Action, repo, ch.Name, ch.Branch))
+		}
+	}
+	out.Print(changes, rows...)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// readFile parses the entries of the YAML file at name, in order
+func readFile(name string) ([]entry, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of repositories", name)
+	}
+	var entries []entry
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		pattern, vars := root.Content[i].Value, root.Content[i+1]
+		owner, repo, ok := strings.Cut(pattern, "/")
+		if !ok || owner == "" || repo == "" || strings.ContainsAny(owner, "*?[") {
+			return nil, fmt.Errorf("%s:%d: %q is not owner/name or owner/glob", name, root.Content[i].Line, pattern)
+		}
+		if _, err := path.Match(repo, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %q: %v", name, root.Content[i].Line, pattern, err)
+		}
+		if vars.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s:%d: expected a mapping of variables", name, vars.Line)
+		}
+		e := entry{pattern: pattern}
+		for j := 0; j+1 < len(vars.Content); j += 2 {
+			v, err := parseVar(vars.Content[j].Value, vars.Content[j+1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %s: %v", name, vars.Content[j].Line, vars.Content[j].Value, err)
+			}
+			e.vars = append(e.vars, v)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// parseVar decodes a variable given as a value or as a mapping of value,
+// public and branch, and expands its value from the environment
+func parseVar(name string, n *yaml.Node) (*travis.EnvVar, error) {
+	v := &travis.EnvVar{Name: name}
+	if n.Kind == yaml.ScalarNode {
+		v.Value = n.Value
+	} else {
+		var spec struct {
+			Value  string `yaml:"value"`
+			Public bool   `yaml:"public"`
+			Branch string `yaml:"branch"`
+		}
+		if err := n.Decode(&spec); err != nil {
+			return nil, err
+		}
+		v.Value, v.Public, v.Branch = spec.Value, spec.Public, spec.Branch
+	}
+
+	var missing []string
+	v.Value = os.Expand(v.Value, func(k string) string {
+		s, ok := os.LookupEnv(k)
+		if !ok {
+			missing = append(missing, k)
+		}
+		return s
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return v, nil
+}
+
+// resolve returns the variables of every repository matched by entries,
+// listing the repositories of the owners of glob patterns
+func resolve(ctx context.Context, c *travis.Client, entries []entry) (map[string]map[varKey]*travis.EnvVar, error) {
+	owners := map[string][]*travis.APIRepository{}
+	desired := map[string]map[varKey]*travis.EnvVar{}
+	for _, e := range entries {
+		owner, pattern, _ := strings.Cut(e.pattern, "/")
+		repos := []string{e.pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			list, ok := owners[owner]
+			if !ok {
+				var err error
+				if list, err = c.Repos(ctx, owner); err != nil {
+					return nil, fmt.Errorf("listing the repositories of %s: %w", owner, err)
+				}
+				owners[owner] = list
+			}
+			repos = repos[:0]
+			for _, r := range list {
+				if ok, _ := path.Match(pattern, r.Name); ok && r.Active {
+					repos = append(repos, r.Slug)
+				}
+			}
+		}
+		for _, repo := range repos {
+			vars := desired[repo]
+			if vars == nil {
+				vars = map[varKey]*travis.EnvVar{}
+				desired[repo] = vars
+			}
+			for _, v := range e.vars {
+				vars[varKey{v.Name, v.Branch}] = v
+			}
+		}
+	}
+	if len(desired) == 0 {
+		return nil, errors.New("no repositories matched")
+	}
+	return desired, nil
+}
+
+// diff returns the changes turning the current variables of repo into the
+// desired ones, sorted by name and branch
+func diff(repo string, current []*travis.EnvVar, desired map[varKey]*travis.EnvVar, rotate, prune bool) []change {
+	var changes []change
+	seen := map[varKey]bool{}
+	for _, cur := range current {
+		k := varKey{cur.Name, cur.Branch}
+		seen[k] = true
+		want, ok := desired[k]
+		switch {
+		case !ok && prune:
+			changes = append(changes, change{Repo: repo, Name: cur.Name, Branch: cur.Branch, Action: actionDelete, v: cur})
+		case !ok:
+		case want.Public != cur.Public || (cur.Public && want.Value != cur.Value) || (!cur.Public && rotate):
+			v := *want
+			v.ID = cur.ID
+			changes = append(changes, change{Repo: repo, Name: v.Name, Branch: v.Branch, Action: actionUpdate, v: &v})
+		}
+	}
+	for k, want := range desired {
+		if !seen[k] {
+			changes = append(changes, change{Repo: repo, Name: want.Name, Branch: want.Branch, Action: actionCreate, v: want})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Branch < changes[j].Branch
+	})
+	return changes
+}
+
+func apply(ctx context.Context, c *travis.Client, ch change) error {
+	switch ch.Action {
+	case actionCreate:
+		_, err := c.CreateEnvVar(ctx, ch.Repo, ch.v)
+		return err
+	case actionUpdate:
+		return c.UpdateEnvVar(ctx, ch.Repo, ch.v)
+	default:
+		return c.DeleteEnvVar(ctx, ch.Repo, ch.v.ID)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-env:", err)
+	os.Exit(2)
+}
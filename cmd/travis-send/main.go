@@ -0,0 +1,158 @@
+// Command travis-send posts a signed test webhook to a receiver, for end to
+// end smoke tests.
+//
+//	travis-send -url https://ci-hooks.example.com/travis -key private.pem \
+//		-repo octocat/hello-world -branch main -state broken -type push
+//
+// The receiver must verify webhooks with the public key of -key, e.g. by
+// serving it on its config URL, with the shared HMAC secret of -secret, or
+// not verify them at all with -insecure.
+// It exits with 1 if the receiver doesn't answer with a 2xx status. With
+// -output json it prints {"status": 204, "body": "..."}.
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/cli"
+	"github.com/jacksgt/travis/travistest"
+)
+
+// result is the JSON output
+type result struct {
+	Status int    `json:"status"`
+	Body   string `json:"body,omitempty"`
+}
+
+func main() {
+	target := flag.String("url", "", "`URL` of the receiver")
+	keyFile := flag.String("key", "", "PEM `file` of the RSA private key signing the payload")
+	secret := flag.String("secret", "", "shared `secret` signing the payload with HMAC-SHA256 instead of -key")
+	header := flag.String("header", travis.DefaultHMACHeader, "`header` of the HMAC signature")
+	insecure := flag.Bool("insecure", false, "send the webhook without a valid signature")
+	payloadFile := flag.String("payload", "", "`file` holding a raw payload to send instead of building one")
+	repo := flag.String("repo", "octocat/hello-world", "repository `slug`")
+	branch := flag.String("branch", "master", "`branch` of the build")
+	state := flag.String("state", "passed", "status of the build: pending, passed, fixed, broken, failed, still-failing, canceled or errored")
+	event := flag.String("type", "push", "build type: push, pull_request, cron or api")
+	pr := flag.Int("pr", 0, "pull request `number`, implies -type pull_request")
+	id := flag.Int64("id", time.Now().Unix(), "build `id`")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	if *target == "" {
+		fatal(errors.New("-url is required"))
+	}
+	if *keyFile == "" && *secret == "" && !*insecure {
+		fatal(errors.New("-key, -secret or -insecure is required"))
+	}
+
+	var payload string
+	if *payloadFile != "" {
+		b, err := os.ReadFile(*payloadFile)
+		if err != nil {
+			fatal(err)
+		}
+		payload = string(b)
+	} else {
+		status, err := parseStatus(*state)
+		if err != nil {
+			fatal(err)
+		}
+		b := travistest.NewPayload().ID(*id).Repo(*repo).Branch(*branch).Event(*event).State(status)
+		if *pr > 0 {
+			b.PR(*pr)
+		}
+		body, err := json.Marshal(b.Build())
+		if err != nil {
+			fatal(err)
+		}
+		payload = string(body)
+	}
+
+	var key *rsa.PrivateKey
+	if !*insecure && *secret == "" {
+		var err error
+		if key, err = readKey(*keyFile); err != nil {
+			fatal(err)
+		}
+	}
+	req, err := travis.NewWebhookRequest(*target, []byte(payload), key)
+	if err != nil {
+		fatal(err)
+	}
+	if *secret != "" && !*insecure {
+		s := &travis.HMAC{Secret: []byte(*secret), Header: *header}
+		s.SignRequest(req, []byte(payload))
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	res := result{Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	rows := []string{resp.Status}
+	if res.Body != "" {
+		rows = append(rows, res.Body)
+	}
+	out.Print(res, rows...)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		os.Exit(1)
+	}
+}
+
+// parseStatus accepts the status messages in any case, with dashes or
+// underscores for spaces
+func parseStatus(s string) (travistest.Status, error) {
+	s = strings.NewReplacer("-", " ", "_", " ").Replace(s)
+	for _, status := range travistest.Statuses {
+		if strings.EqualFold(string(status), s) {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("unknown state %q", s)
+}
+
+// readKey reads a PKCS #1 or PKCS #8 RSA private key
+func readKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA key", path)
+	}
+	return rsaKey, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-send:", err)
+	os.Exit(2)
+}
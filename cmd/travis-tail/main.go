@@ -0,0 +1,150 @@
+// Command travis-tail follows a build from the terminal, printing the state
+// changes of its jobs, and exits with 0 if it passed and 1 otherwise, so it
+// can gate scripts on Travis builds.
+//
+//	travis-tail https://app.travis-ci.com/github/octocat/hello-world/builds/123456
+//	travis-tail -repo octocat/hello-world -number 42 -log
+//
+// With -log the log of the jobs that fail is printed. The API token is read
+// from TRAVIS_TOKEN and the API URL from TRAVIS_API_URL, travis-ci.com by
+// default.
+//
+// With -output json it prints a JSON object per line: a job event
+// {"type": "job", "time": "...", "job_id": 1, "number": "42.1", "state":
+// "failed", "log": "..."} for every state change, the log only with -log,
+// then {"type": "build", "build_id": 2, "number": "42", "state": "failed"}.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/cli"
+)
+
+// event is a line of the JSON output
+type event struct {
+	Type    string     `json:"type"`
+	Time    *time.Time `json:"time,omitempty"`
+	JobID   int64      `json:"job_id,omitempty"`
+	BuildID int64      `json:"build_id,omitempty"`
+	Number  string     `json:"number"`
+	State   string     `json:"state"`
+	Log     string     `json:"log,omitempty"`
+}
+
+var buildURL = regexp.MustCompile(`/builds/(\d+)`)
+
+func main() {
+	repo := flag.String("repo", "", "repository `slug` of the build, with -number")
+	number := flag.String("number", "", "build `number` in the repository")
+	interval := flag.Duration("interval", 10*time.Second, "polling `interval`")
+	timeout := flag.Duration("timeout", 0, "give up after `duration`, never if zero")
+	showLog := flag.Bool("log", false, "print the log of failed jobs")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	c := &travis.Client{BaseURL: os.Getenv("TRAVIS_API_URL"), Token: os.Getenv("TRAVIS_TOKEN")}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	id, err := buildID(ctx, c, flag.Arg(0), *repo, *number)
+	if err != nil {
+		fatal(err)
+	}
+	b, err := tail(ctx, c, out, id, *interval, *showLog)
+	if err != nil {
+		fatal(err)
+	}
+	out.Print(event{Type: "build", BuildID: b.ID, Number: b.Number, State: b.State},
+		fmt.Sprintf("build #%s %s", b.Number, b.State))
+	if b.State != "passed" {
+		os.Exit(1)
+	}
+}
+
+// buildID finds the build of a URL, or of a number in repo
+func buildID(ctx context.Context, c *travis.Client, url, repo, number string) (int64, error) {
+	if url != "" {
+		m := buildURL.FindStringSubmatch(url)
+		if m == nil {
+			return 0, fmt.Errorf("no build id in %s", url)
+		}
+		return strconv.ParseInt(m[1], 10, 64)
+	}
+	if repo == "" || number == "" {
+		return 0, errors.New("a build URL or -repo and -number are required")
+	}
+	const pageSize, maxPages = 100, 10
+	for page := 0; page < maxPages; page++ {
+		builds, p, err := c.Builds(ctx, repo, travis.BuildsOptions{Limit: pageSize, Offset: page * pageSize, SortBy: "id:desc"})
+		if err != nil {
+			return 0, err
+		}
+		for _, b := range builds {
+			if b.Number == number {
+				return b.ID, nil
+			}
+		}
+		if p.IsLast {
+			break
+		}
+	}
+	return 0, fmt.Errorf("build %s of %s not found in the latest %d builds", number, repo, pageSize*maxPages)
+}
+
+// tail polls the build until it is finished, printing job state changes
+func tail(ctx context.Context, c *travis.Client, out *cli.Output, id int64, interval time.Duration, showLog bool) (*travis.Build, error) {
+	states := make(map[int64]string)
+	for {
+		b, err := c.Build(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		jobs, err := c.Jobs(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range jobs {
+			if states[j.ID] == j.State {
+				continue
+			}
+			states[j.ID] = j.State
+			now := time.Now()
+			e := event{Type: "job", Time: &now, JobID: j.ID, Number: j.Number, State: j.State}
+			if showLog && (j.State == "failed" || j.State == "errored") {
+				if e.Log, err = c.Log(ctx, j.ID); err != nil {
+					return nil, err
+				}
+			}
+			out.Print(e, fmt.Sprintf("%s job %s %s", now.Format("15:04:05"), j.Number, j.State))
+			if e.Log != "" {
+				out.Text(e.Log)
+			}
+		}
+		if b.Finished() {
+			return b, nil
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-tail:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,138 @@
+// Command travis-trigger requests a build of a repository and waits for its
+// result, to chain Travis builds in cross-repository pipelines.
+//
+//	travis-trigger -branch main -message "Deploy from api#1234" \
+//		-config-override '{"env": {"global": ["DEPLOY=1"]}}' -timeout 30m octocat/hello-world
+//
+// The API token is read from TRAVIS_TOKEN and the API URL from
+// TRAVIS_API_URL, travis-ci.com by default. The exit code is the outcome:
+//
+//	0 passed
+//	1 failed
+//	2 usage or API error
+//	3 errored
+//	4 canceled
+//	5 request rejected
+//	6 timeout
+//
+// With -output json it prints {"request_id": 1, "build_id": 2, "number":
+// "42", "state": "passed"}, without build fields if -no-wait is set.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/cli"
+)
+
+// result is the JSON output
+type result struct {
+	RequestID int64  `json:"request_id"`
+	BuildID   int64  `json:"build_id,omitempty"`
+	Number    string `json:"number,omitempty"`
+	State     string `json:"state,omitempty"`
+}
+
+func main() {
+	branch := flag.String("branch", "", "`branch` to build, the default branch if empty")
+	message := flag.String("message", "", "build `message`")
+	override := flag.String("config-override", "", "JSON `config` merged with .travis.yml, or @file")
+	mergeMode := flag.String("merge-mode", "", "how the config is merged: deep_merge, merge or replace")
+	timeout := flag.Duration("timeout", time.Hour, "give up after `duration`")
+	interval := flag.Duration("interval", 15*time.Second, "polling `interval`")
+	noWait := flag.Bool("no-wait", false, "exit once the build is requested")
+	out := cli.OutputFlag()
+	flag.Parse()
+	if flag.NArg() != 1 {
+		exit(errors.New("usage: travis-trigger [flags] owner/name"))
+	}
+	slug := flag.Arg(0)
+
+	opts := travis.TriggerOptions{Branch: *branch, Message: *message, MergeMode: *mergeMode}
+	if *override != "" {
+		config, err := readConfig(*override)
+		if err != nil {
+			exit(err)
+		}
+		opts.Config = config
+	}
+
+	c := &travis.Client{BaseURL: os.Getenv("TRAVIS_API_URL"), Token: os.Getenv("TRAVIS_TOKEN")}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	req, err := c.TriggerBuild(ctx, slug, opts)
+	if err != nil {
+		exit(err)
+	}
+	res := result{RequestID: req.ID}
+	if *noWait {
+		out.Print(res, fmt.Sprintf("requested build %d of %s", req.ID, slug))
+		return
+	}
+	out.Text(fmt.Sprintf("requested build %d of %s", req.ID, slug))
+	id, err := c.WaitForRequest(ctx, slug, req.ID, *interval)
+	if err != nil {
+		exit(err)
+	}
+	out.Text(fmt.Sprintf("build %d started", id))
+	b, err := c.WaitForBuild(ctx, id, *interval)
+	if err != nil {
+		exit(err)
+	}
+	res.BuildID, res.Number, res.State = b.ID, b.Number, b.State
+	out.Print(res, fmt.Sprintf("build #%s %s", b.Number, b.State))
+	os.Exit(stateCode(b.State))
+}
+
+// readConfig parses a JSON object given inline or as @file
+func readConfig(s string) (map[string]interface{}, error) {
+	b := []byte(s)
+	if strings.HasPrefix(s, "@") {
+		var err error
+		if b, err = os.ReadFile(s[1:]); err != nil {
+			return nil, err
+		}
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("-config-override: %v", err)
+	}
+	return config, nil
+}
+
+// stateCode maps the state of a finished build to the exit code
+func stateCode(state string) int {
+	switch state {
+	case "passed":
+		return 0
+	case "failed":
+		return 1
+	case "errored":
+		return 3
+	case "canceled":
+		return 4
+	}
+	return 2
+}
+
+// exit reports err with the exit code of its kind
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, "travis-trigger:", err)
+	var rejected *travis.RequestRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		os.Exit(5)
+	case errors.Is(err, context.DeadlineExceeded):
+		os.Exit(6)
+	}
+	os.Exit(2)
+}
@@ -0,0 +1,124 @@
+// Command travis-verify checks whether a captured webhook was signed by
+// Travis, e.g. when a receiver is suspected to have acted on forged
+// webhooks.
+//
+//	travis-verify -payload payload.json -signature "$(cat signature.b64)"
+//	travis-verify -request request.http -key com
+//
+// The payload file holds the raw payload form value, the request file a
+// raw HTTP request as received by the receiver. The key is "org" (the
+// default), "com", the URL of a config endpoint or a PEM file. It exits
+// with 0 if the signature is valid, 1 if it isn't and 2 on other errors.
+// With -output json it prints {"valid": false, "error": "..."}.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rsa"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/cli"
+)
+
+// result is the JSON output
+type result struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+func main() {
+	payloadFile := flag.String("payload", "", "`file` holding the raw payload")
+	signature := flag.String("signature", "", "base64 `signature` of the payload")
+	signatureFile := flag.String("signature-file", "", "`file` holding the signature")
+	requestFile := flag.String("request", "", "`file` holding a raw HTTP webhook request")
+	keyFlag := flag.String("key", "org", "public key: org, com, a config endpoint `URL` or a PEM file")
+	out := cli.OutputFlag()
+	flag.Parse()
+
+	payload, sig, err := load(*payloadFile, *signature, *signatureFile, *requestFile)
+	if err != nil {
+		fatal(err)
+	}
+	key, err := publicKey(*keyFlag)
+	if err != nil {
+		fatal(err)
+	}
+
+	if err := travis.VerifySignature(payload, sig, key); err != nil {
+		out.Print(result{Error: err.Error()}, "invalid: "+err.Error())
+		os.Exit(1)
+	}
+	out.Print(result{Valid: true}, "valid")
+}
+
+// load returns the payload and signature to verify
+func load(payloadFile, signature, signatureFile, requestFile string) (string, string, error) {
+	if requestFile != "" {
+		f, err := os.Open(requestFile)
+		if err != nil {
+			return "", "", err
+		}
+		defer f.Close()
+		r, err := http.ReadRequest(bufio.NewReader(f))
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %v", requestFile, err)
+		}
+		if err := r.ParseForm(); err != nil {
+			return "", "", fmt.Errorf("%s: %v", requestFile, err)
+		}
+		return r.PostForm.Get("payload"), r.Header.Get("Signature"), nil
+	}
+
+	if payloadFile == "" {
+		return "", "", errors.New("-payload or -request is required")
+	}
+	payload, err := os.ReadFile(payloadFile)
+	if err != nil {
+		return "", "", err
+	}
+	if signatureFile != "" {
+		b, err := os.ReadFile(signatureFile)
+		if err != nil {
+			return "", "", err
+		}
+		signature = string(b)
+	}
+	if signature == "" {
+		return "", "", errors.New("-signature or -signature-file is required with -payload")
+	}
+	return string(payload), strings.TrimSpace(signature), nil
+}
+
+// publicKey returns the key named by flag
+func publicKey(flag string) (*rsa.PublicKey, error) {
+	url := flag
+	switch flag {
+	case "org":
+		url = travis.DefaultConfigURL
+	case "com":
+		url = travis.ComConfigURL
+	}
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return travis.FetchPublicKey(ctx, nil, url)
+	}
+	pem, err := os.ReadFile(flag)
+	if err != nil {
+		return nil, err
+	}
+	return travis.ParsePublicKey(string(pem))
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "travis-verify:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,174 @@
+// Command travis-webhookd is a ready-to-run receiver of Travis webhooks. It
+// verifies the webhooks and posts a message about each build to the
+// configured targets, e.g. Slack incoming webhooks.
+//
+//	travis-webhookd -config /etc/travis-webhookd.yml
+//
+// The configuration is a YAML or JSON file in the schema of the
+// webhookconfig package, whose fields can be overridden with
+// TRAVIS_WEBHOOKD_* environment variables:
+//
+//	addr: ":8080"
+//	grpc_addr: ":9090"
+//	path: /travis
+//	config_url: https://api.travis-ci.com/config
+//	key_ttl: 1h
+//	workers: 4
+//	targets:
+//	  - url: https://hooks.slack.com/services/...
+//	    outcomes: [failed, errored]
+//	  - url: https://deploy.example.com/hook
+//	    format: payload
+//	    branches: [main]
+//	store:
+//	  driver: bolt
+//	  dsn: /var/lib/travis-webhookd/builds.db
+//
+// With grpc_addr, the builds are also streamed to the subscribers of the
+// travispb.Builds gRPC service. With a store, every build is saved to it;
+// the bolt driver is built in.
+//
+// The targets and routes are reloaded from the file on SIGHUP and, with
+// reload_interval, whenever it changes. The other keys need a restart.
+//
+// The daemon logs to stderr, serves its health on /healthz and shuts down
+// gracefully on SIGINT and SIGTERM.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+	"github.com/jacksgt/travis/travispb"
+	"github.com/jacksgt/travis/webhookconfig"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("TRAVIS_WEBHOOKD_CONFIG"), "configuration `file`")
+	flag.Parse()
+
+	c, err := webhookconfig.Load(*configPath)
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(2)
+	}
+	log, err := c.Logger(os.Stderr)
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(2)
+	}
+	if err := run(*configPath, c, log); err != nil {
+		log.Error("travis-webhookd failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string, c *webhookconfig.Config, log *slog.Logger) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	router := webhookconfig.NewRouter(c, client)
+	sinks := []travis.Sink{router}
+	st, err := c.OpenStore(context.Background())
+	if err != nil {
+		return err
+	}
+	if st != nil {
+		defer st.Close()
+		sinks = append(sinks, store.Sink(st))
+	}
+	var grpcServer *grpc.Server
+	if c.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", c.GRPCAddr)
+		if err != nil {
+			return err
+		}
+		builds := &travispb.Server{}
+		sinks = append(sinks, builds)
+		grpcServer = grpc.NewServer()
+		travispb.RegisterBuildsServer(grpcServer, builds)
+		go func() {
+			log.Info("serving gRPC", "addr", c.GRPCAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Error("gRPC server failed", "error", err)
+			}
+		}()
+	}
+	h := c.Handler(sinks, client, log)
+
+	mux := http.NewServeMux()
+	mux.Handle(c.Path, h)
+	mux.Handle("/healthz", h.HealthHandler())
+	srv := &http.Server{
+		Addr:              c.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if path != "" {
+		reload := func(nc *webhookconfig.Config, err error) {
+			if err != nil {
+				log.Error("reloading configuration failed", "error", err)
+				return
+			}
+			if keys := webhookconfig.RestartRequired(router.Config(), nc); len(keys) > 0 {
+				log.Warn("configuration changes need a restart", "keys", keys)
+			}
+			if err := router.Reload(ctx, nc); err != nil {
+				log.Error("reloading configuration failed", "error", err)
+				return
+			}
+			log.Info("reloaded configuration", "targets", len(nc.Targets), "routes", len(nc.Routes))
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		go func() {
+			for {
+				select {
+				case <-hup:
+					reload(webhookconfig.Load(path))
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		if c.ReloadInterval > 0 {
+			go webhookconfig.Watch(ctx, path, time.Duration(c.ReloadInterval), reload)
+		}
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Info("listening", "addr", c.Addr, "path", c.Path, "targets", len(c.Targets))
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(c.ShutdownTimeout))
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	h.Dispatcher.Close()
+	return err
+}
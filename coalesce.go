@@ -0,0 +1,211 @@
+package travis
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCoalesceWindow is how long a Coalescer holds messages if Window
+// isn't set
+const DefaultCoalesceWindow = 2 * time.Second
+
+// Coalescer is a Notifier grouping the messages of each target, e.g. a chat
+// channel, sent within Window into a single message, so that the jobs of a
+// large matrix finishing together don't hit the rate limits of the chat.
+// Messages are sent in the background: Notify only queues them, and Close
+// sends the ones still queued.
+type Coalescer struct {
+	// Notifier sends the messages of the targets missing from Targets
+	Notifier Notifier
+	// Targets are the notifiers of the targets returned by Target
+	Targets map[string]Notifier
+	// Target returns the target of m, every message has the same target if
+	// nil. Messages of different targets are never combined.
+	Target func(m *Message) string
+
+	// Window is how long messages are held to be combined,
+	// DefaultCoalesceWindow if zero
+	Window time.Duration
+	// Interval is the minimum time between two messages sent to a target,
+	// messages held longer are combined with the following ones
+	Interval time.Duration
+	// Combine merges the messages of a target, CombineMessages if nil
+	Combine func(ms []*Message) *Message
+
+	// OnError, if set, is called with the errors of sending messages,
+	// which can't be returned by Notify
+	OnError func(target string, err error)
+
+	// Clock ticks the windows, SystemClock if nil
+	Clock Clock
+
+	once    sync.Once
+	mu      sync.Mutex
+	targets map[string]*coalesced
+	closed  bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type coalesced struct {
+	pending []*Message
+	last    time.Time
+}
+
+func (c *Coalescer) init() {
+	c.once.Do(func() {
+		window := c.Window
+		if window <= 0 {
+			window = DefaultCoalesceWindow
+		}
+		c.targets = make(map[string]*coalesced)
+		c.stop = make(chan struct{})
+		c.done = make(chan struct{})
+		go c.run(clockOr(c.Clock).NewTicker(window))
+	})
+}
+
+// Notify queues m to be sent with the other messages of its target at the
+// end of the window
+func (c *Coalescer) Notify(m *Message) error {
+	c.init()
+	target := ""
+	if c.Target != nil {
+		target = c.Target(m)
+	}
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return c.send(target, []*Message{m})
+	}
+	t := c.targets[target]
+	if t == nil {
+		t = new(coalesced)
+		c.targets[target] = t
+	}
+	t.pending = append(t.pending, m)
+	c.mu.Unlock()
+	return nil
+}
+
+// Pending returns the number of messages waiting to be sent
+func (c *Coalescer) Pending() int {
+	c.init()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.targets {
+		n += len(t.pending)
+	}
+	return n
+}
+
+// Flush sends the queued messages of the targets whose Interval allows it,
+// it is called at the end of every window
+func (c *Coalescer) Flush() error {
+	return c.flush(false)
+}
+
+// Close stops the windows and sends every queued message, ignoring
+// Interval. Messages notified afterwards are sent right away.
+func (c *Coalescer) Close() error {
+	c.init()
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	close(c.stop)
+	<-c.done
+	return c.flush(true)
+}
+
+func (c *Coalescer) run(ticker Ticker) {
+	defer close(c.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// flush sends the queued messages, of every target if all, and returns the
+// first error
+func (c *Coalescer) flush(all bool) error {
+	c.init()
+	now := clockOr(c.Clock).Now()
+	batches := make(map[string][]*Message)
+	c.mu.Lock()
+	for target, t := range c.targets {
+		if len(t.pending) == 0 || !all && c.Interval > 0 && now.Sub(t.last) < c.Interval {
+			continue
+		}
+		batches[target] = t.pending
+		t.pending, t.last = nil, now
+	}
+	c.mu.Unlock()
+
+	var first error
+	for target, ms := range batches {
+		if err := c.send(target, ms); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// send combines ms and sends them to the notifier of target
+func (c *Coalescer) send(target string, ms []*Message) error {
+	n := c.Targets[target]
+	if n == nil {
+		n = c.Notifier
+	}
+	m := ms[0]
+	if len(ms) > 1 {
+		combine := c.Combine
+		if combine == nil {
+			combine = CombineMessages
+		}
+		m = combine(ms)
+	}
+	var err error
+	if n == nil {
+		err = fmt.Errorf("no notifier for target %q", target)
+	} else {
+		err = n.Notify(m)
+	}
+	if err != nil && c.OnError != nil {
+		c.OnError(target, err)
+	}
+	return err
+}
+
+// CombineMessages merges ms into one message titled after the first one,
+// with the title and text of each message in its text
+func CombineMessages(ms []*Message) *Message {
+	if len(ms) == 1 {
+		return ms[0]
+	}
+	var text strings.Builder
+	for i, m := range ms {
+		if i > 0 {
+			text.WriteString("\n\n")
+		}
+		text.WriteString(m.Title)
+		if m.Text != "" {
+			text.WriteString("\n" + m.Text)
+		}
+	}
+	return &Message{
+		Title: fmt.Sprintf("%s (and %d more)", ms[0].Title, len(ms)-1),
+		Text:  text.String(),
+	}
+}
@@ -0,0 +1,301 @@
+package travis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VersionKeys are the keys of a config expanding the build matrix besides
+// os, arch and env, stored in Config.Versions
+var VersionKeys = []string{
+	"compiler", "crystal", "d", "dart", "dotnet", "elixir", "gemfile", "ghc",
+	"go", "haxe", "jdk", "julia", "mono", "node_js", "otp_release",
+	"osx_image", "perl", "perl6", "php", "python", "r", "rust", "rvm",
+	"scala", "smalltalk", "xcode_scheme", "xcode_sdk",
+}
+
+// Strings is a config value given as a single value or a list. Numbers
+// and booleans are kept as written, e.g. go: 1.10 is "1.10".
+type Strings []string
+
+// UnmarshalJSON decodes a string, number, boolean or list of them
+func (s *Strings) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		var raw []json.RawMessage
+		if err := decodeJSON(b, &raw); err != nil {
+			return err
+		}
+		*s = make(Strings, 0, len(raw))
+		for _, r := range raw {
+			v, err := scalar(r)
+			if err != nil {
+				return err
+			}
+			*s = append(*s, v)
+		}
+		return nil
+	}
+	if string(b) == "null" {
+		*s = nil
+		return nil
+	}
+	v, err := scalar(b)
+	*s = Strings{v}
+	return err
+}
+
+// scalar returns the text of a JSON string, number or boolean
+func scalar(b json.RawMessage) (string, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("expected a string, got %s", b)
+}
+
+// Env is the env key of a config. Every entry of Jobs, e.g. "DB=pg V=1",
+// is the variables of one job and Global the variables of all jobs.
+// Encrypted entries are kept as "secure:<ciphertext>".
+type Env struct {
+	Global []string `json:"global,omitempty"`
+	Jobs   []string `json:"jobs,omitempty"`
+}
+
+// UnmarshalJSON decodes an env given as a string, a list of jobs or a
+// mapping of global and jobs (or matrix) entries
+func (e *Env) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		var m map[string]json.RawMessage
+		if err := decodeJSON(b, &m); err != nil {
+			return err
+		}
+		global, hasGlobal := m["global"]
+		jobs, hasJobs := m["jobs"]
+		if !hasJobs {
+			jobs, hasJobs = m["matrix"]
+		}
+		if !hasGlobal && !hasJobs {
+			// a mapping of variables is a single job
+			v, err := envEntry(b)
+			e.Jobs = []string{v}
+			return err
+		}
+		var err error
+		if e.Global, err = envEntries(global); err != nil {
+			return err
+		}
+		e.Jobs, err = envEntries(jobs)
+		return err
+	}
+	var err error
+	e.Jobs, err = envEntries(b)
+	return err
+}
+
+// envEntries decodes an entry or a list of entries
+func envEntries(b json.RawMessage) ([]string, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	if b[0] != '[' {
+		v, err := envEntry(b)
+		return []string{v}, err
+	}
+	var raw []json.RawMessage
+	if err := decodeJSON(b, &raw); err != nil {
+		return nil, err
+	}
+	entries := make([]string, 0, len(raw))
+	for _, r := range raw {
+		v, err := envEntry(r)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, v)
+	}
+	return entries, nil
+}
+
+// envEntry decodes "A=1 B=2", {"A": 1, "B": 2} or {"secure": "..."}
+func envEntry(b json.RawMessage) (string, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || b[0] != '{' {
+		return scalar(b)
+	}
+	var m map[string]json.RawMessage
+	if err := decodeJSON(b, &m); err != nil {
+		return "", err
+	}
+	if secure, ok := m["secure"]; ok && len(m) == 1 {
+		v, err := scalar(secure)
+		return "secure:" + v, err
+	}
+	vars := make([]string, 0, len(m))
+	for k, raw := range m {
+		v, err := scalar(raw)
+		if err != nil {
+			return "", err
+		}
+		vars = append(vars, k+"="+v)
+	}
+	sort.Strings(vars)
+	return strings.Join(vars, " "), nil
+}
+
+// Cache is the cache key of a config
+type Cache struct {
+	// Directories are the cached directories
+	Directories []string `json:"directories,omitempty"`
+	// Tools are the caches Travis sets up, e.g. bundler, pip or npm
+	Tools []string `json:"tools,omitempty"`
+}
+
+// UnmarshalJSON decodes a cache given as false, a tool, a list of tools
+// and directories mappings, or a mapping of directories and of tools set
+// to true
+func (c *Cache) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) > 0 && b[0] == '[':
+		var raw []json.RawMessage
+		if err := decodeJSON(b, &raw); err != nil {
+			return err
+		}
+		for _, r := range raw {
+			if err := c.UnmarshalJSON(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case len(b) > 0 && b[0] == '{':
+		var m map[string]json.RawMessage
+		if err := decodeJSON(b, &m); err != nil {
+			return err
+		}
+		for k, raw := range m {
+			switch k {
+			case "directories", "tools":
+				var dirs Strings
+				if err := decodeJSON(raw, &dirs); err != nil {
+					return fmt.Errorf("%s: %w", k, err)
+				}
+				if k == "tools" {
+					c.Tools = append(c.Tools, dirs...)
+				} else {
+					c.Directories = append(c.Directories, dirs...)
+				}
+			default:
+				if string(bytes.TrimSpace(raw)) == "true" {
+					c.Tools = append(c.Tools, k)
+				}
+			}
+		}
+		sort.Strings(c.Tools)
+		return nil
+	}
+	v, err := scalar(b)
+	if err != nil || v == "false" || v == "" {
+		return err
+	}
+	c.Tools = append(c.Tools, v)
+	return nil
+}
+
+// Jobs is the jobs key of a config, adding, removing and allowing the
+// failure of jobs of the build matrix
+type Jobs struct {
+	Include       []*Config `json:"include,omitempty"`
+	Exclude       []*Config `json:"exclude,omitempty"`
+	AllowFailures []*Config `json:"allow_failures,omitempty"`
+	FastFinish    bool      `json:"fast_finish,omitempty"`
+}
+
+// UnmarshalJSON decodes the jobs mapping or a list of included jobs
+func (j *Jobs) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		return decodeJSON(b, &j.Include)
+	}
+	type plain Jobs
+	return decodeJSON(b, (*plain)(j))
+}
+
+// UnmarshalJSON decodes a config, accepting sudo as a boolean or a string
+// such as "required", the matrix key for jobs and the keys of VersionKeys
+func (c *Config) UnmarshalJSON(b []byte) error {
+	type plain Config
+	aux := struct {
+		*plain
+		Sudo   json.RawMessage `json:"sudo,omitempty"`
+		Matrix *Jobs           `json:"matrix,omitempty"`
+	}{plain: (*plain)(c)}
+	if err := decodeJSON(b, &aux); err != nil {
+		return err
+	}
+	if len(aux.Sudo) > 0 {
+		sudo, err := scalar(aux.Sudo)
+		if err != nil && string(aux.Sudo) != "null" {
+			return fmt.Errorf("sudo: %w", err)
+		}
+		c.Sudo = sudo == "true" || sudo == "required" || sudo == "enabled"
+	}
+	if c.Jobs == nil {
+		c.Jobs = aux.Matrix
+	}
+
+	var keys map[string]json.RawMessage
+	if err := decodeJSON(b, &keys); err != nil {
+		return err
+	}
+	c.Versions = nil
+	for _, k := range VersionKeys {
+		raw, ok := keys[k]
+		if !ok {
+			continue
+		}
+		var v Strings
+		if err := decodeJSON(raw, &v); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		if c.Versions == nil {
+			c.Versions = make(map[string]Strings)
+		}
+		c.Versions[k] = v
+	}
+	return nil
+}
+
+// MarshalJSON encodes a config with its Versions as keys
+func (c Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	b, err := json.Marshal(plain(c))
+	if err != nil || len(c.Versions) == 0 {
+		return b, err
+	}
+	var keys map[string]json.RawMessage
+	if err := decodeJSON(b, &keys); err != nil {
+		return nil, err
+	}
+	for k, v := range c.Versions {
+		if keys[k], err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(keys)
+}
@@ -0,0 +1,187 @@
+// Package config parses .travis.yml files into the travis.Config of
+// payloads, for tools inspecting build configurations without the Travis
+// toolchain.
+//
+//	c, err := config.ParseFile(".travis.yml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	fmt.Println(c.Language, c.OS, c.Versions["go"], c.Env.Jobs)
+//
+// Anchors, aliases and merge keys are resolved, and yes, no, on and off
+// values are booleans as for Travis, which reads YAML 1.1.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+)
+
+// Parse parses the contents of a .travis.yml file
+func Parse(data []byte) (*travis.Config, error) {
+	tree, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return fromTree(tree)
+}
+
+// fromTree decodes a tree returned by Decode
+func fromTree(tree map[string]interface{}) (*travis.Config, error) {
+	b, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	c := new(travis.Config)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return c, nil
+}
+
+// ParseFile parses the .travis.yml file name
+func ParseFile(name string) (*travis.Config, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Decode parses a .travis.yml file into a tree of map[string]interface{},
+// []interface{}, string, json.Number, bool and nil values, with the
+// aliases and merge keys resolved. An empty file is an empty map.
+func Decode(data []byte) (map[string]interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	v, err := value(doc.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected a mapping at the top level")
+	}
+	return m, nil
+}
+
+// value converts n and its children
+func value(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return value(n.Alias)
+	case yaml.MappingNode:
+		return mapping(n)
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := value(c)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case yaml.ScalarNode:
+		return scalar(n), nil
+	}
+	return nil, fmt.Errorf("line %d: unexpected YAML node", n.Line)
+}
+
+// mapping converts a mapping, its explicit keys taking precedence over the
+// ones merged with <<
+func mapping(n *yaml.Node) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(n.Content)/2)
+	merged := map[string]interface{}{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Kind == yaml.ScalarNode && k.ShortTag() == "!!merge" {
+			if err := merge(merged, v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		conv, err := value(v)
+		if err != nil {
+			return nil, err
+		}
+		// keys are kept as written, so deploy's on is not a boolean
+		m[k.Value] = conv
+	}
+	for k, v := range merged {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+// merge adds the keys of the mapping, or of the list of mappings, n to
+// dst, earlier mappings taking precedence
+func merge(dst map[string]interface{}, n *yaml.Node) error {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	sources := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		sources = n.Content
+	}
+	for _, s := range sources {
+		v, err := value(s)
+		if err != nil {
+			return err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("line %d: << expects a mapping", s.Line)
+		}
+		for k, v := range m {
+			if _, ok := dst[k]; !ok {
+				dst[k] = v
+			}
+		}
+	}
+	return nil
+}
+
+// scalar converts a scalar, keeping numbers as written
+func scalar(n *yaml.Node) interface{} {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool":
+		return strings.EqualFold(n.Value, "true")
+	case "!!int", "!!float":
+		// json rejects forms such as 0x1F or .inf, keep those as strings
+		if json.Valid([]byte(n.Value)) {
+			return json.Number(n.Value)
+		}
+		return n.Value
+	case "!!str":
+		if n.Style == 0 {
+			switch strings.ToLower(n.Value) {
+			case "yes", "on":
+				return true
+			case "no", "off":
+				return false
+			}
+		}
+	}
+	return n.Value
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/jacksgt/travis"
+)
+
+// Kinds of a Change
+const (
+	// Added values are in the config of the build only
+	Added = "added"
+	// Removed values are in the file only
+	Removed = "removed"
+	// Changed values differ
+	Changed = "changed"
+)
+
+// Change is a difference between the config a build ran and a file
+type Change struct {
+	// Path is the key that differs, e.g. "script[2]" or "env.global[0]"
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Old is the value in the file and New in the config of the build,
+	// decoded from JSON
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	}
+	return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+}
+
+// defaultedKeys are the keys Travis sets in the config of builds when the
+// file doesn't
+var defaultedKeys = []string{"dist", "group", "language", "os"}
+
+// Diff returns the changes between the config of the file, e.g. the
+// .travis.yml of the default branch, and the config run, e.g. the Config
+// of a payload, sorted by path. The keys Travis sets when the file doesn't,
+// such as group or os, are ignored then. A build of a pull request
+// changing its scripts shows as changes of script and the other phases.
+func Diff(run, file *travis.Config) []Change {
+	a, b := tree(file), tree(run)
+	for _, k := range defaultedKeys {
+		if _, ok := a[k]; !ok {
+			delete(b, k)
+		}
+	}
+	var changes []Change
+	diff("", a, b, &changes)
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// tree returns c as decoded JSON, empty if c is nil
+func tree(c *travis.Config) map[string]interface{} {
+	m := map[string]interface{}{}
+	if c == nil {
+		return m
+	}
+	b, err := json.Marshal(c)
+	if err == nil {
+		json.Unmarshal(b, &m)
+	}
+	return m
+}
+
+func diff(path string, old, new interface{}, changes *[]Change) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		n, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		for k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			switch {
+			case !inOld:
+				*changes = append(*changes, Change{Path: p, Kind: Added, New: nv})
+			case !inNew:
+				*changes = append(*changes, Change{Path: p, Kind: Removed, Old: ov})
+			default:
+				diff(p, ov, nv, changes)
+			}
+		}
+		return
+	case []interface{}:
+		n, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(o) || i < len(n); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(o):
+				*changes = append(*changes, Change{Path: p, Kind: Added, New: n[i]})
+			case i >= len(n):
+				*changes = append(*changes, Change{Path: p, Kind: Removed, Old: o[i]})
+			default:
+				diff(p, o[i], n[i], changes)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Old: old, New: new})
+	}
+}
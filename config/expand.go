@@ -0,0 +1,217 @@
+package config
+
+import (
+	"strconv"
+
+	"github.com/jacksgt/travis"
+)
+
+// Expand returns the jobs a build of c runs, as in the matrix of payloads:
+// the combinations of the os, arch, VersionKeys and env job values, the
+// ones matching an exclude entry removed, followed by the include entries
+// on top of the first values of c. Jobs matching an allow_failures entry
+// have AllowFailure set. Number is the position of the job, from 1, and
+// the Config of every job has single values and no Jobs.
+//
+// As for Travis, c is only expanded when it has expansion keys or no
+// include entries.
+func Expand(c *travis.Config) []*travis.Job {
+	var jobs []*travis.Config
+	var include, exclude, allowFailures []*travis.Config
+	if c.Jobs != nil {
+		include, exclude, allowFailures = c.Jobs.Include, c.Jobs.Exclude, c.Jobs.AllowFailures
+	}
+
+	if hasExpansionKeys(c) || len(include) == 0 {
+		for _, job := range product(c) {
+			if !matchesAny(job, exclude) {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	defaults := first(c)
+	for _, entry := range include {
+		jobs = append(jobs, overlay(defaults, entry))
+	}
+
+	matrix := make([]*travis.Job, len(jobs))
+	for i, job := range jobs {
+		matrix[i] = &travis.Job{
+			Number:       strconv.Itoa(i + 1),
+			Config:       job,
+			AllowFailure: matchesAny(job, allowFailures),
+		}
+	}
+	return matrix
+}
+
+// hasExpansionKeys reports whether c has values to expand
+func hasExpansionKeys(c *travis.Config) bool {
+	return len(c.OS) > 0 || len(c.Arch) > 0 || len(c.Versions) > 0 || (c.Env != nil && len(c.Env.Jobs) > 0)
+}
+
+// base returns a copy of c without the expansion keys and jobs
+func base(c *travis.Config) *travis.Config {
+	job := *c
+	job.OS, job.Arch, job.Versions, job.Jobs = nil, nil, nil, nil
+	if c.Env != nil {
+		job.Env = &travis.Env{Global: c.Env.Global}
+	}
+	return &job
+}
+
+// first returns c with the first value of each expansion key, the
+// defaults of include entries
+func first(c *travis.Config) *travis.Config {
+	job := base(c)
+	if len(c.OS) > 0 {
+		job.OS = c.OS[:1]
+	}
+	if len(c.Arch) > 0 {
+		job.Arch = c.Arch[:1]
+	}
+	for _, k := range travis.VersionKeys {
+		if v := c.Versions[k]; len(v) > 0 {
+			setVersion(job, k, v[0])
+		}
+	}
+	return job
+}
+
+// product returns the combinations of the expansion keys of c in the
+// order os, arch, VersionKeys and env
+func product(c *travis.Config) []*travis.Config {
+	jobs := []*travis.Config{base(c)}
+	expand := func(values []string, set func(job *travis.Config, v string)) {
+		if len(values) == 0 {
+			return
+		}
+		next := make([]*travis.Config, 0, len(jobs)*len(values))
+		for _, job := range jobs {
+			for _, v := range values {
+				j := clone(job)
+				set(j, v)
+				next = append(next, j)
+			}
+		}
+		jobs = next
+	}
+	expand(c.OS, func(job *travis.Config, v string) { job.OS = travis.Strings{v} })
+	expand(c.Arch, func(job *travis.Config, v string) { job.Arch = travis.Strings{v} })
+	for _, k := range travis.VersionKeys {
+		k := k
+		expand(c.Versions[k], func(job *travis.Config, v string) { setVersion(job, k, v) })
+	}
+	if c.Env != nil {
+		expand(c.Env.Jobs, func(job *travis.Config, v string) {
+			job.Env = &travis.Env{Global: c.Env.Global, Jobs: []string{v}}
+		})
+	}
+	return jobs
+}
+
+// clone copies job with its own Versions
+func clone(job *travis.Config) *travis.Config {
+	j := *job
+	j.Versions = make(map[string]travis.Strings, len(job.Versions))
+	for k, v := range job.Versions {
+		j.Versions[k] = v
+	}
+	return &j
+}
+
+func setVersion(job *travis.Config, k, v string) {
+	if job.Versions == nil {
+		job.Versions = make(map[string]travis.Strings)
+	}
+	job.Versions[k] = travis.Strings{v}
+}
+
+// overlay returns defaults with the values set in entry
+func overlay(defaults, entry *travis.Config) *travis.Config {
+	job := clone(defaults)
+	job.Sudo = job.Sudo || entry.Sudo
+	setString(&job.Dist, entry.Dist)
+	setString(&job.Language, entry.Language)
+	setString(&job.Group, entry.Group)
+	setString(&job.Name, entry.Name)
+	setString(&job.Stage, entry.Stage)
+	for _, f := range [][2]*travis.Strings{
+		{&job.OS, &entry.OS}, {&job.Arch, &entry.Arch}, {&job.Services, &entry.Services},
+		{&job.BeforeInstall, &entry.BeforeInstall}, {&job.Install, &entry.Install},
+		{&job.BeforeScript, &entry.BeforeScript}, {&job.Script, &entry.Script},
+		{&job.AfterSuccess, &entry.AfterSuccess}, {&job.AfterFailure, &entry.AfterFailure},
+		{&job.AfterScript, &entry.AfterScript},
+	} {
+		if len(*f[1]) > 0 {
+			*f[0] = *f[1]
+		}
+	}
+	for k, v := range entry.Versions {
+		job.Versions[k] = v
+	}
+	if entry.Env != nil {
+		env := &travis.Env{Jobs: entry.Env.Jobs, Global: entry.Env.Global}
+		if defaults.Env != nil {
+			env.Global = append(append([]string(nil), defaults.Env.Global...), entry.Env.Global...)
+		}
+		job.Env = env
+	}
+	return job
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+// matchesAny reports whether job has the values set in one of patterns
+func matchesAny(job *travis.Config, patterns []*travis.Config) bool {
+	for _, p := range patterns {
+		if matches(job, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(job, p *travis.Config) bool {
+	for _, f := range [][2]string{
+		{job.Dist, p.Dist}, {job.Language, p.Language}, {job.Name, p.Name}, {job.Stage, p.Stage},
+	} {
+		if f[1] != "" && f[0] != f[1] {
+			return false
+		}
+	}
+	if !matchesStrings(job.OS, p.OS) || !matchesStrings(job.Arch, p.Arch) {
+		return false
+	}
+	for k, v := range p.Versions {
+		if !matchesStrings(job.Versions[k], v) {
+			return false
+		}
+	}
+	if p.Env != nil && len(p.Env.Jobs) > 0 {
+		if job.Env == nil || !matchesStrings(job.Env.Jobs, p.Env.Jobs) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesStrings reports whether v has the values of p, or p is empty
+func matchesStrings(v, p travis.Strings) bool {
+	if len(p) == 0 {
+		return true
+	}
+	if len(v) != len(p) {
+		return false
+	}
+	for i := range p {
+		if v[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
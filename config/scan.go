@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jacksgt/travis"
+)
+
+var (
+	// secretName matches the names of variables holding credentials
+	secretName = regexp.MustCompile(`(?i)(token|secret|passw(or)?d|api_?key|access_?key|private_?key|credentials?)`)
+	// secretValues match credentials of well-known services
+	secretValues = []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"AWS access key", regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+		{"GitHub token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+		{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+		{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{"password in an URL", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@$]+@`)},
+	}
+	// pipeToShell matches downloads piped into a shell, the host in the
+	// first group
+	pipeToShell = regexp.MustCompile(`(?:curl|wget)\b[^|;&]*?https?://([^/\s'":]+)[^|;&]*\|\s*(?:sudo\s+)?(?:ba|z|da)?sh\b|(?:ba|z)?sh\s+<\(\s*(?:curl|wget)\b[^)]*?https?://([^/\s'":]+)`)
+)
+
+// Scanner looks for plaintext credentials and suspicious scripts in
+// configs. As a travis.Sink, it scans the payloads of pull requests.
+type Scanner struct {
+	// Hosts are the hosts scripts may download a script piped into a shell
+	// from, e.g. get.docker.com. Subdomains are allowed too.
+	Hosts []string
+	// OnFindings, if set, is called by Send with the findings of a pull
+	// request payload, if any
+	OnFindings func(p *travis.Payload, findings []Finding)
+}
+
+// Scan returns the findings of c: variables named like credentials with a
+// plaintext value and credentials of well-known services in the env and
+// scripts are errors, downloads from hosts not in s.Hosts piped into a
+// shell are warnings
+func (s *Scanner) Scan(c *travis.Config) []Finding {
+	var v validator
+	s.scan(&v, "", c)
+	return v.findings
+}
+
+// ScanPayload scans the config of p and of its jobs. The config of a pull
+// request using encrypted variables is a warning too, as Travis doesn't
+// decrypt them for pull requests from forks; the payload doesn't tell
+// whether it is one.
+func (s *Scanner) ScanPayload(p *travis.Payload) []Finding {
+	var v validator
+	if p.Config != nil {
+		s.scan(&v, "", p.Config)
+	}
+	for i, job := range p.Matrix {
+		if job.Config != nil {
+			s.scan(&v, fmt.Sprintf("matrix[%d].", i), job.Config)
+		}
+	}
+	if p.PullRequest != 0 && hasSecure(p) {
+		v.add(LevelWarning, "env", "encrypted variables are not available to pull requests from forks")
+	}
+	return v.findings
+}
+
+// Send scans the payloads of pull requests, calling s.OnFindings
+func (s *Scanner) Send(p *travis.Payload) error {
+	if p.PullRequest == 0 || s.OnFindings == nil {
+		return nil
+	}
+	if findings := s.ScanPayload(p); len(findings) > 0 {
+		s.OnFindings(p, findings)
+	}
+	return nil
+}
+
+func (s *Scanner) scan(v *validator, prefix string, c *travis.Config) {
+	if c.Env != nil {
+		for i, e := range c.Env.Global {
+			s.env(v, fmt.Sprintf("%senv.global[%d]", prefix, i), e)
+		}
+		for i, e := range c.Env.Jobs {
+			s.env(v, fmt.Sprintf("%senv.jobs[%d]", prefix, i), e)
+		}
+	}
+	for _, p := range []struct {
+		name     string
+		commands travis.Strings
+	}{
+		{"before_install", c.BeforeInstall}, {"install", c.Install},
+		{"before_script", c.BeforeScript}, {"script", c.Script},
+		{"after_success", c.AfterSuccess}, {"after_failure", c.AfterFailure},
+		{"after_script", c.AfterScript},
+	} {
+		for i, cmd := range p.commands {
+			path := fmt.Sprintf("%s%s[%d]", prefix, p.name, i)
+			secrets(v, path, cmd)
+			for _, m := range pipeToShell.FindAllStringSubmatch(cmd, -1) {
+				host := m[1] + m[2]
+				if !s.allowed(host) {
+					v.add(LevelWarning, path, "script from %s piped into a shell", host)
+				}
+			}
+		}
+	}
+	if c.Jobs != nil {
+		for i, job := range c.Jobs.Include {
+			s.scan(v, fmt.Sprintf("%sjobs.include[%d].", prefix, i), job)
+		}
+	}
+}
+
+// env checks the variables of an env entry
+func (s *Scanner) env(v *validator, path, entry string) {
+	if strings.HasPrefix(entry, "secure:") {
+		return
+	}
+	secrets(v, path, entry)
+	for _, assignment := range strings.Fields(entry) {
+		name, value, ok := strings.Cut(assignment, "=")
+		value = strings.Trim(value, `"'`)
+		if ok && value != "" && !strings.HasPrefix(value, "$") && secretName.MatchString(name) {
+			v.add(LevelError, path, "%s looks like a credential in plaintext, encrypt it", name)
+		}
+	}
+}
+
+// secrets checks s for the credentials of well-known services
+func secrets(v *validator, path, s string) {
+	for _, sv := range secretValues {
+		if sv.re.MatchString(s) {
+			v.add(LevelError, path, "%s in plaintext", sv.name)
+		}
+	}
+}
+
+func (s *Scanner) allowed(host string) bool {
+	for _, h := range s.Hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSecure reports whether the config of p or of its jobs has encrypted
+// variables
+func hasSecure(p *travis.Payload) bool {
+	configs := []*travis.Config{p.Config}
+	for _, job := range p.Matrix {
+		configs = append(configs, job.Config)
+	}
+	for _, c := range configs {
+		if c == nil || c.Env == nil {
+			continue
+		}
+		for _, e := range append(append([]string(nil), c.Env.Global...), c.Env.Jobs...) {
+			if strings.HasPrefix(e, "secure:") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jacksgt/travis"
+)
+
+// Levels of a Finding
+const (
+	// LevelError findings make Travis reject the config or the build fail
+	LevelError = "error"
+	// LevelWarning findings are ignored or defaulted by Travis, likely not
+	// what the author meant
+	LevelWarning = "warning"
+)
+
+// Finding is a problem of a config found by Validate
+type Finding struct {
+	Level string `json:"level"`
+	// Path is the key the finding is about, e.g. "jobs.include[1].dist",
+	// empty for the whole config
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Path == "" {
+		return f.Level + ": " + f.Message
+	}
+	return fmt.Sprintf("%s: %s: %s", f.Level, f.Path, f.Message)
+}
+
+// Keys Validate accepts in a config, besides VersionKeys. Keys starting with
+// _ are accepted too, as they conventionally hold anchors.
+var (
+	TopLevelKeys = []string{
+		"addons", "after_deploy", "after_failure", "after_script",
+		"after_success", "arch", "before_cache", "before_deploy",
+		"before_install", "before_script", "branches", "bundler_args",
+		"cache", "conditions", "deploy", "dist", "env", "filter_secrets",
+		"git", "group", "if", "import", "install", "jobs", "language",
+		"matrix", "notifications", "os", "script", "services", "stages",
+		"sudo", "version", "virt", "vm",
+	}
+	// JobKeys are the keys of the entries of jobs include, exclude and
+	// allow_failures besides TopLevelKeys
+	JobKeys = []string{"name", "stage"}
+)
+
+// Languages are the values of language Travis supports
+var Languages = []string{
+	"android", "bash", "c", "clojure", "cpp", "crystal", "csharp", "d",
+	"dart", "elixir", "elm", "erlang", "generic", "go", "groovy", "haskell",
+	"haxe", "java", "julia", "minimal", "nix", "node_js", "objective-c",
+	"perl", "perl6", "php", "python", "r", "ruby", "rust", "scala", "sh",
+	"shell", "smalltalk", "swift",
+}
+
+// dists are the values of dist, the deprecated ones with the message
+var dists = map[string]string{
+	"precise":         "Ubuntu 12.04 is end of life, use focal or jammy",
+	"trusty":          "Ubuntu 14.04 is end of life, use focal or jammy",
+	"xenial":          "",
+	"bionic":          "",
+	"focal":           "",
+	"jammy":           "",
+	"noble":           "",
+	"server-2016":     "",
+	"1803-containers": "",
+}
+
+var (
+	operatingSystems = []string{"freebsd", "linux", "osx", "windows"}
+	architectures    = []string{"amd64", "arm64", "arm64-graviton2", "ppc64le", "s390x"}
+)
+
+// Validate checks a config tree returned by Decode for unknown keys and
+// invalid or deprecated values, returning the findings in the order of the
+// keys
+func Validate(tree map[string]interface{}) []Finding {
+	var v validator
+	v.config("", tree, TopLevelKeys)
+	if _, ok := tree["language"]; !ok {
+		v.add(LevelWarning, "language", "missing, Travis defaults to ruby")
+	}
+	if _, err := fromTree(tree); err != nil {
+		v.add(LevelError, "", "%v", err)
+	}
+	return v.findings
+}
+
+// ValidateFile reads and validates the .travis.yml file name. Syntax
+// errors are returned as errors, not findings.
+func ValidateFile(name string) ([]Finding, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return Validate(tree), nil
+}
+
+type validator struct {
+	findings []Finding
+}
+
+func (v *validator) add(level, path, format string, args ...interface{}) {
+	v.findings = append(v.findings, Finding{Level: level, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// config checks the keys of the config or job m at prefix
+func (v *validator) config(prefix string, m map[string]interface{}, known []string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := prefix + k
+		switch {
+		case strings.HasPrefix(k, "_"):
+		case !contains(known, k) && !contains(travis.VersionKeys, k):
+			v.add(LevelWarning, path, "unknown key, ignored by Travis")
+		case k == "sudo":
+			v.add(LevelWarning, path, "deprecated, builds always run in virtual machines")
+		case k == "language":
+			if s, ok := m[k].(string); ok && !contains(Languages, s) {
+				v.add(LevelError, path, "unknown language %q", s)
+			}
+		case k == "dist":
+			if s, ok := m[k].(string); ok {
+				if msg, ok := dists[s]; !ok {
+					v.add(LevelError, path, "unknown dist %q", s)
+				} else if msg != "" {
+					v.add(LevelWarning, path, "%s is deprecated: %s", s, msg)
+				}
+			}
+		case k == "os":
+			v.values(path, m[k], operatingSystems)
+		case k == "arch":
+			v.values(path, m[k], architectures)
+		case k == "jobs" || k == "matrix":
+			v.jobs(path, m[k])
+		}
+	}
+}
+
+// values checks that the string or list of strings x is among valid
+func (v *validator) values(path string, x interface{}, valid []string) {
+	list, ok := x.([]interface{})
+	if !ok {
+		list = []interface{}{x}
+	}
+	for _, e := range list {
+		if s, ok := e.(string); ok && !contains(valid, s) {
+			v.add(LevelError, path, "unknown value %q, expected one of %s", s, strings.Join(valid, ", "))
+		}
+	}
+}
+
+// jobs checks the entries of a jobs or matrix key
+func (v *validator) jobs(path string, x interface{}) {
+	lists := map[string]interface{}{"include": x}
+	if m, ok := x.(map[string]interface{}); ok {
+		lists = m
+	}
+	jobKeys := append(append([]string(nil), TopLevelKeys...), JobKeys...)
+	for _, name := range []string{"include", "exclude", "allow_failures"} {
+		list, _ := lists[name].([]interface{})
+		for i, e := range list {
+			if job, ok := e.(map[string]interface{}); ok {
+				v.config(fmt.Sprintf("%s.%s[%d].", path, name, i), job, jobKeys)
+			}
+		}
+	}
+	if m, ok := x.(map[string]interface{}); ok {
+		var unknown []string
+		for k := range m {
+			if !contains([]string{"include", "exclude", "allow_failures", "fast_finish"}, k) {
+				unknown = append(unknown, k)
+			}
+		}
+		sort.Strings(unknown)
+		for _, k := range unknown {
+			v.add(LevelWarning, path+"."+k, "unknown key, ignored by Travis")
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
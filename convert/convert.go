@@ -0,0 +1,335 @@
+// Package convert translates .travis.yml files into the configs of other
+// CI services, for migrating off Travis one repository at a time.
+//
+//	r, err := convert.GitHubActions(data)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	os.WriteFile(".github/workflows/ci.yml", r.YAML, 0644)
+//	for _, u := range r.Untranslated {
+//		log.Println("port by hand:", u)
+//	}
+//
+// The conversion is best effort: the jobs of config.Expand are grouped by
+// stage and by their scripts, and the constructs without an equivalent are
+// listed in the Result rather than guessed.
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/config"
+)
+
+// Result is a converted config
+type Result struct {
+	// YAML is the converted config
+	YAML []byte
+	// Untranslated describes the constructs of the Travis config missing
+	// from YAML, to port by hand
+	Untranslated []string
+}
+
+func (r *Result) untranslated(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for _, u := range r.Untranslated {
+		if u == msg {
+			return
+		}
+	}
+	r.Untranslated = append(r.Untranslated, msg)
+}
+
+// handledKeys are the top-level keys the converters translate or may
+// ignore, besides travis.VersionKeys
+var handledKeys = []string{
+	"after_failure", "after_script", "after_success", "arch", "before_install",
+	"before_script", "cache", "dist", "env", "group", "install", "jobs",
+	"language", "matrix", "os", "script", "services", "stages", "sudo",
+}
+
+// parse decodes data and reports its top-level keys the converters don't
+// translate
+func parse(data []byte, r *Result) (*travis.Config, error) {
+	tree, err := config.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	c, err := config.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(tree))
+	for k := range tree {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "_") && !contains(handledKeys, k) && !contains(travis.VersionKeys, k) {
+			r.untranslated("%s: not translated", k)
+		}
+	}
+	return c, nil
+}
+
+// stage is the jobs of a stage, grouped by their steps
+type stage struct {
+	name   string
+	groups [][]*travis.Job
+}
+
+// stages returns the jobs of c by stage, as travis.Stages orders them.
+// Stage conditions are reported as untranslated.
+func stages(c *travis.Config, r *Result) []*stage {
+	p := &travis.Payload{Config: c, Matrix: config.Expand(c)}
+	var list []*stage
+	for _, s := range travis.Stages(p) {
+		st := &stage{name: s.Name}
+		for _, job := range s.Jobs {
+			st.add(job)
+		}
+		list = append(list, st)
+	}
+	for _, s := range c.Stages {
+		if s.If != "" {
+			r.untranslated("stages: the condition of %s, %q", s.Name, s.If)
+		}
+	}
+	return list
+}
+
+// add adds job to the group of the jobs with the same steps
+func (s *stage) add(job *travis.Job) {
+	for i, g := range s.groups {
+		if steps(g[0].Config) == steps(job.Config) {
+			s.groups[i] = append(g, job)
+			return
+		}
+	}
+	s.groups = append(s.groups, []*travis.Job{job})
+}
+
+// steps returns a key of everything of a job but its matrix values
+func steps(c *travis.Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%q", c.Language, c.Services)
+	if c.Cache != nil {
+		fmt.Fprintf(&b, "|%q|%q", c.Cache.Directories, c.Cache.Tools)
+	}
+	for _, phase := range phases(c) {
+		fmt.Fprintf(&b, "|%s=%q", phase.name, phase.commands)
+	}
+	return b.String()
+}
+
+// jobIDs names the jobs of a converted config uniquely
+type jobIDs map[string]bool
+
+// next returns the ID of a group of jobs of stage, the name of the job if
+// it is alone and has one
+func (ids jobIDs) next(stage string, group []*travis.Job) string {
+	id := slug(stage)
+	if len(group) == 1 && group[0].Config.Name != "" {
+		id = slug(group[0].Config.Name)
+	}
+	for n := 2; ids[id] || id == ""; n++ {
+		id = slug(stage) + "-" + strconv.Itoa(n)
+	}
+	ids[id] = true
+	return id
+}
+
+// phase is the commands of a phase of a job
+type phase struct {
+	name     string
+	commands travis.Strings
+	// when is the condition of the phase, success, failure or always
+	when string
+}
+
+// phases returns the non-empty phases of c in the order Travis runs them
+func phases(c *travis.Config) []phase {
+	var list []phase
+	for _, p := range []phase{
+		{"before_install", c.BeforeInstall, ""},
+		{"install", c.Install, ""},
+		{"before_script", c.BeforeScript, ""},
+		{"script", c.Script, ""},
+		{"after_success", c.AfterSuccess, "success"},
+		{"after_failure", c.AfterFailure, "failure"},
+		{"after_script", c.AfterScript, "always"},
+	} {
+		if len(p.commands) > 0 && !(len(p.commands) == 1 && p.commands[0] == "skip") {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// versionKey returns the key of VersionKeys holding the runtime versions
+// of language
+func versionKey(language string) string {
+	switch language {
+	case "ruby", "":
+		return "rvm"
+	case "java", "groovy", "clojure":
+		return "jdk"
+	}
+	return language
+}
+
+// runtimeKeys are the keys of VersionKeys the converters translate
+var runtimeKeys = []string{"go", "jdk", "node_js", "php", "python", "rust", "rvm"}
+
+// service is the container image of a Travis service
+type service struct {
+	image string
+	port  int
+	env   map[string]string
+}
+
+var services = map[string]service{
+	"postgresql":    {"postgres:16", 5432, map[string]string{"POSTGRES_HOST_AUTH_METHOD": "trust"}},
+	"mysql":         {"mysql:8", 3306, map[string]string{"MYSQL_ALLOW_EMPTY_PASSWORD": "yes"}},
+	"mariadb":       {"mariadb:11", 3306, map[string]string{"MARIADB_ALLOW_EMPTY_ROOT_PASSWORD": "yes"}},
+	"redis":         {"redis:7", 6379, nil},
+	"redis-server":  {"redis:7", 6379, nil},
+	"mongodb":       {"mongo:7", 27017, nil},
+	"memcached":     {"memcached:1", 11211, nil},
+	"rabbitmq":      {"rabbitmq:3", 5672, nil},
+	"elasticsearch": {"elasticsearch:8.13.0", 9200, map[string]string{"discovery.type": "single-node", "xpack.security.enabled": "false"}},
+}
+
+// cacheDirectories are the directories of the cache tools Travis sets up
+// that a converter can only translate as directories
+var cacheDirectories = map[string][]string{
+	"cargo":     {"$HOME/.cargo", "target"},
+	"ccache":    {"$HOME/.ccache"},
+	"cocoapods": {"Pods"},
+	"packages":  {"$HOME/R/Library"},
+}
+
+// parseEnv splits an env entry such as A=1 B="x y" into variables, false
+// if it is encrypted or not a list of assignments
+func parseEnv(entry string) (map[string]string, bool) {
+	if strings.HasPrefix(entry, "secure:") {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for _, word := range shellWords(entry) {
+		k, v, ok := strings.Cut(word, "=")
+		if !ok || k == "" {
+			return nil, false
+		}
+		vars[k] = v
+	}
+	return vars, true
+}
+
+// shellWords splits s at unquoted spaces, removing the quotes
+func shellWords(s string) []string {
+	var words []string
+	var b strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, b.String())
+				b.Reset()
+				inWord = false
+			}
+		default:
+			b.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+// envVars merges the variables of entries, reporting the ones that can't
+// be translated
+func envVars(entries []string, r *Result) map[string]string {
+	if len(entries) == 0 {
+		return nil
+	}
+	vars := map[string]string{}
+	for _, e := range entries {
+		parsed, ok := parseEnv(e)
+		if !ok {
+			if strings.HasPrefix(e, "secure:") {
+				r.untranslated("env: encrypted variable, add it as a secret")
+			} else {
+				r.untranslated("env: %q is not a list of assignments", e)
+			}
+			continue
+		}
+		for k, v := range parsed {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// slug returns s in lowercase with the characters other than letters and
+// digits replaced by dashes, for job names
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// marshal encodes v as YAML indented by two spaces
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+	if err := e.Encode(v); err != nil {
+		return nil, err
+	}
+	err := e.Close()
+	return b.Bytes(), err
+}
+
+// first returns the first value of s, empty if there is none
+func first(s travis.Strings) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0]
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
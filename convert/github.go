@@ -0,0 +1,300 @@
+package convert
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+)
+
+// ghWorkflow is a GitHub Actions workflow
+type ghWorkflow struct {
+	Name     string            `yaml:"name"`
+	On       []string          `yaml:"on"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	Defaults struct {
+		Run struct {
+			Shell string `yaml:"shell"`
+		} `yaml:"run"`
+	} `yaml:"defaults"`
+	Jobs *yaml.Node `yaml:"jobs"`
+}
+
+type ghJob struct {
+	Name            string               `yaml:"name,omitempty"`
+	Needs           []string             `yaml:"needs,omitempty"`
+	RunsOn          string               `yaml:"runs-on"`
+	ContinueOnError interface{}          `yaml:"continue-on-error,omitempty"`
+	Strategy        *ghStrategy          `yaml:"strategy,omitempty"`
+	Env             map[string]string    `yaml:"env,omitempty"`
+	Services        map[string]ghService `yaml:"services,omitempty"`
+	Steps           []ghStep             `yaml:"steps"`
+}
+
+type ghStrategy struct {
+	FailFast bool `yaml:"fail-fast"`
+	Matrix   struct {
+		Include []map[string]interface{} `yaml:"include"`
+	} `yaml:"matrix"`
+}
+
+type ghService struct {
+	Image string            `yaml:"image"`
+	Env   map[string]string `yaml:"env,omitempty"`
+	Ports []string          `yaml:"ports"`
+}
+
+type ghStep struct {
+	Name string                 `yaml:"name,omitempty"`
+	If   string                 `yaml:"if,omitempty"`
+	Uses string                 `yaml:"uses,omitempty"`
+	With map[string]interface{} `yaml:"with,omitempty"`
+	Run  string                 `yaml:"run,omitempty"`
+}
+
+// ghSetup are the actions installing the runtime of a language, with the
+// input of the version
+var ghSetup = map[string][2]string{
+	"go":      {"actions/setup-go@v5", "go-version"},
+	"python":  {"actions/setup-python@v5", "python-version"},
+	"node_js": {"actions/setup-node@v4", "node-version"},
+	"ruby":    {"ruby/setup-ruby@v1", "ruby-version"},
+	"java":    {"actions/setup-java@v4", "java-version"},
+	"php":     {"shivammathur/setup-php@v2", "php-version"},
+	"rust":    {"dtolnay/rust-toolchain@master", "toolchain"},
+}
+
+// noRuntime are the languages needing no setup on the runners
+var noRuntime = []string{"bash", "c", "cpp", "generic", "minimal", "sh", "shell"}
+
+// GitHubActions converts the .travis.yml data into a GitHub Actions
+// workflow running on push and pull_request. Every stage is a job, or
+// several if the scripts of its jobs differ, needing the jobs of the
+// previous stage, with a matrix of the runners, runtime versions, env
+// entries and allow_failures of its jobs.
+func GitHubActions(data []byte) (*Result, error) {
+	r := new(Result)
+	c, err := parse(data, r)
+	if err != nil {
+		return nil, err
+	}
+
+	w := ghWorkflow{Name: "CI", On: []string{"push", "pull_request"}, Jobs: &yaml.Node{Kind: yaml.MappingNode}}
+	w.Defaults.Run.Shell = "bash"
+	if c.Env != nil {
+		w.Env = envVars(c.Env.Global, r)
+	}
+	fastFinish := c.Jobs != nil && c.Jobs.FastFinish
+
+	var needs []string
+	ids := jobIDs{}
+	for _, s := range stages(c, r) {
+		var stageIDs []string
+		for _, group := range s.groups {
+			id := ids.next(s.name, group)
+			stageIDs = append(stageIDs, id)
+
+			job := ghJobOf(group, fastFinish, r)
+			job.Needs = needs
+			var k, v yaml.Node
+			k.SetString(id)
+			if err := v.Encode(job); err != nil {
+				return nil, err
+			}
+			w.Jobs.Content = append(w.Jobs.Content, &k, &v)
+		}
+		needs = stageIDs
+	}
+
+	if r.YAML, err = marshal(w); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ghJobOf converts a group of jobs with the same steps
+func ghJobOf(group []*travis.Job, fastFinish bool, r *Result) *ghJob {
+	c := group[0].Config
+	lang := c.Language
+	if lang == "" {
+		lang = "ruby"
+	}
+
+	// the values of the jobs, in the matrix if they differ
+	values := make([]map[string]interface{}, len(group))
+	for i, job := range group {
+		values[i] = map[string]interface{}{
+			"os":            ghRunner(job.Config, r),
+			"version":       ghVersion(lang, first(job.Config.Versions[versionKey(lang)])),
+			"name":          job.Config.Name,
+			"allow_failure": job.AllowFailure,
+		}
+		if job.Config.Env != nil {
+			values[i]["env"] = first(job.Config.Env.Jobs)
+		}
+		for k := range job.Config.Versions {
+			// the runtime versions of other languages are inherited from
+			// the root of the config, as are their caches below
+			if !contains(runtimeKeys, k) {
+				r.untranslated("%s: not translated", k)
+			}
+		}
+	}
+	varying := map[string]bool{}
+	for k, v := range values[0] {
+		for _, other := range values[1:] {
+			if other[k] != v {
+				varying[k] = true
+			}
+		}
+	}
+	value := func(k string) interface{} {
+		if varying[k] {
+			return "${{ matrix." + k + " }}"
+		}
+		return values[0][k]
+	}
+
+	job := &ghJob{RunsOn: value("os").(string)}
+	if name, _ := value("name").(string); name != "" {
+		job.Name = name
+	}
+	switch v := value("allow_failure").(type) {
+	case string:
+		job.ContinueOnError = v
+	case bool:
+		if v {
+			job.ContinueOnError = true
+		}
+	}
+	if len(varying) > 0 {
+		job.Strategy = &ghStrategy{FailFast: fastFinish}
+		for _, v := range values {
+			entry := map[string]interface{}{}
+			for k := range varying {
+				entry[k] = v[k]
+			}
+			job.Strategy.Matrix.Include = append(job.Strategy.Matrix.Include, entry)
+		}
+	}
+
+	// env entries are exported by the steps when they differ
+	var export string
+	if varying["env"] {
+		export = "export ${{ matrix.env }}\n"
+		for _, v := range values {
+			envVars([]string{v["env"].(string)}, r)
+		}
+	} else if env, _ := values[0]["env"].(string); env != "" {
+		job.Env = envVars([]string{env}, r)
+	}
+
+	for _, name := range c.Services {
+		svc, ok := services[name]
+		if !ok {
+			if name != "docker" && name != "xvfb" {
+				r.untranslated("services: %s not translated", name)
+			}
+			continue
+		}
+		if job.Services == nil {
+			job.Services = map[string]ghService{}
+		}
+		port := strconv.Itoa(svc.port)
+		job.Services[slug(name)] = ghService{Image: svc.image, Env: svc.env, Ports: []string{port + ":" + port}}
+	}
+
+	job.Steps = append(job.Steps, ghStep{Uses: "actions/checkout@v4"})
+	setup, ok := ghSetup[lang]
+	var setupStep *ghStep
+	switch {
+	case ok:
+		setupStep = &ghStep{Uses: setup[0], With: map[string]interface{}{}}
+		if v := value("version"); v != "" {
+			setupStep.With[setup[1]] = v
+		}
+		if lang == "java" {
+			setupStep.With["distribution"] = "temurin"
+		}
+	case !contains(noRuntime, lang):
+		r.untranslated("language: %s, install the runtime by hand", lang)
+	}
+
+	var dirs []string
+	if c.Cache != nil {
+		dirs = append(dirs, c.Cache.Directories...)
+		for _, tool := range c.Cache.Tools {
+			switch {
+			case tool == "pip" && lang == "python", tool == "npm" && lang == "node_js", tool == "yarn" && lang == "node_js":
+				setupStep.With["cache"] = tool
+			case tool == "bundler" && lang == "ruby":
+				setupStep.With["bundler-cache"] = true
+			case tool == "pip" || tool == "npm" || tool == "yarn" || tool == "bundler":
+			case cacheDirectories[tool] != nil:
+				dirs = append(dirs, cacheDirectories[tool]...)
+			default:
+				r.untranslated("cache: %s not translated", tool)
+			}
+		}
+	}
+	if setupStep != nil {
+		if len(setupStep.With) == 0 {
+			setupStep.With = nil
+		}
+		job.Steps = append(job.Steps, *setupStep)
+	}
+	if len(dirs) > 0 {
+		job.Steps = append(job.Steps, ghStep{Uses: "actions/cache@v4", With: map[string]interface{}{
+			"path":         strings.Join(dirs, "\n"),
+			"key":          "${{ runner.os }}-travis-${{ github.sha }}",
+			"restore-keys": "${{ runner.os }}-travis-",
+		}})
+	}
+
+	if len(c.Script) == 0 {
+		r.untranslated("script: missing, Travis runs the default script of %s", lang)
+	}
+	for _, p := range phases(c) {
+		step := ghStep{Name: p.name, Run: export + strings.Join(p.commands, "\n")}
+		if p.when != "" {
+			step.If = p.when + "()"
+		}
+		job.Steps = append(job.Steps, step)
+	}
+	return job
+}
+
+// ghRunner returns the runner of the os, dist and arch of c
+func ghRunner(c *travis.Config, r *Result) string {
+	if arch := first(c.Arch); arch != "" && arch != "amd64" {
+		r.untranslated("arch: %s not translated", arch)
+	}
+	switch os := first(c.OS); os {
+	case "osx":
+		return "macos-latest"
+	case "windows":
+		return "windows-latest"
+	case "", "linux":
+		switch c.Dist {
+		case "jammy":
+			return "ubuntu-22.04"
+		case "noble":
+			return "ubuntu-24.04"
+		}
+		return "ubuntu-latest"
+	default:
+		r.untranslated("os: %s not translated", os)
+		return "ubuntu-latest"
+	}
+}
+
+// ghVersion returns the version of the runtime of lang for a Travis
+// version, e.g. 11 for openjdk11
+func ghVersion(lang, v string) string {
+	if lang == "java" {
+		return strings.TrimLeft(v, "abcdefghijklmnopqrstuvwxyz")
+	}
+	return v
+}
@@ -0,0 +1,285 @@
+package convert
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+)
+
+type glConfig struct {
+	Stages    []string          `yaml:"stages"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+type glJob struct {
+	Stage        string            `yaml:"stage"`
+	Image        string            `yaml:"image"`
+	Services     []glService       `yaml:"services,omitempty"`
+	Variables    map[string]string `yaml:"variables,omitempty"`
+	Parallel     *glParallel       `yaml:"parallel,omitempty"`
+	AllowFailure bool              `yaml:"allow_failure,omitempty"`
+	Cache        *glCache          `yaml:"cache,omitempty"`
+	BeforeScript []string          `yaml:"before_script,omitempty"`
+	Script       []string          `yaml:"script"`
+	AfterScript  []string          `yaml:"after_script,omitempty"`
+}
+
+type glService struct {
+	Name  string `yaml:"name"`
+	Alias string `yaml:"alias"`
+}
+
+type glParallel struct {
+	Matrix []map[string]string `yaml:"matrix"`
+}
+
+type glCache struct {
+	Key   string   `yaml:"key"`
+	Paths []string `yaml:"paths"`
+}
+
+// glImages are the images of the runtimes of languages
+var glImages = map[string]string{
+	"go":      "golang",
+	"python":  "python",
+	"node_js": "node",
+	"ruby":    "ruby",
+	"java":    "eclipse-temurin",
+	"php":     "php",
+	"rust":    "rust",
+}
+
+// glDists are the ubuntu images of the dists
+var glDists = map[string]string{
+	"xenial": "16.04", "bionic": "18.04", "focal": "20.04", "jammy": "22.04", "noble": "24.04",
+}
+
+// glCacheTools are the cache tools translated to a directory of the
+// project, with the variable pointing the tool at it
+var glCacheTools = map[string][2]string{
+	"pip":     {".cache/pip", "PIP_CACHE_DIR"},
+	"npm":     {".npm", "npm_config_cache"},
+	"yarn":    {".cache/yarn", "YARN_CACHE_FOLDER"},
+	"bundler": {"vendor/bundle", "BUNDLE_PATH"},
+}
+
+// glVersion is the matrix variable of differing runtime versions
+const glVersion = "RUNTIME_VERSION"
+
+// GitLab converts the .travis.yml data into a .gitlab-ci.yml. The stages
+// keep their order, every stage having a job, or several if the scripts
+// or allow_failures of its jobs differ, with a parallel matrix of the
+// runtime versions and env entries of its jobs. The runtimes are the
+// official Docker images of the languages.
+func GitLab(data []byte) (*Result, error) {
+	r := new(Result)
+	c, err := parse(data, r)
+	if err != nil {
+		return nil, err
+	}
+	if c.Jobs != nil && c.Jobs.FastFinish {
+		r.untranslated("jobs.fast_finish: not translated")
+	}
+
+	var top glConfig
+	if c.Env != nil {
+		top.Variables = envVars(c.Env.Global, r)
+	}
+	jobs := &yaml.Node{Kind: yaml.MappingNode}
+	ids := jobIDs{}
+	for _, s := range stages(c, r) {
+		top.Stages = append(top.Stages, s.name)
+		for _, group := range s.groups {
+			for _, g := range splitAllowFailure(group) {
+				var k, v yaml.Node
+				k.SetString(ids.next(s.name, g))
+				if err := v.Encode(glJobOf(s.name, g, r)); err != nil {
+					return nil, err
+				}
+				jobs.Content = append(jobs.Content, &k, &v)
+			}
+		}
+	}
+
+	// the jobs are keys of the top-level mapping
+	var doc yaml.Node
+	if err := doc.Encode(top); err != nil {
+		return nil, err
+	}
+	doc.Content = append(doc.Content, jobs.Content...)
+	if r.YAML, err = marshal(&doc); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// splitAllowFailure splits group into the jobs allowed to fail and the
+// others, GitLab not allowing the failure of some jobs of a matrix
+func splitAllowFailure(group []*travis.Job) [][]*travis.Job {
+	var allowed, required []*travis.Job
+	for _, job := range group {
+		if job.AllowFailure {
+			allowed = append(allowed, job)
+		} else {
+			required = append(required, job)
+		}
+	}
+	var groups [][]*travis.Job
+	for _, g := range [][]*travis.Job{required, allowed} {
+		if len(g) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// glJobOf converts a group of jobs of stage with the same steps and
+// allow_failure
+func glJobOf(stage string, group []*travis.Job, r *Result) *glJob {
+	c := group[0].Config
+	lang := c.Language
+	if lang == "" {
+		lang = "ruby"
+	}
+	job := &glJob{Stage: stage, AllowFailure: group[0].AllowFailure}
+
+	versions := make([]string, len(group))
+	envs := make([]string, len(group))
+	for i, j := range group {
+		versions[i] = strings.TrimSuffix(ghVersion(lang, first(j.Config.Versions[versionKey(lang)])), ".x")
+		if j.Config.Env != nil {
+			envs[i] = first(j.Config.Env.Jobs)
+		}
+		if os := first(j.Config.OS); os != "" && os != "linux" {
+			r.untranslated("os: %s, set the tags of a %s runner", os, os)
+		}
+		if arch := first(j.Config.Arch); arch != "" && arch != "amd64" {
+			r.untranslated("arch: %s, set the tags of a %s runner", arch, arch)
+		}
+		for k := range j.Config.Versions {
+			if !contains(runtimeKeys, k) {
+				r.untranslated("%s: not translated", k)
+			}
+		}
+	}
+	versionVaries, envVaries := differ(versions), differ(envs)
+
+	version := versions[0]
+	if versionVaries {
+		version = "${" + glVersion + "}"
+	}
+	switch image, ok := glImages[lang]; {
+	case ok:
+		if version == "" {
+			version = "latest"
+		}
+		job.Image = image + ":" + version
+	case contains(noRuntime, lang):
+		ubuntu := glDists[c.Dist]
+		if ubuntu == "" {
+			ubuntu = "latest"
+		}
+		job.Image = "ubuntu:" + ubuntu
+	default:
+		job.Image = "ubuntu:latest"
+		r.untranslated("language: %s, pick an image with the runtime", lang)
+	}
+
+	if versionVaries || envVaries {
+		job.Parallel = new(glParallel)
+		for i := range group {
+			entry := map[string]string{}
+			if versionVaries {
+				entry[glVersion] = versions[i]
+			}
+			if envVaries {
+				for k, v := range envVars([]string{envs[i]}, r) {
+					entry[k] = v
+				}
+			}
+			job.Parallel.Matrix = append(job.Parallel.Matrix, entry)
+		}
+	} else if envs[0] != "" {
+		job.Variables = envVars(envs[:1], r)
+	}
+
+	for _, name := range c.Services {
+		svc, ok := services[name]
+		if !ok {
+			if name != "xvfb" {
+				r.untranslated("services: %s not translated", name)
+			}
+			continue
+		}
+		job.Services = append(job.Services, glService{Name: svc.image, Alias: slug(name)})
+		for k, v := range svc.env {
+			job.setVariable(k, v)
+		}
+		r.untranslated("services: %s is reachable at the host %s instead of localhost", name, slug(name))
+	}
+
+	if c.Cache != nil {
+		var paths []string
+		for _, dir := range c.Cache.Directories {
+			if strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, "$HOME") || strings.HasPrefix(dir, "~") {
+				r.untranslated("cache: %s is outside of the project", dir)
+				continue
+			}
+			paths = append(paths, dir)
+		}
+		for _, tool := range c.Cache.Tools {
+			if t, ok := glCacheTools[tool]; ok {
+				paths = append(paths, t[0])
+				job.setVariable(t[1], "$CI_PROJECT_DIR/"+t[0])
+			} else {
+				r.untranslated("cache: %s not translated", tool)
+			}
+		}
+		if len(paths) > 0 {
+			job.Cache = &glCache{Key: "$CI_JOB_NAME", Paths: paths}
+		}
+	}
+
+	if len(c.Script) == 0 {
+		r.untranslated("script: missing, Travis runs the default script of %s", lang)
+		job.Script = []string{"echo 'port the default script of " + lang + "'"}
+	}
+	for _, p := range phases(c) {
+		switch p.name {
+		case "before_install", "install", "before_script":
+			job.BeforeScript = append(job.BeforeScript, p.commands...)
+		case "script":
+			job.Script = append(job.Script, p.commands...)
+		case "after_success", "after_failure":
+			status := strings.TrimPrefix(p.name, "after_")
+			if status == "failure" {
+				status = "failed"
+			}
+			for _, cmd := range p.commands {
+				job.AfterScript = append(job.AfterScript, `if [ "$CI_JOB_STATUS" = `+status+` ]; then `+cmd+`; fi`)
+			}
+		default:
+			job.AfterScript = append(job.AfterScript, p.commands...)
+		}
+	}
+	return job
+}
+
+func (j *glJob) setVariable(k, v string) {
+	if j.Variables == nil {
+		j.Variables = map[string]string{}
+	}
+	j.Variables[k] = v
+}
+
+// differ reports whether the values of list are not all the same
+func differ(list []string) bool {
+	for _, v := range list[1:] {
+		if v != list[0] {
+			return true
+		}
+	}
+	return false
+}
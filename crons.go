@@ -0,0 +1,68 @@
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Cron is a cron job of a repository's settings
+type Cron struct {
+	ID     int64  `json:"id"`
+	Branch string `json:"-"`
+	// Interval is daily, weekly or monthly
+	Interval string `json:"interval"`
+	// DontRunIfRecentBuildExists skips the run if the branch was built in
+	// the last 24 hours
+	DontRunIfRecentBuildExists bool      `json:"dont_run_if_recent_build_exists"`
+	LastRun                    time.Time `json:"last_run"`
+	NextRun                    time.Time `json:"next_run"`
+	Active                     bool      `json:"active"`
+}
+
+// UnmarshalJSON decodes a cron of the API, whose branch is an object
+func (c *Cron) UnmarshalJSON(b []byte) error {
+	type plain Cron
+	aux := struct {
+		*plain
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+		// last_run is null until the first run
+		LastRun *time.Time `json:"last_run"`
+		NextRun *time.Time `json:"next_run"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	c.Branch = aux.Branch.Name
+	if aux.LastRun != nil {
+		c.LastRun = *aux.LastRun
+	}
+	if aux.NextRun != nil {
+		c.NextRun = *aux.NextRun
+	}
+	return nil
+}
+
+// Crons returns the cron jobs of the repository slug
+func (c *Client) Crons(ctx context.Context, slug string) ([]*Cron, error) {
+	var crons []*Cron
+	for offset := 0; ; {
+		var res struct {
+			Pagination Pagination `json:"@pagination"`
+			Crons      []*Cron    `json:"crons"`
+		}
+		path := fmt.Sprintf("/repo/%s/crons?limit=100&offset=%d", url.PathEscape(slug), offset)
+		if err := c.do(ctx, "GET", path, nil, &res); err != nil {
+			return nil, err
+		}
+		crons = append(crons, res.Crons...)
+		if res.Pagination.IsLast || len(res.Crons) == 0 {
+			return crons, nil
+		}
+		offset += len(res.Crons)
+	}
+}
@@ -0,0 +1,90 @@
+package travis
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HandlerVars are internals of a Handler exposed for troubleshooting
+type HandlerVars struct {
+	// Dispositions counts the deliveries per disposition
+	Dispositions map[string]int64 `json:"dispositions"`
+	// LastLag is the delivery lag of the last verified webhook
+	LastLag time.Duration `json:"last_lag"`
+	// LastKeyFetch is the last time the Travis public key was fetched
+	LastKeyFetch time.Time `json:"last_key_fetch"`
+	// QueueDepth is the number of payloads waiting to be dispatched
+	QueueDepth int `json:"queue_depth"`
+	// DedupeCacheSize is the number of entries of the cache of Dedupe, if
+	// it is in memory, e.g. a MemoryCache
+	DedupeCacheSize int `json:"dedupe_cache_size"`
+	// Workers is the number of dispatch workers, BusyWorkers those sending
+	// a payload
+	Workers     int `json:"workers"`
+	BusyWorkers int `json:"busy_workers"`
+	Sinks       int `json:"sinks"`
+}
+
+// Vars returns a snapshot of the internals of h
+func (h *Handler) Vars() HandlerVars {
+	h.init()
+	v := HandlerVars{
+		Dispositions: h.counts.snapshot(),
+		LastLag:      time.Duration(h.lastLag.Load()),
+		QueueDepth:   h.dispatcher.Backlog(),
+		Workers:      h.dispatcher.Workers,
+		BusyWorkers:  h.dispatcher.Busy(),
+		Sinks:        len(h.dispatcher.Sinks),
+	}
+	if c, ok := h.cache.(interface{ Memory() MemoryStats }); ok && h.Dedupe {
+		v.DedupeCacheSize = c.Memory().Entries
+	}
+	if t := h.verifier.lastKeyFetch.Load(); t != 0 {
+		v.LastKeyFetch = time.Unix(0, t)
+	}
+	return v
+}
+
+// DebugHandler returns an http.Handler serving the Vars of h as JSON, it
+// should only be reachable by operators
+func (h *Handler) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Vars())
+	})
+}
+
+// PublishExpvar publishes the Vars of h as the expvar name, it panics if
+// name is already in use
+func (h *Handler) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return h.Vars()
+	}))
+}
+
+type dispositionCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *dispositionCounts) add(disposition string) {
+	c.mu.Lock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[disposition]++
+	c.mu.Unlock()
+}
+
+func (c *dispositionCounts) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := make(map[string]int64, len(c.counts))
+	for k, v := range c.counts {
+		s[k] = v
+	}
+	return s
+}
@@ -0,0 +1,34 @@
+package travis_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func TestHandlerVarsDedupe(t *testing.T) {
+	s := travistest.NewServer()
+	defer s.Close()
+	tests := []struct {
+		name  string
+		cache travis.CacheBackend
+		ids   []int64
+		want  int
+	}{
+		{"default cache", nil, []int64{1, 2, 1}, 2},
+		{"memory cache", &travis.MemoryCache{MaxEntries: 2}, []int64{1, 2, 3}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &travis.Handler{ConfigURL: s.ConfigURL(), Dedupe: true, Cache: tt.cache}
+			for _, id := range tt.ids {
+				h.ServeHTTP(httptest.NewRecorder(), travistest.NewPayload().ID(id).Request(s.Key))
+			}
+			if got := h.Vars().DedupeCacheSize; got != tt.want {
+				t.Errorf("DedupeCacheSize = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
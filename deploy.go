@@ -0,0 +1,207 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Promotion is the request to deploy the artifacts of a passed build
+type Promotion struct {
+	// ID is the content address of the promotion: the SHA-256 of the
+	// repository, ref and commit, so rebuilds of a commit request the same
+	// promotion and targets can deduplicate them
+	ID   string `json:"id"`
+	Repo string `json:"repo"`
+	// Tag is set for tag builds, Branch for the others
+	Branch      string    `json:"branch,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+	Commit      string    `json:"commit"`
+	BuildNumber string    `json:"build_number"`
+	BuildID     int64     `json:"build_id"`
+	BuildURL    string    `json:"build_url"`
+	Event       string    `json:"event"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// NewPromotion returns the promotion of the build of p
+func NewPromotion(p *Payload) *Promotion {
+	pr := &Promotion{
+		Repo:        p.Slug(),
+		Commit:      p.Commit,
+		BuildNumber: p.Number,
+		BuildID:     p.ID,
+		BuildURL:    p.BuildURL,
+		Event:       p.Type,
+		FinishedAt:  p.FinishedAt,
+	}
+	ref := "refs/heads/" + p.Branch
+	if p.Tag != "" {
+		pr.Tag, ref = p.Tag, "refs/tags/"+p.Tag
+	} else {
+		pr.Branch = p.Branch
+	}
+	sum := sha256.Sum256([]byte(pr.Repo + "\x00" + ref + "\x00" + pr.Commit))
+	pr.ID = hex.EncodeToString(sum[:])
+	return pr
+}
+
+// PromotionTarget receives promotions, e.g. a deployment system
+type PromotionTarget interface {
+	Promote(ctx context.Context, pr *Promotion) error
+}
+
+// PromotionTargetFunc is an adapter to allow the use of ordinary functions
+// as a PromotionTarget
+type PromotionTargetFunc func(ctx context.Context, pr *Promotion) error
+
+// Promote calls f(ctx, pr)
+func (f PromotionTargetFunc) Promote(ctx context.Context, pr *Promotion) error {
+	return f(ctx, pr)
+}
+
+// DeploymentTrigger is a Sink handing passed builds over to deployment: it
+// sends the Promotion of each passed build of Branches or Tags to every
+// target. Pull request builds are never promoted.
+type DeploymentTrigger struct {
+	Targets []PromotionTarget
+
+	// Repos, Branches and Tags are path.Match patterns of the builds
+	// promoted. Every repository matches if Repos is empty, and every
+	// branch and tag if both Branches and Tags are.
+	Repos    []string
+	Branches []string
+	Tags     []string
+}
+
+// Send promotes p if it passed and matches
+func (t *DeploymentTrigger) Send(p *Payload) error {
+	return t.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (t *DeploymentTrigger) SendContext(ctx context.Context, p *Payload) error {
+	if Outcome(p) != OutcomePassed || p.IsPullRequest() || !MatchAny(t.Repos, p.Slug()) {
+		return nil
+	}
+	if len(t.Branches) > 0 || len(t.Tags) > 0 {
+		ok := p.Tag != "" && len(t.Tags) > 0 && MatchAny(t.Tags, p.Tag) ||
+			p.Tag == "" && len(t.Branches) > 0 && MatchAny(t.Branches, p.Branch)
+		if !ok {
+			return nil
+		}
+	}
+	pr := NewPromotion(p)
+	var errs []error
+	for _, target := range t.Targets {
+		if err := target.Promote(ctx, pr); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HTTPTarget is a PromotionTarget posting promotions as JSON to URL, with
+// their ID as the Idempotency-Key header
+type HTTPTarget struct {
+	URL string
+	// Header is added to the requests, e.g. for authorization
+	Header http.Header
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Promote posts pr
+func (t *HTTPTarget) Promote(ctx context.Context, pr *Promotion) error {
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range t.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", pr.ID)
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("promotion of %s: %s: %s", pr.Repo, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// QueueTarget is a PromotionTarget publishing promotions as JSON keyed by
+// their ID, with Publish wrapping the client of a queue, e.g. SQS, Kafka or
+// NATS
+type QueueTarget struct {
+	Publish func(ctx context.Context, key string, body []byte) error
+}
+
+// Promote publishes pr
+func (t *QueueTarget) Promote(ctx context.Context, pr *Promotion) error {
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return err
+	}
+	return t.Publish(ctx, pr.ID, body)
+}
+
+// ExecTarget is a PromotionTarget running a command for each promotion, the
+// JSON of the promotion on its standard input and its ID, repository, ref
+// and commit in the TRAVIS_PROMOTION_ID, TRAVIS_PROMOTION_REPO,
+// TRAVIS_PROMOTION_BRANCH, TRAVIS_PROMOTION_TAG and TRAVIS_PROMOTION_COMMIT
+// environment variables
+type ExecTarget struct {
+	Path string
+	Args []string
+	// Timeout stops the command after this duration, no limit if zero
+	Timeout time.Duration
+}
+
+// Promote runs the command for pr and returns its output with the error
+// if it fails
+func (t *ExecTarget) Promote(ctx context.Context, pr *Promotion) error {
+	body, err := json.Marshal(pr)
+	if err != nil {
+		return err
+	}
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, t.Path, t.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"TRAVIS_PROMOTION_ID="+pr.ID,
+		"TRAVIS_PROMOTION_REPO="+pr.Repo,
+		"TRAVIS_PROMOTION_BRANCH="+pr.Branch,
+		"TRAVIS_PROMOTION_TAG="+pr.Tag,
+		"TRAVIS_PROMOTION_COMMIT="+pr.Commit,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("promotion of %s: %s: %w: %s", pr.Repo, t.Path, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
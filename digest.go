@@ -0,0 +1,156 @@
+package travis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDigestInterval is how often a Digest is sent if NewDigest is
+// given no interval
+const DefaultDigestInterval = time.Hour
+
+// Digest buffers payloads and periodically sends a single summary message
+// to a Notifier instead of one message per build
+type Digest struct {
+	notifier Notifier
+	onError  func(err error)
+
+	mu       sync.Mutex
+	payloads []*Payload
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewDigest returns a Digest that sends a summary to n every interval,
+// DefaultDigestInterval if it isn't positive. Nothing is sent for an
+// interval without payloads. WithClock measures the
+// intervals with another clock, WithOnError reports the errors of the
+// periodic digests.
+func NewDigest(n Notifier, interval time.Duration, opts ...DigestOption) *Digest {
+	if interval <= 0 {
+		interval = DefaultDigestInterval
+	}
+	o := &digestOptions{clock: SystemClock}
+	for _, opt := range opts {
+		opt.applyDigest(o)
+	}
+	d := &Digest{
+		notifier: n,
+		onError:  o.onError,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	// the ticker starts before returning so intervals count from now
+	go d.run(clockOr(o.clock).NewTicker(interval))
+	return d
+}
+
+// Add buffers p until the next digest is sent
+func (d *Digest) Add(p *Payload) {
+	if p == nil {
+		return
+	}
+	d.mu.Lock()
+	d.payloads = append(d.payloads, p)
+	d.mu.Unlock()
+}
+
+// Flush sends a digest of the buffered payloads right away
+func (d *Digest) Flush() error {
+	d.mu.Lock()
+	payloads := d.payloads
+	d.payloads = nil
+	d.mu.Unlock()
+
+	if len(payloads) == 0 {
+		return nil
+	}
+	return d.notifier.Notify(Summarize(payloads))
+}
+
+// Close stops the periodic digests and sends the remaining payloads. It
+// can be called more than once.
+func (d *Digest) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+		<-d.done
+	})
+	return d.Flush()
+}
+
+func (d *Digest) run(ticker Ticker) {
+	defer close(d.done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C():
+			if err := d.Flush(); err != nil && d.onError != nil {
+				d.onError(err)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// Summarize builds a message counting the outcome of payloads and listing
+// the branches whose latest build is failing
+func Summarize(payloads []*Payload) *Message {
+	var passed, failed, errored, canceled, other int
+	latest := make(map[string]*Payload)
+	for _, p := range payloads {
+		switch Outcome(p) {
+		case OutcomePassed:
+			passed++
+		case OutcomeFailed:
+			failed++
+		case OutcomeErrored:
+			errored++
+		case OutcomeCanceled:
+			canceled++
+		default:
+			other++
+		}
+		key := p.Slug() + "@" + p.Branch
+		if l, ok := latest[key]; !ok || p.ID >= l.ID {
+			latest[key] = p
+		}
+	}
+
+	counts := []string{
+		fmt.Sprintf("%d passed", passed),
+		fmt.Sprintf("%d failed", failed),
+	}
+	if errored > 0 {
+		counts = append(counts, fmt.Sprintf("%d errored", errored))
+	}
+	if canceled > 0 {
+		counts = append(counts, fmt.Sprintf("%d canceled", canceled))
+	}
+	if other > 0 {
+		counts = append(counts, fmt.Sprintf("%d other", other))
+	}
+
+	var broken []string
+	for _, p := range latest {
+		if o := Outcome(p); o == OutcomeFailed || o == OutcomeErrored {
+			broken = append(broken, fmt.Sprintf("%s %s (%s) %s", StateEmoji(p), p.Slug(), p.Branch, p.BuildURL))
+		}
+	}
+	sort.Strings(broken)
+
+	text := strings.Join(counts, ", ")
+	if len(broken) > 0 {
+		text += "\nBroken:\n" + strings.Join(broken, "\n")
+	}
+
+	return &Message{
+		Title: fmt.Sprintf("Travis digest: %d builds", len(payloads)),
+		Text:  text,
+	}
+}
@@ -0,0 +1,46 @@
+package travis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func TestDigestInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		want     time.Duration
+	}{
+		{"interval", 10 * time.Minute, 10 * time.Minute},
+		{"zero", 0, travis.DefaultDigestInterval},
+		{"negative", -time.Minute, travis.DefaultDigestInterval},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := travistest.NewFakeClock(travistest.DefaultStart)
+			sent := make(chan *travis.Message, 1)
+			d := travis.NewDigest(travis.NotifierFunc(func(m *travis.Message) error {
+				sent <- m
+				return nil
+			}), tt.interval, travis.WithClock(clock))
+			defer d.Close()
+			d.Add(travistest.NewPayload().Build())
+
+			clock.Advance(tt.want - time.Second)
+			select {
+			case <-sent:
+				t.Fatal("digest sent early")
+			case <-time.After(10 * time.Millisecond):
+			}
+			clock.Advance(time.Second)
+			select {
+			case <-sent:
+			case <-time.After(time.Second):
+				t.Fatal("digest not sent")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package travis
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Directive is a bracketed command of a commit message, e.g. [skip notify]
+// or [deploy staging]
+type Directive struct {
+	// Name is the registered command, e.g. "deploy", or the first word of
+	// the directive when parsed without Commands
+	Name string
+	Args []string
+}
+
+var directivePattern = regexp.MustCompile(`\[([^\[\]]+)\]`)
+
+// ParseDirectives returns the bracketed directives of message, in order,
+// split in their first word and arguments
+func ParseDirectives(message string) []Directive {
+	var ds []Directive
+	for _, m := range directivePattern.FindAllStringSubmatch(message, -1) {
+		fields := strings.Fields(m[1])
+		if len(fields) == 0 {
+			continue
+		}
+		ds = append(ds, Directive{Name: strings.ToLower(fields[0]), Args: fields[1:]})
+	}
+	return ds
+}
+
+// HasDirective returns the directive of the commit message of p starting
+// with name, e.g. [deploy staging] for "deploy" with the argument staging,
+// for sinks acting on directives themselves
+func HasDirective(p *Payload, name string) (Directive, bool) {
+	words := strings.Fields(strings.ToLower(name))
+	for _, d := range ParseDirectives(p.Message) {
+		if args, ok := cutWords(append([]string{d.Name}, d.Args...), words); ok {
+			return Directive{Name: name, Args: args}, true
+		}
+	}
+	return Directive{}, false
+}
+
+// Command handles a directive of a commit message. It returns false to
+// keep the payload from the sinks of the Dispatcher not wrapped by Record.
+type Command func(ctx context.Context, p *Payload, d Directive) (send bool, err error)
+
+// SkipNotify is a Command keeping payloads from the sinks not wrapped by
+// Record, registered as [skip notify] by NewCommands
+func SkipNotify(ctx context.Context, p *Payload, d Directive) (bool, error) {
+	return false, nil
+}
+
+// Commands is a registry of the directives developers write in commit
+// messages to influence automation per commit, like [skip ci] for Travis.
+// A Dispatcher with Commands runs the commands of each payload before
+// sending it. Directives that aren't registered are ignored.
+type Commands struct {
+	mu       sync.RWMutex
+	commands map[string]Command
+}
+
+// NewCommands returns a registry with SkipNotify as [skip notify]
+func NewCommands() *Commands {
+	c := new(Commands)
+	c.Register("skip notify", SkipNotify)
+	return c
+}
+
+// Register registers cmd as name, one or more words matched
+// case-insensitively, the following words of the directive being its
+// arguments
+func (c *Commands) Register(name string, cmd Command) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.commands == nil {
+		c.commands = make(map[string]Command)
+	}
+	c.commands[strings.Join(strings.Fields(strings.ToLower(name)), " ")] = cmd
+}
+
+// Directives returns the directives of p matching a registered command,
+// with the name of the longest matching command
+func (c *Commands) Directives(p *Payload) []Directive {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var ds []Directive
+	for _, d := range ParseDirectives(p.Message) {
+		words := append([]string{d.Name}, d.Args...)
+		for n := len(words); n > 0; n-- {
+			name := strings.ToLower(strings.Join(words[:n], " "))
+			if _, ok := c.commands[name]; ok {
+				ds = append(ds, Directive{Name: name, Args: words[n:]})
+				break
+			}
+		}
+	}
+	return ds
+}
+
+// Run runs the commands of the directives of p and returns false if one of
+// them keeps p from the sinks
+func (c *Commands) Run(ctx context.Context, p *Payload) (bool, error) {
+	send := true
+	var errs []error
+	for _, d := range c.Directives(p) {
+		c.mu.RLock()
+		cmd := c.commands[d.Name]
+		c.mu.RUnlock()
+		ok, err := cmd(ctx, p, d)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		send = send && ok
+	}
+	return send, errors.Join(errs...)
+}
+
+// cutWords returns the words following prefix if words starts with it,
+// compared case-insensitively
+func cutWords(words, prefix []string) ([]string, bool) {
+	if len(prefix) == 0 || len(words) < len(prefix) {
+		return nil, false
+	}
+	for i, w := range prefix {
+		if !strings.EqualFold(words[i], w) {
+			return nil, false
+		}
+	}
+	return words[len(prefix):], true
+}
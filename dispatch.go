@@ -0,0 +1,408 @@
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrDispatcherClosed is returned when dispatching to a closed Dispatcher
+var ErrDispatcherClosed = errors.New("dispatcher closed")
+
+// ErrQueueFull is returned by a Dispatcher with FailFast whose queue is full
+var ErrQueueFull = errors.New("dispatcher queue full")
+
+// DefaultQueueSize is the queue capacity of a Dispatcher if QueueSize isn't set
+const DefaultQueueSize = 100
+
+// Dispatcher sends payloads to sinks. Without Workers, Dispatch sends the
+// payload before returning. With Workers, Dispatch queues the payload and
+// returns while the workers send queued payloads in the background.
+//
+// Payloads with the same Key are never sent by more than KeyConcurrency
+// goroutines at a time and are sent in the order they were dispatched, e.g.
+// with RepoKey two deployments of a repository never run concurrently while
+// different repositories proceed in parallel.
+//
+// The fields must not be changed after the first call to Dispatch.
+type Dispatcher struct {
+	Sinks []Sink
+
+	// Workers is the number of goroutines sending queued payloads, zero
+	// dispatches synchronously
+	Workers int
+	// QueueSize is the capacity of the queue, DefaultQueueSize if zero.
+	// Dispatch blocks while the queue is full, unless FailFast is set.
+	QueueSize int
+	// FailFast, if true, makes Dispatch return ErrQueueFull rather than
+	// block while the queue is full, for the Handler to shed load
+	FailFast bool
+
+	// Key, if set, groups the payloads that must be serialized
+	Key func(p *Payload) string
+	// KeyConcurrency is the number of payloads of a key sent concurrently, 1 if zero
+	KeyConcurrency int
+
+	// Priority, if set, ranks the queued payloads: the workers send the
+	// ones of the highest priority first and the ones of a priority in the
+	// order they were dispatched, e.g. with BranchPriority the failures of
+	// the default branch overtake a burst of pull requests. The payloads of
+	// a Key are still sent in order: a payload waits behind the ones of its
+	// key queued before it, whatever their priority.
+	Priority func(p *Payload) int
+
+	// OnError, if set, is called with the sink errors of queued payloads,
+	// which can't be returned by Dispatch
+	OnError func(p *Payload, err error)
+
+	// Maintenance, if set, suppresses the sinks not wrapped by Record for
+	// the payloads covered by its windows
+	Maintenance *Maintenance
+
+	// Commands, if set, run the directives of the commit message of each
+	// payload before it is sent, see NewCommands
+	Commands *Commands
+
+	// Journal, if set, persists queued payloads until they are sent, it is
+	// unused without Workers. The payloads still pending when the
+	// Dispatcher starts are queued first.
+	Journal Journal
+
+	Tracer Tracer
+	Logger *slog.Logger
+
+	once   sync.Once
+	log    *slog.Logger
+	queue  *dispatchQueue
+	wg     sync.WaitGroup
+	closed bool
+	closer sync.RWMutex
+	busy   atomic.Int64
+
+	keysMu  sync.Mutex
+	running map[string]int
+	pending map[string][]JournalEntry
+	sems    map[string]*keySem
+}
+
+// keySem limits the synchronous sends of a key, refs counts the sends
+// holding or waiting for it so it is deleted once idle
+type keySem struct {
+	c    chan struct{}
+	refs int
+}
+
+// RepoKey is a Dispatcher.Key serializing payloads per repository
+func RepoKey(p *Payload) string {
+	return p.Slug()
+}
+
+func (d *Dispatcher) init() {
+	d.once.Do(func() {
+		d.log = d.Logger
+		if d.log == nil {
+			d.log = discardLogger
+		}
+		d.running = make(map[string]int)
+		d.pending = make(map[string][]JournalEntry)
+		d.sems = make(map[string]*keySem)
+		if d.Workers <= 0 {
+			return
+		}
+		size := d.QueueSize
+		if size <= 0 {
+			size = DefaultQueueSize
+		}
+		var restored []JournalEntry
+		if d.Journal != nil {
+			var err error
+			if restored, err = d.Journal.Pending(); err != nil {
+				d.log.Error("restoring dispatcher journal failed", "error", err)
+			}
+		}
+		d.queue = newDispatchQueue(size + len(restored))
+		for _, e := range restored {
+			d.push(context.Background(), e, false)
+		}
+		if len(restored) > 0 {
+			d.log.Info("restored queued payloads", "count", len(restored))
+		}
+		d.wg.Add(d.Workers)
+		for i := 0; i < d.Workers; i++ {
+			go d.work()
+		}
+	})
+}
+
+// Async returns true if payloads are queued
+func (d *Dispatcher) Async() bool {
+	return d.Workers > 0
+}
+
+// Dispatch sends p to the sinks, or queues it if the Dispatcher has workers.
+// It returns the first sink error of a synchronous dispatch.
+func (d *Dispatcher) Dispatch(ctx context.Context, p *Payload) error {
+	d.init()
+	d.closer.RLock()
+	defer d.closer.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	if d.queue == nil {
+		release, err := d.acquire(ctx, p)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return d.send(ctx, p, nil)
+	}
+
+	e := JournalEntry{Payload: p, ctx: context.WithoutCancel(ctx)}
+	if d.Journal != nil {
+		var err error
+		if e.Key, err = d.Journal.Append(p); err != nil {
+			return err
+		}
+	}
+	if err := d.push(ctx, e, d.FailFast); err != nil {
+		if d.Journal != nil {
+			d.Journal.Remove(e.Key)
+		}
+		return err
+	}
+	return nil
+}
+
+// dispatchTo sends p to s alone, or queues it if the Dispatcher has
+// workers, and calls done with the error of s once it is sent. The payload
+// isn't journaled, the Scheduler keeps it until done.
+func (d *Dispatcher) dispatchTo(ctx context.Context, s Sink, p *Payload, done func(err error)) error {
+	d.init()
+	d.closer.RLock()
+	defer d.closer.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	if d.queue == nil {
+		release, err := d.acquire(ctx, p)
+		if err != nil {
+			return err
+		}
+		defer release()
+		done(d.send(ctx, p, s))
+		return nil
+	}
+	e := JournalEntry{Payload: p, ctx: context.WithoutCancel(ctx), sink: s, done: done}
+	return d.push(ctx, e, d.FailFast)
+}
+
+// Close stops accepting payloads and waits for the queued ones to be sent
+func (d *Dispatcher) Close() error {
+	d.init()
+	d.closer.Lock()
+	if d.closed {
+		d.closer.Unlock()
+		return nil
+	}
+	d.closed = true
+	if d.queue != nil {
+		d.queue.close()
+	}
+	d.closer.Unlock()
+	d.wg.Wait()
+	return nil
+}
+
+// Backlog returns the number of payloads waiting to be sent
+func (d *Dispatcher) Backlog() int {
+	d.init()
+	d.keysMu.Lock()
+	n := 0
+	for _, pending := range d.pending {
+		n += len(pending)
+	}
+	d.keysMu.Unlock()
+	return n + d.queue.len()
+}
+
+// Busy returns the number of payloads being sent
+func (d *Dispatcher) Busy() int {
+	return int(d.busy.Load())
+}
+
+// work sends queued payloads. A payload whose key is at its concurrency
+// limit is parked until a worker sending the same key is done, that worker
+// then sends it so the order of payloads of a key is kept.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		e, ok := d.queue.pop()
+		if !ok {
+			return
+		}
+		key := d.key(e.Payload)
+		d.keysMu.Lock()
+		if d.running[key] >= d.keyConcurrency() {
+			d.pending[key] = append(d.pending[key], e)
+			d.keysMu.Unlock()
+			continue
+		}
+		d.running[key]++
+		d.keysMu.Unlock()
+
+		for e.Payload != nil {
+			ctx := e.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			err := d.send(ctx, e.Payload, e.sink)
+			if err != nil && d.OnError != nil {
+				d.OnError(e.Payload, err)
+			}
+			if e.done != nil {
+				e.done(err)
+			}
+			if d.Journal != nil && e.Key != "" {
+				if err := d.Journal.Remove(e.Key); err != nil {
+					d.log.Error("removing payload from dispatcher journal failed", "build", e.Payload.ID, "error", err)
+				}
+			}
+			d.keysMu.Lock()
+			e = JournalEntry{}
+			if pending := d.pending[key]; len(pending) > 0 {
+				e = pending[0]
+				if len(pending) == 1 {
+					delete(d.pending, key)
+				} else {
+					d.pending[key] = pending[1:]
+				}
+			} else if d.running[key]--; d.running[key] == 0 {
+				delete(d.running, key)
+			}
+			d.keysMu.Unlock()
+		}
+	}
+}
+
+// acquire blocks until p may be sent synchronously
+func (d *Dispatcher) acquire(ctx context.Context, p *Payload) (release func(), err error) {
+	if d.Key == nil {
+		return func() {}, nil
+	}
+	key := d.key(p)
+	d.keysMu.Lock()
+	sem, ok := d.sems[key]
+	if !ok {
+		sem = &keySem{c: make(chan struct{}, d.keyConcurrency())}
+		d.sems[key] = sem
+	}
+	sem.refs++
+	d.keysMu.Unlock()
+
+	unref := func() {
+		d.keysMu.Lock()
+		if sem.refs--; sem.refs == 0 {
+			delete(d.sems, key)
+		}
+		d.keysMu.Unlock()
+	}
+	select {
+	case sem.c <- struct{}{}:
+		return func() {
+			<-sem.c
+			unref()
+		}, nil
+	case <-ctx.Done():
+		unref()
+		return nil, ctx.Err()
+	}
+}
+
+func (d *Dispatcher) key(p *Payload) string {
+	if d.Key == nil {
+		return ""
+	}
+	return d.Key(p)
+}
+
+// push queues e with its key and priority
+func (d *Dispatcher) push(ctx context.Context, e JournalEntry, failFast bool) error {
+	return d.queue.push(ctx, queued{
+		JournalEntry: e,
+		priority:     d.priority(e.Payload),
+		key:          d.key(e.Payload),
+		keyed:        d.Key != nil,
+	}, failFast)
+}
+
+func (d *Dispatcher) priority(p *Payload) int {
+	if d.Priority == nil {
+		return 0
+	}
+	return d.Priority(p)
+}
+
+func (d *Dispatcher) keyConcurrency() int {
+	if d.Key == nil {
+		// every payload shares the empty key, don't limit them
+		return int(^uint(0) >> 1)
+	}
+	if d.KeyConcurrency <= 0 {
+		return 1
+	}
+	return d.KeyConcurrency
+}
+
+// send sends p to every sink, or to only if it is set, and returns the
+// first error
+func (d *Dispatcher) send(ctx context.Context, p *Payload, only Sink) error {
+	d.busy.Add(1)
+	defer d.busy.Add(-1)
+
+	var window MaintenanceWindow
+	suppress := false
+	if d.Maintenance != nil {
+		if window, suppress = d.Maintenance.Active(p); suppress {
+			d.log.Info("webhook sinks suppressed by maintenance window", "window", window.ID, "repo", p.Slug(), "build", p.ID)
+		}
+	}
+	sinks := d.Sinks
+	if only != nil {
+		// the commands already ran when p was first dispatched
+		sinks = []Sink{only}
+	} else if d.Commands != nil {
+		send, err := d.Commands.Run(ctx, p)
+		if err != nil {
+			d.log.Error("webhook command failed", "repo", p.Slug(), "build", p.ID, "error", err)
+		}
+		if !send {
+			d.log.Info("webhook sinks suppressed by commit message", "repo", p.Slug(), "build", p.ID)
+			suppress = true
+		}
+	}
+
+	var first error
+	for _, s := range sinks {
+		r, recording := s.(recorder)
+		if recording {
+			s = r.Sink
+		} else if suppress {
+			continue
+		}
+		sctx, end := startSpan(d.Tracer, ctx, fmt.Sprintf("travis.sink %T", s))
+		err := SendContext(sctx, s, p)
+		end(p, err)
+		if err != nil {
+			d.log.Error("webhook sink failed", "sink", fmt.Sprintf("%T", s), "repo", p.Slug(), "build", p.ID, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
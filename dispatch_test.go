@@ -0,0 +1,150 @@
+package travis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPayload(id int64, repo, branch string) *Payload {
+	return &Payload{ID: id, Branch: branch, Repository: &Repository{OwnerName: "acme", Name: repo}}
+}
+
+// orderSink records the payload IDs sent per repository and fails if more
+// than limit payloads of a repository are sent at a time
+type orderSink struct {
+	t     *testing.T
+	limit int
+
+	mu      sync.Mutex
+	running map[string]int
+	sent    map[string][]int64
+}
+
+func (s *orderSink) Send(p *Payload) error {
+	key := p.Slug()
+	s.mu.Lock()
+	if s.running[key]++; s.running[key] > s.limit {
+		s.t.Errorf("%s: %d payloads sent concurrently, want at most %d", key, s.running[key], s.limit)
+	}
+	s.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	s.mu.Lock()
+	s.running[key]--
+	s.sent[key] = append(s.sent[key], p.ID)
+	s.mu.Unlock()
+	return nil
+}
+
+func TestDispatcherKeyOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		workers  int
+		priority func(p *Payload) int
+	}{
+		{"one worker", 1, nil},
+		{"workers", 4, nil},
+		{"workers with priority", 4, BranchPriority()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &orderSink{t: t, limit: 1, running: make(map[string]int), sent: make(map[string][]int64)}
+			d := &Dispatcher{Sinks: []Sink{s}, Workers: tt.workers, Key: RepoKey, Priority: tt.priority}
+			want := make(map[string][]int64)
+			for i := int64(1); i <= 30; i++ {
+				// the branches vary the priority of the payloads of a key
+				p := testPayload(i, fmt.Sprintf("repo%d", i%3), []string{"main", "feature"}[i%2])
+				p.State = OutcomeFailed
+				want[p.Slug()] = append(want[p.Slug()], p.ID)
+				if err := d.Dispatch(context.Background(), p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d.Close()
+			for key, ids := range want {
+				if fmt.Sprint(s.sent[key]) != fmt.Sprint(ids) {
+					t.Errorf("%s: sent %v, want %v", key, s.sent[key], ids)
+				}
+			}
+			if len(d.running) != 0 || len(d.pending) != 0 {
+				t.Errorf("running %v, pending %v, want none", d.running, d.pending)
+			}
+		})
+	}
+}
+
+func TestDispatcherKeyConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		workers     int
+		concurrency int
+	}{
+		{"sync", 0, 1},
+		{"sync concurrency", 0, 2},
+		{"workers", 8, 1},
+		{"workers concurrency", 8, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &orderSink{t: t, limit: tt.concurrency, running: make(map[string]int), sent: make(map[string][]int64)}
+			d := &Dispatcher{Sinks: []Sink{s}, Workers: tt.workers, Key: RepoKey, KeyConcurrency: tt.concurrency}
+			var wg sync.WaitGroup
+			for i := int64(1); i <= 40; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := d.Dispatch(context.Background(), testPayload(i, fmt.Sprintf("repo%d", i%2), "main")); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+			d.Close()
+			if n := len(s.sent["acme/repo0"]) + len(s.sent["acme/repo1"]); n != 40 {
+				t.Errorf("sent %d payloads, want 40", n)
+			}
+		})
+	}
+}
+
+func TestDispatcherSemsCleanup(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 10)
+	d := &Dispatcher{
+		Sinks: []Sink{SinkFunc(func(p *Payload) error {
+			started <- struct{}{}
+			<-block
+			return nil
+		})},
+		Key: RepoKey,
+	}
+
+	var wg sync.WaitGroup
+	for i := int64(1); i <= 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Dispatch(context.Background(), testPayload(i, fmt.Sprintf("repo%d", i%3), "main"))
+		}()
+	}
+	// wait for the three keys to be sent, then for a dispatch giving up
+	// while waiting for its key
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Dispatch(ctx, testPayload(11, "repo0", "main")); err != context.DeadlineExceeded {
+		t.Errorf("Dispatch() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(block)
+	wg.Wait()
+
+	d.keysMu.Lock()
+	defer d.keysMu.Unlock()
+	if len(d.sems) != 0 {
+		t.Errorf("%d key semaphores left, want 0", len(d.sems))
+	}
+}
@@ -0,0 +1,120 @@
+package travis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Regression reports a build that took longer than its baseline
+type Regression struct {
+	Payload  *Payload
+	Duration time.Duration
+	// Baseline is the median duration of the previous passed builds
+	Baseline time.Duration
+	// Increase is the relative increase over the baseline, 0.5 is 50% slower
+	Increase float64
+}
+
+// DurationMonitor is a Sink comparing the duration of passed builds with the
+// median of the previous passed builds of their branch, reporting the builds
+// slower than the baseline by more than Threshold. Failed builds are ignored
+// as they often stop early.
+type DurationMonitor struct {
+	// Threshold is the relative increase reported, 0.2 reports builds 20%
+	// slower than the baseline
+	Threshold float64
+	// Window is the number of builds the baseline is computed over, 20 if zero
+	Window int
+	// MinBuilds is the number of builds required for a baseline, 5 if zero
+	MinBuilds int
+
+	// OnRegression and Notifier, if set, are told about regressions
+	OnRegression func(r Regression)
+	Notifier     Notifier
+
+	// MaxEntries is the number of branches tracked, DefaultMaxEntries if
+	// zero, and MaxBytes an optional bound of their estimated size. The
+	// least recently built are evicted first.
+	MaxEntries int
+	MaxBytes   int64
+
+	mu        sync.Mutex
+	durations lru[string, []time.Duration]
+}
+
+// Send compares the duration of p to its baseline and records it
+func (m *DurationMonitor) Send(p *Payload) error {
+	return m.SendContext(context.Background(), p)
+}
+
+// SendContext is Send notifying regressions with ctx
+func (m *DurationMonitor) SendContext(ctx context.Context, p *Payload) error {
+	if Outcome(p) != OutcomePassed || p.Duration <= 0 {
+		return nil
+	}
+	window, minBuilds := m.Window, m.MinBuilds
+	if window <= 0 {
+		window = 20
+	}
+	if minBuilds <= 0 {
+		minBuilds = 5
+	}
+
+	key := p.Slug() + "@" + p.Branch
+	duration := time.Duration(p.Duration) * time.Second
+
+	m.mu.Lock()
+	m.durations.Size = durationsSize
+	m.durations.limit(m.MaxEntries, m.MaxBytes)
+	previous, _ := m.durations.Get(key)
+	var baseline time.Duration
+	if len(previous) >= minBuilds {
+		sorted := append([]time.Duration(nil), previous...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		baseline = percentile(sorted, 50)
+	}
+	previous = append(previous, duration)
+	if len(previous) > window {
+		previous = previous[len(previous)-window:]
+	}
+	m.durations.Put(key, previous)
+	m.mu.Unlock()
+
+	if baseline <= 0 {
+		return nil
+	}
+	increase := float64(duration-baseline) / float64(baseline)
+	if increase <= m.Threshold {
+		return nil
+	}
+
+	r := Regression{Payload: p, Duration: duration, Baseline: baseline, Increase: increase}
+	if m.OnRegression != nil {
+		m.OnRegression(r)
+	}
+	if m.Notifier != nil {
+		return NotifyContext(ctx, m.Notifier, &Message{
+			Title: fmt.Sprintf("Build duration regression: %s (%s)", p.Slug(), p.Branch),
+			Text: fmt.Sprintf("Build #%s took %s, %.0f%% more than the median of %s\n%s",
+				p.Number, duration, increase*100, baseline, p.BuildURL),
+			Payload: p,
+		})
+	}
+	return nil
+}
+
+// Memory returns the number of branches tracked, their estimated size and
+// the number evicted
+func (m *DurationMonitor) Memory() MemoryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.durations.stats()
+}
+
+// durationsSize estimates the memory taken by the durations of key
+func durationsSize(key string, durations []time.Duration) int {
+	return 64 + len(key) + cap(durations)*8
+}
@@ -0,0 +1,95 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// EnvVar is an environment variable of a repository's settings. The API
+// only returns the Value of public variables.
+type EnvVar struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Public bool   `json:"public"`
+	// Branch restricts the variable to the builds of a branch, all if empty
+	Branch string `json:"branch,omitempty"`
+}
+
+// APIRepository is a repository as returned by the API
+type APIRepository struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	Slug          string `json:"slug"`
+	Active        bool   `json:"active"`
+	DefaultBranch struct {
+		Name string `json:"name"`
+	} `json:"default_branch"`
+}
+
+// Repos returns the repositories of owner, a user or an organization
+func (c *Client) Repos(ctx context.Context, owner string) ([]*APIRepository, error) {
+	var repos []*APIRepository
+	for offset := 0; ; {
+		var res struct {
+			Pagination   Pagination       `json:"@pagination"`
+			Repositories []*APIRepository `json:"repositories"`
+		}
+		path := fmt.Sprintf("/owner/%s/repos?limit=100&offset=%d", url.PathEscape(owner), offset)
+		if err := c.do(ctx, "GET", path, nil, &res); err != nil {
+			return nil, err
+		}
+		repos = append(repos, res.Repositories...)
+		if res.Pagination.IsLast || len(res.Repositories) == 0 {
+			return repos, nil
+		}
+		offset += len(res.Repositories)
+	}
+}
+
+// EnvVars returns the environment variables of the repository slug
+func (c *Client) EnvVars(ctx context.Context, slug string) ([]*EnvVar, error) {
+	var res struct {
+		EnvVars []*EnvVar `json:"env_vars"`
+	}
+	err := c.do(ctx, "GET", "/repo/"+url.PathEscape(slug)+"/env_vars", nil, &res)
+	return res.EnvVars, err
+}
+
+// CreateEnvVar adds v to the repository slug and returns it with its ID
+func (c *Client) CreateEnvVar(ctx context.Context, slug string, v *EnvVar) (*EnvVar, error) {
+	created := new(EnvVar)
+	err := c.do(ctx, "POST", "/repo/"+url.PathEscape(slug)+"/env_vars", envVarBody(v), created)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateEnvVar replaces the variable with v.ID in the repository slug
+func (c *Client) UpdateEnvVar(ctx context.Context, slug string, v *EnvVar) error {
+	path := fmt.Sprintf("/repo/%s/env_var/%s", url.PathEscape(slug), url.PathEscape(v.ID))
+	return c.do(ctx, "PATCH", path, envVarBody(v), nil)
+}
+
+// DeleteEnvVar removes the variable with id from the repository slug
+func (c *Client) DeleteEnvVar(ctx context.Context, slug, id string) error {
+	path := fmt.Sprintf("/repo/%s/env_var/%s", url.PathEscape(slug), url.PathEscape(id))
+	return c.do(ctx, "DELETE", path, nil, nil)
+}
+
+func envVarBody(v *EnvVar) *bytes.Reader {
+	body := map[string]interface{}{
+		"env_var.name":   v.Name,
+		"env_var.value":  v.Value,
+		"env_var.public": v.Public,
+	}
+	if v.Branch != "" {
+		body["env_var.branch"] = v.Branch
+	}
+	b, _ := json.Marshal(body)
+	return bytes.NewReader(b)
+}
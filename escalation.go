@@ -0,0 +1,198 @@
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EscalationLevel is a step of an Escalation, reached once the branch
+// failed Builds times in a row or has been broken for After. A level
+// without thresholds is reached by the first failure.
+type EscalationLevel struct {
+	// Name identifies the level in messages, e.g. "team lead"
+	Name   string
+	Builds int
+	After  time.Duration
+	// Notifier is the action of the level, e.g. a channel, a Mention of
+	// the team lead or a pager
+	Notifier Notifier
+}
+
+// Escalation is a Sink escalating persistent failures of a branch through
+// its Levels: e.g. a message to the channel of the team at the first
+// failure, a mention of the team lead after 3 failing builds or an hour,
+// and a page to the on-call engineer after a day. Each level is notified
+// once per breakage, and a passed build resets the branch. Pull request
+// builds are ignored.
+//
+// Time thresholds are checked on each build and by Tick, which Run calls
+// every Interval.
+type Escalation struct {
+	Levels []EscalationLevel
+
+	// Interval is how often Run checks the time thresholds, a minute if
+	// zero
+	Interval time.Duration
+
+	// MaxEntries is the number of broken branches tracked,
+	// DefaultMaxEntries if zero. The least recently built are forgotten
+	// first.
+	MaxEntries int
+
+	// Clock tells the time, SystemClock if nil
+	Clock Clock
+
+	mu     sync.Mutex
+	broken lru[string, *escalated]
+}
+
+type escalated struct {
+	last    *Payload
+	since   time.Time
+	builds  int
+	reached int
+}
+
+// Send records the outcome of p and notifies the levels it reaches
+func (e *Escalation) Send(p *Payload) error {
+	return e.SendContext(context.Background(), p)
+}
+
+// SendContext is Send notifying with ctx
+func (e *Escalation) SendContext(ctx context.Context, p *Payload) error {
+	if p.IsPullRequest() {
+		return nil
+	}
+	key := p.Slug() + "@" + p.Branch
+	now := clockOr(e.Clock).Now()
+
+	e.mu.Lock()
+	e.broken.limit(e.MaxEntries, 0)
+	switch Outcome(p) {
+	case OutcomePassed:
+		e.broken.Remove(key)
+		e.mu.Unlock()
+		return nil
+	case OutcomeFailed, OutcomeErrored:
+	default:
+		e.mu.Unlock()
+		return nil
+	}
+	s, ok := e.broken.Get(key)
+	if !ok {
+		s = &escalated{since: now}
+		e.broken.Put(key, s)
+	}
+	s.last = p
+	s.builds++
+	r := e.reach(s, now)
+	e.mu.Unlock()
+	return e.notify(ctx, r)
+}
+
+// Tick notifies the levels reached at now by the branches still broken
+func (e *Escalation) Tick(now time.Time) error {
+	return e.TickContext(context.Background(), now)
+}
+
+// TickContext is Tick notifying with ctx
+func (e *Escalation) TickContext(ctx context.Context, now time.Time) error {
+	var all []escalation
+	e.mu.Lock()
+	e.broken.Each(func(_ string, s *escalated) {
+		if r := e.reach(s, now); len(r.levels) > 0 {
+			all = append(all, r)
+		}
+	})
+	e.mu.Unlock()
+
+	var errs []error
+	for _, r := range all {
+		if err := e.notify(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run calls Tick every Interval until ctx is done
+func (e *Escalation) Run(ctx context.Context) {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := clockOr(e.Clock).NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			e.TickContext(ctx, now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Level returns the name of the highest level reached by branch of the
+// repository slug, "" if it isn't broken or reached none
+func (e *Escalation) Level(slug, branch string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.broken.Peek(slug + "@" + branch)
+	if !ok || s.reached == 0 {
+		return ""
+	}
+	return e.Levels[s.reached-1].Name
+}
+
+// Memory reports the broken branches tracked
+func (e *Escalation) Memory() MemoryStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.broken.stats()
+}
+
+// escalation is the levels a broken branch reached
+type escalation struct {
+	p      *Payload
+	levels []EscalationLevel
+	builds int
+	broken time.Duration
+}
+
+// reach returns the levels s reaches at now and wasn't notified of
+func (e *Escalation) reach(s *escalated, now time.Time) escalation {
+	from := s.reached
+	for i := s.reached; i < len(e.Levels); i++ {
+		l := e.Levels[i]
+		byBuilds := l.Builds > 0 && s.builds >= l.Builds
+		byTime := l.After > 0 && now.Sub(s.since) >= l.After
+		if !byBuilds && !byTime && (l.Builds > 0 || l.After > 0) {
+			break
+		}
+		s.reached = i + 1
+	}
+	return escalation{p: s.last, levels: e.Levels[from:s.reached], builds: s.builds, broken: now.Sub(s.since)}
+}
+
+func (e *Escalation) notify(ctx context.Context, r escalation) error {
+	var errs []error
+	for _, l := range r.levels {
+		if l.Notifier == nil {
+			continue
+		}
+		err := NotifyContext(ctx, l.Notifier, &Message{
+			Title: fmt.Sprintf("%s (%s) failing, escalated to %s", r.p.Slug(), r.p.Branch, l.Name),
+			Text: fmt.Sprintf("Build #%s %s, %d builds failing in a row for %s\n%s",
+				r.p.Number, Outcome(r.p), r.builds, r.broken.Round(time.Minute), r.p.BuildURL),
+			Payload: r.p,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+// Package etcdlock is a travis.Locker in etcd, for receivers whose replicas
+// coordinate with etcd rather than Redis, e.g. on Kubernetes.
+//
+//	client, err := clientv3.New(clientv3.Config{Endpoints: []string{"etcd:2379"}})
+//	locker := &etcdlock.Locker{Client: client, Prefix: "/ci-hooks/"}
+//	h := &travis.Handler{Sinks: []travis.Sink{travis.Once(locker, "restart", restarter)}}
+//	leader := &travis.Leader{Locker: locker}
+//	go leader.Run(ctx, escalation.Run)
+//
+// Locks are keys attached to a lease of their TTL, which is the token of
+// their holder: refreshing a lock keeps its lease alive and unlocking it
+// revokes the lease.
+package etcdlock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var _ travis.Locker = (*Locker)(nil)
+
+// Locker is a travis.Locker in etcd
+type Locker struct {
+	// Client connects to etcd, with the endpoints and the credentials of
+	// its clientv3.Config
+	Client *clientv3.Client
+	// Prefix is prepended to the keys of the locks
+	Prefix string
+}
+
+// Lock acquires key for ttl, rounded up to a second, with a new lease
+func (l *Locker) Lock(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	lease, err := l.Client.Grant(ctx, max(secs, 1))
+	if err != nil {
+		return "", err
+	}
+	token := strconv.FormatInt(int64(lease.ID), 10)
+	k := l.Prefix + key
+	res, err := l.Client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, token, clientv3.WithLease(lease.ID))).
+		Commit()
+	if err != nil || !res.Succeeded {
+		l.revoke(context.WithoutCancel(ctx), lease.ID)
+		return "", err
+	}
+	return token, nil
+}
+
+// Refresh keeps the lease of token alive. Its TTL stays the one of Lock.
+func (l *Locker) Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	id, err := leaseID(token)
+	if err != nil {
+		return false, err
+	}
+	res, err := l.Client.KeepAliveOnce(ctx, id)
+	if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return res.TTL > 0, nil
+}
+
+// Unlock revokes the lease of token, deleting key
+func (l *Locker) Unlock(ctx context.Context, key, token string) error {
+	id, err := leaseID(token)
+	if err != nil {
+		return err
+	}
+	return l.revoke(ctx, id)
+}
+
+func (l *Locker) revoke(ctx context.Context, id clientv3.LeaseID) error {
+	_, err := l.Client.Revoke(ctx, id)
+	if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+		return nil
+	}
+	return err
+}
+
+func leaseID(token string) (clientv3.LeaseID, error) {
+	id, err := strconv.ParseInt(token, 10, 64)
+	return clientv3.LeaseID(id), err
+}
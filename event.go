@@ -0,0 +1,66 @@
+package travis
+
+import (
+	"time"
+
+	"github.com/jacksgt/travis/ci"
+)
+
+// ProviderTravis is the provider of the ci.Event of payloads
+const ProviderTravis = "travis"
+
+var _ ci.Event = (*Payload)(nil)
+
+// Provider returns ProviderTravis
+func (p *Payload) Provider() string {
+	return ProviderTravis
+}
+
+// EventID returns SourceID prefixed with the provider
+func (p *Payload) EventID() string {
+	return ProviderTravis + ":" + p.SourceID()
+}
+
+// EventState returns Outcome as a ci.State, running for started builds
+func (p *Payload) EventState() ci.State {
+	outcome := Outcome(p)
+	if outcome == OutcomePending && p.State == "started" {
+		return ci.StateRunning
+	}
+	return ci.State(outcome)
+}
+
+// Ref returns the tag built, or the branch
+func (p *Payload) Ref() string {
+	if p.Tag != "" {
+		return p.Tag
+	}
+	return p.Branch
+}
+
+// SHA returns the commit built
+func (p *Payload) SHA() string {
+	return p.Commit
+}
+
+// WebURL returns the build URL
+func (p *Payload) WebURL() string {
+	return p.BuildURL
+}
+
+// StartTime returns StartedAt
+func (p *Payload) StartTime() time.Time {
+	return p.StartedAt
+}
+
+// EndTime returns FinishedAt
+func (p *Payload) EndTime() time.Time {
+	return p.FinishedAt
+}
+
+// CISink returns a Sink passing payloads to the provider-agnostic s
+func CISink(s ci.Sink) Sink {
+	return SinkFunc(func(p *Payload) error {
+		return s.Send(p)
+	})
+}
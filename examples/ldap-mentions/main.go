@@ -0,0 +1,96 @@
+// Command ldap-mentions shows an IdentityResolver looking up the Slack ID of
+// commit authors in an LDAP directory, where it is stored in a custom
+// attribute of the user entry.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/jacksgt/travis"
+)
+
+// ldapResolver resolves emails with a search on the mail attribute
+type ldapResolver struct {
+	url      string
+	bindDN   string
+	password string
+	baseDN   string
+	attr     string
+}
+
+func (r *ldapResolver) Resolve(ctx context.Context, email string) (string, error) {
+	// the ldap client has no contexts, its timeouts follow the deadline
+	dialer := &net.Dialer{}
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		dialer.Deadline = deadline
+	}
+	conn, err := ldap.DialURL(r.url, ldap.DialWithDialer(dialer))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if hasDeadline {
+		conn.SetTimeout(time.Until(deadline))
+	}
+
+	if err := conn.Bind(r.bindDN, r.password); err != nil {
+		return "", err
+	}
+
+	req := ldap.NewSearchRequest(
+		r.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 0, false,
+		fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(email)),
+		[]string{r.attr}, nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		return "", err
+	}
+	if len(res.Entries) == 0 {
+		return "", nil
+	}
+	return res.Entries[0].GetAttributeValue(r.attr), nil
+}
+
+func main() {
+	notifier := &travis.Mention{
+		Notifier: travis.NotifierFunc(func(m *travis.Message) error {
+			// post to Slack here
+			log.Printf("%s: %s", m.Title, m.Text)
+			return nil
+		}),
+		Resolver: &ldapResolver{
+			url:      os.Getenv("LDAP_URL"),
+			bindDN:   os.Getenv("LDAP_BIND_DN"),
+			password: os.Getenv("LDAP_PASSWORD"),
+			baseDN:   os.Getenv("LDAP_BASE_DN"),
+			attr:     "slackId",
+		},
+		Format: travis.SlackMention,
+	}
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		p, err := travis.GetPayloadFromRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = notifier.NotifyContext(r.Context(), &travis.Message{
+			Title:   fmt.Sprintf("%s #%s %s", p.Slug(), p.Number, p.StatusMessage),
+			Text:    p.BuildURL,
+			Payload: p,
+		})
+		if err != nil {
+			log.Print(err)
+		}
+	})
+	log.Fatal(http.ListenAndServe(":8080", nil))
+}
@@ -0,0 +1,162 @@
+package travis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// DefaultExecTimeout is how long an Exec command may run if Timeout isn't
+// set
+const DefaultExecTimeout = time.Minute
+
+// maxExecOutput is the size of the output of a command kept by Exec
+const maxExecOutput = 64 << 10
+
+// execWaitDelay is how long Exec waits for the output of a command after
+// killing it, e.g. while a child it started still holds its stdout
+const execWaitDelay = 5 * time.Second
+
+// Exec is a Sink and a Notifier running a command for each build, the
+// simplest integration for ops scripts. The command gets the JSON of the
+// payload on its standard input, as Travis sent it if the payload was
+// decoded, and the build in the environment variables of Travis builds:
+//
+//	TRAVIS_BUILD_ID, TRAVIS_BUILD_NUMBER, TRAVIS_BUILD_WEB_URL,
+//	TRAVIS_BUILD_STATE (the outcome), TRAVIS_EVENT_TYPE, TRAVIS_REPO_SLUG,
+//	TRAVIS_BRANCH, TRAVIS_TAG, TRAVIS_COMMIT, TRAVIS_COMMIT_MESSAGE,
+//	TRAVIS_PULL_REQUEST (the number or false), TRAVIS_AUTHOR_NAME and
+//	TRAVIS_AUTHOR_EMAIL
+//
+// As a Notifier it also sets TRAVIS_MESSAGE_TITLE and TRAVIS_MESSAGE_TEXT.
+type Exec struct {
+	Path string
+	Args []string
+	// Dir is the working directory of the command, the current one if
+	// empty
+	Dir string
+	// Env is added to the environment of the process
+	Env []string
+
+	// Repos, Branches and Outcomes are path.Match patterns of the builds
+	// the command runs for, all if empty
+	Repos    []string
+	Branches []string
+	Outcomes []string
+
+	// Timeout kills the command after this duration, DefaultExecTimeout if
+	// zero
+	Timeout time.Duration
+	// OnOutput, if set, is called with the combined output of each run,
+	// up to 64KiB, and its error
+	OnOutput func(p *Payload, output []byte, err error)
+}
+
+// Send runs the command for p if it matches
+func (e *Exec) Send(p *Payload) error {
+	return e.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (e *Exec) SendContext(ctx context.Context, p *Payload) error {
+	return e.run(ctx, p, nil)
+}
+
+// Notify runs the command for the build of m, if it matches, with the
+// title and text of m
+func (e *Exec) Notify(m *Message) error {
+	return e.NotifyContext(context.Background(), m)
+}
+
+// NotifyContext is Notify with ctx
+func (e *Exec) NotifyContext(ctx context.Context, m *Message) error {
+	p := m.Payload
+	if p == nil {
+		p = &Payload{}
+	}
+	return e.run(ctx, p, []string{"TRAVIS_MESSAGE_TITLE=" + m.Title, "TRAVIS_MESSAGE_TEXT=" + m.Text})
+}
+
+func (e *Exec) run(ctx context.Context, p *Payload, env []string) error {
+	if !MatchAny(e.Repos, p.Slug()) || !MatchAny(e.Branches, p.Branch) || !MatchAny(e.Outcomes, Outcome(p)) {
+		return nil
+	}
+	body := []byte(p.Raw())
+	if len(body) == 0 {
+		var err error
+		if body, err = json.Marshal(p); err != nil {
+			return err
+		}
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.Path, e.Args...)
+	cmd.Dir = e.Dir
+	cmd.WaitDelay = execWaitDelay
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(append(append(os.Environ(), e.Env...), BuildEnv(p)...), env...)
+	out := &limitedBuffer{max: maxExecOutput}
+	cmd.Stdout, cmd.Stderr = out, out
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%w after %s", ctx.Err(), timeout)
+	}
+	if e.OnOutput != nil {
+		e.OnOutput(p, out.Bytes(), err)
+	}
+	if err != nil {
+		return fmt.Errorf("exec %s: %w: %s", e.Path, err, bytes.TrimSpace(out.Bytes()))
+	}
+	return nil
+}
+
+// BuildEnv returns the TRAVIS_* environment variables of the build of p,
+// see Exec
+func BuildEnv(p *Payload) []string {
+	pr := "false"
+	if p.IsPullRequest() {
+		pr = strconv.Itoa(p.PullRequestNumber)
+	}
+	return []string{
+		"TRAVIS_BUILD_ID=" + strconv.FormatInt(p.ID, 10),
+		"TRAVIS_BUILD_NUMBER=" + p.Number,
+		"TRAVIS_BUILD_WEB_URL=" + p.BuildURL,
+		"TRAVIS_BUILD_STATE=" + Outcome(p),
+		"TRAVIS_EVENT_TYPE=" + p.Type,
+		"TRAVIS_REPO_SLUG=" + p.Slug(),
+		"TRAVIS_BRANCH=" + p.Branch,
+		"TRAVIS_TAG=" + p.Tag,
+		"TRAVIS_COMMIT=" + p.Commit,
+		"TRAVIS_COMMIT_MESSAGE=" + p.Message,
+		"TRAVIS_PULL_REQUEST=" + pr,
+		"TRAVIS_AUTHOR_NAME=" + p.AuthorName,
+		"TRAVIS_AUTHOR_EMAIL=" + p.AuthorEmail,
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if n := b.max - b.Len(); n > 0 {
+		if len(p) > n {
+			b.Buffer.Write(p[:n])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
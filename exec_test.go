@@ -0,0 +1,38 @@
+package travis_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestExecStdin(t *testing.T) {
+	decoded := new(travis.Payload)
+	if err := json.Unmarshal([]byte(`{"id": 1, "branch": "main", "unmodeled": "kept"}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		p    *travis.Payload
+		want string
+	}{
+		{"decoded", decoded, `{"id": 1, "branch": "main", "unmodeled": "kept"}`},
+		{"built", &travis.Payload{ID: 2, Branch: "main"}, `{"id":2,`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			e := &travis.Exec{Path: "cat", OnOutput: func(p *travis.Payload, output []byte, err error) {
+				got = string(output)
+			}}
+			if err := e.Send(tt.p); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(got, tt.want) {
+				t.Errorf("stdin = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
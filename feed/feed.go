@@ -0,0 +1,173 @@
+// Package feed serves the recent builds of repositories as Atom feeds, for
+// following CI status in a feed reader.
+//
+//	http.Handle("/feeds/", http.StripPrefix("/feeds/", &feed.Handler{Store: s}))
+//
+// serves the builds of acme/api at /feeds/acme/api.atom and those of its main
+// branch at /feeds/acme/api/main.atom.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+)
+
+// DefaultLimit is the number of builds of a feed when the Handler has no
+// Limit
+const DefaultLimit = 20
+
+// Feed is an Atom feed
+type Feed struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    []Link   `xml:"link"`
+	Entries []*Entry `xml:"entry"`
+}
+
+// Link is the link of a feed or an entry
+type Link struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// Entry is a build of a Feed
+type Entry struct {
+	ID       string   `xml:"id"`
+	Title    string   `xml:"title"`
+	Updated  string   `xml:"updated"`
+	Author   Author   `xml:"author"`
+	Link     Link     `xml:"link"`
+	Category Category `xml:"category"`
+	Summary  string   `xml:"summary"`
+}
+
+// Author is the author of the commit of an Entry
+type Author struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+// Category is the outcome of the build of an Entry
+type Category struct {
+	Term string `xml:"term,attr"`
+}
+
+// New returns the feed with id and title of builds, newest first
+func New(id, title string, builds []*travis.Payload) *Feed {
+	f := &Feed{ID: id, Title: title, Link: []Link{{Href: id, Rel: "self"}}}
+	var updated time.Time
+	for _, p := range builds {
+		e := NewEntry(p)
+		f.Entries = append(f.Entries, e)
+		if t := store.BuildTime(p); t.After(updated) {
+			updated = t
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	f.Updated = updated.UTC().Format(time.RFC3339)
+	return f
+}
+
+// NewEntry returns the entry of the build of p, identified by its build URL
+func NewEntry(p *travis.Payload) *Entry {
+	id := p.BuildURL
+	if id == "" {
+		id = "urn:travis:build:" + p.SourceID()
+	}
+	message, _, _ := strings.Cut(p.Message, "\n")
+	author := p.AuthorName
+	if author == "" {
+		author = "unknown"
+	}
+	summary := fmt.Sprintf("%s on %s by %s: %s", travis.StageStatus(p), p.Branch, author, message)
+	if !p.StartedAt.IsZero() && p.FinishedAt.After(p.StartedAt) {
+		summary += fmt.Sprintf(" (%s)", p.FinishedAt.Sub(p.StartedAt).Round(time.Second))
+	}
+	return &Entry{
+		ID:       id,
+		Title:    fmt.Sprintf("%s #%s %s (%s)", p.Slug(), p.Number, travis.Outcome(p), p.Branch),
+		Updated:  store.BuildTime(p).UTC().Format(time.RFC3339),
+		Author:   Author{Name: author, Email: p.AuthorEmail},
+		Link:     Link{Href: p.BuildURL},
+		Category: Category{Term: travis.Outcome(p)},
+		Summary:  summary,
+	}
+}
+
+// Write writes f as XML
+func (f *Feed) Write(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(f); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Handler serves the feeds of a store at <owner>/<repo>.atom for every
+// branch and <owner>/<repo>/<branch>.atom for one, relative to where it is
+// mounted with http.StripPrefix. Branches may contain slashes.
+type Handler struct {
+	Store store.Store
+	// Limit is the number of builds of a feed, DefaultLimit if zero
+	Limit int
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if !strings.HasSuffix(path, ".atom") {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.SplitN(strings.TrimSuffix(path, ".atom"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" || (len(parts) == 3 && parts[2] == "") {
+		http.NotFound(w, r)
+		return
+	}
+	q := store.Query{Repo: parts[0] + "/" + parts[1], Limit: h.Limit}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	title := q.Repo + " builds"
+	if len(parts) == 3 {
+		q.Branch = parts[2]
+		title = q.Repo + " builds on " + q.Branch
+	}
+	builds, err := h.Store.Builds(r.Context(), q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	New(selfURL(r), title, builds).Write(w)
+}
+
+// selfURL returns the absolute URL of r, the ID of its feed
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	// RequestURI is the path before http.StripPrefix
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	return scheme + "://" + r.Host + path
+}
@@ -0,0 +1,520 @@
+package travis
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter is a compiled filter expression, for filters written in
+// configuration files rather than code:
+//
+//	repo == "acme/api" && branch matches "release/*" && state in ["failed", "errored"]
+//
+// The fields of the payload are
+//
+//	repo, owner, name     the repository, e.g. acme/api, acme and api
+//	branch, tag, commit   the ref and commit of the build
+//	state                 the outcome: passed, failed, errored, canceled or pending
+//	status                the status message, e.g. Fixed, Broken or Still Failing
+//	type                  the event: push, pull_request, cron or api
+//	message, author, email, language, number
+//	id, pr, duration      numbers: the build ID, the pull request number, 0
+//	                      for other events, and the duration in seconds
+//	pull_request          true for pull request builds
+//
+// Strings are written in double quotes or backquotes, with the escapes of
+// Go, lists in brackets. The operators are, by increasing precedence,
+// || (or), && (and), ! (not) and the comparisons:
+//
+//	== != < <= > >=       strings, numbers and booleans of the same type
+//	matches               a path.Match pattern, e.g. branch matches "release/*"
+//	=~                    a regular expression, e.g. message =~ "(?i)hotfix"
+//	contains              a substring, e.g. message contains "[deploy]"
+//	in, not in            the elements of a list, e.g. type in ["push", "cron"]
+//
+// Patterns and regular expressions are string literals compiled with the
+// expression, so that errors are reported by CompileFilter.
+type Filter struct {
+	src   string
+	match func(p *Payload) bool
+}
+
+// CompileFilter compiles the filter expression expr
+func CompileFilter(expr string) (*Filter, error) {
+	toks, err := lexFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	ps := &filterParser{src: expr, toks: toks}
+	match, err := ps.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := ps.peek(); t.kind != 0 {
+		return nil, ps.errorf(t.pos, "unexpected %q", t.text)
+	}
+	return &Filter{src: expr, match: match}, nil
+}
+
+// MustCompileFilter is CompileFilter panicking on errors, for expressions
+// in code
+func MustCompileFilter(expr string) *Filter {
+	f, err := CompileFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Match returns true if p matches f, a nil Filter matches every payload
+func (f *Filter) Match(p *Payload) bool {
+	return f == nil || f.match(p)
+}
+
+// String returns the expression of f
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (f *Filter) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, compiling the filter
+// of configuration files
+func (f *Filter) UnmarshalText(b []byte) error {
+	c, err := CompileFilter(string(b))
+	if err != nil {
+		return err
+	}
+	*f = *c
+	return nil
+}
+
+// Filtered returns a Sink sending the payloads matching f to s
+func Filtered(f *Filter, s Sink) Sink {
+	return &filteredSink{f, s}
+}
+
+type filteredSink struct {
+	f *Filter
+	s Sink
+}
+
+func (fs *filteredSink) Send(p *Payload) error {
+	return fs.SendContext(context.Background(), p)
+}
+
+func (fs *filteredSink) SendContext(ctx context.Context, p *Payload) error {
+	if !fs.f.Match(p) {
+		return nil
+	}
+	return SendContext(ctx, fs.s, p)
+}
+
+// filterKind is the type of an operand
+type filterKind int
+
+const (
+	filterString filterKind = iota
+	filterNumber
+	filterBool
+	filterList
+)
+
+func (k filterKind) String() string {
+	return [...]string{"string", "number", "boolean", "list"}[k]
+}
+
+// filterValue is an operand, the function of its kind returning its value
+type filterValue struct {
+	kind filterKind
+	pos  int
+	str  func(p *Payload) string
+	num  func(p *Payload) float64
+	b    func(p *Payload) bool
+	// literal is set for literals, with the value of strings in text
+	literal bool
+	text    string
+	// strs or nums are the elements of lists, of kind elem
+	elem filterKind
+	strs []string
+	nums []float64
+}
+
+func stringField(f func(p *Payload) string) filterValue {
+	return filterValue{kind: filterString, str: f}
+}
+
+func numberField(f func(p *Payload) float64) filterValue {
+	return filterValue{kind: filterNumber, num: f}
+}
+
+var filterFields = map[string]filterValue{
+	"repo": stringField((*Payload).Slug),
+	"owner": stringField(func(p *Payload) string {
+		if p.Repository == nil {
+			return ""
+		}
+		return p.Repository.OwnerName
+	}),
+	"name": stringField(func(p *Payload) string {
+		if p.Repository == nil {
+			return ""
+		}
+		return p.Repository.Name
+	}),
+	"branch":  stringField(func(p *Payload) string { return p.Branch }),
+	"tag":     stringField(func(p *Payload) string { return p.Tag }),
+	"commit":  stringField(func(p *Payload) string { return p.Commit }),
+	"state":   stringField(Outcome),
+	"status":  stringField(func(p *Payload) string { return p.StatusMessage }),
+	"type":    stringField(func(p *Payload) string { return p.Type }),
+	"message": stringField(func(p *Payload) string { return p.Message }),
+	"author":  stringField(func(p *Payload) string { return p.AuthorName }),
+	"email":   stringField(func(p *Payload) string { return p.AuthorEmail }),
+	"language": stringField(func(p *Payload) string {
+		if p.Config == nil {
+			return ""
+		}
+		return p.Config.Language
+	}),
+	"number":       stringField(func(p *Payload) string { return p.Number }),
+	"id":           numberField(func(p *Payload) float64 { return float64(p.ID) }),
+	"pr":           numberField(func(p *Payload) float64 { return float64(p.PullRequestNumber) }),
+	"duration":     numberField(func(p *Payload) float64 { return float64(p.Duration) }),
+	"pull_request": {kind: filterBool, b: (*Payload).IsPullRequest},
+}
+
+// filterToken is a token of an expression: an identifier ('i'), a string
+// ('s'), a number ('n'), an operator ('o') or the end (0)
+type filterToken struct {
+	kind byte
+	text string
+	pos  int
+}
+
+var filterOperators = []string{"==", "!=", "<=", ">=", "=~", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lexFilter(src string) ([]filterToken, error) {
+	var toks []filterToken
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	isLetter := func(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(src) && src[j] != c; j++ {
+				if src[j] == '\\' && c == '"' {
+					j++
+				}
+			}
+			if j >= len(src) {
+				return nil, filterError(src, i, "unterminated string")
+			}
+			s, err := strconv.Unquote(src[i : j+1])
+			if err != nil {
+				return nil, filterError(src, i, "bad string")
+			}
+			toks = append(toks, filterToken{'s', s, i})
+			i = j + 1
+		case isDigit(c) || c == '-' && i+1 < len(src) && isDigit(src[i+1]):
+			j := i + 1
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.') {
+				j++
+			}
+			toks = append(toks, filterToken{'n', src[i:j], i})
+			i = j
+		case isLetter(c):
+			j := i + 1
+			for j < len(src) && (isLetter(src[j]) || isDigit(src[j])) {
+				j++
+			}
+			toks = append(toks, filterToken{'i', src[i:j], i})
+			i = j
+		default:
+			op := ""
+			for _, o := range filterOperators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, filterError(src, i, fmt.Sprintf("unexpected %q", c))
+			}
+			toks = append(toks, filterToken{'o', op, i})
+			i += len(op)
+		}
+	}
+	return append(toks, filterToken{pos: len(src)}), nil
+}
+
+func filterError(src string, pos int, msg string) error {
+	return fmt.Errorf("filter %q: column %d: %s", src, pos+1, msg)
+}
+
+type filterParser struct {
+	src  string
+	toks []filterToken
+	i    int
+}
+
+func (ps *filterParser) errorf(pos int, format string, args ...any) error {
+	return filterError(ps.src, pos, fmt.Sprintf(format, args...))
+}
+
+func (ps *filterParser) peek() filterToken {
+	return ps.toks[ps.i]
+}
+
+// accept consumes the next token if it is one of the operators or keywords
+func (ps *filterParser) accept(texts ...string) bool {
+	t := ps.peek()
+	if t.kind != 'o' && t.kind != 'i' {
+		return false
+	}
+	for _, s := range texts {
+		if t.text == s {
+			ps.i++
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *filterParser) or() (func(p *Payload) bool, error) {
+	l, err := ps.and()
+	for err == nil && ps.accept("||", "or") {
+		var r func(p *Payload) bool
+		if r, err = ps.and(); err == nil {
+			l0 := l
+			l = func(p *Payload) bool { return l0(p) || r(p) }
+		}
+	}
+	return l, err
+}
+
+func (ps *filterParser) and() (func(p *Payload) bool, error) {
+	l, err := ps.unary()
+	for err == nil && ps.accept("&&", "and") {
+		var r func(p *Payload) bool
+		if r, err = ps.unary(); err == nil {
+			l0 := l
+			l = func(p *Payload) bool { return l0(p) && r(p) }
+		}
+	}
+	return l, err
+}
+
+func (ps *filterParser) unary() (func(p *Payload) bool, error) {
+	if ps.accept("!", "not") {
+		f, err := ps.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(p *Payload) bool { return !f(p) }, nil
+	}
+	return ps.comparison()
+}
+
+func (ps *filterParser) comparison() (func(p *Payload) bool, error) {
+	l, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	t := ps.peek()
+	op, negate := t.text, false
+	switch {
+	case t.kind == 'o' && (op == "==" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">=" || op == "=~"):
+	case t.kind == 'i' && (op == "in" || op == "matches" || op == "contains"):
+	case t.kind == 'i' && op == "not" && ps.toks[ps.i+1].kind == 'i' && ps.toks[ps.i+1].text == "in":
+		ps.i++
+		t.text, negate = "in", true
+	default:
+		if l.kind != filterBool {
+			return nil, ps.errorf(l.pos, "%s is not a condition", l.kind)
+		}
+		return l.b, nil
+	}
+	ps.i++
+	r, err := ps.operand()
+	if err != nil {
+		return nil, err
+	}
+	f, err := ps.compare(t, l, r)
+	if err != nil || !negate {
+		return f, err
+	}
+	return func(p *Payload) bool { return !f(p) }, nil
+}
+
+func (ps *filterParser) compare(t filterToken, l, r filterValue) (func(p *Payload) bool, error) {
+	switch t.text {
+	case "matches", "=~", "contains":
+		if l.kind != filterString || r.kind != filterString {
+			return nil, ps.errorf(t.pos, "%s needs strings", t.text)
+		}
+		if t.text == "contains" {
+			return func(p *Payload) bool { return strings.Contains(l.str(p), r.str(p)) }, nil
+		}
+		if !r.literal {
+			return nil, ps.errorf(r.pos, "%s needs a string literal", t.text)
+		}
+		if t.text == "=~" {
+			re, err := regexp.Compile(r.text)
+			if err != nil {
+				return nil, ps.errorf(r.pos, "%v", err)
+			}
+			return func(p *Payload) bool { return re.MatchString(l.str(p)) }, nil
+		}
+		if _, err := path.Match(r.text, ""); err != nil {
+			return nil, ps.errorf(r.pos, "bad pattern %q", r.text)
+		}
+		return func(p *Payload) bool {
+			ok, _ := path.Match(r.text, l.str(p))
+			return ok
+		}, nil
+
+	case "in":
+		if r.kind != filterList || l.kind != r.elem && len(r.strs)+len(r.nums) > 0 {
+			return nil, ps.errorf(t.pos, "in needs a list of %ss", l.kind)
+		}
+		switch l.kind {
+		case filterString:
+			return func(p *Payload) bool {
+				v := l.str(p)
+				for _, s := range r.strs {
+					if v == s {
+						return true
+					}
+				}
+				return false
+			}, nil
+		case filterNumber:
+			return func(p *Payload) bool {
+				v := l.num(p)
+				for _, n := range r.nums {
+					if v == n {
+						return true
+					}
+				}
+				return false
+			}, nil
+		}
+		return nil, ps.errorf(l.pos, "in needs a string or a number")
+	}
+
+	if l.kind != r.kind || l.kind == filterList {
+		return nil, ps.errorf(t.pos, "cannot compare %s and %s", l.kind, r.kind)
+	}
+	if l.kind == filterBool {
+		switch t.text {
+		case "==":
+			return func(p *Payload) bool { return l.b(p) == r.b(p) }, nil
+		case "!=":
+			return func(p *Payload) bool { return l.b(p) != r.b(p) }, nil
+		}
+		return nil, ps.errorf(t.pos, "cannot order booleans")
+	}
+	if l.kind == filterString {
+		return orderFunc(t.text, l.str, r.str), nil
+	}
+	return orderFunc(t.text, l.num, r.num), nil
+}
+
+// orderFunc returns the comparison op of the values of l and r
+func orderFunc[T string | float64](op string, l, r func(p *Payload) T) func(p *Payload) bool {
+	switch op {
+	case "==":
+		return func(p *Payload) bool { return l(p) == r(p) }
+	case "!=":
+		return func(p *Payload) bool { return l(p) != r(p) }
+	case "<":
+		return func(p *Payload) bool { return l(p) < r(p) }
+	case "<=":
+		return func(p *Payload) bool { return l(p) <= r(p) }
+	case ">":
+		return func(p *Payload) bool { return l(p) > r(p) }
+	}
+	return func(p *Payload) bool { return l(p) >= r(p) }
+}
+
+func (ps *filterParser) operand() (filterValue, error) {
+	t := ps.peek()
+	ps.i++
+	switch {
+	case t.kind == 's':
+		return filterValue{
+			kind:    filterString,
+			pos:     t.pos,
+			str:     func(*Payload) string { return t.text },
+			literal: true,
+			text:    t.text,
+		}, nil
+	case t.kind == 'n':
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return filterValue{}, ps.errorf(t.pos, "bad number %q", t.text)
+		}
+		return filterValue{kind: filterNumber, pos: t.pos, num: func(*Payload) float64 { return n }, literal: true}, nil
+	case t.kind == 'i' && (t.text == "true" || t.text == "false"):
+		b := t.text == "true"
+		return filterValue{kind: filterBool, pos: t.pos, b: func(*Payload) bool { return b }}, nil
+	case t.kind == 'i':
+		v, ok := filterFields[t.text]
+		if !ok {
+			return filterValue{}, ps.errorf(t.pos, "unknown field %q", t.text)
+		}
+		v.pos = t.pos
+		return v, nil
+	case t.kind == 'o' && t.text == "(":
+		f, err := ps.or()
+		if err != nil {
+			return filterValue{}, err
+		}
+		if !ps.accept(")") {
+			return filterValue{}, ps.errorf(ps.peek().pos, "missing )")
+		}
+		return filterValue{kind: filterBool, pos: t.pos, b: f}, nil
+	case t.kind == 'o' && t.text == "[":
+		return ps.list(t.pos)
+	case t.kind == 0:
+		return filterValue{}, ps.errorf(t.pos, "unexpected end")
+	}
+	return filterValue{}, ps.errorf(t.pos, "unexpected %q", t.text)
+}
+
+// list parses the elements of a list literal, after its [
+func (ps *filterParser) list(pos int) (filterValue, error) {
+	v := filterValue{kind: filterList, pos: pos}
+	for !ps.accept("]") {
+		if len(v.strs)+len(v.nums) > 0 && !ps.accept(",") {
+			return filterValue{}, ps.errorf(ps.peek().pos, "missing , or ]")
+		}
+		e, err := ps.operand()
+		if err != nil {
+			return filterValue{}, err
+		}
+		switch {
+		case e.kind == filterString && e.literal && len(v.nums) == 0:
+			v.elem, v.strs = filterString, append(v.strs, e.text)
+		case e.kind == filterNumber && e.literal && len(v.strs) == 0:
+			v.elem, v.nums = filterNumber, append(v.nums, e.num(nil))
+		default:
+			return filterValue{}, ps.errorf(e.pos, "lists hold string or number literals of one type")
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,121 @@
+package travis_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestFilterMatch(t *testing.T) {
+	p := &travis.Payload{
+		ID:                42,
+		Type:              "pull_request",
+		Branch:            "release/1.2",
+		State:             "failed",
+		Status:            1,
+		StatusMessage:     "Broken",
+		Message:           "Fix the [deploy] HOTFIX",
+		AuthorName:        "Jane",
+		PullRequest:       1,
+		PullRequestNumber: 7,
+		Duration:          90,
+		Repository:        &travis.Repository{OwnerName: "acme", Name: "api"},
+		Config:            &travis.Config{Language: "go"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`repo == "acme/api"`, true},
+		{`repo != "acme/api"`, false},
+		{`owner == "acme" && name == "api"`, true},
+		{"branch == `release/1.2`", true},
+		{`branch matches "release/*"`, true},
+		{`branch matches "main"`, false},
+		{`state in ["failed", "errored"]`, true},
+		{`state not in ["failed", "errored"]`, false},
+		{`status == "Broken"`, true},
+		{`message =~ "(?i)hotfix"`, true},
+		{`message =~ "^hotfix"`, false},
+		{`message contains "[deploy]"`, true},
+		{`language == "go"`, true},
+		{`pull_request`, true},
+		{`!pull_request`, false},
+		{`not pull_request or pr == 7`, true},
+		{`pull_request == false`, false},
+		{`pr >= 7 && pr < 8`, true},
+		{`id in [1, 42]`, true},
+		{`duration > 60 and duration <= 90`, true},
+		{`duration > -1`, true},
+		{`author < "K"`, true},
+		{`type in []`, false},
+		{`state == "passed" || branch matches "release/*" && !pull_request`, false},
+		{`(state == "passed" || branch matches "release/*") && pull_request`, true},
+		{`!(repo == "acme/web")`, true},
+		{`true`, true},
+	}
+	for _, tt := range tests {
+		f, err := travis.CompileFilter(tt.expr)
+		if err != nil {
+			t.Errorf("CompileFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(p); got != tt.want {
+			t.Errorf("%q: Match() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	var f *travis.Filter
+	if !f.Match(p) {
+		t.Error("a nil Filter doesn't match")
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, "column 1: unexpected end"},
+		{`repo ==`, "column 8: unexpected end"},
+		{`repo == "acme`, "column 9: unterminated string"},
+		{`repo == "a\q"`, "bad string"},
+		{`repo # 1`, "unexpected '#'"},
+		{`foo == 1`, `unknown field "foo"`},
+		{`repo`, "string is not a condition"},
+		{`repo == 1`, "cannot compare string and number"},
+		{`pull_request < true`, "cannot order booleans"},
+		{`id matches "1"`, "matches needs strings"},
+		{`branch matches branch`, "matches needs a string literal"},
+		{`branch matches "[main"`, `bad pattern "[main"`},
+		{`message =~ "("`, "missing closing )"},
+		{`state in "failed"`, "in needs a list of strings"},
+		{`state in [1, 2]`, "in needs a list of strings"},
+		{`state in ["failed", 1]`, "lists hold string or number literals of one type"},
+		{`state in ["failed" "errored"]`, "missing , or ]"},
+		{`(pull_request`, "missing )"},
+		{`pull_request)`, `column 13: unexpected ")"`},
+		{`id == 1.2.3`, `bad number "1.2.3"`},
+	}
+	for _, tt := range tests {
+		_, err := travis.CompileFilter(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("CompileFilter(%q) = %v, want an error containing %q", tt.expr, err, tt.err)
+		}
+	}
+}
+
+func TestFilterText(t *testing.T) {
+	var f travis.Filter
+	if err := f.UnmarshalText([]byte(`branch matches "release/*"`)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.MarshalText()
+	if err != nil || string(b) != `branch matches "release/*"` {
+		t.Errorf("MarshalText() = %q, %v", b, err)
+	}
+	if err := f.UnmarshalText([]byte(`branch matches`)); err == nil {
+		t.Error("UnmarshalText of a bad expression succeeded")
+	}
+}
@@ -0,0 +1,169 @@
+package travis
+
+import (
+	"strings"
+	"sync"
+)
+
+// Flaky reports a branch, or a job of its matrix, whose builds alternate
+// between passing and failing
+type Flaky struct {
+	Payload *Payload
+	// Job is the position of the job in the matrix (the part of the job
+	// number after the dot), it is empty when the whole build is flaky
+	Job string
+	// Confidence the builds are flaky, between 0 and 1
+	Confidence float64
+	// Flips is the number of pass/fail changes in the window
+	Flips int
+}
+
+// FlakyDetector is a Sink tracking the outcome of builds and matrix jobs per
+// repository and branch, calling OnFlaky when they look flaky.
+//
+// The confidence is the share of consecutive builds with different outcomes
+// in the window. It is at least 0.9 when a commit that failed passes on a
+// rebuild, which is the strongest hint of flakiness.
+type FlakyDetector struct {
+	// Window is the number of latest builds considered, 10 if zero
+	Window int
+	// MinBuilds is the number of builds required before reporting, 4 if zero
+	MinBuilds int
+	// Threshold is the minimum confidence reported, 0.5 if zero
+	Threshold float64
+
+	// MaxEntries is the number of branches and jobs tracked,
+	// DefaultMaxEntries if zero, and MaxBytes an optional bound of their
+	// estimated size. The least recently built are evicted first.
+	MaxEntries int
+	MaxBytes   int64
+
+	OnFlaky func(f Flaky)
+
+	mu      sync.Mutex
+	history lru[string, []flakyRun]
+}
+
+type flakyRun struct {
+	commit string
+	passed bool
+}
+
+// Send records the outcome of p and its jobs
+func (f *FlakyDetector) Send(p *Payload) error {
+	var passed bool
+	switch Outcome(p) {
+	case OutcomePassed:
+		passed = true
+	case OutcomeFailed, OutcomeErrored:
+	default:
+		return nil
+	}
+
+	var found []Flaky
+	f.mu.Lock()
+	f.history.Size = flakySize
+	f.history.limit(f.MaxEntries, f.MaxBytes)
+	key := p.Slug() + "@" + p.Branch
+	if c, flips, ok := f.record(key, p.Commit, passed); ok {
+		found = append(found, Flaky{Payload: p, Confidence: c, Flips: flips})
+	}
+	for _, j := range p.Matrix {
+		if j.AllowFailure {
+			continue
+		}
+		var passed bool
+		switch j.State {
+		case OutcomePassed:
+			passed = true
+		case OutcomeFailed, OutcomeErrored:
+		default:
+			continue
+		}
+		pos := jobPosition(j.Number)
+		if c, flips, ok := f.record(key+"#"+pos, p.Commit, passed); ok {
+			found = append(found, Flaky{Payload: p, Job: pos, Confidence: c, Flips: flips})
+		}
+	}
+	f.mu.Unlock()
+
+	if f.OnFlaky != nil {
+		for _, fl := range found {
+			f.OnFlaky(fl)
+		}
+	}
+	return nil
+}
+
+// record appends a run to the history of key and returns the confidence
+// and flips if it is over the threshold
+func (f *FlakyDetector) record(key, commit string, passed bool) (float64, int, bool) {
+	window, minBuilds, threshold := f.Window, f.MinBuilds, f.Threshold
+	if window <= 0 {
+		window = 10
+	}
+	if minBuilds <= 0 {
+		minBuilds = 4
+	}
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+
+	runs, _ := f.history.Get(key)
+	runs = append(runs, flakyRun{commit: commit, passed: passed})
+	if len(runs) > window {
+		runs = runs[len(runs)-window:]
+	}
+	f.history.Put(key, runs)
+
+	flips := 0
+	for i := 1; i < len(runs); i++ {
+		if runs[i].passed != runs[i-1].passed {
+			flips++
+		}
+	}
+	confidence := 0.0
+	if len(runs) > 1 {
+		confidence = float64(flips) / float64(len(runs)-1)
+	}
+	rebuilt := false
+	if passed && commit != "" {
+		for _, r := range runs[:len(runs)-1] {
+			if r.commit == commit && !r.passed {
+				rebuilt = true
+			}
+		}
+	}
+	if rebuilt && confidence < 0.9 {
+		confidence = 0.9
+	}
+	if !rebuilt && len(runs) < minBuilds {
+		return 0, 0, false
+	}
+	return confidence, flips, confidence >= threshold
+}
+
+// Memory returns the number of branches and jobs tracked, their estimated
+// size and the number evicted
+func (f *FlakyDetector) Memory() MemoryStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.history.stats()
+}
+
+// flakySize estimates the memory taken by the runs of key
+func flakySize(key string, runs []flakyRun) int {
+	size := 64 + len(key) + cap(runs)*24
+	for _, r := range runs {
+		size += len(r.commit)
+	}
+	return size
+}
+
+// jobPosition returns the part of a job number after the dot, e.g. "2" for "123.2"
+func jobPosition(number string) string {
+	if i := strings.LastIndex(number, "."); i >= 0 {
+		return number[i+1:]
+	}
+	return number
+}
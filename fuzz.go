@@ -0,0 +1,64 @@
+package travis
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// DecodeAny runs data through every parser of the package: as a JSON
+// payload, as a webhook form body, as a Signature header and as a public
+// key, then passes the payloads decoded to the helpers and sinks reading
+// them. It returns the error of decoding data as a payload and must never
+// panic, which makes it a fuzz target for services embedding the package:
+//
+//	func FuzzTravis(f *testing.F) {
+//		for _, s := range travistest.Samples() {
+//			f.Add([]byte(s.Raw))
+//		}
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			travis.DecodeAny(data)
+//		})
+//	}
+func DecodeAny(data []byte) error {
+	p, err := GetPayload(bytes.NewReader(data))
+	if err == nil {
+		exercise(p)
+	}
+
+	r, rerr := http.NewRequest("POST", "/", bytes.NewReader(data))
+	if rerr == nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header["Signature"] = []string{string(data)}
+		parsePayloadSignature(r)
+		if fp, err := decodePayload(r.PostFormValue("payload")); err == nil {
+			exercise(fp)
+		}
+	}
+
+	if pk, err := PeekPayload(string(data)); err == nil {
+		pk.Slug()
+		pk.Outcome()
+	}
+	parsePublicKey(string(data))
+	ParseTestReport(string(data)).Summary()
+	return err
+}
+
+// exercise passes p to the code reading payloads
+func exercise(p *Payload) {
+	Outcome(p)
+	StateEmoji(p)
+	StateColor(p).Hex()
+	DefaultIcons.StateIcon(p)
+	p.Slug()
+	DeliveryLag(p, time.Now())
+	Summarize([]*Payload{p, p})
+	(&Stats{}).Send(p)
+	(&FlakyDetector{}).Send(p)
+	(&DurationMonitor{}).Send(p)
+	RepoKey(p)
+	p.SourceID()
+	StageStatus(p)
+	ToCheckRun(p)
+}
@@ -0,0 +1,21 @@
+package travis_test
+
+import (
+	"testing"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func FuzzDecodeAny(f *testing.F) {
+	for _, s := range travistest.Samples() {
+		f.Add([]byte(s.Raw))
+		f.Add([]byte("payload=" + s.Raw))
+	}
+	_, pub := travistest.KeyPair()
+	f.Add([]byte(pub))
+	f.Add([]byte(""))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		travis.DecodeAny(data)
+	})
+}
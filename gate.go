@@ -0,0 +1,90 @@
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultWaitInterval is how often WaitForGreen polls a running build if
+// Interval isn't set
+const DefaultWaitInterval = 15 * time.Second
+
+// ErrNoBuild is returned by WaitForGreen when the branch has no build
+var ErrNoBuild = errors.New("travis: no build of the branch")
+
+// WaitOptions configure WaitForGreen
+type WaitOptions struct {
+	// Wait makes WaitForGreen wait for the latest build to finish if it
+	// is running, rather than return a NotGreenError
+	Wait bool
+	// Interval is how often the running build is polled,
+	// DefaultWaitInterval if zero
+	Interval time.Duration
+	// Events are the event types of the builds considered, all but
+	// pull_request if empty
+	Events []string
+}
+
+// NotGreenError is returned by WaitForGreen when the latest build of the
+// branch didn't pass
+type NotGreenError struct {
+	Build *Build
+}
+
+func (e *NotGreenError) Error() string {
+	return fmt.Sprintf("travis: build #%s of %s is %s", e.Build.Number, e.Build.Branch.Name, e.Build.State)
+}
+
+// WaitForGreen returns the latest build of branch of the repository slug if
+// it passed, a *NotGreenError otherwise. With opts.Wait, a running build is
+// polled until it finishes or ctx is done. Release scripts gate on CI with
+// it:
+//
+//	if _, err := travis.WaitForGreen(ctx, c, "acme/api", "main", travis.WaitOptions{Wait: true}); err != nil {
+//		log.Fatal(err)
+//	}
+func WaitForGreen(ctx context.Context, c *Client, slug, branch string, opts WaitOptions) (*Build, error) {
+	builds, _, err := c.Builds(ctx, slug, BuildsOptions{Branch: branch, SortBy: "id:desc"})
+	if err != nil {
+		return nil, err
+	}
+	var b *Build
+	for _, candidate := range builds {
+		if gated(opts.Events, candidate.EventType) {
+			b = candidate
+			break
+		}
+	}
+	if b == nil {
+		return nil, ErrNoBuild
+	}
+
+	if !b.Finished() && opts.Wait {
+		interval := opts.Interval
+		if interval <= 0 {
+			interval = DefaultWaitInterval
+		}
+		if b, err = c.WaitForBuild(ctx, b.ID, interval); err != nil {
+			return nil, err
+		}
+	}
+	if b.State != OutcomePassed {
+		return b, &NotGreenError{Build: b}
+	}
+	return b, nil
+}
+
+// gated returns true if builds of event are considered by WaitForGreen
+func gated(events []string, event string) bool {
+	if len(events) == 0 {
+		return event != "pull_request"
+	}
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
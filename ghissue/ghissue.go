@@ -0,0 +1,319 @@
+// Package ghissue tracks the breakages of the default branch of GitHub
+// repositories with issues: an issue is opened when a build of the branch
+// breaks, with the build URL, the commit and the tail of the log of the
+// first failed job, and closed when a build passes again.
+//
+//	h := &travis.Handler{Sinks: []travis.Sink{&ghissue.Sink{
+//		Token: os.Getenv("GITHUB_TOKEN"),
+//		Logs:  &travis.Client{Token: os.Getenv("TRAVIS_TOKEN")},
+//	}}}
+//
+// While the branch keeps failing no other issue is opened: the open issue
+// of a branch is found by its label and a marker in its body, so it is
+// also found after a restart.
+package ghissue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/jacksgt/travis"
+)
+
+const (
+	// DefaultAPI is the API a Sink calls if BaseURL isn't set
+	DefaultAPI = "https://api.github.com"
+	// DefaultLabel labels the issues of a Sink if Label isn't set
+	DefaultLabel = "ci-broken"
+	// DefaultLogLines is the number of lines of the log in issues if
+	// LogLines isn't set
+	DefaultLogLines = 30
+)
+
+// DefaultTitle and DefaultBody are the templates of issues if Title and
+// Body aren't set
+const (
+	DefaultTitle = `{{.Payload.Branch}} is broken since build #{{.Payload.Number}}`
+	DefaultBody  = `Build [#{{.Payload.Number}}]({{.Payload.BuildURL}}) of ` +
+		"`{{.Payload.Branch}}`" + ` {{.Outcome}} at {{.Payload.Commit}} by {{.Payload.AuthorName}}:
+
+> {{.Message}}
+{{if .Log}}
+Last lines of the log of job {{.Job.Number}}:
+
+` + "```" + `
+{{.Log}}
+` + "```" + `
+{{end}}`
+)
+
+// LogReader returns the log of a job, it is implemented by travis.Client
+type LogReader interface {
+	Log(ctx context.Context, jobID int64) (string, error)
+}
+
+// Issue is the data of the Title and Body templates
+type Issue struct {
+	Payload *travis.Payload
+	// Outcome is failed or errored
+	Outcome string
+	// Message is the first line of the commit message
+	Message string
+	// Job is the first failed job, nil if the payload has no matrix
+	Job *travis.Job
+	// Log is the tail of the log of Job, empty without Logs
+	Log string
+}
+
+// Sink is a travis.Sink opening an issue when a push build of the default
+// branch, or of Branches, fails or errors, and closing it with a comment
+// when a build passes
+type Sink struct {
+	// BaseURL of the API, DefaultAPI if empty, e.g. the /api/v3 URL of a
+	// GitHub Enterprise server
+	BaseURL string
+	// Token authenticates the requests, it needs the issues permission
+	Token string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	// Branches are path.Match patterns of the branches tracked, the
+	// default branch of each repository as returned by GitHub if empty
+	Branches []string
+	// Label is the label of the issues, DefaultLabel if empty
+	Label string
+	// Title and Body are text/template templates of Issue, DefaultTitle
+	// and DefaultBody if empty. The body is written in markdown.
+	Title, Body string
+	// Comment, if true, comments on the issue the builds still failing
+	Comment bool
+
+	// Logs, if set, reads the log of the first failed job, of which
+	// LogLines lines, DefaultLogLines if zero, are quoted in the issue
+	Logs     LogReader
+	LogLines int
+
+	once        sync.Once
+	title, body *template.Template
+	err         error
+
+	mu              sync.Mutex
+	defaultBranches map[string]string
+}
+
+// Send opens or closes the issue of the branch of p
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	outcome := travis.Outcome(p)
+	switch outcome {
+	case travis.OutcomePassed, travis.OutcomeFailed, travis.OutcomeErrored:
+	default:
+		return nil
+	}
+	if p.IsPullRequest() || p.Tag != "" {
+		return nil
+	}
+	if ok, err := s.tracked(ctx, p); !ok || err != nil {
+		return err
+	}
+	s.once.Do(s.parse)
+	if s.err != nil {
+		return s.err
+	}
+
+	marker := fmt.Sprintf("<!-- travis-breakage %s@%s -->", p.Slug(), p.Branch)
+	number, err := s.find(ctx, p.Slug(), marker)
+	if err != nil {
+		return err
+	}
+	switch {
+	case outcome == travis.OutcomePassed && number != 0:
+		body := fmt.Sprintf("Fixed by build [#%s](%s).", p.Number, p.BuildURL)
+		if err := s.do(ctx, "POST", fmt.Sprintf("/repos/%s/issues/%d/comments", p.Slug(), number), map[string]any{"body": body}, nil); err != nil {
+			return err
+		}
+		return s.do(ctx, "PATCH", fmt.Sprintf("/repos/%s/issues/%d", p.Slug(), number), map[string]any{"state": "closed"}, nil)
+	case outcome == travis.OutcomePassed:
+		return nil
+	case number != 0:
+		if !s.Comment {
+			return nil
+		}
+		body := fmt.Sprintf("Build [#%s](%s) %s at %s.", p.Number, p.BuildURL, outcome, p.Commit)
+		return s.do(ctx, "POST", fmt.Sprintf("/repos/%s/issues/%d/comments", p.Slug(), number), map[string]any{"body": body}, nil)
+	}
+
+	issue := s.issue(ctx, p, outcome)
+	var title, body bytes.Buffer
+	if err := s.title.Execute(&title, issue); err != nil {
+		return err
+	}
+	if err := s.body.Execute(&body, issue); err != nil {
+		return err
+	}
+	body.WriteString("\n" + marker + "\n")
+	return s.do(ctx, "POST", "/repos/"+p.Slug()+"/issues", map[string]any{
+		"title":  strings.TrimSpace(title.String()),
+		"body":   body.String(),
+		"labels": []string{s.label()},
+	}, nil)
+}
+
+func (s *Sink) parse() {
+	title, body := s.Title, s.Body
+	if title == "" {
+		title = DefaultTitle
+	}
+	if body == "" {
+		body = DefaultBody
+	}
+	if s.title, s.err = template.New("title").Parse(title); s.err != nil {
+		return
+	}
+	s.body, s.err = template.New("body").Parse(body)
+}
+
+func (s *Sink) label() string {
+	if s.Label == "" {
+		return DefaultLabel
+	}
+	return s.Label
+}
+
+// tracked returns true if the branch of p is tracked
+func (s *Sink) tracked(ctx context.Context, p *travis.Payload) (bool, error) {
+	if len(s.Branches) > 0 {
+		for _, b := range s.Branches {
+			if ok, _ := path.Match(b, p.Branch); ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	s.mu.Lock()
+	branch, ok := s.defaultBranches[p.Slug()]
+	s.mu.Unlock()
+	if !ok {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := s.do(ctx, "GET", "/repos/"+p.Slug(), nil, &repo); err != nil {
+			return false, err
+		}
+		branch = repo.DefaultBranch
+		s.mu.Lock()
+		if s.defaultBranches == nil {
+			s.defaultBranches = make(map[string]string)
+		}
+		s.defaultBranches[p.Slug()] = branch
+		s.mu.Unlock()
+	}
+	return p.Branch == branch, nil
+}
+
+// find returns the number of the open issue of the repository slug with
+// marker in its body, 0 if none
+func (s *Sink) find(ctx context.Context, slug, marker string) (int, error) {
+	var issues []struct {
+		Number int    `json:"number"`
+		Body   string `json:"body"`
+	}
+	path := fmt.Sprintf("/repos/%s/issues?state=open&per_page=100&labels=%s", slug, url.QueryEscape(s.label()))
+	if err := s.do(ctx, "GET", path, nil, &issues); err != nil {
+		return 0, err
+	}
+	for _, i := range issues {
+		if strings.Contains(i.Body, marker) {
+			return i.Number, nil
+		}
+	}
+	return 0, nil
+}
+
+// issue returns the template data of p, the log is left out if it can't be
+// read
+func (s *Sink) issue(ctx context.Context, p *travis.Payload, outcome string) *Issue {
+	message, _, _ := strings.Cut(p.Message, "\n")
+	issue := &Issue{Payload: p, Outcome: outcome, Message: message}
+	for _, j := range p.Matrix {
+		if !j.AllowFailure && (j.State == travis.OutcomeFailed || j.State == travis.OutcomeErrored) {
+			issue.Job = j
+			break
+		}
+	}
+	if issue.Job == nil || s.Logs == nil {
+		return issue
+	}
+	log, err := s.Logs.Log(ctx, issue.Job.ID)
+	if err != nil {
+		return issue
+	}
+	n := s.LogLines
+	if n <= 0 {
+		n = DefaultLogLines
+	}
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	issue.Log = strings.Join(lines, "\n")
+	return issue
+}
+
+// do sends a request with the JSON of in, if not nil, to path and decodes
+// the response into out, if not nil
+func (s *Sink) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	base := s.BaseURL
+	if base == "" {
+		base = DefaultAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(base, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ghissue: %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
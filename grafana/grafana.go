@@ -0,0 +1,133 @@
+// Package grafana marks finished builds on Grafana dashboards with
+// annotations, so build and deploy markers line up with the metrics they
+// affect.
+//
+//	h := &travis.Handler{Sinks: []travis.Sink{&grafana.Sink{
+//		URL:   "https://grafana.example.com",
+//		Token: os.Getenv("GRAFANA_TOKEN"),
+//	}}}
+//
+// Annotations are tagged travis, repo:<owner/name>, branch:<branch>,
+// state:<outcome> and event:<type>, for dashboards to query e.g. the
+// deploys of a repository with the tags repo:acme/api and branch:main.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// Annotation is a marker of a build, as the body of a Grafana
+// POST /api/annotations request
+type Annotation struct {
+	DashboardUID string `json:"dashboardUID,omitempty"`
+	PanelID      int    `json:"panelId,omitempty"`
+	// Time and TimeEnd are in milliseconds since the epoch, TimeEnd is
+	// zero for a point in time rather than a region
+	Time    int64    `json:"time"`
+	TimeEnd int64    `json:"timeEnd,omitempty"`
+	Tags    []string `json:"tags"`
+	Text    string   `json:"text"`
+}
+
+// NewAnnotation returns the annotation of p: a region from the start to the
+// end of the build, or the end alone if it didn't start, tagged by
+// repository, branch, state and event type, linking to the build
+func NewAnnotation(p *travis.Payload) *Annotation {
+	a := &Annotation{
+		Tags: []string{
+			"travis",
+			"repo:" + p.Slug(),
+			"branch:" + p.Branch,
+			"state:" + travis.Outcome(p),
+			"event:" + p.Type,
+		},
+	}
+	end := p.FinishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	a.Time = end.UnixMilli()
+	if !p.StartedAt.IsZero() && p.StartedAt.Before(end) {
+		a.Time, a.TimeEnd = p.StartedAt.UnixMilli(), end.UnixMilli()
+	}
+
+	message, _, _ := strings.Cut(p.Message, "\n")
+	a.Text = fmt.Sprintf(`<a href="%s">%s #%s</a> %s on %s by %s: %s`,
+		html.EscapeString(p.BuildURL), html.EscapeString(p.Slug()), html.EscapeString(p.Number),
+		html.EscapeString(travis.StageStatus(p)), html.EscapeString(p.Branch),
+		html.EscapeString(p.AuthorName), html.EscapeString(message))
+	return a
+}
+
+// Sink is a travis.Sink creating the annotations of finished builds
+type Sink struct {
+	// URL is the base URL of Grafana, e.g. https://grafana.example.com
+	URL string
+	// Token is a service account token with the annotations:write
+	// permission
+	Token string
+	// DashboardUID and PanelID, if set, restrict the annotations to a
+	// dashboard and a panel, else they are organization-wide
+	DashboardUID string
+	PanelID      int
+	// Tags are added to the tags of every annotation
+	Tags []string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Send annotates p if its build is finished
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	if travis.Outcome(p) == travis.OutcomePending {
+		return nil
+	}
+	a := NewAnnotation(p)
+	a.DashboardUID, a.PanelID = s.DashboardUID, s.PanelID
+	a.Tags = append(a.Tags, s.Tags...)
+	return s.Annotate(ctx, a)
+}
+
+// Annotate creates a
+func (s *Sink) Annotate(ctx context.Context, a *Annotation) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(s.URL, "/")+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("grafana: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,369 @@
+package travis
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Dispositions of a delivery
+const (
+	// DispositionRejected deliveries failed verification
+	DispositionRejected = "rejected"
+	// DispositionHandled deliveries were verified and accepted by every sink
+	DispositionHandled = "handled"
+	// DispositionFailed deliveries were verified but a sink returned an error
+	DispositionFailed = "failed"
+	// DispositionQueued deliveries were verified and queued by an
+	// asynchronous Dispatcher
+	DispositionQueued = "queued"
+	// DispositionSkipped deliveries were verified but filtered out by the
+	// Peek of the Handler, without decoding them
+	DispositionSkipped = "skipped"
+	// DispositionShed deliveries were verified but answered with 503 as
+	// the queue of the Dispatcher was full, for Travis to deliver them again
+	DispositionShed = "shed"
+	// DispositionDuplicate deliveries were verified but not dispatched as
+	// the same payload was handled before, see Handler.Dedupe
+	DispositionDuplicate = "duplicate"
+)
+
+// DefaultKeyTTL is how long a Handler caches the Travis public key by default
+const DefaultKeyTTL = time.Hour
+
+// DefaultRetryAfter is the Retry-After of shed deliveries if the Handler
+// doesn't set RetryAfter
+const DefaultRetryAfter = 30 * time.Second
+
+// Delivery describes how the Handler processed one webhook request
+type Delivery struct {
+	Received    time.Time
+	Disposition string
+	// Payload is nil for rejected and skipped deliveries
+	Payload *Payload
+	// RawPayload and Signature are the payload form value and Signature
+	// header of the request as received
+	RawPayload string
+	Signature  string
+	// Err is the verification error or the first sink error
+	Err error
+	// Duration is the time spent handling the request
+	Duration time.Duration
+	// Lag is the time between the end of the build, or its start if it
+	// isn't finished, and the receipt of the webhook
+	Lag time.Duration
+
+	ctx context.Context
+}
+
+// Context returns the context of the request of d, OnDelivery hooks doing
+// I/O should use it. It is context.Background for the deliveries not
+// received by a Handler.
+func (d *Delivery) Context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// DeliveryLag returns the time between the end of the build of p, or its
+// start if it isn't finished, and received. It is zero if neither is known.
+func DeliveryLag(p *Payload, received time.Time) time.Duration {
+	switch {
+	case !p.FinishedAt.IsZero():
+		return received.Sub(p.FinishedAt)
+	case !p.StartedAt.IsZero():
+		return received.Sub(p.StartedAt)
+	}
+	return 0
+}
+
+// Handler is an http.Handler receiving Travis webhooks. Every request is
+// verified with GetPayloadFromRequest and its payload sent to all Sinks.
+type Handler struct {
+	Sinks []Sink
+	// Dispatcher, if set, is used instead of Sinks, e.g. to queue payloads
+	// or serialize them per repository
+	Dispatcher *Dispatcher
+
+	// Client fetches the Travis public key, http.DefaultClient if nil
+	Client *http.Client
+	// ConfigURL serves the Travis public key, DefaultConfigURL if empty
+	ConfigURL string
+	// KeyTTL is how long the public key is cached, DefaultKeyTTL if zero.
+	// The key is fetched again when a signature doesn't match a cached key.
+	KeyTTL time.Duration
+	// Scheme, if set, verifies the signatures instead of the Travis public
+	// key, e.g. HMAC for webhooks relayed by internal systems. Client,
+	// ConfigURL and KeyTTL then don't apply.
+	Scheme SignatureScheme
+
+	// Cache, if set, keeps the public key and the deliveries of Dedupe,
+	// e.g. a Redis shared by the replicas of a receiver
+	Cache CacheBackend
+	// Dedupe, if true, answers the deliveries of a payload already handled
+	// or queued in the last DedupeTTL, DefaultDedupeTTL if zero, with 204
+	// without dispatching it, e.g. one delivered again after a timeout.
+	// They are remembered in Cache, or in memory if it is nil.
+	Dedupe    bool
+	DedupeTTL time.Duration
+
+	// Source, if set, replaces the verification and decoding of requests,
+	// e.g. with the canned payloads of a travistest.Source in tests. Peek
+	// and PoolPayloads don't apply to its payloads.
+	Source PayloadSource
+
+	// Peek, if set, filters the verified payloads before they are decoded,
+	// with PeekPayload: those it returns false for are answered with 204
+	// and not dispatched
+	Peek func(pk Peek) bool
+
+	// PoolPayloads, if true, decodes the payloads into ones of
+	// AcquirePayload, released once the delivery is handled, to reduce
+	// allocations. The Sinks and OnDelivery must then not keep the payload
+	// past their return. It is ignored with an asynchronous Dispatcher,
+	// whose payloads outlive the request.
+	PoolPayloads bool
+
+	// OnDelivery, if set, is called once every request has been handled
+	OnDelivery func(d *Delivery)
+	// OnLate, if set, is called for verified deliveries with a Lag over
+	// MaxLag, a sign the Travis notification pipeline is delayed
+	OnLate func(d *Delivery)
+	MaxLag time.Duration
+
+	// RetryAfter is the Retry-After of the 503 responses to deliveries shed
+	// when the Dispatcher, with FailFast, has a full queue,
+	// DefaultRetryAfter if zero. OnShed, if set, is called with them.
+	RetryAfter time.Duration
+	OnShed     func(d *Delivery)
+
+	// Tracer, if set, traces the handling of requests
+	Tracer Tracer
+
+	// Logger, if set, logs the key fetches, verification decisions and
+	// the outcome of deliveries
+	Logger *slog.Logger
+
+	// Clock times deliveries and the key TTL, SystemClock if nil
+	Clock Clock
+
+	once       sync.Once
+	verifier   *verifier
+	cache      CacheBackend
+	dispatcher *Dispatcher
+	counts     dispositionCounts
+	lastLag    atomic.Int64
+}
+
+// init sets up the state derived from the fields of h on first use
+func (h *Handler) init() {
+	h.once.Do(func() {
+		client := h.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		log := h.Logger
+		if log == nil {
+			log = discardLogger
+		}
+		ttl := h.KeyTTL
+		if ttl == 0 {
+			ttl = DefaultKeyTTL
+		}
+		h.verifier = &verifier{client: client, log: log, keyTTL: ttl, configURL: h.ConfigURL, clock: h.Clock, scheme: h.Scheme, cache: h.Cache}
+		h.cache = h.Cache
+		if h.cache == nil && h.Dedupe {
+			h.cache = &MemoryCache{Clock: h.Clock}
+		}
+		h.dispatcher = h.Dispatcher
+		if h.dispatcher == nil {
+			h.dispatcher = &Dispatcher{Sinks: h.Sinks, Tracer: h.Tracer, Logger: h.Logger}
+		}
+	})
+}
+
+// ServeHTTP verifies and dispatches the payload of r. It responds 401 for
+// a bad signature, 400 for other invalid requests, 500 if a sink failed,
+// 503 if the queue was full and 202 if the payload was queued.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.init()
+	log := h.verifier.log
+
+	clock := clockOr(h.Clock)
+	d := &Delivery{Received: clock.Now(), ctx: r.Context()}
+	pool := h.PoolPayloads && !h.dispatcher.Async() && h.Source == nil
+	defer func() {
+		d.Duration = clock.Now().Sub(d.Received)
+		h.counts.add(d.Disposition)
+		logDelivery(log, d)
+		if h.OnDelivery != nil {
+			h.OnDelivery(d)
+		}
+		if h.OnLate != nil && d.Payload != nil && d.Lag > h.MaxLag {
+			h.OnLate(d)
+		}
+		if h.OnShed != nil && d.Disposition == DispositionShed {
+			h.OnShed(d)
+		}
+		if pool && d.Payload != nil {
+			d.Payload.Release()
+		}
+	}()
+
+	var skipped bool
+	var err error
+	if h.Source != nil {
+		if d.Payload, err = h.Source.Payload(r); err == nil && d.Payload == nil {
+			err = ErrNoPayload
+		}
+	} else {
+		skipped, err = h.read(r, d, pool)
+	}
+	if skipped {
+		d.Disposition = DispositionSkipped
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err == nil {
+		d.Lag = DeliveryLag(d.Payload, d.Received)
+		h.lastLag.Store(int64(d.Lag))
+	}
+	if err != nil {
+		d.Disposition, d.Err = DispositionRejected, err
+		code := http.StatusBadRequest
+		if err == ErrUnauthorized {
+			code = http.StatusUnauthorized
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	var claim string
+	if h.Dedupe && h.Source == nil {
+		var first bool
+		if claim, first = h.claim(r.Context(), d.RawPayload); !first {
+			d.Disposition = DispositionDuplicate
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	if err := h.dispatcher.Dispatch(r.Context(), d.Payload); errors.Is(err, ErrQueueFull) {
+		h.unclaim(r.Context(), claim)
+		d.Disposition, d.Err = DispositionShed, err
+		retry := h.RetryAfter
+		if retry <= 0 {
+			retry = DefaultRetryAfter
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		h.unclaim(r.Context(), claim)
+		d.Disposition, d.Err = DispositionFailed, err
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if h.dispatcher.Async() {
+		d.Disposition = DispositionQueued
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	d.Disposition = DispositionHandled
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// claim records the delivery of payload for Dedupe and returns its key,
+// false if it was already recorded. Deliveries are dispatched if the cache
+// fails.
+func (h *Handler) claim(ctx context.Context, payload string) (string, bool) {
+	ttl := h.DedupeTTL
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	key := "travis:delivery:" + hex.EncodeToString(payloadDigest(payload))
+	first, err := h.cache.Add(ctx, key, []byte{'1'}, ttl)
+	if err != nil {
+		h.verifier.log.Warn("deduplicating webhook", "error", err)
+		return "", true
+	}
+	return key, first
+}
+
+// unclaim forgets the delivery of claim, which wasn't handled, for Travis
+// to deliver it again
+func (h *Handler) unclaim(ctx context.Context, claim string) {
+	if claim == "" {
+		return
+	}
+	if err := h.cache.Delete(context.WithoutCancel(ctx), claim); err != nil {
+		h.verifier.log.Warn("deduplicating webhook", "error", err)
+	}
+}
+
+// read verifies r, peeks at its payload and decodes it into d.Payload. It
+// returns true if Peek filtered the payload out.
+func (h *Handler) read(r *http.Request, d *Delivery, pool bool) (bool, error) {
+	ctx, end := startSpan(h.Tracer, r.Context(), "travis.verify")
+	// the form is parsed into the request verified, r has no body left
+	vr := r.WithContext(ctx)
+	payload, err := h.verifier.verify(vr)
+	end(nil, err)
+	d.RawPayload, d.Signature = vr.PostFormValue("payload"), r.Header.Get("Signature")
+	if err != nil {
+		return false, err
+	}
+	if h.Peek != nil {
+		pk, err := PeekPayload(payload)
+		if err != nil {
+			return false, err
+		}
+		if !h.Peek(pk) {
+			return true, nil
+		}
+	}
+
+	_, end = startSpan(h.Tracer, r.Context(), "travis.decode")
+	if pool {
+		d.Payload = AcquirePayload()
+		if err = DecodePayloadInto(payload, d.Payload); err != nil {
+			d.Payload.Release()
+			d.Payload = nil
+		}
+	} else {
+		d.Payload, err = decodePayload(payload)
+	}
+	end(d.Payload, err)
+	return false, err
+}
+
+func logDelivery(log *slog.Logger, d *Delivery) {
+	if d.Disposition == DispositionSkipped {
+		log.Debug("skipped webhook", "duration", d.Duration)
+		return
+	}
+	if d.Disposition == DispositionShed {
+		log.Warn("shed webhook", "repo", d.Payload.Slug(), "build", d.Payload.ID, "error", d.Err)
+		return
+	}
+	if d.Payload == nil {
+		log.Info("rejected webhook", "error", d.Err, "duration", d.Duration)
+		return
+	}
+	attrs := []any{
+		"repo", d.Payload.Slug(),
+		"build", d.Payload.ID,
+		"branch", d.Payload.Branch,
+		"state", Outcome(d.Payload),
+		"duration", d.Duration,
+		"lag", d.Lag,
+	}
+	log.Debug("handled webhook", append(attrs, "disposition", d.Disposition)...)
+}
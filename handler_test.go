@@ -0,0 +1,25 @@
+package travis_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func TestHandlerDeliveryRaw(t *testing.T) {
+	s := travistest.NewServer()
+	defer s.Close()
+	for _, pool := range []bool{false, true} {
+		var raw, signature string
+		h := &travis.Handler{ConfigURL: s.ConfigURL(), PoolPayloads: pool, OnDelivery: func(d *travis.Delivery) {
+			raw, signature = d.RawPayload, d.Signature
+		}}
+		h.ServeHTTP(httptest.NewRecorder(), travistest.NewPayload().ID(42).Request(s.Key))
+		if !strings.Contains(raw, `"id":42`) || signature == "" {
+			t.Errorf("pool=%t: RawPayload %q, Signature %q", pool, raw, signature)
+		}
+	}
+}
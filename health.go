@@ -0,0 +1,60 @@
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// Health is the state of a Handler as reported by Probe
+type Health struct {
+	// Healthy is true if the public key is loaded and fresh
+	Healthy bool `json:"healthy"`
+	// KeyLoaded is true if a public key is cached, KeyAge is its age
+	KeyLoaded bool          `json:"key_loaded"`
+	KeyAge    time.Duration `json:"key_age"`
+	// LastAPISuccess is the last time the Travis API was successfully called
+	LastAPISuccess time.Time `json:"last_api_success"`
+	// QueueBacklog is the number of payloads waiting to be dispatched
+	QueueBacklog int `json:"queue_backlog"`
+	// Error is the reason the Handler isn't healthy
+	Error string `json:"error,omitempty"`
+}
+
+// Probe reports the health of h. It fetches the public key if it isn't
+// loaded or has expired, which checks that the Travis API is reachable, so
+// it can be used as a readiness check. A Handler with a Scheme has no key
+// to load.
+func (h *Handler) Probe(ctx context.Context) Health {
+	h.init()
+	var health Health
+	if h.Scheme != nil {
+		health.QueueBacklog = h.dispatcher.Backlog()
+		health.Healthy = true
+		return health
+	}
+	if _, _, err := h.verifier.cachedKey(ctx, false); err != nil {
+		health.Error = err.Error()
+	}
+	health.KeyLoaded, health.KeyAge = h.verifier.keyAge()
+	if t := h.verifier.lastKeyFetch.Load(); t != 0 {
+		health.LastAPISuccess = time.Unix(0, t)
+	}
+	health.QueueBacklog = h.dispatcher.Backlog()
+	health.Healthy = health.Error == "" && health.KeyLoaded
+	return health
+}
+
+// HealthHandler returns an http.Handler serving the result of Probe as JSON,
+// with a 503 status when unhealthy
+func (h *Handler) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := h.Probe(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		if !health.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(health)
+	})
+}
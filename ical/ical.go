@@ -0,0 +1,207 @@
+// Package ical exports cron schedules and failure windows of builds as
+// iCalendar (.ics) files, for overlaying CI on planning calendars.
+//
+//	cal := &ical.Calendar{Name: "CI"}
+//	cal.Events = append(cal.Events, ical.CronEvents("acme/api", crons)...)
+//	cal.Events = append(cal.Events, ical.FailureEvents(builds, time.Now())...)
+//	cal.Write(w)
+//
+// Crons are recurring events starting at their next run, failure windows
+// span from the first failed build of a branch to the build fixing it.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+)
+
+// CronDuration is the length of the events of crons
+const CronDuration = 30 * time.Minute
+
+// Event is an event of a Calendar
+type Event struct {
+	// UID identifies the event across exports
+	UID         string
+	Summary     string
+	Description string
+	URL         string
+	Start, End  time.Time
+	// RRule is the recurrence rule of the event, e.g. FREQ=DAILY
+	RRule string
+	// Categories are e.g. the outcome of a build
+	Categories []string
+}
+
+// Calendar is an iCalendar file of events
+type Calendar struct {
+	// Name is shown by calendar apps subscribing to the file
+	Name   string
+	Events []*Event
+}
+
+// CronEvents returns the recurring events of the active crons of the
+// repository slug
+func CronEvents(slug string, crons []*travis.Cron) []*Event {
+	var events []*Event
+	for _, c := range crons {
+		start := c.NextRun
+		if start.IsZero() {
+			start = c.LastRun
+		}
+		if !c.Active || start.IsZero() {
+			continue
+		}
+		rrule := map[string]string{"daily": "FREQ=DAILY", "weekly": "FREQ=WEEKLY", "monthly": "FREQ=MONTHLY"}[c.Interval]
+		description := fmt.Sprintf("Cron build of %s on %s, %s.", slug, c.Branch, c.Interval)
+		if c.DontRunIfRecentBuildExists {
+			description += " Skipped if the branch was built in the last 24 hours."
+		}
+		events = append(events, &Event{
+			UID:         fmt.Sprintf("cron-%d@travis", c.ID),
+			Summary:     fmt.Sprintf("Cron: %s (%s)", slug, c.Branch),
+			Description: description,
+			Start:       start,
+			End:         start.Add(CronDuration),
+			RRule:       rrule,
+			Categories:  []string{"cron"},
+		})
+	}
+	return events
+}
+
+// FailureEvents returns the failure windows of the push, cron and api
+// builds in builds, in any order: an event per branch from the first failed
+// or errored build to the next passed one, or to now if the branch is still
+// broken
+func FailureEvents(builds []*travis.Payload, now time.Time) []*Event {
+	sorted := make([]*travis.Payload, 0, len(builds))
+	for _, p := range builds {
+		if !p.IsPullRequest() && travis.Outcome(p) != travis.OutcomePending && travis.Outcome(p) != travis.OutcomeCanceled {
+			sorted = append(sorted, p)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return store.BuildTime(sorted[i]).Before(store.BuildTime(sorted[j]))
+	})
+
+	var events []*Event
+	open := map[string]*Event{}
+	failures := map[string]int{}
+	for _, p := range sorted {
+		key := p.Slug() + "@" + p.Branch
+		failed := travis.Outcome(p) != travis.OutcomePassed
+		e := open[key]
+		switch {
+		case failed && e == nil:
+			e = &Event{
+				UID:        fmt.Sprintf("failure-%s@travis", strings.ReplaceAll(p.SourceID(), "/", "-")),
+				Summary:    fmt.Sprintf("Broken: %s (%s)", p.Slug(), p.Branch),
+				URL:        p.BuildURL,
+				Start:      store.BuildTime(p),
+				Categories: []string{"failure"},
+			}
+			open[key], failures[key] = e, 1
+			events = append(events, e)
+			e.Description = fmt.Sprintf("Broken by #%s by %s: %s", p.Number, p.AuthorName, firstLine(p.Message))
+		case failed:
+			failures[key]++
+		case e != nil:
+			e.End = store.BuildTime(p)
+			e.Description += fmt.Sprintf("\nFixed by #%s after %d failed builds.", p.Number, failures[key])
+			delete(open, key)
+		}
+	}
+	for _, e := range open {
+		e.End = now
+		e.Description += "\nStill broken."
+	}
+	return events
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// Write writes c in the iCalendar format
+func (c *Calendar) Write(w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:-//jacksgt//travis//EN")
+	lw.line("CALSCALE:GREGORIAN")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	stamp := time.Now()
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + escape(e.UID))
+		lw.line("DTSTAMP:" + format(stamp))
+		lw.line("DTSTART:" + format(e.Start))
+		if !e.End.IsZero() {
+			lw.line("DTEND:" + format(e.End))
+		}
+		if e.RRule != "" {
+			lw.line("RRULE:" + e.RRule)
+		}
+		lw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.URL != "" {
+			lw.line("URL:" + e.URL)
+		}
+		if len(e.Categories) > 0 {
+			cats := make([]string, len(e.Categories))
+			for i, cat := range e.Categories {
+				cats[i] = escape(cat)
+			}
+			lw.line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+// format returns t in UTC in the iCalendar date-time format
+func format(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// escape escapes the special characters of a text value
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// lineWriter writes content lines, folded at 75 octets and ended with CRLF
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	b.WriteString("\r\n")
+	_, lw.err = io.WriteString(lw.w, b.String())
+}
@@ -0,0 +1,91 @@
+package travis
+
+// Outcomes of a build as returned by Outcome
+const (
+	OutcomePassed   = "passed"
+	OutcomeFailed   = "failed"
+	OutcomeErrored  = "errored"
+	OutcomeCanceled = "canceled"
+	OutcomePending  = "pending"
+)
+
+// Outcome reduces the status of p to one of the Outcome constants, Fixed
+// counts as passed while Broken and Still Failing count as failed
+func Outcome(p *Payload) string {
+	switch {
+	case p.Passed(), p.Fixed():
+		return OutcomePassed
+	case p.Broken(), p.Failed(), p.StillFailing():
+		return OutcomeFailed
+	case p.Errored():
+		return OutcomeErrored
+	case p.Canceled():
+		return OutcomeCanceled
+	}
+	switch p.State {
+	case OutcomePassed, OutcomeFailed, OutcomeErrored, OutcomeCanceled:
+		return p.State
+	}
+	return OutcomePending
+}
+
+// StateEmoji returns an emoji representing the outcome of p
+func StateEmoji(p *Payload) string {
+	switch Outcome(p) {
+	case OutcomePassed:
+		return "✅"
+	case OutcomeFailed:
+		return "❌"
+	case OutcomeErrored:
+		return "⚠️"
+	case OutcomeCanceled:
+		return "🚫"
+	}
+	return "⏳"
+}
+
+// StateColor returns the color representing the outcome of p
+func StateColor(p *Payload) Color {
+	switch Outcome(p) {
+	case OutcomePassed:
+		return Passed
+	case OutcomeFailed, OutcomeErrored:
+		return Fail
+	case OutcomeCanceled:
+		return Cancel
+	}
+	return InProgress
+}
+
+// Icons is a set of icon URLs, one per outcome
+type Icons struct {
+	Passed   string
+	Failed   string
+	Errored  string
+	Canceled string
+	Pending  string
+}
+
+// DefaultIcons are shields.io badges in the colors of the Color constants
+var DefaultIcons = Icons{
+	Passed:   "https://img.shields.io/badge/build-passed-39AA56.svg",
+	Failed:   "https://img.shields.io/badge/build-failed-DB4545.svg",
+	Errored:  "https://img.shields.io/badge/build-errored-DB4545.svg",
+	Canceled: "https://img.shields.io/badge/build-canceled-9D9D9D.svg",
+	Pending:  "https://img.shields.io/badge/build-pending-EDDE3F.svg",
+}
+
+// StateIcon returns the URL of the icon representing the outcome of p
+func (i Icons) StateIcon(p *Payload) string {
+	switch Outcome(p) {
+	case OutcomePassed:
+		return i.Passed
+	case OutcomeFailed:
+		return i.Failed
+	case OutcomeErrored:
+		return i.Errored
+	case OutcomeCanceled:
+		return i.Canceled
+	}
+	return i.Pending
+}
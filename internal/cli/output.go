@@ -0,0 +1,76 @@
+// Package cli holds what the commands share: the -output flag and the
+// printing of results in its formats.
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// The output formats
+const (
+	// Table prints aligned columns for humans, the default
+	Table = "table"
+	// JSON prints results as JSON objects, one per line when streaming
+	JSON = "json"
+	// Quiet prints nothing, the exit code tells the outcome
+	Quiet = "quiet"
+)
+
+// Output prints results in the format chosen with the -output flag
+type Output struct {
+	Format string
+	W      io.Writer
+}
+
+// OutputFlag registers -output on the command line flags
+func OutputFlag() *Output {
+	o := &Output{Format: Table, W: os.Stdout}
+	flag.Var(o, "output", "output `format`: table, json or quiet")
+	return o
+}
+
+// String implements flag.Value
+func (o *Output) String() string {
+	if o == nil {
+		return ""
+	}
+	return o.Format
+}
+
+// Set implements flag.Value
+func (o *Output) Set(s string) error {
+	switch s {
+	case Table, JSON, Quiet:
+		o.Format = s
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", s)
+}
+
+// Print prints v as JSON, or the rows as a table whose cells are separated
+// by tabs
+func (o *Output) Print(v interface{}, rows ...string) {
+	switch o.Format {
+	case JSON:
+		json.NewEncoder(o.W).Encode(v)
+	case Table:
+		tw := tabwriter.NewWriter(o.W, 0, 4, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(tw, row)
+		}
+		tw.Flush()
+	}
+}
+
+// Text prints s as is in the table format only, e.g. logs
+func (o *Output) Text(s string) {
+	if o.Format == Table {
+		fmt.Fprintln(o.W, strings.TrimRight(s, "\n"))
+	}
+}
@@ -0,0 +1,126 @@
+// Package lrumap holds the map evicting its least recently used entries
+// that bounds the in-memory state of the travis sinks and rules.
+package lrumap
+
+import "container/list"
+
+// Map is a map evicting its least recently used entries once it holds more
+// than its maximum number of entries or, if set, of bytes as estimated by
+// Size. The zero Map is unbounded. It isn't safe for concurrent use.
+type Map[K comparable, V any] struct {
+	// Size, if set, estimates the bytes of an entry
+	Size func(K, V) int
+
+	maxEntries int
+	maxBytes   int64
+
+	entries   map[K]*list.Element
+	order     list.List
+	bytes     int64
+	evictions int64
+}
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int
+}
+
+// Limit sets the bounds of m, none if zero, and evicts the entries over
+// them
+func (m *Map[K, V]) Limit(maxEntries int, maxBytes int64) {
+	m.maxEntries, m.maxBytes = maxEntries, maxBytes
+	m.evict(nil)
+}
+
+// Get returns the value of k and marks it used
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	if e, ok := m.entries[k]; ok {
+		m.order.MoveToFront(e)
+		return e.Value.(*entry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Peek returns the value of k without marking it used
+func (m *Map[K, V]) Peek(k K) (V, bool) {
+	if e, ok := m.entries[k]; ok {
+		return e.Value.(*entry[K, V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Put sets the value of k, marks it used and evicts the least recently used
+// entries over the bounds. It must be called again when a value changes
+// size.
+func (m *Map[K, V]) Put(k K, v V) {
+	if m.entries == nil {
+		m.entries = make(map[K]*list.Element)
+	}
+	size := 0
+	if m.Size != nil {
+		size = m.Size(k, v)
+	}
+	e, ok := m.entries[k]
+	if ok {
+		le := e.Value.(*entry[K, V])
+		m.bytes += int64(size - le.size)
+		le.value, le.size = v, size
+		m.order.MoveToFront(e)
+	} else {
+		e = m.order.PushFront(&entry[K, V]{key: k, value: v, size: size})
+		m.entries[k] = e
+		m.bytes += int64(size)
+	}
+	m.evict(e)
+}
+
+// Remove deletes the entry of k
+func (m *Map[K, V]) Remove(k K) {
+	if e, ok := m.entries[k]; ok {
+		le := m.order.Remove(e).(*entry[K, V])
+		delete(m.entries, k)
+		m.bytes -= int64(le.size)
+	}
+}
+
+// evict removes the least recently used entries but keep until m is within
+// its bounds
+func (m *Map[K, V]) evict(keep *list.Element) {
+	for m.over() {
+		e := m.order.Back()
+		if e == nil || e == keep {
+			return
+		}
+		le := m.order.Remove(e).(*entry[K, V])
+		delete(m.entries, le.key)
+		m.bytes -= int64(le.size)
+		m.evictions++
+	}
+}
+
+func (m *Map[K, V]) over() bool {
+	if m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		return true
+	}
+	return m.maxBytes > 0 && m.bytes > m.maxBytes
+}
+
+// Each calls f with every entry, most recently used first
+func (m *Map[K, V]) Each(f func(K, V)) {
+	for e := m.order.Front(); e != nil; e = e.Next() {
+		le := e.Value.(*entry[K, V])
+		f(le.key, le.value)
+	}
+}
+
+// Len returns the number of entries
+func (m *Map[K, V]) Len() int { return m.order.Len() }
+
+// Bytes returns the estimated size of the entries
+func (m *Map[K, V]) Bytes() int64 { return m.bytes }
+
+// Evictions returns the number of entries evicted
+func (m *Map[K, V]) Evictions() int64 { return m.evictions }
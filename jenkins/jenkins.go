@@ -0,0 +1,203 @@
+// Package jenkins emits finished builds as the JSON of the Jenkins
+// Notification plugin, for tools of mixed Jenkins and Travis setups that
+// only understand the notifications of Jenkins.
+//
+//	h := &travis.Handler{Sinks: []travis.Sink{&jenkins.Sink{
+//		URLs: []string{"https://dashboard.example.com/jenkins"},
+//	}}}
+//
+// A build is reported as the job named after its repository slug, with the
+// COMPLETED then the FINALIZED phase, as Jenkins sends them.
+package jenkins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/jacksgt/travis"
+)
+
+// Phases of a Jenkins build
+const (
+	PhaseStarted   = "STARTED"
+	PhaseCompleted = "COMPLETED"
+	PhaseFinalized = "FINALIZED"
+)
+
+// Notification is the notification of a build phase, as the Notification
+// plugin posts it
+type Notification struct {
+	// Name is the name of the job, the slug of the repository
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Build Build  `json:"build"`
+}
+
+// Build is the build of a Notification
+type Build struct {
+	FullURL string `json:"full_url"`
+	Number  int    `json:"number"`
+	QueueID int64  `json:"queue_id"`
+	// Timestamp is the start of the build and Duration its length, in
+	// milliseconds
+	Timestamp  int64             `json:"timestamp"`
+	Duration   int64             `json:"duration"`
+	Phase      string            `json:"phase"`
+	Status     string            `json:"status,omitempty"`
+	URL        string            `json:"url"`
+	SCM        SCM               `json:"scm"`
+	Parameters map[string]string `json:"parameters"`
+	Log        string            `json:"log"`
+	Notes      string            `json:"notes"`
+	Artifacts  map[string]string `json:"artifacts"`
+}
+
+// SCM is the commit of a Build
+type SCM struct {
+	URL      string   `json:"url"`
+	Branch   string   `json:"branch"`
+	Commit   string   `json:"commit"`
+	Changes  []string `json:"changes"`
+	Culprits []string `json:"culprits"`
+}
+
+// Status returns the Jenkins result of an outcome: SUCCESS, FAILURE,
+// ABORTED, or NOT_BUILT for pending builds. Errored builds are failures,
+// Jenkins doesn't tell them apart.
+func Status(outcome string) string {
+	switch outcome {
+	case travis.OutcomePassed:
+		return "SUCCESS"
+	case travis.OutcomeFailed, travis.OutcomeErrored:
+		return "FAILURE"
+	case travis.OutcomeCanceled:
+		return "ABORTED"
+	}
+	return "NOT_BUILT"
+}
+
+// NewNotification returns the notification of phase of the build of p
+func NewNotification(p *travis.Payload, phase string) *Notification {
+	slug := p.Slug()
+	number, _ := strconv.Atoi(p.Number)
+	n := &Notification{
+		Name: slug,
+		URL:  "job/" + slug + "/",
+		Build: Build{
+			FullURL:    p.BuildURL,
+			Number:     number,
+			QueueID:    p.ID,
+			Phase:      phase,
+			URL:        fmt.Sprintf("job/%s/%s/", slug, p.Number),
+			Parameters: parameters(p),
+			Artifacts:  map[string]string{},
+			SCM: SCM{
+				Branch:   p.Branch,
+				Commit:   p.Commit,
+				Changes:  []string{},
+				Culprits: []string{},
+			},
+		},
+	}
+	if p.Repository != nil {
+		n.Build.SCM.URL = p.Repository.URL
+	}
+	if p.AuthorName != "" {
+		n.Build.SCM.Culprits = []string{p.AuthorName}
+	}
+	if !p.StartedAt.IsZero() {
+		n.Build.Timestamp = p.StartedAt.UnixMilli()
+	}
+	if !p.StartedAt.IsZero() && p.FinishedAt.After(p.StartedAt) {
+		n.Build.Duration = p.FinishedAt.Sub(p.StartedAt).Milliseconds()
+	} else {
+		n.Build.Duration = int64(p.Duration) * 1000
+	}
+	if phase != PhaseStarted {
+		n.Build.Status = Status(travis.Outcome(p))
+	}
+	return n
+}
+
+// parameters returns the build parameters of p, the Travis variables
+// telling what triggered it
+func parameters(p *travis.Payload) map[string]string {
+	params := map[string]string{
+		"TRAVIS_EVENT_TYPE":   p.Type,
+		"TRAVIS_BRANCH":       p.Branch,
+		"TRAVIS_COMMIT":       p.Commit,
+		"TRAVIS_PULL_REQUEST": "false",
+	}
+	if p.IsPullRequest() {
+		params["TRAVIS_PULL_REQUEST"] = strconv.Itoa(p.PullRequestNumber)
+	}
+	if p.Tag != "" {
+		params["TRAVIS_TAG"] = p.Tag
+	}
+	return params
+}
+
+// Sink is a travis.Sink posting the notifications of finished builds
+type Sink struct {
+	// URLs receive the notifications
+	URLs []string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Send posts the COMPLETED and FINALIZED notifications of p to every URL if
+// its build is finished. A URL failing doesn't stop the others, the errors
+// are joined.
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	if travis.Outcome(p) == travis.OutcomePending {
+		return nil
+	}
+	var errs []error
+	for _, url := range s.URLs {
+		for _, phase := range []string{PhaseCompleted, PhaseFinalized} {
+			if err := s.Notify(ctx, url, NewNotification(p, phase)); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notify posts n to url
+func (s *Sink) Notify(ctx context.Context, url string, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("jenkins: %s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
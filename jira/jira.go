@@ -0,0 +1,283 @@
+// Package jira reports builds on the Jira issues their commit messages
+// reference, e.g. "PROJ-123 fix the login": each issue gets a comment with
+// the result of the build and is moved through a workflow transition, such
+// as "Ready for QA" when the build passes.
+//
+//	h := &travis.Handler{Sinks: []travis.Sink{&jira.Sink{
+//		URL:         "https://acme.atlassian.net",
+//		User:        "ci@acme.com",
+//		Token:       os.Getenv("JIRA_TOKEN"),
+//		Branches:    []string{"main"},
+//		Transitions: map[string]string{travis.OutcomePassed: "Ready for QA"},
+//	}}}
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/jacksgt/travis"
+)
+
+// DefaultComment is the template of comments if Comment isn't set, in the
+// wiki markup of the Jira API v2
+const DefaultComment = `Build [#{{.Payload.Number}}|{{.Payload.BuildURL}}] of {{.Payload.Slug}} ({{.Payload.Branch}}) *{{.Outcome}}* at {{.Payload.Commit}} by {{.Payload.AuthorName}}.`
+
+// DefaultSummary is the template of the summary of created issues if
+// Create.Summary isn't set
+const DefaultSummary = `Build #{{.Payload.Number}} of {{.Payload.Slug}} ({{.Payload.Branch}}) {{.Outcome}}`
+
+var keyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// IssueKeys returns the issue keys referenced by message, e.g. PROJ-123, in
+// order and without duplicates
+func IssueKeys(message string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, k := range keyPattern.FindAllString(message, -1) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Event is the data of the templates
+type Event struct {
+	Payload *travis.Payload
+	// Outcome is passed, failed or errored
+	Outcome string
+	// Key is the issue commented, empty in the templates of created issues
+	Key string
+}
+
+// Create configures the issues created for failed builds referencing none
+type Create struct {
+	// Project is the key of the project of the issues
+	Project string
+	// IssueType is the name of the type of the issues, Bug if empty
+	IssueType string
+	// Summary and Description are templates of Event, DefaultSummary
+	// and the comment template if empty
+	Summary, Description string
+}
+
+// Sink is a travis.Sink commenting and transitioning the issues referenced
+// by the commit message of finished builds
+type Sink struct {
+	// URL is the base URL of Jira, e.g. https://acme.atlassian.net
+	URL string
+	// User and Token authenticate with basic authentication, as the API
+	// tokens of Jira Cloud require; Token alone is sent as a bearer token,
+	// e.g. a personal access token of Jira Data Center
+	User, Token string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+
+	// Branches and Projects are path.Match patterns of the branches and
+	// issue project keys acted on, all if empty. Pull request builds are
+	// matched by their target branch.
+	Branches []string
+	Projects []string
+	// Outcomes are the outcomes commented, passed, failed and errored if
+	// empty
+	Outcomes []string
+
+	// Comment is a text/template template of Event, DefaultComment if
+	// empty
+	Comment string
+	// Transitions are the names of the transitions applied to the issues
+	// by outcome, e.g. "Ready for QA" for passed builds. Issues without
+	// the transition, e.g. already moved, are only commented.
+	Transitions map[string]string
+	// Create, if set, creates an issue for failed and errored builds whose
+	// commit message references none
+	Create *Create
+
+	once    sync.Once
+	comment *template.Template
+	summary *template.Template
+	desc    *template.Template
+	err     error
+}
+
+// Send reports p on the issues it references
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	outcome := travis.Outcome(p)
+	outcomes := s.Outcomes
+	if len(outcomes) == 0 {
+		outcomes = []string{travis.OutcomePassed, travis.OutcomeFailed, travis.OutcomeErrored}
+	}
+	if !travis.MatchAny(outcomes, outcome) || !travis.MatchAny(s.Branches, p.Branch) {
+		return nil
+	}
+	s.once.Do(s.parse)
+	if s.err != nil {
+		return s.err
+	}
+
+	var keys []string
+	for _, k := range IssueKeys(p.Message) {
+		project, _, _ := strings.Cut(k, "-")
+		if travis.MatchAny(s.Projects, project) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		if s.Create != nil && (outcome == travis.OutcomeFailed || outcome == travis.OutcomeErrored) {
+			return s.create(ctx, &Event{Payload: p, Outcome: outcome})
+		}
+		return nil
+	}
+
+	var errs []error
+	for _, k := range keys {
+		if err := s.report(ctx, &Event{Payload: p, Outcome: outcome, Key: k}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sink) parse() {
+	parse := func(name, text, def string) *template.Template {
+		if s.err != nil {
+			return nil
+		}
+		if text == "" {
+			text = def
+		}
+		t, err := template.New(name).Parse(text)
+		s.err = err
+		return t
+	}
+	s.comment = parse("comment", s.Comment, DefaultComment)
+	if s.Create != nil {
+		s.summary = parse("summary", s.Create.Summary, DefaultSummary)
+		s.desc = s.comment
+		if s.Create.Description != "" {
+			s.desc = parse("description", s.Create.Description, "")
+		}
+	}
+}
+
+// report comments and transitions the issue of e
+func (s *Sink) report(ctx context.Context, e *Event) error {
+	comment, err := execute(s.comment, e)
+	if err != nil {
+		return err
+	}
+	base := "/rest/api/2/issue/" + url.PathEscape(e.Key)
+	if err := s.do(ctx, "POST", base+"/comment", map[string]any{"body": comment}, nil); err != nil {
+		return err
+	}
+	name := s.Transitions[e.Outcome]
+	if name == "" {
+		return nil
+	}
+	var res struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := s.do(ctx, "GET", base+"/transitions", nil, &res); err != nil {
+		return err
+	}
+	for _, t := range res.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			return s.do(ctx, "POST", base+"/transitions", map[string]any{"transition": map[string]string{"id": t.ID}}, nil)
+		}
+	}
+	return nil
+}
+
+// create creates the issue of e
+func (s *Sink) create(ctx context.Context, e *Event) error {
+	summary, err := execute(s.summary, e)
+	if err != nil {
+		return err
+	}
+	desc, err := execute(s.desc, e)
+	if err != nil {
+		return err
+	}
+	issueType := s.Create.IssueType
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	return s.do(ctx, "POST", "/rest/api/2/issue", map[string]any{
+		"fields": map[string]any{
+			"project":     map[string]string{"key": s.Create.Project},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     strings.TrimSpace(summary),
+			"description": desc,
+		},
+	}, nil)
+}
+
+func execute(t *template.Template, e *Event) (string, error) {
+	var b bytes.Buffer
+	err := t.Execute(&b, e)
+	return b.String(), err
+}
+
+// do sends a request with the JSON of in, if not nil, to path and decodes
+// the response into out, if not nil
+func (s *Sink) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.URL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case s.User != "":
+		req.SetBasicAuth(s.User, s.Token)
+	case s.Token != "":
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("jira: %s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
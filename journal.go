@@ -0,0 +1,117 @@
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Journal persists the payloads queued by a Dispatcher so the ones not sent
+// yet survive a restart
+type Journal interface {
+	// Append records p before it is queued and returns its key
+	Append(p *Payload) (key string, err error)
+	// Remove forgets the payload with key once it has been sent
+	Remove(key string) error
+	// Pending returns the payloads appended but not removed, oldest first.
+	// It returns the payloads it could read along with the error of the
+	// others.
+	Pending() ([]JournalEntry, error)
+}
+
+// JournalEntry is a payload waiting in a Journal
+type JournalEntry struct {
+	Key     string
+	Payload *Payload
+
+	// ctx carries the values of the context of the dispatch, such as the
+	// trace, to the worker sending the payload
+	ctx context.Context
+	// sink, if set, is the only sink the payload is sent to, and done is
+	// called with its error, for the follow-ups of a Scheduler
+	sink Sink
+	done func(err error)
+}
+
+// FileJournal is a Journal keeping each pending payload as a JSON file in Dir
+type FileJournal struct {
+	Dir string
+
+	seq atomic.Int64
+}
+
+// Append writes p to a new file, synced before returning
+func (j *FileJournal) Append(p *Payload) (string, error) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	// keys sort in dispatch order, the sequence breaks ties in the same nanosecond
+	key := fmt.Sprintf("%020d-%06d-%d", time.Now().UnixNano(), j.seq.Add(1)%1e6, p.ID)
+	tmp := filepath.Join(j.Dir, key+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return key, os.Rename(tmp, filepath.Join(j.Dir, key+".json"))
+}
+
+// Remove deletes the file of key
+func (j *FileJournal) Remove(key string) error {
+	err := os.Remove(filepath.Join(j.Dir, key+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Pending reads the files left in Dir. The files it can't read or decode,
+// e.g. truncated by a crash, are renamed with a .corrupt suffix so they
+// aren't read again, and reported in the error.
+func (j *FileJournal) Pending() ([]JournalEntry, error) {
+	names, err := filepath.Glob(filepath.Join(j.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	entries := make([]JournalEntry, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		body, err := os.ReadFile(name)
+		p := new(Payload)
+		if err == nil {
+			err = decodeJSON(body, p)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+			if err := os.Rename(name, name+".corrupt"); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		key := strings.TrimSuffix(filepath.Base(name), ".json")
+		entries = append(entries, JournalEntry{Key: key, Payload: p})
+	}
+	return entries, errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package travis_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestFileJournalPending(t *testing.T) {
+	j := &travis.FileJournal{Dir: t.TempDir()}
+	var keys []string
+	for id := int64(1); id <= 3; id++ {
+		key, err := j.Append(&travis.Payload{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	// a file truncated by a crash
+	bad := filepath.Join(j.Dir, keys[1]+".json")
+	if err := os.WriteFile(bad, []byte(`{"id": 2, "numb`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := j.Pending()
+	if err == nil {
+		t.Error("Pending() didn't report the corrupt file")
+	}
+	if len(entries) != 2 || entries[0].Payload.ID != 1 || entries[1].Payload.ID != 3 {
+		t.Errorf("Pending() = %v, want the payloads 1 and 3", entries)
+	}
+	if _, err := os.Stat(bad + ".corrupt"); err != nil {
+		t.Errorf("corrupt file not renamed: %v", err)
+	}
+
+	if err := j.Remove(entries[0].Key); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = j.Pending()
+	if err != nil || len(entries) != 1 || entries[0].Payload.ID != 3 {
+		t.Errorf("Pending() = %v, %v, want the payload 3", entries, err)
+	}
+}
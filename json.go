@@ -0,0 +1,21 @@
+package travis
+
+import "encoding/json"
+
+// JSONDecoder decodes data into v like json.Unmarshal. Decoders other than
+// encoding/json must call the json.Unmarshaler methods of the types they
+// decode, as Payload and Config depend on them.
+type JSONDecoder func(data []byte, v any) error
+
+// decodeJSON decodes the payloads and their configs
+var decodeJSON JSONDecoder = json.Unmarshal
+
+// SetJSONDecoder sets the decoder of payloads and of their configs, e.g. a
+// faster third-party decoder, or restores the default if d is nil. It must
+// be called before decoding payloads, typically from an init function.
+func SetJSONDecoder(d JSONDecoder) {
+	if d == nil {
+		d = json.Unmarshal
+	}
+	decodeJSON = d
+}
@@ -0,0 +1,71 @@
+// Package kafkasink publishes travis payloads to a Kafka topic.
+package kafkasink
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer is the part of *kafka.Writer used by Sink
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Envelope wraps payloads when Sink.Schema is set, so consumers can tell
+// event versions apart
+type Envelope struct {
+	Schema  string          `json:"schema"`
+	ID      string          `json:"id"`
+	Time    time.Time       `json:"time"`
+	Repo    string          `json:"repo"`
+	State   string          `json:"state"`
+	Payload *travis.Payload `json:"payload"`
+}
+
+// Sink is a travis.Sink writing payloads as JSON, keyed by repo slug so the
+// builds of a repository stay ordered within a partition
+type Sink struct {
+	Writer Writer
+	// Topic to write to, leave empty if the Writer has a topic configured
+	Topic string
+	// Schema, if set, wraps payloads in an Envelope with this schema name
+	Schema string
+}
+
+// Send writes p to the topic
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	var v interface{} = p
+	if s.Schema != "" {
+		v = &Envelope{
+			Schema:  s.Schema,
+			ID:      strconv.FormatInt(p.ID, 10) + "-" + travis.Outcome(p),
+			Time:    time.Now().UTC(),
+			Repo:    p.Slug(),
+			State:   travis.Outcome(p),
+			Payload: p,
+		}
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.Writer.WriteMessages(ctx, kafka.Message{
+		Topic: s.Topic,
+		Key:   []byte(p.Slug()),
+		Value: body,
+		Headers: []kafka.Header{
+			{Key: "branch", Value: []byte(p.Branch)},
+			{Key: "state", Value: []byte(travis.Outcome(p))},
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package travis
+
+import (
+	"crypto/rsa"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// sharedKeys are the public keys fetched in the process by config URL, so
+// handlers of the same host and GetPayloadFromRequest fetch and parse a key
+// once
+var sharedKeys = struct {
+	sync.Mutex
+	keys map[string]sharedKey
+}{keys: make(map[string]sharedKey)}
+
+type sharedKey struct {
+	key    *rsa.PublicKey
+	loaded time.Time
+}
+
+// lookupSharedKey returns the key of url fetched less than ttl before now
+func lookupSharedKey(url string, now time.Time, ttl time.Duration) (*rsa.PublicKey, time.Time, bool) {
+	sharedKeys.Lock()
+	defer sharedKeys.Unlock()
+	k, ok := sharedKeys.keys[url]
+	if !ok || now.Sub(k.loaded) >= ttl {
+		return nil, time.Time{}, false
+	}
+	return k.key, k.loaded, true
+}
+
+func storeSharedKey(url string, key *rsa.PublicKey, loaded time.Time) {
+	sharedKeys.Lock()
+	sharedKeys.keys[url] = sharedKey{key: key, loaded: loaded}
+	sharedKeys.Unlock()
+}
+
+// maxParsedKeys bounds parsedKeys, which only holds a few keys in practice
+const maxParsedKeys = 16
+
+// parsedKeys are the keys parsed by parsePublicKey by PEM, so fetching an
+// unchanged key doesn't parse it again
+var parsedKeys = struct {
+	sync.Mutex
+	keys map[string]*rsa.PublicKey
+}{keys: make(map[string]*rsa.PublicKey)}
+
+func lookupParsedKey(pem string) *rsa.PublicKey {
+	parsedKeys.Lock()
+	defer parsedKeys.Unlock()
+	return parsedKeys.keys[pem]
+}
+
+func storeParsedKey(pem string, key *rsa.PublicKey) {
+	parsedKeys.Lock()
+	defer parsedKeys.Unlock()
+	if len(parsedKeys.keys) >= maxParsedKeys {
+		clear(parsedKeys.keys)
+	}
+	parsedKeys.keys[pem] = key
+}
+
+// bytesOf returns the bytes of s without copying them, for the functions
+// that only read their input such as hashes and json.Unmarshal. They must
+// not be modified.
+func bytesOf(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
@@ -0,0 +1,124 @@
+package travis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Broadcaster is a Sink streaming the payloads it receives to browsers as
+// Server-Sent Events. Clients connect to its ServeHTTP, optionally filtering
+// with the repo ("owner/name") and branch query parameters, and receive
+// events named after the Outcome of the build with the payload as JSON data.
+//
+// A client whose buffer is full is disconnected rather than slowing down
+// the other clients, EventSource reconnects on its own.
+type Broadcaster struct {
+	// Buffer is the number of events buffered per client, 16 if zero
+	Buffer int
+	// KeepAlive is the interval of comments sent to keep idle connections
+	// open, 30 seconds if zero
+	KeepAlive time.Duration
+
+	mu      sync.Mutex
+	clients map[*liveClient]struct{}
+}
+
+type liveClient struct {
+	repo, branch string
+	events       chan []byte
+	dropped      chan struct{}
+}
+
+// Send broadcasts p to the connected clients
+func (b *Broadcaster) Send(p *Payload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	event := []byte(fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", p.ID, Outcome(p), data))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		if (c.repo != "" && c.repo != p.Slug()) || (c.branch != "" && c.branch != p.Branch) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			delete(b.clients, c)
+			close(c.dropped)
+		}
+	}
+	return nil
+}
+
+// Clients returns the number of connected clients
+func (b *Broadcaster) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// ServeHTTP streams events to the client until it disconnects
+func (b *Broadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	size := b.Buffer
+	if size <= 0 {
+		size = 16
+	}
+	keepAlive := b.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = 30 * time.Second
+	}
+
+	c := &liveClient{
+		repo:    r.URL.Query().Get("repo"),
+		branch:  r.URL.Query().Get("branch"),
+		events:  make(chan []byte, size),
+		dropped: make(chan struct{}),
+	}
+	b.mu.Lock()
+	if b.clients == nil {
+		b.clients = make(map[*liveClient]struct{})
+	}
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case event := <-c.events:
+			if _, err := w.Write(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+				return
+			}
+		case <-c.dropped:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,219 @@
+package travis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultOnceTTL is how long Once holds the lock of a delivery
+const DefaultOnceTTL = 24 * time.Hour
+
+// DefaultLeaderTTL is the lease of a Leader if TTL isn't set
+const DefaultLeaderTTL = 30 * time.Second
+
+// Locker grants locks exclusive across the replicas of a receiver, e.g. in
+// Redis with rediscache.Cache or in etcd with the etcdlock package
+type Locker interface {
+	// Lock acquires key for ttl and returns the token of the holder, ""
+	// if another holds it
+	Lock(ctx context.Context, key string, ttl time.Duration) (token string, err error)
+	// Refresh extends the lock of token on key for ttl and returns false if
+	// it expired or another holds it
+	Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock of token on key, if it still holds it
+	Unlock(ctx context.Context, key, token string) error
+}
+
+// NewLockToken returns a random token for the implementations of Locker
+func NewLockToken() string {
+	return NewID()
+}
+
+// NewID returns 16 random bytes in hex, for lock tokens and the other IDs
+// such as those of follow-ups. It panics if the system has no randomness to
+// give.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// MemoryLocker is a Locker in the memory of the process, for receivers with
+// a single replica and tests
+type MemoryLocker struct {
+	// MaxEntries is the number of locks kept, DefaultMaxEntries if zero.
+	// The least recently taken are forgotten first.
+	MaxEntries int
+
+	// Clock expires the locks, SystemClock if nil
+	Clock Clock
+
+	mu    sync.Mutex
+	locks lru[string, memoryLock]
+}
+
+type memoryLock struct {
+	token   string
+	expires time.Time
+}
+
+// Lock acquires key for ttl
+func (l *MemoryLocker) Lock(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.locks.limit(l.MaxEntries, 0)
+	now := clockOr(l.Clock).Now()
+	if m, ok := l.locks.Peek(key); ok && now.Before(m.expires) {
+		return "", nil
+	}
+	token := NewLockToken()
+	l.locks.Put(key, memoryLock{token: token, expires: now.Add(ttl)})
+	return token, nil
+}
+
+// Refresh extends the lock of token on key for ttl
+func (l *MemoryLocker) Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := clockOr(l.Clock).Now()
+	m, ok := l.locks.Peek(key)
+	if !ok || m.token != token || !now.Before(m.expires) {
+		return false, nil
+	}
+	l.locks.Put(key, memoryLock{token: token, expires: now.Add(ttl)})
+	return true, nil
+}
+
+// Unlock releases the lock of token on key
+func (l *MemoryLocker) Unlock(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m, ok := l.locks.Peek(key); ok && m.token == token {
+		l.locks.Remove(key)
+	}
+	return nil
+}
+
+// Once returns a Sink sending each delivery to s on a single replica, for
+// the sinks with side effects such as AutoRestart: the replica locking the
+// build and state of a payload under name with l first sends it, the others
+// drop it. The lock is held for DefaultOnceTTL, so that the deliveries sent
+// again are dropped too, unless s fails, for a redelivery to retry. Payloads
+// are dropped with the error of l if it fails.
+func Once(l Locker, name string, s Sink) Sink {
+	return &onceSink{l, name, s}
+}
+
+type onceSink struct {
+	l    Locker
+	name string
+	s    Sink
+}
+
+func (o *onceSink) Send(p *Payload) error {
+	return o.SendContext(context.Background(), p)
+}
+
+func (o *onceSink) SendContext(ctx context.Context, p *Payload) error {
+	key := "travis:once:" + o.name + ":" + p.SourceID() + ":" + p.State + ":" + p.StatusMessage
+	token, err := o.l.Lock(ctx, key, DefaultOnceTTL)
+	if err != nil || token == "" {
+		return err
+	}
+	if err := SendContext(ctx, o.s, p); err != nil {
+		return errors.Join(err, o.l.Unlock(context.WithoutCancel(ctx), key, token))
+	}
+	return nil
+}
+
+// Leader elects one replica of a receiver to run the periodic reactors,
+// such as Escalation.Run or a policy Engine, with a lock of Locker it holds
+// while leading and refreshes every third of TTL
+type Leader struct {
+	Locker Locker
+	// Key is the lock of the leadership, "travis:leader" if empty
+	Key string
+	// TTL is the lease of the lock, DefaultLeaderTTL if zero: another
+	// replica takes over within TTL of the leader going away
+	TTL time.Duration
+
+	// OnChange, if set, is called when the replica becomes or stops being
+	// the leader
+	OnChange func(leader bool)
+
+	// Clock times the refreshes, SystemClock if nil
+	Clock Clock
+
+	leader atomic.Bool
+}
+
+// IsLeader returns true while the replica is the leader
+func (l *Leader) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// Run runs f while the replica is the leader, with a context canceled once
+// it stops being the leader, until ctx is done. It tries to become the
+// leader every third of TTL and runs f again each time it does.
+func (l *Leader) Run(ctx context.Context, f func(ctx context.Context)) {
+	key := l.Key
+	if key == "" {
+		key = "travis:leader"
+	}
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = DefaultLeaderTTL
+	}
+	ticker := clockOr(l.Clock).NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	var token string
+	// stop cancels the context of f and waits for it to return
+	var stop func()
+	step := func(leader bool) {
+		l.leader.Store(leader)
+		if l.OnChange != nil {
+			l.OnChange(leader)
+		}
+	}
+	stepDown := func() {
+		stop()
+		l.Locker.Unlock(context.WithoutCancel(ctx), key, token)
+		token = ""
+		step(false)
+	}
+	for {
+		if token == "" {
+			if token, _ = l.Locker.Lock(ctx, key, ttl); token != "" {
+				step(true)
+				fctx, cancel := context.WithCancel(ctx)
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					f(fctx)
+				}()
+				stop = func() {
+					cancel()
+					<-done
+				}
+			}
+		} else if ok, err := l.Locker.Refresh(ctx, key, token, ttl); !ok || err != nil {
+			stepDown()
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			if token != "" {
+				stepDown()
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package travis
+
+import "github.com/jacksgt/travis/internal/lrumap"
+
+// DefaultMaxEntries is the number of repositories and branches the in-memory
+// sinks such as Stats keep if MaxEntries isn't set
+const DefaultMaxEntries = 10000
+
+// MemoryStats describes the in-memory state of a sink
+type MemoryStats struct {
+	// Entries is the number of repositories, branches or jobs tracked
+	Entries int
+	// Bytes is an estimate of the memory they take
+	Bytes int64
+	// Evictions is the number of entries evicted, least recently updated
+	// first, to stay under MaxEntries and MaxBytes
+	Evictions int64
+}
+
+// lru is an lrumap.Map defaulting to DefaultMaxEntries and reporting
+// MemoryStats. It isn't safe for concurrent use.
+type lru[K comparable, V any] struct {
+	lrumap.Map[K, V]
+}
+
+// limit sets the bounds of c, DefaultMaxEntries if maxEntries is zero and
+// no byte limit if maxBytes is zero, and evicts the entries over them
+func (c *lru[K, V]) limit(maxEntries int, maxBytes int64) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	c.Limit(maxEntries, maxBytes)
+}
+
+func (c *lru[K, V]) stats() MemoryStats {
+	return MemoryStats{Entries: c.Len(), Bytes: c.Bytes(), Evictions: c.Evictions()}
+}
@@ -0,0 +1,173 @@
+package travis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a period of known outage of the builds of
+// repositories and branches, e.g. while a dependency is upgraded
+type MaintenanceWindow struct {
+	// ID identifies the window, it is set by Maintenance.Add if empty
+	ID string `json:"id"`
+	// Repo and Branch are path.Match patterns of the builds covered, e.g.
+	// acme/*, all if empty
+	Repo   string    `json:"repo,omitempty"`
+	Branch string    `json:"branch,omitempty"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Covers returns true if the window covers the build of p at t
+func (w *MaintenanceWindow) Covers(p *Payload, t time.Time) bool {
+	if t.Before(w.Start) || !t.Before(w.End) {
+		return false
+	}
+	return (w.Repo == "" || MatchAny([]string{w.Repo}, p.Slug())) &&
+		(w.Branch == "" || MatchAny([]string{w.Branch}, p.Branch))
+}
+
+// Maintenance is a set of maintenance windows. A Dispatcher with
+// Maintenance only sends the payloads covered by a window to the sinks
+// wrapped by Record, such as stores, suppressing the alerts and automatic
+// reactions of the other sinks. Windows are added with Add, e.g. from a
+// change calendar, or through Handler.
+type Maintenance struct {
+	// Clock tells whether a window is active, SystemClock if nil
+	Clock Clock
+
+	mu      sync.Mutex
+	windows []MaintenanceWindow
+	lastID  int
+}
+
+// Add adds w and returns its ID. Windows that ended are removed.
+func (m *Maintenance) Add(w MaintenanceWindow) (string, error) {
+	if !w.End.After(w.Start) {
+		return "", errors.New("travis: maintenance window ends before it starts")
+	}
+	for _, p := range []string{w.Repo, w.Branch} {
+		if _, err := path.Match(p, ""); err != nil {
+			return "", err
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	if w.ID == "" {
+		m.lastID++
+		w.ID = strconv.Itoa(m.lastID)
+	}
+	for i := range m.windows {
+		if m.windows[i].ID == w.ID {
+			m.windows[i] = w
+			return w.ID, nil
+		}
+	}
+	m.windows = append(m.windows, w)
+	return w.ID, nil
+}
+
+// Remove removes the window with id and returns true if it existed
+func (m *Maintenance) Remove(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.windows {
+		if m.windows[i].ID == id {
+			m.windows = append(m.windows[:i], m.windows[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Windows returns the windows that haven't ended
+func (m *Maintenance) Windows() []MaintenanceWindow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	return append([]MaintenanceWindow(nil), m.windows...)
+}
+
+// Active returns the window covering p now, if any
+func (m *Maintenance) Active(p *Payload) (MaintenanceWindow, bool) {
+	now := clockOr(m.Clock).Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, w := range m.windows {
+		if w.Covers(p, now) {
+			return w, true
+		}
+	}
+	return MaintenanceWindow{}, false
+}
+
+// expire removes the windows that ended
+func (m *Maintenance) expire() {
+	now := clockOr(m.Clock).Now()
+	windows := m.windows[:0]
+	for _, w := range m.windows {
+		if now.Before(w.End) {
+			windows = append(windows, w)
+		}
+	}
+	m.windows = windows
+}
+
+// Handler returns an http.Handler managing the windows as JSON: GET lists
+// them, POST adds the window of the body and responds with it, and DELETE
+// removes the window of the id query parameter
+func (m *Maintenance) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(m.Windows())
+		case http.MethodPost:
+			var mw MaintenanceWindow
+			if err := json.NewDecoder(r.Body).Decode(&mw); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			id, err := m.Add(mw)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			mw.ID = id
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(mw)
+		case http.MethodDelete:
+			if !m.Remove(r.URL.Query().Get("id")) {
+				http.NotFound(w, r)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", "GET, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// Record marks s as recording builds: a Dispatcher keeps sending it the
+// payloads covered by a maintenance window
+func Record(s Sink) Sink {
+	return recorder{s}
+}
+
+type recorder struct {
+	Sink
+}
+
+func (r recorder) SendContext(ctx context.Context, p *Payload) error {
+	return SendContext(ctx, r.Sink, p)
+}
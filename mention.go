@@ -0,0 +1,78 @@
+package travis
+
+import (
+	"context"
+	"strings"
+)
+
+// IdentityResolver maps the email of a commit author to a chat user ID
+// (Slack, Discord, Teams...). It returns "" if the author is unknown.
+type IdentityResolver interface {
+	Resolve(ctx context.Context, email string) (string, error)
+}
+
+// StaticIdentities is an IdentityResolver backed by a map of emails to user IDs,
+// emails are matched case-insensitively
+type StaticIdentities map[string]string
+
+// Resolve returns the user ID of email
+func (s StaticIdentities) Resolve(ctx context.Context, email string) (string, error) {
+	if id, ok := s[email]; ok {
+		return id, nil
+	}
+	for e, id := range s {
+		if strings.EqualFold(e, email) {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// SlackMention formats a mention of a Slack user ID
+func SlackMention(id string) string {
+	return "<@" + id + ">"
+}
+
+// DiscordMention formats a mention of a Discord user ID
+func DiscordMention(id string) string {
+	return "<@" + id + ">"
+}
+
+// TeamsMention formats a mention of a Microsoft Teams user
+func TeamsMention(id string) string {
+	return "<at>" + id + "</at>"
+}
+
+// Mention is a Notifier that mentions the commit author in messages about
+// broken builds. Messages are sent without a mention if the author can't be
+// resolved.
+type Mention struct {
+	Notifier Notifier
+	Resolver IdentityResolver
+	// Format renders the mention of a user ID, e.g. SlackMention, "@"+id
+	// if nil
+	Format func(id string) string
+}
+
+// Notify sends m, prefixed by a mention of the author if the build is broken
+func (n *Mention) Notify(m *Message) error {
+	return n.NotifyContext(context.Background(), m)
+}
+
+// NotifyContext is Notify sending with ctx
+func (n *Mention) NotifyContext(ctx context.Context, m *Message) error {
+	if m.Payload == nil || !m.Payload.Broken() {
+		return NotifyContext(ctx, n.Notifier, m)
+	}
+	id, err := n.Resolver.Resolve(ctx, m.Payload.AuthorEmail)
+	if err != nil || id == "" {
+		return NotifyContext(ctx, n.Notifier, m)
+	}
+	mention := "@" + id
+	if n.Format != nil {
+		mention = n.Format(id)
+	}
+	mentioned := *m
+	mentioned.Text = mention + " " + m.Text
+	return NotifyContext(ctx, n.Notifier, &mentioned)
+}
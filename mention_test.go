@@ -0,0 +1,43 @@
+package travis_test
+
+import (
+	"testing"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func TestMention(t *testing.T) {
+	broken := travistest.NewPayload().State(travistest.Broken).Author("Jane", "jane@example.com").Build()
+	tests := []struct {
+		name   string
+		p      *travis.Payload
+		format func(id string) string
+		want   string
+	}{
+		{"slack", broken, travis.SlackMention, "<@U1> build broken"},
+		{"teams", broken, travis.TeamsMention, "<at>U1</at> build broken"},
+		{"default format", broken, nil, "@U1 build broken"},
+		{"passed", travistest.NewPayload().Author("Jane", "jane@example.com").Build(), nil, "build broken"},
+		{"unknown author", travistest.NewPayload().State(travistest.Broken).Build(), nil, "build broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			n := &travis.Mention{
+				Notifier: travis.NotifierFunc(func(m *travis.Message) error {
+					got = m.Text
+					return nil
+				}),
+				Resolver: travis.StaticIdentities{"Jane@example.com": "U1"},
+				Format:   tt.format,
+			}
+			if err := n.Notify(&travis.Message{Payload: tt.p, Text: "build broken"}); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package metrics exposes Prometheus metrics about the travis webhook Handler
+// and the requests it makes to the Travis API.
+//
+//	m := metrics.New()
+//	prometheus.MustRegister(m)
+//	h := &travis.Handler{
+//		OnDelivery: m.ObserveDelivery,
+//		Client:     &http.Client{Transport: m.RoundTripper(nil)},
+//	}
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is a prometheus.Collector of webhook and API client metrics
+type Metrics struct {
+	received        *prometheus.CounterVec
+	verified        *prometheus.CounterVec
+	rejected        *prometheus.CounterVec
+	skipped         prometheus.Counter
+	latency         *prometheus.HistogramVec
+	buildDuration   *prometheus.HistogramVec
+	deliveryLag     *prometheus.HistogramVec
+	apiRequests     *prometheus.CounterVec
+	apiRequestTimes *prometheus.HistogramVec
+}
+
+// New returns metrics named travis_*, they still have to be registered
+func New() *Metrics {
+	labels := []string{"repo", "type", "state"}
+	return &Metrics{
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "travis_webhooks_received_total",
+			Help: "Webhook requests received.",
+		}, labels),
+		verified: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "travis_webhooks_verified_total",
+			Help: "Webhook requests with a valid signature.",
+		}, labels),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "travis_webhooks_rejected_total",
+			Help: "Webhook requests that failed verification, by reason.",
+		}, []string{"reason"}),
+		skipped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "travis_webhooks_skipped_total",
+			Help: "Webhook requests skipped by Handler.Peek without being decoded.",
+		}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "travis_webhook_handler_duration_seconds",
+			Help:    "Time spent handling webhook requests.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"disposition"}),
+		buildDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "travis_build_duration_seconds",
+			Help:    "Duration of finished builds as reported in payloads.",
+			Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+		}, []string{"repo", "state"}),
+		deliveryLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "travis_webhook_delivery_lag_seconds",
+			Help:    "Time between the end (or start) of builds and the receipt of their webhook.",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12),
+		}, []string{"state"}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "travis_api_requests_total",
+			Help: "Requests made to the Travis API.",
+		}, []string{"host", "code"}),
+		apiRequestTimes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "travis_api_request_duration_seconds",
+			Help:    "Duration of requests made to the Travis API.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"host"}),
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.received, m.verified, m.rejected, m.skipped, m.latency,
+		m.buildDuration, m.deliveryLag, m.apiRequests, m.apiRequestTimes,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// ObserveDelivery records d, it is meant to be used as Handler.OnDelivery
+func (m *Metrics) ObserveDelivery(d *travis.Delivery) {
+	m.latency.WithLabelValues(d.Disposition).Observe(d.Duration.Seconds())
+
+	if d.Disposition == travis.DispositionSkipped {
+		// skipped deliveries are neither verified nor decoded
+		m.skipped.Inc()
+		return
+	}
+	if d.Payload == nil {
+		m.received.WithLabelValues("", "", "").Inc()
+		reason := "invalid"
+		if d.Err == travis.ErrUnauthorized {
+			reason = "signature"
+		}
+		m.rejected.WithLabelValues(reason).Inc()
+		return
+	}
+
+	p := d.Payload
+	repo, state := p.Slug(), travis.Outcome(p)
+	m.received.WithLabelValues(repo, p.Type, state).Inc()
+	m.verified.WithLabelValues(repo, p.Type, state).Inc()
+	if d.Lag > 0 {
+		m.deliveryLag.WithLabelValues(state).Observe(d.Lag.Seconds())
+	}
+	if p.Duration > 0 && !p.FinishedAt.IsZero() {
+		m.buildDuration.WithLabelValues(repo, state).Observe(float64(p.Duration))
+	}
+}
+
+// RoundTripper wraps next, http.DefaultTransport if nil, to record the
+// requests made through it
+func (m *Metrics) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(r)
+		m.apiRequestTimes.WithLabelValues(r.URL.Host).Observe(time.Since(start).Seconds())
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		m.apiRequests.WithLabelValues(r.URL.Host, code).Inc()
+		return resp, err
+	})
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
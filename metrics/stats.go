@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"github.com/jacksgt/travis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	successRateDesc = prometheus.NewDesc("travis_branch_success_rate",
+		"Ratio of passed builds over the stats window.", []string{"repo", "branch"}, nil)
+	durationDesc = prometheus.NewDesc("travis_branch_build_duration_seconds",
+		"Build duration statistics over the stats window.", []string{"repo", "branch", "stat"}, nil)
+	streakDesc = prometheus.NewDesc("travis_branch_failure_streak",
+		"Failed or errored builds since the last passed one.", []string{"repo", "branch"}, nil)
+)
+
+// StatsCollector is a prometheus.Collector exporting the statistics of
+// every branch tracked by a travis.Stats
+type StatsCollector struct {
+	Stats *travis.Stats
+}
+
+// Describe implements prometheus.Collector
+func (c *StatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- successRateDesc
+	ch <- durationDesc
+	ch <- streakDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *StatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.Stats.All() {
+		if s.Branch == "" {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(successRateDesc, prometheus.GaugeValue, s.SuccessRate, s.Repo, s.Branch)
+		ch <- prometheus.MustNewConstMetric(streakDesc, prometheus.GaugeValue, float64(s.FailureStreak), s.Repo, s.Branch)
+		ch <- prometheus.MustNewConstMetric(durationDesc, prometheus.GaugeValue, s.MeanDuration.Seconds(), s.Repo, s.Branch, "mean")
+		ch <- prometheus.MustNewConstMetric(durationDesc, prometheus.GaugeValue, s.MedianDuration.Seconds(), s.Repo, s.Branch, "median")
+		ch <- prometheus.MustNewConstMetric(durationDesc, prometheus.GaugeValue, s.P95Duration.Seconds(), s.Repo, s.Branch, "p95")
+	}
+}
+
+var (
+	entriesDesc = prometheus.NewDesc("travis_sink_memory_entries",
+		"Repositories, branches or jobs tracked in memory by a sink.", []string{"sink"}, nil)
+	bytesDesc = prometheus.NewDesc("travis_sink_memory_bytes",
+		"Estimated memory taken by the state of a sink.", []string{"sink"}, nil)
+	evictionsDesc = prometheus.NewDesc("travis_sink_memory_evictions_total",
+		"Entries evicted by a sink to stay within its bounds.", []string{"sink"}, nil)
+)
+
+// MemoryReporter is a sink with bounded in-memory state, such as
+// travis.Stats, travis.FlakyDetector and travis.DurationMonitor
+type MemoryReporter interface {
+	Memory() travis.MemoryStats
+}
+
+// MemoryCollector is a prometheus.Collector exporting the entries, size and
+// evictions of sinks, labeled by the keys of Sinks
+type MemoryCollector struct {
+	Sinks map[string]MemoryReporter
+}
+
+// Describe implements prometheus.Collector
+func (c *MemoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- entriesDesc
+	ch <- bytesDesc
+	ch <- evictionsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *MemoryCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, s := range c.Sinks {
+		m := s.Memory()
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(m.Entries), name)
+		ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(m.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(evictionsDesc, prometheus.CounterValue, float64(m.Evictions), name)
+	}
+}
@@ -0,0 +1,76 @@
+// Package natssink publishes travis payloads to NATS subjects.
+package natssink
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/jacksgt/travis"
+	"github.com/nats-io/nats.go"
+)
+
+// Publisher is the part of *nats.Conn used by Sink
+type Publisher interface {
+	PublishMsg(m *nats.Msg) error
+}
+
+// Sink is a travis.Sink publishing payloads as JSON to a subject per repo
+// and state: <Prefix>.<owner>.<name>.<state>, e.g. travis.acme.api.failed.
+// Subscribers can use wildcards such as travis.acme.*.failed.
+type Sink struct {
+	Conn Publisher
+	// Prefix of the subjects, "travis" if empty
+	Prefix string
+}
+
+// Send publishes p
+func (s *Sink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx. Publishing doesn't wait for the server,
+// the message isn't published once ctx is done.
+func (s *Sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(s.Subject(p))
+	msg.Data = body
+	msg.Header.Set("Travis-Repo", p.Slug())
+	msg.Header.Set("Travis-Branch", p.Branch)
+	msg.Header.Set("Travis-State", travis.Outcome(p))
+	return s.Conn.PublishMsg(msg)
+}
+
+// Subject returns the subject p is published on
+func (s *Sink) Subject(p *travis.Payload) string {
+	prefix := s.Prefix
+	if prefix == "" {
+		prefix = "travis"
+	}
+	owner, name := "unknown", "unknown"
+	if p.Repository != nil {
+		owner, name = token(p.Repository.OwnerName), token(p.Repository.Name)
+	}
+	return strings.Join([]string{prefix, owner, name, travis.Outcome(p)}, ".")
+}
+
+// token makes s usable as a single subject token, which can't contain
+// separators, wildcards or whitespace
+func token(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
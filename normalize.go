@@ -0,0 +1,130 @@
+package travis
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Sources of a payload as returned by Payload.Source
+const (
+	// SourceOrg payloads come from travis-ci.org, shut down in 2021
+	SourceOrg = "travis-ci.org"
+	// SourceCom payloads come from travis-ci.com
+	SourceCom = "travis-ci.com"
+	// SourceEnterprise payloads come from a Travis CI Enterprise install
+	SourceEnterprise = "enterprise"
+)
+
+// Source returns where p comes from, from the host of its build URL:
+// SourceOrg, SourceCom, SourceEnterprise for any other host or "" without
+// a build URL. The IDs of builds of different sources may collide.
+func (p *Payload) Source() string {
+	u, err := url.Parse(p.BuildURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "travis-ci.org" || strings.HasSuffix(host, ".travis-ci.org"):
+		return SourceOrg
+	case host == "travis-ci.com" || strings.HasSuffix(host, ".travis-ci.com"):
+		return SourceCom
+	}
+	return SourceEnterprise
+}
+
+// SourceID returns the ID of the build of p unique across sources, e.g.
+// "travis-ci.com/112233445", the host of the build URL for Enterprise
+func (p *Payload) SourceID() string {
+	source := p.Source()
+	if source == SourceEnterprise {
+		u, _ := url.Parse(p.BuildURL)
+		source = strings.ToLower(u.Host)
+	}
+	return source + "/" + strconv.FormatInt(p.ID, 10)
+}
+
+// statusMessages are the status messages by their lowercase spellings,
+// including the ones of older Enterprise versions
+var statusMessages = map[string]string{
+	"pending":       "Pending",
+	"passed":        "Passed",
+	"fixed":         "Fixed",
+	"broken":        "Broken",
+	"failed":        "Failed",
+	"still failing": "Still Failing",
+	"canceled":      "Canceled",
+	"cancelled":     "Canceled",
+	"errored":       "Errored",
+	"error":         "Errored",
+}
+
+// Normalize rewrites the fields of p that differ between sources, so the
+// rest of the package and its users see the same payload from travis-ci.org,
+// travis-ci.com and Enterprise. Payloads are normalized when decoded by a
+// Handler or GetPayloadFromRequest.
+//
+//   - StatusMessage and ResultMessage are spelled as on travis-ci.com, e.g.
+//     "Still Failing" for the "Still failing" of Enterprise 2 or "Canceled"
+//     for "Cancelled", and one is set from the other if it is missing
+//   - State and the states of jobs are lowercase, "canceled" for
+//     "cancelled"
+//   - Type is "pull_request" for the payloads of pull requests without
+//     one, as sent by Enterprise 2
+//   - PullRequestNumber is parsed from the compare URL of pull requests if
+//     missing, as on travis-ci.org
+//   - BuildURL, CompareURL and Repository.URL have no trailing slash
+//   - Repository.Name is the name only, Enterprise sending the slug
+//     of some repositories
+func (p *Payload) Normalize() {
+	p.StatusMessage = normalizeMessage(p.StatusMessage)
+	p.ResultMessage = normalizeMessage(p.ResultMessage)
+	if p.StatusMessage == "" {
+		p.StatusMessage = p.ResultMessage
+	}
+	if p.ResultMessage == "" {
+		p.ResultMessage = p.StatusMessage
+	}
+
+	p.State = normalizeState(p.State)
+	for _, j := range p.Matrix {
+		j.State = normalizeState(j.State)
+	}
+
+	if p.Type == "" && p.PullRequest != 0 {
+		p.Type = "pull_request"
+	}
+	if p.IsPullRequest() && p.PullRequestNumber == 0 {
+		if i := strings.LastIndex(p.CompareURL, "/pull/"); i >= 0 {
+			p.PullRequestNumber, _ = strconv.Atoi(strings.TrimSuffix(p.CompareURL[i+len("/pull/"):], "/"))
+		}
+	}
+
+	p.BuildURL = strings.TrimSuffix(p.BuildURL, "/")
+	p.CompareURL = strings.TrimSuffix(p.CompareURL, "/")
+	if r := p.Repository; r != nil {
+		r.URL = strings.TrimSuffix(r.URL, "/")
+		if owner, name, ok := strings.Cut(r.Name, "/"); ok {
+			if r.OwnerName == "" {
+				r.OwnerName = owner
+			}
+			r.Name = name
+		}
+	}
+}
+
+func normalizeMessage(s string) string {
+	if m, ok := statusMessages[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return m
+	}
+	return s
+}
+
+func normalizeState(s string) string {
+	s = strings.ToLower(s)
+	if s == "cancelled" {
+		return OutcomeCanceled
+	}
+	return s
+}
@@ -0,0 +1,40 @@
+package travis
+
+import "context"
+
+// Message is a notification about one or more builds
+type Message struct {
+	Title string
+	Text  string
+	// Payload is the build the message is about, it is nil for messages
+	// that summarize several builds
+	Payload *Payload
+}
+
+// Notifier sends messages to a chat, email or similar destination
+type Notifier interface {
+	Notify(m *Message) error
+}
+
+// ContextNotifier is a Notifier whose sends can be canceled
+type ContextNotifier interface {
+	Notifier
+	NotifyContext(ctx context.Context, m *Message) error
+}
+
+// NotifyContext sends m to n with ctx if n is a ContextNotifier, else with
+// Notify
+func NotifyContext(ctx context.Context, n Notifier, m *Message) error {
+	if cn, ok := n.(ContextNotifier); ok {
+		return cn.NotifyContext(ctx, m)
+	}
+	return n.Notify(m)
+}
+
+// NotifierFunc is an adapter to allow the use of ordinary functions as a Notifier
+type NotifierFunc func(m *Message) error
+
+// Notify calls f(m)
+func (f NotifierFunc) Notify(m *Message) error {
+	return f(m)
+}
@@ -0,0 +1,298 @@
+package travis
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Options configure what NewHandler, NewDispatcher, NewClient or NewDigest
+// build. They are the setters of the exported fields, which can still be
+// set directly: they keep the calls stable as settings are added. Each
+// constructor takes its own option type, so an option passed to a
+// constructor it doesn't apply to fails to compile.
+
+// HandlerOption configures NewHandler
+type HandlerOption interface {
+	applyHandler(h *Handler)
+}
+
+// DispatcherOption configures NewDispatcher, or the Dispatcher of
+// NewHandler
+type DispatcherOption interface {
+	HandlerOption
+	applyDispatcher(d *Dispatcher)
+}
+
+// ClientOption configures NewClient
+type ClientOption interface {
+	applyClient(c *Client)
+}
+
+// DigestOption configures NewDigest
+type DigestOption interface {
+	applyDigest(o *digestOptions)
+}
+
+// HandlerClientOption configures NewHandler and NewClient
+type HandlerClientOption interface {
+	HandlerOption
+	ClientOption
+}
+
+// DispatcherClientOption configures NewDispatcher, NewHandler and NewClient
+type DispatcherClientOption interface {
+	DispatcherOption
+	ClientOption
+}
+
+// HandlerDigestOption configures NewHandler and NewDigest
+type HandlerDigestOption interface {
+	HandlerOption
+	DigestOption
+}
+
+// option sets what it has a function for, a Dispatcher option without
+// handler function sets the Dispatcher of a Handler
+type option struct {
+	handler    func(h *Handler)
+	dispatcher func(d *Dispatcher)
+	client     func(c *Client)
+	digest     func(o *digestOptions)
+}
+
+func (o option) applyHandler(h *Handler) {
+	if o.handler != nil {
+		o.handler(h)
+	} else if o.dispatcher != nil {
+		o.dispatcher(dispatcherOf(h))
+	}
+}
+
+func (o option) applyDispatcher(d *Dispatcher) {
+	if o.dispatcher != nil {
+		o.dispatcher(d)
+	}
+}
+
+func (o option) applyClient(c *Client) {
+	if o.client != nil {
+		o.client(c)
+	}
+}
+
+func (o option) applyDigest(d *digestOptions) {
+	if o.digest != nil {
+		o.digest(d)
+	}
+}
+
+// NewHandler returns a Handler configured by opts. The options of a
+// Dispatcher, such as WithWorkers, give the handler its own Dispatcher,
+// sending to the sinks of WithSinks.
+func NewHandler(opts ...HandlerOption) *Handler {
+	h := new(Handler)
+	for _, o := range opts {
+		o.applyHandler(h)
+	}
+	if d := h.Dispatcher; d != nil {
+		if d.Sinks == nil {
+			d.Sinks, h.Sinks = h.Sinks, nil
+		}
+		if d.Logger == nil {
+			d.Logger = h.Logger
+		}
+		if d.Tracer == nil {
+			d.Tracer = h.Tracer
+		}
+	}
+	return h
+}
+
+// NewDispatcher returns a Dispatcher configured by opts
+func NewDispatcher(opts ...DispatcherOption) *Dispatcher {
+	d := new(Dispatcher)
+	for _, o := range opts {
+		o.applyDispatcher(d)
+	}
+	return d
+}
+
+// NewClient returns a Client configured by opts
+func NewClient(opts ...ClientOption) *Client {
+	c := new(Client)
+	for _, o := range opts {
+		o.applyClient(c)
+	}
+	return c
+}
+
+// digestOptions are the settings of NewDigest
+type digestOptions struct {
+	clock   Clock
+	onError func(err error)
+}
+
+// dispatcherOf returns the Dispatcher of h, creating it if needed
+func dispatcherOf(h *Handler) *Dispatcher {
+	if h.Dispatcher == nil {
+		h.Dispatcher = new(Dispatcher)
+	}
+	return h.Dispatcher
+}
+
+// WithSinks adds sinks to a Handler or a Dispatcher
+func WithSinks(sinks ...Sink) DispatcherOption {
+	return option{
+		handler:    func(h *Handler) { h.Sinks = append(h.Sinks, sinks...) },
+		dispatcher: func(d *Dispatcher) { d.Sinks = append(d.Sinks, sinks...) },
+	}
+}
+
+// WithDispatcher sets the Dispatcher of a Handler
+func WithDispatcher(d *Dispatcher) HandlerOption {
+	return option{handler: func(h *Handler) { h.Dispatcher = d }}
+}
+
+// WithWorkers sets the number of workers of a Dispatcher
+func WithWorkers(n int) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Workers = n }}
+}
+
+// WithQueueSize sets the capacity of the queue of a Dispatcher
+func WithQueueSize(n int) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.QueueSize = n }}
+}
+
+// WithFailFast makes a Dispatcher return ErrQueueFull rather than block
+// while its queue is full, and a Handler shed the deliveries with 503
+// and a Retry-After of retryAfter, DefaultRetryAfter if zero
+func WithFailFast(retryAfter time.Duration) DispatcherOption {
+	return option{
+		handler: func(h *Handler) {
+			dispatcherOf(h).FailFast = true
+			h.RetryAfter = retryAfter
+		},
+		dispatcher: func(d *Dispatcher) { d.FailFast = true },
+	}
+}
+
+// WithKey serializes the payloads of a Dispatcher by key, sending up to
+// concurrency of them at a time
+func WithKey(key func(p *Payload) string, concurrency int) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Key, d.KeyConcurrency = key, concurrency }}
+}
+
+// WithPriority ranks the queued payloads of a Dispatcher with priority,
+// e.g. BranchPriority
+func WithPriority(priority func(p *Payload) int) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Priority = priority }}
+}
+
+// WithJournal persists the queued payloads of a Dispatcher to j
+func WithJournal(j Journal) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Journal = j }}
+}
+
+// WithMaintenance suppresses the sinks of a Dispatcher not wrapped by
+// Record during the windows of m
+func WithMaintenance(m *Maintenance) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Maintenance = m }}
+}
+
+// WithCommands runs the commands of the directives of commit messages in a
+// Dispatcher
+func WithCommands(c *Commands) DispatcherOption {
+	return option{dispatcher: func(d *Dispatcher) { d.Commands = c }}
+}
+
+// WithHTTPClient sets the HTTP client a Handler fetches the public key
+// with, or a Client calls the API with
+func WithHTTPClient(c *http.Client) HandlerClientOption {
+	return option{
+		handler: func(h *Handler) { h.Client = c },
+		client:  func(cl *Client) { cl.HTTPClient = c },
+	}
+}
+
+// WithConfigURL sets the URL serving the public key of a Handler, e.g.
+// ComConfigURL, and the time it is cached, DefaultKeyTTL if zero
+func WithConfigURL(url string, ttl time.Duration) HandlerOption {
+	return option{handler: func(h *Handler) { h.ConfigURL, h.KeyTTL = url, ttl }}
+}
+
+// WithScheme sets the SignatureScheme verifying the webhooks of a Handler
+// instead of the Travis public key
+func WithScheme(s SignatureScheme) HandlerOption {
+	return option{handler: func(h *Handler) { h.Scheme = s }}
+}
+
+// WithCache sets the CacheBackend of a Handler or Client
+func WithCache(c CacheBackend) HandlerClientOption {
+	return option{
+		handler: func(h *Handler) { h.Cache = c },
+		client:  func(cl *Client) { cl.Cache = c },
+	}
+}
+
+// WithDedupe makes a Handler drop the deliveries of payloads handled in the
+// last ttl, DefaultDedupeTTL if zero
+func WithDedupe(ttl time.Duration) HandlerOption {
+	return option{handler: func(h *Handler) { h.Dedupe, h.DedupeTTL = true, ttl }}
+}
+
+// WithPeek sets the filter of a Handler run before decoding payloads
+func WithPeek(peek func(pk Peek) bool) HandlerOption {
+	return option{handler: func(h *Handler) { h.Peek = peek }}
+}
+
+// WithPoolPayloads makes a Handler decode payloads into pooled ones
+func WithPoolPayloads() HandlerOption {
+	return option{handler: func(h *Handler) { h.PoolPayloads = true }}
+}
+
+// WithOnDelivery sets the function a Handler calls with every delivery
+func WithOnDelivery(f func(d *Delivery)) HandlerOption {
+	return option{handler: func(h *Handler) { h.OnDelivery = f }}
+}
+
+// WithBaseURL sets the API URL of a Client, e.g. of an enterprise install
+func WithBaseURL(url string) ClientOption {
+	return option{client: func(c *Client) { c.BaseURL = url }}
+}
+
+// WithToken sets the API token of a Client
+func WithToken(token string) ClientOption {
+	return option{client: func(c *Client) { c.Token = token }}
+}
+
+// WithLogger sets the logger of a Handler, Dispatcher or Client
+func WithLogger(l *slog.Logger) DispatcherClientOption {
+	return option{
+		handler:    func(h *Handler) { h.Logger = l },
+		dispatcher: func(d *Dispatcher) { d.Logger = l },
+		client:     func(c *Client) { c.Logger = l },
+	}
+}
+
+// WithTracer sets the tracer of a Handler or Dispatcher
+func WithTracer(t Tracer) DispatcherOption {
+	return option{
+		handler:    func(h *Handler) { h.Tracer = t },
+		dispatcher: func(d *Dispatcher) { d.Tracer = t },
+	}
+}
+
+// WithClock sets the clock of a Handler or Digest
+func WithClock(c Clock) HandlerDigestOption {
+	return option{
+		handler: func(h *Handler) { h.Clock = c },
+		digest:  func(o *digestOptions) { o.clock = c },
+	}
+}
+
+// WithOnError sets the function a Digest calls with the errors of the
+// digests it sends periodically
+func WithOnError(f func(err error)) DigestOption {
+	return option{digest: func(o *digestOptions) { o.onError = f }}
+}
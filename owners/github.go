@@ -0,0 +1,104 @@
+package owners
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jacksgt/travis"
+)
+
+// DefaultGitHubAPI is the API GitHubFiles calls if BaseURL isn't set
+const DefaultGitHubAPI = "https://api.github.com"
+
+// GitHubFiles lists the files changed by builds of GitHub repositories
+// from the compare URL of their payload: the range of a push, the commit
+// of a push of a single commit or the pull request. Its Files method is a
+// ChangedFiles. Only the first 100 files of a pull request are listed.
+type GitHubFiles struct {
+	// BaseURL of the API, DefaultGitHubAPI if empty, e.g. the /api/v3 URL
+	// of a GitHub Enterprise server
+	BaseURL string
+	// Token authenticates the requests, it is required for private
+	// repositories
+	Token string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Files returns the files changed by p
+func (g *GitHubFiles) Files(ctx context.Context, p *travis.Payload) ([]string, error) {
+	u, err := url.Parse(p.CompareURL)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 4)
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("owners: unsupported compare URL %s", p.CompareURL)
+	}
+	repo := parts[0] + "/" + parts[1]
+
+	var files []githubFile
+	switch parts[2] {
+	case "compare", "commit":
+		var res struct {
+			Files []githubFile `json:"files"`
+		}
+		path := "/repos/" + repo + "/compare/" + parts[3]
+		if parts[2] == "commit" {
+			path = "/repos/" + repo + "/commits/" + parts[3]
+		}
+		err = g.get(ctx, path, &res)
+		files = res.Files
+	case "pull":
+		err = g.get(ctx, "/repos/"+repo+"/pulls/"+parts[3]+"/files?per_page=100", &files)
+	default:
+		return nil, fmt.Errorf("owners: unsupported compare URL %s", p.CompareURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Filename
+	}
+	return names, nil
+}
+
+type githubFile struct {
+	Filename string `json:"filename"`
+}
+
+func (g *GitHubFiles) get(ctx context.Context, path string, out any) error {
+	base := g.BaseURL
+	if base == "" {
+		base = DefaultGitHubAPI
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(base, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("owners: github: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,216 @@
+// Package owners maps builds to the teams owning them with a
+// CODEOWNERS-like file, so notifications go to the channel of the right
+// team across many repositories without a route per repository.
+//
+// Each line of the file is a pattern followed by the teams owning what it
+// matches. The pattern is a path.Match pattern of repository slugs,
+// optionally followed by a colon and a pattern of the files of the
+// repository; a pattern of files ending with a slash matches the files of
+// the directory and its subdirectories. As in CODEOWNERS, the last matching
+// line wins. Blank lines and lines starting with # are ignored.
+//
+//	# repositories
+//	acme/*              @platform
+//	acme/api            @backend
+//	# files of a repository
+//	acme/api:docs/      @docs
+//	acme/*:*.tf         @infra
+package owners
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jacksgt/travis"
+)
+
+// Rule is a line of an ownership file
+type Rule struct {
+	// Repo is a path.Match pattern of repository slugs
+	Repo string
+	// Path, if set, is a pattern of the files of the repositories
+	Path  string
+	Teams []string
+}
+
+// File is an ownership file
+type File struct {
+	Rules []Rule
+}
+
+// Parse reads an ownership file from r
+func Parse(r io.Reader) (*File, error) {
+	f := new(File)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("owners: line %d: no team for %s", n, fields[0])
+		}
+		rule := Rule{Repo: fields[0], Teams: fields[1:]}
+		if i := strings.Index(rule.Repo, ":"); i >= 0 {
+			rule.Repo, rule.Path = rule.Repo[:i], rule.Repo[i+1:]
+		}
+		for _, p := range []string{rule.Repo, strings.TrimSuffix(rule.Path, "/")} {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("owners: line %d: %w", n, err)
+			}
+		}
+		f.Rules = append(f.Rules, rule)
+	}
+	return f, s.Err()
+}
+
+// Load reads the ownership file name
+func Load(name string) (*File, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
+
+// Repo returns the teams owning the repository slug, nil if none
+func (f *File) Repo(slug string) []string {
+	var teams []string
+	for _, r := range f.Rules {
+		if r.Path == "" && match(r.Repo, slug) {
+			teams = r.Teams
+		}
+	}
+	return teams
+}
+
+// Teams returns the teams owning files of the repository slug, the owners
+// of the repository for the files without owners of their own. It returns
+// the owners of the repository if files is empty.
+func (f *File) Teams(slug string, files []string) []string {
+	repo := f.Repo(slug)
+	if len(files) == 0 {
+		return repo
+	}
+	var teams []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		owners := repo
+		for _, r := range f.Rules {
+			if r.Path != "" && match(r.Repo, slug) && matchFile(r.Path, file) {
+				owners = r.Teams
+			}
+		}
+		for _, t := range owners {
+			if !seen[t] {
+				seen[t] = true
+				teams = append(teams, t)
+			}
+		}
+	}
+	return teams
+}
+
+func match(pattern, v string) bool {
+	ok, _ := path.Match(pattern, v)
+	return ok
+}
+
+// matchFile returns true if pattern matches file: the directory patterns,
+// ending with a slash, match the files under them, the patterns without a
+// slash match the base name of file in any directory, and the others the
+// whole path
+func matchFile(pattern, file string) bool {
+	pattern = strings.TrimPrefix(pattern, "/")
+	switch {
+	case strings.HasSuffix(pattern, "/"):
+		for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+			if match(strings.TrimSuffix(pattern, "/"), dir) {
+				return true
+			}
+		}
+		return false
+	case !strings.Contains(pattern, "/"):
+		return match(pattern, path.Base(file))
+	}
+	return match(pattern, file)
+}
+
+// ChangedFiles returns the files changed by the commits of a build, see
+// GitHubFiles
+type ChangedFiles func(ctx context.Context, p *travis.Payload) ([]string, error)
+
+// Resolver returns the teams owning builds
+type Resolver struct {
+	File *File
+	// Files, if set, lists the files changed by a build, so the owners of
+	// the files are notified rather than of the whole repository
+	Files ChangedFiles
+}
+
+// Teams returns the teams owning p. If the changed files can't be listed
+// the owners of the repository are returned along with the error.
+func (r *Resolver) Teams(ctx context.Context, p *travis.Payload) ([]string, error) {
+	if r.Files == nil {
+		return r.File.Repo(p.Slug()), nil
+	}
+	files, err := r.Files(ctx, p)
+	if err != nil {
+		return r.File.Repo(p.Slug()), err
+	}
+	return r.File.Teams(p.Slug(), files), nil
+}
+
+// Notifier is a travis.Notifier sending messages about a build to the
+// notifiers of the teams owning it, e.g. their Slack channels, and the
+// others to Default
+type Notifier struct {
+	Resolver *Resolver
+	// Teams are the notifiers of the teams, by team as written in the file
+	Teams map[string]travis.Notifier
+	// Default, if set, is notified of the builds without an owner in Teams
+	// and of the messages without a payload
+	Default travis.Notifier
+}
+
+// Notify sends m to the teams owning its build
+func (n *Notifier) Notify(m *travis.Message) error {
+	return n.NotifyContext(context.Background(), m)
+}
+
+// NotifyContext is Notify with ctx
+func (n *Notifier) NotifyContext(ctx context.Context, m *travis.Message) error {
+	var (
+		teams []string
+		errs  []error
+	)
+	if m.Payload != nil {
+		var err error
+		if teams, err = n.Resolver.Teams(ctx, m.Payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	sent := false
+	for _, t := range teams {
+		if tn := n.Teams[t]; tn != nil {
+			sent = true
+			if err := travis.NotifyContext(ctx, tn, m); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if !sent && n.Default != nil {
+		if err := travis.NotifyContext(ctx, n.Default, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,231 @@
+package travis
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// errPeek is returned by PeekPayload for malformed payloads
+var errPeek = errors.New("cannot peek payload")
+
+// Peek is the fields of a payload read by PeekPayload, for filtering
+// payloads before decoding them
+type Peek struct {
+	Type          string
+	State         string
+	StatusMessage string
+	Branch        string
+	// Owner and Name are the owner_name and name of the repository
+	Owner, Name string
+}
+
+// Slug returns the "owner/name" of the repository, as Payload.Slug
+func (pk Peek) Slug() string {
+	if strings.Contains(pk.Name, "/") {
+		return pk.Name
+	}
+	return pk.Owner + "/" + pk.Name
+}
+
+// Outcome returns the Outcome of the payload
+func (pk Peek) Outcome() string {
+	p := Payload{State: pk.State, StatusMessage: pk.StatusMessage}
+	return Outcome(&p)
+}
+
+// PeekPayload reads the type, state, status message, branch and
+// repository of the JSON payload without decoding the rest, for relays
+// filtering many payloads. The strings of the Peek share the memory of
+// payload, so that peeking doesn't allocate unless they contain escapes.
+// The parts of payload it skips aren't validated.
+func PeekPayload(payload string) (Peek, error) {
+	var pk Peek
+	sc := peekScanner{s: payload}
+	for sc.object() {
+		key, err := sc.key()
+		if err != nil {
+			return pk, err
+		}
+		switch key {
+		case "type":
+			pk.Type, err = sc.str()
+		case "state":
+			pk.State, err = sc.str()
+		case "status_message":
+			pk.StatusMessage, err = sc.str()
+		case "branch":
+			pk.Branch, err = sc.str()
+		case "repository":
+			err = sc.repository(&pk)
+		default:
+			err = sc.skip()
+		}
+		if err != nil {
+			return pk, err
+		}
+	}
+	return pk, sc.err
+}
+
+// peekScanner reads the JSON s from i
+type peekScanner struct {
+	s   string
+	i   int
+	err error
+	// open is true once the { of the current object was read
+	open bool
+}
+
+func (sc *peekScanner) ws() {
+	for sc.i < len(sc.s) && (sc.s[sc.i] == ' ' || sc.s[sc.i] == '\t' || sc.s[sc.i] == '\n' || sc.s[sc.i] == '\r') {
+		sc.i++
+	}
+}
+
+// consume reads c, skipping whitespace before it
+func (sc *peekScanner) consume(c byte) bool {
+	sc.ws()
+	if sc.i < len(sc.s) && sc.s[sc.i] == c {
+		sc.i++
+		return true
+	}
+	return false
+}
+
+// object advances to the next key of the object at i and returns true, or
+// reads its end and returns false, setting err if the object is malformed
+func (sc *peekScanner) object() bool {
+	switch {
+	case !sc.open:
+		if !sc.consume('{') {
+			sc.err = errPeek
+			return false
+		}
+		sc.open = true
+		if sc.consume('}') {
+			sc.open = false
+			return false
+		}
+	case sc.consume('}'):
+		sc.open = false
+		return false
+	case !sc.consume(','):
+		sc.err = errPeek
+		return false
+	}
+	return true
+}
+
+// str reads a string value, or null as ""
+func (sc *peekScanner) str() (string, error) {
+	sc.ws()
+	if strings.HasPrefix(sc.s[sc.i:], "null") {
+		sc.i += len("null")
+		return "", nil
+	}
+	start := sc.i
+	escaped, err := sc.skipString()
+	if err != nil {
+		return "", err
+	}
+	if escaped {
+		var s string
+		if err := json.Unmarshal([]byte(sc.s[start:sc.i]), &s); err != nil {
+			return "", errPeek
+		}
+		return s, nil
+	}
+	return sc.s[start+1 : sc.i-1], nil
+}
+
+// key reads the key of an object member and the colon following it
+func (sc *peekScanner) key() (string, error) {
+	key, err := sc.str()
+	if err == nil && !sc.consume(':') {
+		err = errPeek
+	}
+	return key, err
+}
+
+// skipString skips a string, reporting whether it contains escapes
+func (sc *peekScanner) skipString() (escaped bool, err error) {
+	if sc.i >= len(sc.s) || sc.s[sc.i] != '"' {
+		return false, errPeek
+	}
+	for sc.i++; sc.i < len(sc.s) && sc.s[sc.i] != '"'; sc.i++ {
+		if sc.s[sc.i] == '\\' {
+			escaped = true
+			sc.i++
+		}
+	}
+	if sc.i >= len(sc.s) {
+		return false, errPeek
+	}
+	sc.i++
+	return escaped, nil
+}
+
+// skip skips a value
+func (sc *peekScanner) skip() error {
+	sc.ws()
+	depth := 0
+	for sc.i < len(sc.s) {
+		switch sc.s[sc.i] {
+		case '"':
+			if _, err := sc.skipString(); err != nil {
+				return err
+			}
+			if depth == 0 {
+				return nil
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return nil
+			}
+			depth--
+			if depth == 0 {
+				sc.i++
+				return nil
+			}
+		case ',':
+			if depth == 0 {
+				return nil
+			}
+		}
+		sc.i++
+	}
+	return errPeek
+}
+
+// repository reads the owner and name of the repository object
+func (sc *peekScanner) repository(pk *Peek) error {
+	sc.ws()
+	if strings.HasPrefix(sc.s[sc.i:], "null") {
+		sc.i += len("null")
+		return nil
+	}
+	repo := peekScanner{s: sc.s, i: sc.i}
+	for repo.object() {
+		key, err := repo.key()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "owner_name":
+			pk.Owner, err = repo.str()
+		case "name":
+			pk.Name, err = repo.str()
+		default:
+			err = repo.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	sc.i = repo.i
+	return repo.err
+}
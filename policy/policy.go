@@ -0,0 +1,279 @@
+// Package policy evaluates rules about builds on every webhook, such as
+// "main must not stay broken for more than 30 minutes" or "pull requests
+// must not run deploys", and reports their violations.
+//
+//	e := &policy.Engine{
+//		Rules: []policy.Rule{
+//			&policy.MaxBroken{Branches: []string{"main"}, For: 30 * time.Minute},
+//			&policy.NoDeploy{},
+//		},
+//		OnViolation: func(v policy.Violation) {
+//			log.Printf("%s: %s", v.Rule, v.Message)
+//		},
+//	}
+//	go e.Run(ctx)
+//	h := &travis.Handler{Sinks: []travis.Sink{e}}
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/internal/lrumap"
+)
+
+// DefaultInterval is how often an Engine evaluates the rules that depend
+// on time if Interval isn't set
+const DefaultInterval = time.Minute
+
+// Violation is a breach of a rule
+type Violation struct {
+	// Rule is the name of the rule broken
+	Rule   string
+	Repo   string
+	Branch string
+	// Payload is the build breaking the rule, for time based rules the
+	// last build of the branch
+	Payload *travis.Payload
+	Message string
+	// Time is when the rule was broken
+	Time time.Time
+}
+
+// Rule is evaluated on each payload
+type Rule interface {
+	// Name identifies the rule in violations
+	Name() string
+	// Check returns the violations of p, received at now
+	Check(p *travis.Payload, now time.Time) []Violation
+}
+
+// TimedRule is a Rule that can also be broken by time passing, e.g. a
+// branch staying broken
+type TimedRule interface {
+	Rule
+	// Tick returns the violations at now
+	Tick(now time.Time) []Violation
+}
+
+// Engine is a travis.Sink checking every payload against Rules and calling
+// OnViolation with the violations. Run evaluates the TimedRules
+// periodically.
+type Engine struct {
+	Rules       []Rule
+	OnViolation func(v Violation)
+
+	// Interval is how often Run evaluates TimedRules, DefaultInterval if
+	// zero
+	Interval time.Duration
+	// Clock tells the time, travis.SystemClock if nil
+	Clock travis.Clock
+
+	mu sync.Mutex
+}
+
+// Send checks p against every rule
+func (e *Engine) Send(p *travis.Payload) error {
+	now := e.clock().Now()
+	e.mu.Lock()
+	var found []Violation
+	for _, r := range e.Rules {
+		found = append(found, r.Check(p, now)...)
+	}
+	e.mu.Unlock()
+	e.report(found)
+	return nil
+}
+
+// Tick evaluates the TimedRules at now
+func (e *Engine) Tick(now time.Time) {
+	e.mu.Lock()
+	var found []Violation
+	for _, r := range e.Rules {
+		if tr, ok := r.(TimedRule); ok {
+			found = append(found, tr.Tick(now)...)
+		}
+	}
+	e.mu.Unlock()
+	e.report(found)
+}
+
+// Run calls Tick every Interval until ctx is done
+func (e *Engine) Run(ctx context.Context) {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := e.clock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			e.Tick(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Engine) clock() travis.Clock {
+	if e.Clock == nil {
+		return travis.SystemClock
+	}
+	return e.Clock
+}
+
+func (e *Engine) report(found []Violation) {
+	if e.OnViolation == nil {
+		return
+	}
+	for _, v := range found {
+		e.OnViolation(v)
+	}
+}
+
+// Func returns a rule named name calling check on each payload, which
+// returns the message of the violation or "" if p complies
+func Func(name string, check func(p *travis.Payload) string) Rule {
+	return &funcRule{name: name, check: check}
+}
+
+type funcRule struct {
+	name  string
+	check func(p *travis.Payload) string
+}
+
+func (r *funcRule) Name() string { return r.name }
+
+func (r *funcRule) Check(p *travis.Payload, now time.Time) []Violation {
+	msg := r.check(p)
+	if msg == "" {
+		return nil
+	}
+	return []Violation{violation(r.name, p, msg, now)}
+}
+
+func violation(rule string, p *travis.Payload, msg string, now time.Time) Violation {
+	return Violation{Rule: rule, Repo: p.Slug(), Branch: p.Branch, Payload: p, Message: msg, Time: now}
+}
+
+// MaxBroken is broken when a branch stays broken, its push, cron or api
+// builds failing or erroring, for longer than For. It is reported once per
+// breakage, at the first Tick or build after For.
+type MaxBroken struct {
+	// Repos and Branches are path.Match patterns of the branches checked,
+	// all if empty
+	Repos    []string
+	Branches []string
+	For      time.Duration
+
+	// MaxEntries is the number of broken branches tracked,
+	// travis.DefaultMaxEntries if zero. The least recently built are
+	// forgotten first.
+	MaxEntries int
+
+	broken lrumap.Map[string, *breakage]
+}
+
+type breakage struct {
+	since    time.Time
+	last     *travis.Payload
+	reported bool
+}
+
+// Name returns "max-broken"
+func (r *MaxBroken) Name() string { return "max-broken" }
+
+// Check records whether the branch of p is broken
+func (r *MaxBroken) Check(p *travis.Payload, now time.Time) []Violation {
+	if p.IsPullRequest() || !travis.MatchAny(r.Repos, p.Slug()) || !travis.MatchAny(r.Branches, p.Branch) {
+		return nil
+	}
+	key := p.Slug() + "@" + p.Branch
+	switch travis.Outcome(p) {
+	case travis.OutcomePassed:
+		r.broken.Remove(key)
+		return nil
+	case travis.OutcomeFailed, travis.OutcomeErrored:
+	default:
+		return nil
+	}
+	max := r.MaxEntries
+	if max <= 0 {
+		max = travis.DefaultMaxEntries
+	}
+	r.broken.Limit(max, 0)
+	b, ok := r.broken.Get(key)
+	if !ok {
+		since := p.FinishedAt
+		if since.IsZero() || since.After(now) {
+			since = now
+		}
+		b = &breakage{since: since}
+		r.broken.Put(key, b)
+	}
+	b.last = p
+	return r.check(b, now)
+}
+
+// Tick returns the branches broken for longer than For
+func (r *MaxBroken) Tick(now time.Time) []Violation {
+	var found []Violation
+	r.broken.Each(func(_ string, b *breakage) {
+		found = append(found, r.check(b, now)...)
+	})
+	return found
+}
+
+func (r *MaxBroken) check(b *breakage, now time.Time) []Violation {
+	if b.reported || now.Sub(b.since) < r.For {
+		return nil
+	}
+	b.reported = true
+	msg := fmt.Sprintf("%s (%s) broken for %s, since %s", b.last.Slug(), b.last.Branch,
+		now.Sub(b.since).Round(time.Minute), b.since.Format(time.RFC3339))
+	return []Violation{violation(r.Name(), b.last, msg, now)}
+}
+
+// NoDeploy is broken by builds of Events running jobs of a deploy stage
+type NoDeploy struct {
+	// Events are the event types not allowed to deploy, pull_request if
+	// empty
+	Events []string
+	// Stages are path.Match patterns of the deploy stages, deploy* if empty
+	Stages []string
+}
+
+// Name returns "no-deploy"
+func (r *NoDeploy) Name() string { return "no-deploy" }
+
+// Check reports p if it is of Events and started a job of a deploy stage
+func (r *NoDeploy) Check(p *travis.Payload, now time.Time) []Violation {
+	events := r.Events
+	if len(events) == 0 {
+		events = []string{"pull_request"}
+	}
+	stages := r.Stages
+	if len(stages) == 0 {
+		stages = []string{"deploy*"}
+	}
+	if !travis.MatchAny(events, p.Type) {
+		return nil
+	}
+	for _, s := range travis.Stages(p) {
+		if !travis.MatchAny(stages, s.Name) {
+			continue
+		}
+		for _, j := range s.Jobs {
+			switch j.State {
+			case "started", "passed", "failed", "errored":
+				msg := fmt.Sprintf("%s build #%s of %s ran the %s stage", p.Type, p.Number, p.Slug(), s.Name)
+				return []Violation{violation(r.Name(), p, msg, now)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package travis
+
+import (
+	"crypto/sha1"
+	"errors"
+	"sync"
+)
+
+var payloadPool = sync.Pool{New: func() any { return new(Payload) }}
+
+// AcquirePayload returns an empty payload from a pool, to decode a payload
+// into and give back with Release
+func AcquirePayload() *Payload {
+	return payloadPool.Get().(*Payload)
+}
+
+// Release empties p and returns it to the pool of AcquirePayload. Neither
+// p nor its fields may be used afterwards, copy what must outlive it. The
+// backing arrays of Matrix and Raw are reused, the jobs are not.
+func (p *Payload) Release() {
+	matrix := p.Matrix[:cap(p.Matrix)]
+	clear(matrix)
+	*p = Payload{Matrix: matrix[:0], raw: p.raw[:0]}
+	payloadPool.Put(p)
+}
+
+// DecodePayloadInto decodes the JSON payload into p, e.g. one of
+// AcquirePayload, and normalizes it
+func DecodePayloadInto(payload string, p *Payload) error {
+	if err := decodeJSON(bytesOf(payload), p); err != nil {
+		return errors.New("cannot decode payload")
+	}
+	if len(p.Matrix) == 0 {
+		p.Matrix = nil
+	}
+	p.Normalize()
+	return nil
+}
+
+func decodePayload(payload string) (*Payload, error) {
+	p := new(Payload)
+	if err := DecodePayloadInto(payload, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func payloadDigest(payload string) []byte {
+	sum := sha1.Sum(bytesOf(payload))
+	return sum[:]
+}
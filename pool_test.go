@@ -0,0 +1,23 @@
+package travis_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+func BenchmarkHandler(b *testing.B) {
+	s := travistest.NewServer()
+	defer s.Close()
+	for _, jobs := range travistest.BenchmarkSizes {
+		p := travistest.NewPayload().Jobs(jobs).Build()
+		for _, pool := range []bool{false, true} {
+			b.Run(fmt.Sprintf("jobs=%d/pool=%t", jobs, pool), func(b *testing.B) {
+				h := &travis.Handler{ConfigURL: s.ConfigURL(), PoolPayloads: pool}
+				travistest.BenchmarkHandler(b, h, p, s.Key)
+			})
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package travis
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// BranchPriority returns a Dispatcher.Priority sending first the failed or
+// errored builds of branches, path.Match patterns, "main" and "master" if
+// none, then the other failures, then the other builds of branches and
+// last the rest, such as the passed builds of pull requests. Pull request
+// builds are never builds of branches.
+func BranchPriority(branches ...string) func(p *Payload) int {
+	if len(branches) == 0 {
+		branches = []string{"main", "master"}
+	}
+	return func(p *Payload) int {
+		outcome := Outcome(p)
+		failed := outcome == OutcomeFailed || outcome == OutcomeErrored
+		branch := !p.IsPullRequest() && MatchAny(branches, p.Branch)
+		switch {
+		case failed && branch:
+			return 3
+		case failed:
+			return 2
+		case branch:
+			return 1
+		}
+		return 0
+	}
+}
+
+// dispatchQueue is the queue of a Dispatcher with workers, it takes the
+// entries of the highest priority first and the ones of a priority in the
+// order they were queued. The entries of a key are taken in the order they
+// were queued whatever their priority: only the first of each key is
+// ranked, the others wait behind it.
+type dispatchQueue struct {
+	// slots has a token per queued entry, to block when the queue is full
+	slots chan struct{}
+	// ready has a token per queued entry, to wake up the workers
+	ready chan struct{}
+
+	mu sync.Mutex
+	// heads has the first entry of each key and the entries without key
+	heads queueHeap
+	// behind has the entries queued after the head of their key, a key
+	// is in it while its head is in heads
+	behind map[string][]queued
+	seq    int64
+	n      int
+}
+
+func newDispatchQueue(size int) *dispatchQueue {
+	return &dispatchQueue{
+		slots:  make(chan struct{}, size),
+		ready:  make(chan struct{}, size),
+		behind: make(map[string][]queued),
+	}
+}
+
+// push queues e, blocking while the queue is full unless failFast is set
+func (q *dispatchQueue) push(ctx context.Context, e queued, failFast bool) error {
+	if failFast {
+		select {
+		case q.slots <- struct{}{}:
+		default:
+			return ErrQueueFull
+		}
+	} else {
+		select {
+		case q.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	q.mu.Lock()
+	q.seq++
+	e.seq = q.seq
+	if behind, ok := q.behind[e.key]; ok && e.keyed {
+		q.behind[e.key] = append(behind, e)
+	} else {
+		if e.keyed {
+			q.behind[e.key] = nil
+		}
+		heap.Push(&q.heads, e)
+	}
+	q.n++
+	q.mu.Unlock()
+	q.ready <- struct{}{}
+	return nil
+}
+
+// pop waits for an entry, it returns false once the queue is closed and
+// empty
+func (q *dispatchQueue) pop() (JournalEntry, bool) {
+	if _, ok := <-q.ready; !ok {
+		return JournalEntry{}, false
+	}
+	q.mu.Lock()
+	e := heap.Pop(&q.heads).(queued)
+	if e.keyed {
+		if behind := q.behind[e.key]; len(behind) > 0 {
+			heap.Push(&q.heads, behind[0])
+			behind[0] = queued{}
+			q.behind[e.key] = behind[1:]
+		} else {
+			delete(q.behind, e.key)
+		}
+	}
+	q.n--
+	q.mu.Unlock()
+	<-q.slots
+	return e.JournalEntry, true
+}
+
+// close wakes up the workers once the queue is empty, push must not be
+// called anymore
+func (q *dispatchQueue) close() {
+	close(q.ready)
+}
+
+func (q *dispatchQueue) len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.n
+}
+
+// queued is an entry of a dispatchQueue
+type queued struct {
+	JournalEntry
+	priority int
+	// key is the Dispatcher.Key of the entry, if keyed
+	key   string
+	keyed bool
+	seq   int64
+}
+
+type queueHeap []queued
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h queueHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *queueHeap) Push(x any) { *h = append(*h, x.(queued)) }
+
+func (h *queueHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = queued{}
+	*h = old[:len(old)-1]
+	return e
+}
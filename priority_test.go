@@ -0,0 +1,93 @@
+package travis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestDispatchQueue(t *testing.T) {
+	type entry struct {
+		id       int64
+		key      string
+		priority int
+	}
+	tests := []struct {
+		name    string
+		keyed   bool
+		entries []entry
+		want    []int64
+	}{
+		{
+			"priority then order",
+			false,
+			[]entry{{1, "", 0}, {2, "", 1}, {3, "", 0}, {4, "", 1}},
+			[]int64{2, 4, 1, 3},
+		},
+		{
+			"unkeyed entries overtake",
+			false,
+			[]entry{{1, "a", 0}, {2, "a", 1}},
+			[]int64{2, 1},
+		},
+		{
+			"key waits behind its head",
+			true,
+			[]entry{{1, "a", 0}, {2, "a", 2}, {3, "b", 1}},
+			[]int64{3, 1, 2},
+		},
+		{
+			"next of key ranked once head is taken",
+			true,
+			[]entry{{1, "a", 2}, {2, "a", 0}, {3, "b", 1}, {4, "a", 3}},
+			[]int64{1, 3, 2, 4},
+		},
+		{
+			"keys interleaved",
+			true,
+			[]entry{{1, "a", 1}, {2, "b", 1}, {3, "a", 1}, {4, "b", 1}},
+			[]int64{1, 2, 3, 4},
+		},
+		{
+			"empty key is a key",
+			true,
+			[]entry{{1, "", 0}, {2, "", 5}, {3, "a", 1}},
+			[]int64{3, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newDispatchQueue(len(tt.entries))
+			for _, e := range tt.entries {
+				err := q.push(context.Background(), queued{
+					JournalEntry: JournalEntry{Payload: &Payload{ID: e.id}},
+					priority:     e.priority,
+					key:          e.key,
+					keyed:        tt.keyed,
+				}, true)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := q.push(context.Background(), queued{}, true); err != ErrQueueFull {
+				t.Errorf("push() on a full queue = %v, want %v", err, ErrQueueFull)
+			}
+			q.close()
+
+			var got []int64
+			for {
+				e, ok := q.pop()
+				if !ok {
+					break
+				}
+				got = append(got, e.Payload.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("popped %v, want %v", got, tt.want)
+			}
+			if q.len() != 0 || len(q.heads) != 0 || len(q.behind) != 0 {
+				t.Errorf("len %d, heads %d, behind %v, want an empty queue", q.len(), len(q.heads), q.behind)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package travis
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The states of PRStatus
+const (
+	PRPending = "pending"
+	PRSuccess = "success"
+	PRFailure = "failure"
+	// PRUnknown is the state of the pull requests without builds
+	PRUnknown = "unknown"
+)
+
+// PRStatus is the result of the required Travis status of a pull request,
+// the latest build of its head commit
+type PRStatus struct {
+	State    string `json:"state"`
+	SHA      string `json:"sha,omitempty"`
+	BuildID  int64  `json:"build_id,omitempty"`
+	BuildURL string `json:"build_url,omitempty"`
+	// Builds is the number of builds of the head commit received, e.g.
+	// more than one after a rebuild
+	Builds    int       `json:"builds,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// PRTracker is a Sink tracking the builds of pull requests for merge bots,
+// which poll PRStatus or its HTTP handler rather than parse webhooks. The
+// latest build of the head commit of a pull request decides its status:
+// a rebuild or a later build of the same commit replaces the previous one,
+// and builds of older commits arriving late are ignored.
+type PRTracker struct {
+	// MaxEntries is the number of pull requests tracked, DefaultMaxEntries
+	// if zero. The least recently built are forgotten first.
+	MaxEntries int
+
+	// Clock dates the updates, SystemClock if nil
+	Clock Clock
+
+	mu  sync.Mutex
+	prs lru[prKey, *PRStatus]
+}
+
+type prKey struct {
+	repo   string
+	number int
+}
+
+// Send records p if it is a pull request build
+func (t *PRTracker) Send(p *Payload) error {
+	if !p.IsPullRequest() || p.PullRequestNumber == 0 {
+		return nil
+	}
+	sha := p.HeadCommit
+	if sha == "" {
+		sha = p.Commit
+	}
+	state := PRPending
+	switch Outcome(p) {
+	case OutcomePassed:
+		state = PRSuccess
+	case OutcomeFailed, OutcomeErrored, OutcomeCanceled:
+		state = PRFailure
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prs.limit(t.MaxEntries, 0)
+	key := prKey{p.Slug(), p.PullRequestNumber}
+	s, ok := t.prs.Get(key)
+	switch {
+	case !ok || sha != s.SHA && p.ID > s.BuildID:
+		s = &PRStatus{SHA: sha}
+	case sha != s.SHA || p.ID < s.BuildID:
+		return nil
+	}
+	if p.ID != s.BuildID {
+		s.Builds++
+	}
+	s.State, s.BuildID, s.BuildURL = state, p.ID, p.BuildURL
+	s.UpdatedAt = clockOr(t.Clock).Now()
+	t.prs.Put(key, s)
+	return nil
+}
+
+// PRStatus returns the status of the pull request number of the repository
+// slug, PRUnknown if none of its builds was received
+func (t *PRTracker) PRStatus(repo string, number int) PRStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.prs.Peek(prKey{repo, number}); ok {
+		return *s
+	}
+	return PRStatus{State: PRUnknown}
+}
+
+// Memory reports the pull requests tracked
+func (t *PRTracker) Memory() MemoryStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.prs.stats()
+}
+
+// Handler returns an http.Handler serving the PRStatus of the repo and pr
+// query parameters as JSON, e.g. /?repo=acme/api&pr=42
+func (t *PRTracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		repo := r.URL.Query().Get("repo")
+		number, err := strconv.Atoi(r.URL.Query().Get("pr"))
+		if repo == "" || err != nil {
+			http.Error(w, "repo and pr query parameters required", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.PRStatus(repo, number))
+	})
+}
@@ -0,0 +1,147 @@
+package travis
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuietHours is a Notifier that holds back non-critical messages during a
+// daily quiet period and drops messages about builds of opted-out authors.
+// Deferred messages are sent by Deliver once the quiet period is over.
+type QuietHours struct {
+	Notifier Notifier
+
+	// From and To delimit the quiet period as wall clock times written as
+	// offsets from midnight, e.g. 20h and 8h, 20:00 and 08:00 even on the
+	// days clocks change. The period wraps around midnight when From is
+	// after To.
+	From, To time.Duration
+	// Location is the timezone of From and To, UTC if nil
+	Location *time.Location
+
+	// Critical reports whether m must be sent even during the quiet period,
+	// if nil CriticalMessage is used
+	Critical func(m *Message) bool
+	// Drop discards non-critical messages during the quiet period instead
+	// of deferring them
+	Drop bool
+
+	// OptOut lists the author emails that don't want non-critical messages
+	// about their builds
+	OptOut []string
+
+	// Clock tells whether it is quiet, SystemClock if nil
+	Clock Clock
+
+	mu       sync.Mutex
+	deferred []*Message
+}
+
+// CriticalMessage returns true if m is about a broken, failing or errored build
+func CriticalMessage(m *Message) bool {
+	p := m.Payload
+	if p == nil {
+		return false
+	}
+	return p.Broken() || p.Failed() || p.StillFailing() || p.Errored()
+}
+
+// Notify sends m right away, defers it or drops it
+func (q *QuietHours) Notify(m *Message) error {
+	return q.NotifyContext(context.Background(), m)
+}
+
+// NotifyContext is Notify sending with ctx
+func (q *QuietHours) NotifyContext(ctx context.Context, m *Message) error {
+	critical := CriticalMessage
+	if q.Critical != nil {
+		critical = q.Critical
+	}
+	if critical(m) {
+		return NotifyContext(ctx, q.Notifier, m)
+	}
+	if m.Payload != nil && q.optedOut(m.Payload.AuthorEmail) {
+		return nil
+	}
+	if !q.Quiet(clockOr(q.Clock).Now()) {
+		return NotifyContext(ctx, q.Notifier, m)
+	}
+	if q.Drop {
+		return nil
+	}
+	q.mu.Lock()
+	q.deferred = append(q.deferred, m)
+	q.mu.Unlock()
+	return nil
+}
+
+// Quiet returns true if t is inside the quiet period
+func (q *QuietHours) Quiet(t time.Time) bool {
+	if q.From == q.To {
+		return false
+	}
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	from, to := wallClock(t, q.From), wallClock(t, q.To)
+	if q.From < q.To {
+		return !t.Before(from) && t.Before(to)
+	}
+	return !t.Before(from) || t.Before(to)
+}
+
+// wallClock returns the time of the day of t at the wall clock time d
+// after midnight, in the location of t
+func wallClock(t time.Time, d time.Duration) time.Time {
+	h, m, s := int(d/time.Hour), int(d%time.Hour/time.Minute), int(d%time.Minute/time.Second)
+	return time.Date(t.Year(), t.Month(), t.Day(), h, m, s, 0, t.Location())
+}
+
+// Pending returns the number of deferred messages
+func (q *QuietHours) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.deferred)
+}
+
+// Deliver sends the deferred messages if the quiet period is over, it is
+// meant to be called periodically. Messages that fail to send are kept for
+// the next call.
+func (q *QuietHours) Deliver() error {
+	return q.DeliverContext(context.Background())
+}
+
+// DeliverContext is Deliver sending with ctx
+func (q *QuietHours) DeliverContext(ctx context.Context) error {
+	if q.Quiet(clockOr(q.Clock).Now()) {
+		return nil
+	}
+	q.mu.Lock()
+	deferred := q.deferred
+	q.deferred = nil
+	q.mu.Unlock()
+
+	var err error
+	for i, m := range deferred {
+		if err = NotifyContext(ctx, q.Notifier, m); err != nil {
+			q.mu.Lock()
+			q.deferred = append(deferred[i:], q.deferred...)
+			q.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *QuietHours) optedOut(email string) bool {
+	for _, e := range q.OptOut {
+		if strings.EqualFold(e, email) {
+			return true
+		}
+	}
+	return false
+}
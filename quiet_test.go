@@ -0,0 +1,48 @@
+package travis_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestQuietHours(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, berlin)
+	}
+	tests := []struct {
+		name     string
+		from, to time.Duration
+		t        time.Time
+		want     bool
+	}{
+		{"evening", 20 * time.Hour, 8 * time.Hour, at(time.June, 1, 21, 0), true},
+		{"morning", 20 * time.Hour, 8 * time.Hour, at(time.June, 1, 7, 59), true},
+		{"day", 20 * time.Hour, 8 * time.Hour, at(time.June, 1, 8, 0), false},
+		{"from", 20 * time.Hour, 8 * time.Hour, at(time.June, 1, 20, 0), true},
+		{"lunch", 12 * time.Hour, 13*time.Hour + 30*time.Minute, at(time.June, 1, 13, 29), true},
+		{"after lunch", 12 * time.Hour, 13*time.Hour + 30*time.Minute, at(time.June, 1, 13, 30), false},
+		{"empty", 8 * time.Hour, 8 * time.Hour, at(time.June, 1, 8, 0), false},
+		// the clocks move from 02:00 to 03:00 on March 29 and from 03:00
+		// to 02:00 on October 25
+		{"spring morning", 20 * time.Hour, 8 * time.Hour, at(time.March, 29, 7, 30), true},
+		{"spring day", 20 * time.Hour, 8 * time.Hour, at(time.March, 29, 8, 0), false},
+		{"spring evening", 20 * time.Hour, 8 * time.Hour, at(time.March, 29, 19, 30), false},
+		{"autumn morning", 20 * time.Hour, 8 * time.Hour, at(time.October, 25, 7, 30), true},
+		{"autumn day", 20 * time.Hour, 8 * time.Hour, at(time.October, 25, 8, 0), false},
+		{"autumn evening", 20 * time.Hour, 8 * time.Hour, at(time.October, 25, 20, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &travis.QuietHours{From: tt.from, To: tt.to, Location: berlin}
+			if got := q.Quiet(tt.t.UTC()); got != tt.want {
+				t.Errorf("Quiet(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
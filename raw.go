@@ -0,0 +1,66 @@
+package travis
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Raw returns the JSON p was decoded from, nil if it wasn't decoded, for
+// the fields Payload doesn't model. Like the other fields it is only valid
+// until p is released.
+func (p *Payload) Raw() json.RawMessage {
+	return p.raw
+}
+
+// Get returns the value at path in the JSON p was decoded from, decoded
+// like json.Unmarshal decodes into an any: a string, a float64, a bool, nil,
+// a []any or a map[string]any. The path is made of keys and array indexes
+// separated by dots or in brackets, e.g. "repository.id", "matrix.0.state"
+// or "$.matrix[0].config.os". Get returns false if p wasn't decoded from
+// JSON or has nothing at path.
+func (p *Payload) Get(path string) (any, bool) {
+	raw := json.RawMessage(bytes.TrimSpace(p.raw))
+	if len(raw) == 0 {
+		return nil, false
+	}
+	for _, key := range splitJSONPath(path) {
+		if raw[0] == '[' {
+			i, err := strconv.Atoi(key)
+			var a []json.RawMessage
+			if err != nil || json.Unmarshal(raw, &a) != nil || i < 0 || i >= len(a) {
+				return nil, false
+			}
+			raw = a[i]
+			continue
+		}
+		var m map[string]json.RawMessage
+		if json.Unmarshal(raw, &m) != nil {
+			return nil, false
+		}
+		v, ok := m[key]
+		if !ok {
+			return nil, false
+		}
+		raw = v
+	}
+	var v any
+	if json.Unmarshal(raw, &v) != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// splitJSONPath returns the keys of path, see Get
+func splitJSONPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	var keys []string
+	for _, k := range strings.Split(path, ".") {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
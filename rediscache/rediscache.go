@@ -0,0 +1,112 @@
+// Package rediscache is a travis.CacheBackend in Redis, so that the
+// replicas of a receiver share the public key and the deliveries they
+// handled rather than each keeping its own in memory. It is a
+// travis.Locker as well, for the reactions that must happen once.
+//
+//	redis := &rediscache.Cache{Client: redis.NewClient(&redis.Options{Addr: "redis:6379"}), Prefix: "ci-hooks:"}
+//	h := &travis.Handler{
+//		Sinks:  []travis.Sink{travis.Once(redis, "restart", restarter)},
+//		Cache:  redis,
+//		Dedupe: true,
+//	}
+//
+// It uses the go-redis client, any of its clients works: a single node,
+// Sentinel or a cluster. Only the GET, SET, DEL and EVALSHA commands are
+// needed, so Valkey, KeyDB and managed Redis services work as well.
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	_ travis.CacheBackend = (*Cache)(nil)
+	_ travis.Locker       = (*Cache)(nil)
+)
+
+// Cache is a travis.CacheBackend in Redis. It is safe for concurrent use.
+type Cache struct {
+	// Client connects to Redis, e.g. redis.NewClient or
+	// redis.NewClusterClient
+	Client redis.UniversalClient
+	// Prefix is prepended to the keys, to share a database
+	Prefix string
+}
+
+// Get returns the value of key
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := c.Client.Get(ctx, c.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+// Set sets the value of key, expiring after ttl unless it is zero
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.Client.Set(ctx, c.Prefix+key, value, expiration(ttl)).Err()
+}
+
+// Add sets the value of key if it is missing, with SET NX
+func (c *Cache) Add(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.Client.SetNX(ctx, c.Prefix+key, value, expiration(ttl)).Result()
+}
+
+// Delete removes key
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.Client.Del(ctx, c.Prefix+key).Err()
+}
+
+// The scripts of Refresh and Unlock, changing the lock of a key only if it
+// still holds the token
+var (
+	refreshScript = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`)
+	unlockScript  = redis.NewScript(`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+)
+
+// Lock acquires key for ttl, setting it to a random token if it is missing
+func (c *Cache) Lock(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	token := travis.NewLockToken()
+	ok, err := c.Add(ctx, key, []byte(token), ttl)
+	if err != nil || !ok {
+		return "", err
+	}
+	return token, nil
+}
+
+// Refresh extends the lock of token on key for ttl
+func (c *Cache) Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	n, err := refreshScript.Run(ctx, c.Client, []string{c.Prefix + key}, token, max(ttl.Milliseconds(), 1)).Int64()
+	return n == 1, err
+}
+
+// Unlock releases the lock of token on key
+func (c *Cache) Unlock(ctx context.Context, key, token string) error {
+	return unlockScript.Run(ctx, c.Client, []string{c.Prefix + key}, token).Err()
+}
+
+// Ping checks that Redis answers, e.g. for a readiness check
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.Client.Ping(ctx).Err()
+}
+
+// Close closes the client
+func (c *Cache) Close() error {
+	return c.Client.Close()
+}
+
+// expiration rounds ttl up to the millisecond, the precision of Redis
+func expiration(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < time.Millisecond {
+		return time.Millisecond
+	}
+	return ttl
+}
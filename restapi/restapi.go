@@ -0,0 +1,172 @@
+// Package restapi serves the build history of a store as a read-only JSON
+// API, for dashboards that shouldn't access the database directly.
+//
+//	GET /builds?repo=acme/api&branch=main&type=push&since=2024-01-02T15:04:05Z&until=...&limit=50
+//	GET /builds/{id}
+//	GET /repos/{owner}/{name}/summary
+//
+// Builds are returned in the travis payload format. Mount the server with
+// http.StripPrefix to serve it under a path.
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+)
+
+// DefaultLimit is the number of builds listed when the request has no limit
+const DefaultLimit = 50
+
+// MaxLimit is the maximum number of builds listed by a request
+const MaxLimit = 1000
+
+// Summary is the state of a repository
+type Summary struct {
+	Repo string `json:"repo"`
+	// Branches are the latest push, cron or api build of each branch
+	Branches []BranchSummary `json:"branches"`
+	// Outcomes counts the outcome of the last Builds builds
+	Builds   int            `json:"builds"`
+	Outcomes map[string]int `json:"outcomes"`
+}
+
+// BranchSummary is the latest build of a branch
+type BranchSummary struct {
+	Branch     string    `json:"branch"`
+	State      string    `json:"state"`
+	BuildID    int64     `json:"build_id"`
+	Number     string    `json:"number"`
+	BuildURL   string    `json:"build_url"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Server is an http.Handler serving the API
+type Server struct {
+	mux   *http.ServeMux
+	store store.Store
+}
+
+// New returns a Server reading from s
+func New(s store.Store) *Server {
+	srv := &Server{mux: http.NewServeMux(), store: s}
+	srv.mux.HandleFunc("GET /builds", srv.builds)
+	srv.mux.HandleFunc("GET /builds/{id}", srv.build)
+	srv.mux.HandleFunc("GET /repos/{owner}/{name}/summary", srv.summary)
+	return srv
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) builds(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := store.Query{
+		Repo:   v.Get("repo"),
+		Branch: v.Get("branch"),
+		Type:   v.Get("type"),
+		Limit:  DefaultLimit,
+	}
+	var err error
+	if q.Since, err = parseTime(v.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since")
+		return
+	}
+	if q.Until, err = parseTime(v.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid until")
+		return
+	}
+	if l := v.Get("limit"); l != "" {
+		q.Limit, err = strconv.Atoi(l)
+		if err != nil || q.Limit <= 0 || q.Limit > MaxLimit {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	builds, err := s.store.Builds(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if builds == nil {
+		builds = []*travis.Payload{}
+	}
+	writeJSON(w, http.StatusOK, builds)
+}
+
+func (s *Server) build(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid build id")
+		return
+	}
+	p, err := s.store.Build(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) summary(w http.ResponseWriter, r *http.Request) {
+	repo := r.PathValue("owner") + "/" + r.PathValue("name")
+	latest, err := s.store.LatestPerBranch(r.Context(), repo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	recent, err := s.store.Builds(r.Context(), store.Query{Repo: repo, Limit: 100})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sum := Summary{
+		Repo:     repo,
+		Branches: []BranchSummary{},
+		Builds:   len(recent),
+		Outcomes: make(map[string]int),
+	}
+	for _, p := range latest {
+		sum.Branches = append(sum.Branches, BranchSummary{
+			Branch:     p.Branch,
+			State:      travis.Outcome(p),
+			BuildID:    p.ID,
+			Number:     p.Number,
+			BuildURL:   p.BuildURL,
+			FinishedAt: p.FinishedAt,
+		})
+	}
+	for _, p := range recent {
+		sum.Outcomes[travis.Outcome(p)]++
+	}
+	writeJSON(w, http.StatusOK, sum)
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
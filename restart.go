@@ -0,0 +1,198 @@
+package travis
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxRestarts is the number of times an AutoRestart restarts a
+	// build if MaxAttempts isn't set
+	DefaultMaxRestarts = 2
+	// DefaultRestartBackoff is the delay before the first restart of a
+	// build if Backoff isn't set
+	DefaultRestartBackoff = time.Minute
+)
+
+// Restarter restarts builds, it is implemented by Client
+type Restarter interface {
+	RestartBuild(ctx context.Context, id int64) error
+}
+
+// RestartResult is the end of the restarts of a build
+type RestartResult struct {
+	// Payload is the last build received, or the one failing to restart
+	Payload *Payload
+	// Attempts is the number of restarts
+	Attempts int
+	// Fixed is true if a restart passed
+	Fixed bool
+	// Err is the error of the last restart, if it failed
+	Err error
+}
+
+// AutoRestart is a Sink restarting the builds ending in States, errored
+// ones by default, up to MaxAttempts times. Restarts wait Backoff, doubled
+// at each attempt, and are made in the background: Send doesn't block, and
+// Close cancels the ones waiting. OnResult reports whether the restarts
+// fixed the build once it passes, ends in another state, or runs out of
+// attempts.
+type AutoRestart struct {
+	Restarter Restarter
+
+	// Repos and Branches are path.Match patterns of the builds restarted,
+	// e.g. acme/*, all if empty. Pull request builds are matched by their
+	// target branch.
+	Repos    []string
+	Branches []string
+	// States are the outcomes restarted, errored if empty
+	States []string
+
+	// MaxAttempts is the number of restarts of a build,
+	// DefaultMaxRestarts if zero
+	MaxAttempts int
+	// Backoff is the delay before the first restart, DefaultRestartBackoff
+	// if zero
+	Backoff time.Duration
+
+	OnResult func(r RestartResult)
+
+	// MaxEntries is the number of builds tracked, DefaultMaxEntries if
+	// zero. The least recently restarted are forgotten first.
+	MaxEntries int
+
+	// Clock times the backoff, SystemClock if nil
+	Clock Clock
+
+	once     sync.Once
+	mu       sync.Mutex
+	attempts lru[int64, int]
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func (a *AutoRestart) init() {
+	a.once.Do(func() {
+		a.ctx, a.cancel = context.WithCancel(context.Background())
+	})
+}
+
+// Send schedules a restart of p if it ended in States, or reports the
+// result of the restarts of p
+func (a *AutoRestart) Send(p *Payload) error {
+	if !MatchAny(a.Repos, p.Slug()) || !MatchAny(a.Branches, p.Branch) {
+		return nil
+	}
+	outcome := Outcome(p)
+	if outcome == OutcomePending {
+		return nil
+	}
+	a.init()
+
+	a.mu.Lock()
+	a.attempts.limit(a.MaxEntries, 0)
+	n, tracked := a.attempts.Get(p.ID)
+	max := a.MaxAttempts
+	if max <= 0 {
+		max = DefaultMaxRestarts
+	}
+	restart := a.restartable(outcome) && n < max && a.ctx.Err() == nil
+	if restart {
+		a.attempts.Put(p.ID, n+1)
+		a.wg.Add(1)
+	} else {
+		a.attempts.Remove(p.ID)
+	}
+	a.mu.Unlock()
+
+	switch {
+	case restart:
+		go a.restart(p, n+1)
+	case tracked:
+		a.report(RestartResult{Payload: p, Attempts: n, Fixed: outcome == OutcomePassed})
+	}
+	return nil
+}
+
+// Attempts returns the number of restarts of the build with id so far
+func (a *AutoRestart) Attempts(id int64) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n, _ := a.attempts.Peek(id)
+	return n
+}
+
+// Close cancels the restarts waiting for their backoff or being requested
+// and waits for them to return
+func (a *AutoRestart) Close() error {
+	a.init()
+	a.cancel()
+	a.wg.Wait()
+	return nil
+}
+
+// Memory reports the builds tracked
+func (a *AutoRestart) Memory() MemoryStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.attempts.stats()
+}
+
+func (a *AutoRestart) restartable(outcome string) bool {
+	if len(a.States) == 0 {
+		return outcome == OutcomeErrored
+	}
+	for _, s := range a.States {
+		if s == outcome {
+			return true
+		}
+	}
+	return false
+}
+
+// restart restarts the build of p after the backoff of attempt
+func (a *AutoRestart) restart(p *Payload, attempt int) {
+	defer a.wg.Done()
+	backoff := a.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRestartBackoff
+	}
+	t := clockOr(a.Clock).NewTicker(backoff << (attempt - 1))
+	select {
+	case <-t.C():
+		t.Stop()
+	case <-a.ctx.Done():
+		t.Stop()
+		return
+	}
+	if err := a.Restarter.RestartBuild(a.ctx, p.ID); err != nil {
+		a.mu.Lock()
+		a.attempts.Remove(p.ID)
+		a.mu.Unlock()
+		a.report(RestartResult{Payload: p, Attempts: attempt, Err: err})
+	}
+}
+
+func (a *AutoRestart) report(r RestartResult) {
+	if a.OnResult != nil {
+		a.OnResult(r)
+	}
+}
+
+// MatchAny returns true if patterns is empty or one of the path.Match
+// patterns matches v, the matching of the repository and branch lists of
+// the sinks and rules
+func MatchAny(patterns []string, v string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, v); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package travis_test
+
+import (
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		v        string
+		want     bool
+	}{
+		{nil, "acme/api", true},
+		{[]string{"acme/api"}, "acme/api", true},
+		{[]string{"acme/web", "acme/*"}, "acme/api", true},
+		{[]string{"acme/*"}, "other/api", false},
+		{[]string{"*"}, "acme/api", false},
+		{[]string{"release/*"}, "release/1.2", true},
+		{[]string{"release-[0-9]*"}, "release-2", true},
+		{[]string{"[release"}, "[release", false},
+	}
+	for _, tt := range tests {
+		if got := travis.MatchAny(tt.patterns, tt.v); got != tt.want {
+			t.Errorf("MatchAny(%q, %q) = %v, want %v", tt.patterns, tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package travis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSchedulerInterval is how often a Scheduler looks for due follow-ups
+// if Interval isn't set
+const DefaultSchedulerInterval = 10 * time.Second
+
+// FollowUp is a payload sent again to a sink of a Scheduler at a later time
+type FollowUp struct {
+	ID string `json:"id"`
+	// Name is the sink of the Scheduler the payload is sent to
+	Name    string    `json:"name"`
+	Payload *Payload  `json:"payload"`
+	At      time.Time `json:"at"`
+}
+
+// FollowUpStore persists the follow-ups of a Scheduler so they survive a
+// restart, e.g. store.SQL or boltstore.Store
+type FollowUpStore interface {
+	AddFollowUp(ctx context.Context, f *FollowUp) error
+	// DueFollowUps returns the follow-ups due at now, the earliest first
+	DueFollowUps(ctx context.Context, now time.Time) ([]*FollowUp, error)
+	// RemoveFollowUp forgets the follow-up with id
+	RemoveFollowUp(ctx context.Context, id string) error
+}
+
+// Scheduler sends payloads again to one of its Sinks after a delay, for
+// the sinks that check on a build later, e.g. whether a branch is still
+// broken 10 minutes after a failure:
+//
+//	s := &travis.Scheduler{Dispatcher: d, Store: db}
+//	s.Sinks = map[string]travis.Sink{"recheck": travis.SinkFunc(func(p *travis.Payload) error {
+//		// look the branch up, escalate if it is still broken
+//		return nil
+//	})}
+//	d.Sinks = append(d.Sinks, travis.SinkFunc(func(p *travis.Payload) error {
+//		if p.Broken() {
+//			_, err := s.After(context.Background(), 10*time.Minute, "recheck", p)
+//			return err
+//		}
+//		return nil
+//	}))
+//	go s.Run(ctx)
+//
+// The due follow-ups are queued in Dispatcher, if set, so they share its
+// workers, keys and priorities, and are removed from Store once sent. A
+// follow-up is sent once, a sink wanting another one schedules it again,
+// and FollowUpFromContext tells the sink it is a follow-up. Run the
+// Scheduler of a receiver with several replicas on one of them, see Leader.
+type Scheduler struct {
+	// Sinks receive the follow-ups by name
+	Sinks map[string]Sink
+	// Dispatcher, if set, queues the due follow-ups, else Tick sends them
+	Dispatcher *Dispatcher
+	// Store, if set, persists the follow-ups, else they are in memory
+	Store FollowUpStore
+
+	// Interval is how often Run looks for due follow-ups,
+	// DefaultSchedulerInterval if zero
+	Interval time.Duration
+
+	// OnError, if set, is called with the errors of the sinks and of the
+	// store, f is nil for the latter
+	OnError func(f *FollowUp, err error)
+
+	// Clock tells the time, SystemClock if nil
+	Clock Clock
+
+	once     sync.Once
+	store    FollowUpStore
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+func (s *Scheduler) init() {
+	s.once.Do(func() {
+		s.store = s.Store
+		if s.store == nil {
+			s.store = &memoryFollowUps{followUps: make(map[string]*FollowUp)}
+		}
+		s.inflight = make(map[string]bool)
+	})
+}
+
+type followUpKey struct{}
+
+// FollowUpFromContext returns the follow-up a sink is sent by a Scheduler
+func FollowUpFromContext(ctx context.Context) (*FollowUp, bool) {
+	f, ok := ctx.Value(followUpKey{}).(*FollowUp)
+	return f, ok
+}
+
+// After schedules p to be sent to the sink name after delay
+func (s *Scheduler) After(ctx context.Context, delay time.Duration, name string, p *Payload) (*FollowUp, error) {
+	s.init()
+	if _, ok := s.Sinks[name]; !ok {
+		return nil, fmt.Errorf("no follow-up sink %q", name)
+	}
+	f := &FollowUp{ID: NewID(), Name: name, Payload: p, At: clockOr(s.Clock).Now().Add(delay)}
+	if err := s.store.AddFollowUp(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Cancel forgets the follow-up with id, unless it is already being sent
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	s.init()
+	return s.store.RemoveFollowUp(ctx, id)
+}
+
+// Tick sends the follow-ups due at now, or queues them in Dispatcher. The
+// ones the Dispatcher can't queue are tried again on the next tick.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) error {
+	s.init()
+	due, err := s.store.DueFollowUps(ctx, now)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, f := range due {
+		s.mu.Lock()
+		if s.inflight[f.ID] {
+			s.mu.Unlock()
+			continue
+		}
+		s.inflight[f.ID] = true
+		s.mu.Unlock()
+
+		sink, ok := s.Sinks[f.Name]
+		if !ok {
+			s.done(ctx, f, fmt.Errorf("no follow-up sink %q", f.Name))
+			continue
+		}
+		fctx := context.WithValue(ctx, followUpKey{}, f)
+		if s.Dispatcher == nil {
+			s.done(ctx, f, SendContext(fctx, sink, f.Payload))
+			continue
+		}
+		err := s.Dispatcher.dispatchTo(fctx, sink, f.Payload, func(err error) {
+			s.done(context.WithoutCancel(ctx), f, err)
+		})
+		if err != nil {
+			s.mu.Lock()
+			delete(s.inflight, f.ID)
+			s.mu.Unlock()
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// done removes f once it was sent, with the error of its sink
+func (s *Scheduler) done(ctx context.Context, f *FollowUp, err error) {
+	if err != nil && s.OnError != nil {
+		s.OnError(f, err)
+	}
+	if err := s.store.RemoveFollowUp(ctx, f.ID); err != nil && s.OnError != nil {
+		s.OnError(nil, err)
+	}
+	s.mu.Lock()
+	delete(s.inflight, f.ID)
+	s.mu.Unlock()
+}
+
+// Run calls Tick every Interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+	ticker := clockOr(s.Clock).NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C():
+			if err := s.Tick(ctx, now); err != nil && s.OnError != nil {
+				s.OnError(nil, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// memoryFollowUps is the FollowUpStore of a Scheduler without Store
+type memoryFollowUps struct {
+	mu        sync.Mutex
+	followUps map[string]*FollowUp
+}
+
+func (m *memoryFollowUps) AddFollowUp(ctx context.Context, f *FollowUp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.followUps[f.ID] = f
+	return nil
+}
+
+func (m *memoryFollowUps) DueFollowUps(ctx context.Context, now time.Time) ([]*FollowUp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []*FollowUp
+	for _, f := range m.followUps {
+		if !f.At.After(now) {
+			due = append(due, f)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].At.Before(due[j].At) })
+	return due, nil
+}
+
+func (m *memoryFollowUps) RemoveFollowUp(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.followUps, id)
+	return nil
+}
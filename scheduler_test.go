@@ -0,0 +1,158 @@
+package travis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/travistest"
+)
+
+// followUpSink records the IDs of the follow-ups it is sent
+type followUpSink struct {
+	mu   sync.Mutex
+	sent []int64
+	err  error
+	c    chan struct{}
+}
+
+func (s *followUpSink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+func (s *followUpSink) SendContext(ctx context.Context, p *travis.Payload) error {
+	if _, ok := travis.FollowUpFromContext(ctx); !ok {
+		return errors.New("not a follow-up")
+	}
+	s.mu.Lock()
+	s.sent = append(s.sent, p.ID)
+	s.mu.Unlock()
+	if s.c != nil {
+		s.c <- struct{}{}
+	}
+	return s.err
+}
+
+func (s *followUpSink) ids() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprint(s.sent)
+}
+
+func TestSchedulerTick(t *testing.T) {
+	delays := []time.Duration{10 * time.Minute, time.Minute, 5 * time.Minute}
+	tests := []struct {
+		name    string
+		advance []time.Duration
+		cancel  int
+		want    []string
+	}{
+		{"not due", []time.Duration{59 * time.Second}, -1, []string{"[]"}},
+		{"due at its time", []time.Duration{time.Minute}, -1, []string{"[2]"}},
+		{"earliest first", []time.Duration{time.Hour}, -1, []string{"[2 3 1]"}},
+		{"sent once", []time.Duration{5 * time.Minute, 5 * time.Minute, time.Hour}, -1, []string{"[2 3]", "[2 3 1]", "[2 3 1]"}},
+		{"canceled", []time.Duration{time.Hour}, 1, []string{"[3 1]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := travistest.NewFakeClock(travistest.DefaultStart)
+			sink := &followUpSink{}
+			s := &travis.Scheduler{Sinks: map[string]travis.Sink{"recheck": sink}, Clock: clock}
+			ctx := context.Background()
+			for i, delay := range delays {
+				f, err := s.After(ctx, delay, "recheck", &travis.Payload{ID: int64(i + 1)})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := travistest.DefaultStart.Add(delay); !f.At.Equal(want) {
+					t.Errorf("At = %v, want %v", f.At, want)
+				}
+				if i == tt.cancel {
+					if err := s.Cancel(ctx, f.ID); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			for i, d := range tt.advance {
+				clock.Advance(d)
+				if err := s.Tick(ctx, clock.Now()); err != nil {
+					t.Fatal(err)
+				}
+				if got := sink.ids(); got != tt.want[i] {
+					t.Errorf("tick %d: sent %s, want %s", i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSchedulerAfterUnknownSink(t *testing.T) {
+	s := &travis.Scheduler{}
+	if _, err := s.After(context.Background(), time.Minute, "recheck", &travis.Payload{}); err == nil {
+		t.Error("After() with an unknown sink succeeded")
+	}
+}
+
+func TestSchedulerRun(t *testing.T) {
+	clock := travistest.NewFakeClock(travistest.DefaultStart)
+	sink := &followUpSink{err: errors.New("still broken"), c: make(chan struct{}, 1)}
+	errs := make(chan error, 1)
+	s := &travis.Scheduler{
+		Sinks:      map[string]travis.Sink{"recheck": sink},
+		Dispatcher: &travis.Dispatcher{Workers: 1},
+		Interval:   time.Minute,
+		Clock:      clock,
+		OnError: func(f *travis.FollowUp, err error) {
+			errs <- err
+		},
+	}
+	defer s.Dispatcher.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := s.After(ctx, 90*time.Second, "recheck", &travis.Payload{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	// tick until the follow-up is sent, Run may start its ticker late
+	sent := false
+	for i := 0; i < 100 && !sent; i++ {
+		clock.Advance(30 * time.Second)
+		select {
+		case <-sink.c:
+			sent = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !sent {
+		t.Fatal("follow-up not sent")
+	}
+	if got := clock.Now().Sub(travistest.DefaultStart); got < 90*time.Second {
+		t.Errorf("sent after %v, want at least 1m30s", got)
+	}
+	if err := <-errs; err == nil || err.Error() != "still broken" {
+		t.Errorf("OnError got %v, want the sink error", err)
+	}
+	// the follow-up was removed even though its sink failed
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Minute)
+		select {
+		case <-sink.c:
+			t.Error("follow-up sent again")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+	if got := sink.ids(); got != "[1]" {
+		t.Errorf("sent %s, want [1]", got)
+	}
+}
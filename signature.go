@@ -0,0 +1,75 @@
+package travis
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// DefaultHMACHeader is the header of HMAC signatures if Header isn't set,
+// the one Travis signs with
+const DefaultHMACHeader = "Signature"
+
+// SignatureScheme verifies the signature of webhook requests in place of
+// the Travis public key, e.g. HMAC for webhooks relayed by internal systems
+type SignatureScheme interface {
+	// Verify checks the signature of r against its raw payload, returning
+	// ErrUnauthorized if they don't match
+	Verify(r *http.Request, payload string) error
+}
+
+// HMAC is a SignatureScheme of a shared secret: the signature is the hex
+// HMAC-SHA256 of the raw payload, optionally prefixed with "sha256=" as
+// GitHub writes it
+type HMAC struct {
+	Secret []byte
+	// Header carries the signature, DefaultHMACHeader if empty
+	Header string
+}
+
+// Verify checks the HMAC of payload in the header of r
+func (s *HMAC) Verify(r *http.Request, payload string) error {
+	if len(s.Secret) == 0 {
+		return errors.New("missing HMAC secret")
+	}
+	header := s.header()
+	signature := r.Header.Get(header)
+	if signature == "" {
+		return errors.New("missing " + header + " header")
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return errors.New("cannot decode signature")
+	}
+	if !hmac.Equal(sig, s.sum([]byte(payload))) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// Sign returns the signature of payload, for the senders of the webhooks
+func (s *HMAC) Sign(payload []byte) string {
+	return hex.EncodeToString(s.sum(payload))
+}
+
+// SignRequest sets the header of the signature of payload on r, e.g. a
+// request of NewWebhookRequest
+func (s *HMAC) SignRequest(r *http.Request, payload []byte) {
+	r.Header.Set(s.header(), s.Sign(payload))
+}
+
+func (s *HMAC) header() string {
+	if s.Header == "" {
+		return DefaultHMACHeader
+	}
+	return s.Header
+}
+
+func (s *HMAC) sum(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
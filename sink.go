@@ -0,0 +1,32 @@
+package travis
+
+import "context"
+
+// Sink receives verified payloads, usually to forward them to another system
+type Sink interface {
+	Send(p *Payload) error
+}
+
+// ContextSink is a Sink whose sends can be canceled. A Dispatcher sends
+// through SendContext with the context of the webhook request when it
+// dispatches synchronously.
+type ContextSink interface {
+	Sink
+	SendContext(ctx context.Context, p *Payload) error
+}
+
+// SendContext sends p to s with ctx if s is a ContextSink, else with Send
+func SendContext(ctx context.Context, s Sink, p *Payload) error {
+	if cs, ok := s.(ContextSink); ok {
+		return cs.SendContext(ctx, p)
+	}
+	return s.Send(p)
+}
+
+// SinkFunc is an adapter to allow the use of ordinary functions as a Sink
+type SinkFunc func(p *Payload) error
+
+// Send calls f(p)
+func (f SinkFunc) Send(p *Payload) error {
+	return f(p)
+}
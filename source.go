@@ -0,0 +1,30 @@
+package travis
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrNoPayload is the error of a Handler whose PayloadSource returned
+// neither a payload nor an error
+var ErrNoPayload = errors.New("no payload")
+
+// PayloadSource returns the payload of a webhook request. Code depending on
+// it rather than on GetPayloadFromRequest can be given canned payloads in
+// tests, see travistest.Source.
+type PayloadSource interface {
+	Payload(r *http.Request) (*Payload, error)
+}
+
+// PayloadSourceFunc is an adapter to allow the use of ordinary functions as
+// a PayloadSource
+type PayloadSourceFunc func(r *http.Request) (*Payload, error)
+
+// Payload calls f(r)
+func (f PayloadSourceFunc) Payload(r *http.Request) (*Payload, error) {
+	return f(r)
+}
+
+// RequestSource is the PayloadSource verifying requests with the
+// travis-ci.org public key, as GetPayloadFromRequest
+var RequestSource PayloadSource = PayloadSourceFunc(GetPayloadFromRequest)
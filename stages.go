@@ -0,0 +1,153 @@
+package travis
+
+import "bytes"
+
+// DefaultStage is the stage of the jobs of the build matrix and of the
+// first include entries without a stage
+const DefaultStage = "test"
+
+// StageConfig is an entry of the stages key of a config
+type StageConfig struct {
+	Name string `json:"name"`
+	// If is the condition of the stage, e.g. "branch = main"
+	If string `json:"if,omitempty"`
+}
+
+// UnmarshalJSON decodes a stage given as its name or as a mapping
+func (s *StageConfig) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '{' {
+		type plain StageConfig
+		return decodeJSON(b, (*plain)(s))
+	}
+	name, err := scalar(b)
+	s.Name = name
+	return err
+}
+
+// Outcome reduces the state of j to one of the Outcome constants. Webhook
+// jobs are "finished" with a status of 0 when they passed and 1 when they
+// failed, the API ones are in their final state.
+func (j *Job) Outcome() string {
+	switch j.State {
+	case OutcomePassed, OutcomeFailed, OutcomeErrored, OutcomeCanceled:
+		return j.State
+	case "finished":
+		switch j.Status {
+		case 0:
+			return OutcomePassed
+		case 1:
+			return OutcomeFailed
+		}
+		return OutcomeErrored
+	}
+	return OutcomePending
+}
+
+// Stage is a stage of a build and its jobs
+type Stage struct {
+	Name string
+	Jobs []*Job
+}
+
+// Outcome reduces the states of the jobs of s not allowed to fail to one
+// of the Outcome constants: pending while one runs, then errored, failed
+// or canceled if one did, else passed
+func (s *Stage) Outcome() string {
+	var errored, failed, canceled bool
+	for _, j := range s.Jobs {
+		if j.AllowFailure {
+			continue
+		}
+		switch j.Outcome() {
+		case OutcomePassed:
+		case OutcomeErrored:
+			errored = true
+		case OutcomeFailed:
+			failed = true
+		case OutcomeCanceled:
+			canceled = true
+		default:
+			return OutcomePending
+		}
+	}
+	switch {
+	case errored:
+		return OutcomeErrored
+	case failed:
+		return OutcomeFailed
+	case canceled:
+		return OutcomeCanceled
+	}
+	return OutcomePassed
+}
+
+// Passed returns true if every job of s not allowed to fail passed
+func (s *Stage) Passed() bool {
+	return s.Outcome() == OutcomePassed
+}
+
+// Stages returns the stages of the jobs of p, in the order of the stages
+// key of its config, then of their first job. A job without a stage in
+// its config is in the stage of the previous job, DefaultStage for the
+// first.
+func Stages(p *Payload) []*Stage {
+	var stages []*Stage
+	byName := map[string]*Stage{}
+	if p.Config != nil {
+		for _, s := range p.Config.Stages {
+			if byName[s.Name] == nil {
+				byName[s.Name] = &Stage{Name: s.Name}
+				stages = append(stages, byName[s.Name])
+			}
+		}
+	}
+	current := DefaultStage
+	for _, j := range p.Matrix {
+		if j.Config != nil && j.Config.Stage != "" {
+			current = j.Config.Stage
+		}
+		s := byName[current]
+		if s == nil {
+			s = &Stage{Name: current}
+			byName[current] = s
+			stages = append(stages, s)
+		}
+		s.Jobs = append(s.Jobs, j)
+	}
+
+	// drop the stages without jobs, skipped by their condition
+	used := stages[:0]
+	for _, s := range stages {
+		if len(s.Jobs) > 0 {
+			used = append(used, s)
+		}
+	}
+	return used
+}
+
+// FailedStage returns the first stage of p that errored, failed or was
+// canceled, the one that stopped the build, nil if there is none
+func FailedStage(p *Payload) *Stage {
+	for _, s := range Stages(p) {
+		switch s.Outcome() {
+		case OutcomeErrored, OutcomeFailed, OutcomeCanceled:
+			return s
+		}
+	}
+	return nil
+}
+
+// StageStatus returns the status message of p followed by the stage that
+// stopped it when the build has several stages, e.g. "Broken in 'deploy'
+// stage"
+func StageStatus(p *Payload) string {
+	stages := Stages(p)
+	if len(stages) < 2 {
+		return p.StatusMessage
+	}
+	if s := FailedStage(p); s != nil {
+		return p.StatusMessage + " in '" + s.Name + "' stage"
+	}
+	return p.StatusMessage
+}
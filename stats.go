@@ -0,0 +1,191 @@
+package travis
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BuildStats are statistics over the latest finished builds of a repository
+// or branch
+type BuildStats struct {
+	Repo string
+	// Branch is empty for the statistics of a whole repository
+	Branch string
+	// Builds is the number of builds the statistics are computed over
+	Builds int
+	// SuccessRate is the ratio of passed builds, between 0 and 1
+	SuccessRate    float64
+	MeanDuration   time.Duration
+	MedianDuration time.Duration
+	P95Duration    time.Duration
+	// FailureStreak is the number of failed or errored builds since the
+	// last passed one
+	FailureStreak int
+	LastOutcome   string
+	LastBuild     time.Time
+}
+
+// DefaultStatsWindow is the number of builds Stats keeps per branch if
+// Window isn't set
+const DefaultStatsWindow = 50
+
+// Stats is a Sink keeping rolling statistics per repository and per branch.
+// Only passed, failed and errored builds are counted.
+type Stats struct {
+	// Window is the number of latest builds statistics are computed over
+	Window int
+	// MaxEntries is the number of repositories and branches kept,
+	// DefaultMaxEntries if zero, and MaxBytes an optional bound of their
+	// estimated size. The least recently built are evicted first.
+	MaxEntries int
+	MaxBytes   int64
+
+	mu      sync.Mutex
+	history lru[statsKey, *buildHistory]
+}
+
+type statsKey struct {
+	repo, branch string
+}
+
+type buildHistory struct {
+	outcomes  []string
+	durations []time.Duration
+	streak    int
+	last      time.Time
+}
+
+// Send records p if it is a finished build
+func (s *Stats) Send(p *Payload) error {
+	switch Outcome(p) {
+	case OutcomePassed, OutcomeFailed, OutcomeErrored:
+	default:
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history.Size = historySize
+	s.history.limit(s.MaxEntries, s.MaxBytes)
+	s.record(statsKey{p.Slug(), ""}, p)
+	s.record(statsKey{p.Slug(), p.Branch}, p)
+	return nil
+}
+
+func (s *Stats) record(k statsKey, p *Payload) {
+	window := s.Window
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+
+	h, _ := s.history.Get(k)
+	if h == nil {
+		h = new(buildHistory)
+	}
+
+	outcome := Outcome(p)
+	h.outcomes = append(h.outcomes, outcome)
+	h.durations = append(h.durations, time.Duration(p.Duration)*time.Second)
+	if len(h.outcomes) > window {
+		h.outcomes = h.outcomes[len(h.outcomes)-window:]
+		h.durations = h.durations[len(h.durations)-window:]
+	}
+	if outcome == OutcomePassed {
+		h.streak = 0
+	} else {
+		h.streak++
+	}
+	h.last = p.FinishedAt
+	s.history.Put(k, h)
+}
+
+// historySize estimates the memory taken by the history of k
+func historySize(k statsKey, h *buildHistory) int {
+	return 64 + len(k.repo) + len(k.branch) + cap(h.outcomes)*16 + cap(h.durations)*8
+}
+
+// Repo returns the statistics of all branches of repo
+func (s *Stats) Repo(repo string) (BuildStats, bool) {
+	return s.Branch(repo, "")
+}
+
+// Branch returns the statistics of branch in repo
+func (s *Stats) Branch(repo, branch string) (BuildStats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.history.Peek(statsKey{repo, branch})
+	if !ok {
+		return BuildStats{}, false
+	}
+	return h.stats(repo, branch), true
+}
+
+// All returns the statistics of every repository and branch seen so far,
+// sorted by repository then branch
+func (s *Stats) All() []BuildStats {
+	s.mu.Lock()
+	all := make([]BuildStats, 0, s.history.Len())
+	s.history.Each(func(k statsKey, h *buildHistory) {
+		all = append(all, h.stats(k.repo, k.branch))
+	})
+	s.mu.Unlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Repo != all[j].Repo {
+			return all[i].Repo < all[j].Repo
+		}
+		return all[i].Branch < all[j].Branch
+	})
+	return all
+}
+
+// Memory returns the number of repositories and branches tracked, their
+// estimated size and the number evicted
+func (s *Stats) Memory() MemoryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.history.stats()
+}
+
+func (h *buildHistory) stats(repo, branch string) BuildStats {
+	bs := BuildStats{
+		Repo:          repo,
+		Branch:        branch,
+		Builds:        len(h.outcomes),
+		FailureStreak: h.streak,
+		LastBuild:     h.last,
+	}
+	if len(h.outcomes) == 0 {
+		return bs
+	}
+	bs.LastOutcome = h.outcomes[len(h.outcomes)-1]
+
+	passed := 0
+	for _, o := range h.outcomes {
+		if o == OutcomePassed {
+			passed++
+		}
+	}
+	bs.SuccessRate = float64(passed) / float64(len(h.outcomes))
+
+	sorted := append([]time.Duration(nil), h.durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	bs.MeanDuration = total / time.Duration(len(sorted))
+	bs.MedianDuration = percentile(sorted, 50)
+	bs.P95Duration = percentile(sorted, 95)
+	return bs
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
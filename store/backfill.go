@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// backfillPageSize is the number of builds requested per API call
+const backfillPageSize = 100
+
+// Backfill saves the builds of the repository slug that started since
+// since, fetched from the Travis API newest first, into s. It returns the
+// number of builds saved. Builds already in the store are replaced with
+// the API version, which lacks a few fields like author emails.
+func Backfill(ctx context.Context, s Store, client *travis.Client, slug string, since time.Time) (int, error) {
+	saved := 0
+	offset := 0
+	for {
+		builds, page, err := client.Builds(ctx, slug, travis.BuildsOptions{
+			Limit:  backfillPageSize,
+			Offset: offset,
+			SortBy: "id:desc",
+		})
+		if err != nil {
+			return saved, err
+		}
+		for _, b := range builds {
+			if !b.StartedAt.IsZero() && b.StartedAt.Before(since) {
+				return saved, nil
+			}
+			if err := s.Save(ctx, b.Payload()); err != nil {
+				return saved, err
+			}
+			saved++
+		}
+		if page.IsLast || len(builds) == 0 {
+			return saved, nil
+		}
+		offset += len(builds)
+	}
+}
@@ -0,0 +1,500 @@
+// Package boltstore is a store.Store kept in a single bbolt file, for
+// receivers that can't depend on a database server.
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	buildsBucket = []byte("builds")
+	// repoBucket indexes builds by repo + 0 + id
+	repoBucket = []byte("repo")
+	// latestBucket maps repo + 0 + branch to the id of its latest build
+	latestBucket = []byte("latest")
+	// outboxBucket maps the ids of actions to their JSON
+	outboxBucket = []byte("outbox")
+	// followUpsBucket maps the ids of the follow-ups of a travis.Scheduler
+	// to their JSON
+	followUpsBucket = []byte("followups")
+)
+
+// Options configure a Store
+type Options struct {
+	// Retention is the policy of Prune
+	Retention store.Retention
+	// Timeout is how long Open waits for the file lock, forever if zero
+	Timeout time.Duration
+}
+
+// Store is a store.Store, a store.Outbox and a travis.FollowUpStore backed
+// by bbolt
+type Store struct {
+	db   *bolt.DB
+	opts Options
+}
+
+var (
+	_ store.Outbox         = (*Store)(nil)
+	_ travis.FollowUpStore = (*Store)(nil)
+)
+
+// Open opens or creates the store at path
+func Open(path string, opts *Options) (*Store, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: opts.Timeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{buildsBucket, repoBucket, latestBucket, outboxBucket, followUpsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, opts: *opts}, nil
+}
+
+// Save inserts or replaces p
+func (s *Store) Save(ctx context.Context, p *travis.Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return saveBuild(tx, p, body)
+	})
+}
+
+func saveBuild(tx *bolt.Tx, p *travis.Payload, body []byte) error {
+	id := itob(p.ID)
+	if err := tx.Bucket(buildsBucket).Put(id, body); err != nil {
+		return err
+	}
+	if err := tx.Bucket(repoBucket).Put(join(p.Slug(), id), nil); err != nil {
+		return err
+	}
+	if p.IsPullRequest() {
+		return nil
+	}
+	latest := tx.Bucket(latestBucket)
+	key := join(p.Slug(), []byte(p.Branch))
+	if cur := latest.Get(key); cur != nil && bytes.Compare(cur, id) > 0 {
+		return nil
+	}
+	return latest.Put(key, id)
+}
+
+// Build returns the build with id
+func (s *Store) Build(ctx context.Context, id int64) (*travis.Payload, error) {
+	var p *travis.Payload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		body := tx.Bucket(buildsBucket).Get(itob(id))
+		if body == nil {
+			return store.ErrNotFound
+		}
+		var err error
+		p, err = decode(body)
+		return err
+	})
+	return p, err
+}
+
+// Builds returns the builds matching q, newest first. Only the repository
+// is indexed, the other fields are matched by scanning.
+func (s *Store) Builds(ctx context.Context, q store.Query) ([]*travis.Payload, error) {
+	var builds []*travis.Payload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		payloads := tx.Bucket(buildsBucket)
+		match := func(body []byte) (bool, error) {
+			p, err := decode(body)
+			if err != nil {
+				return false, err
+			}
+			if !matches(p, q) {
+				return true, nil
+			}
+			builds = append(builds, p)
+			return q.Limit <= 0 || len(builds) < q.Limit, nil
+		}
+
+		if q.Repo == "" {
+			c := payloads.Cursor()
+			for k, v := c.Last(); k != nil; k, v = c.Prev() {
+				if more, err := match(v); err != nil || !more {
+					return err
+				}
+			}
+			return nil
+		}
+
+		prefix := join(q.Repo, nil)
+		c := tx.Bucket(repoBucket).Cursor()
+		// seek past the last id of the repository then walk backwards
+		k, _ := c.Seek(join(q.Repo, itob(-1)))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Prev() {
+			body := payloads.Get(k[len(prefix):])
+			if body == nil {
+				continue
+			}
+			if more, err := match(body); err != nil || !more {
+				return err
+			}
+		}
+		return nil
+	})
+	return builds, err
+}
+
+// LatestPerBranch returns the latest push, cron or api build of each branch of repo
+func (s *Store) LatestPerBranch(ctx context.Context, repo string) ([]*travis.Payload, error) {
+	var builds []*travis.Payload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		payloads := tx.Bucket(buildsBucket)
+		prefix := join(repo, nil)
+		c := tx.Bucket(latestBucket).Cursor()
+		for k, id := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, id = c.Next() {
+			body := payloads.Get(id)
+			if body == nil {
+				continue
+			}
+			p, err := decode(body)
+			if err != nil {
+				return err
+			}
+			builds = append(builds, p)
+		}
+		return nil
+	})
+	return builds, err
+}
+
+// Latest returns the latest push, cron or api build of branch in repo
+func (s *Store) Latest(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+	var p *travis.Payload
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(latestBucket).Get(join(repo, []byte(branch)))
+		if id == nil {
+			return store.ErrNotFound
+		}
+		body := tx.Bucket(buildsBucket).Get(id)
+		if body == nil {
+			return store.ErrNotFound
+		}
+		var err error
+		p, err = decode(body)
+		return err
+	})
+	return p, err
+}
+
+// Prune deletes the builds expired according to the Retention option
+func (s *Store) Prune(ctx context.Context) error {
+	if s.opts.Retention.IsZero() {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var all []*travis.Payload
+		err := tx.Bucket(buildsBucket).ForEach(func(k, v []byte) error {
+			p, err := decode(v)
+			if err != nil {
+				return err
+			}
+			all = append(all, p)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, p := range s.opts.Retention.Expired(all, time.Now()) {
+			if err := deleteBuild(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Record saves p and inserts actions in a transaction
+func (s *Store) Record(ctx context.Context, p *travis.Payload, actions []store.Action) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := saveBuild(tx, p, body); err != nil {
+			return err
+		}
+		for _, a := range actions {
+			if err := putAction(tx, &a); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Claim returns up to n actions due at now, the outbox is scanned
+func (s *Store) Claim(ctx context.Context, now time.Time, n int, lease time.Duration) ([]store.Action, error) {
+	var claimed []store.Action
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var due []store.Action
+		err := tx.Bucket(outboxBucket).ForEach(func(k, v []byte) error {
+			var a store.Action
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			if !a.Failed && !a.Due.After(now) {
+				due = append(due, a)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(due, func(i, j int) bool {
+			if !due[i].Due.Equal(due[j].Due) {
+				return due[i].Due.Before(due[j].Due)
+			}
+			return due[i].ID < due[j].ID
+		})
+		if len(due) > n {
+			due = due[:n]
+		}
+		for _, a := range due {
+			a.Attempts++
+			a.Due = now.Add(lease)
+			if err := putAction(tx, &a); err != nil {
+				return err
+			}
+			claimed = append(claimed, a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// Complete deletes the action with id
+func (s *Store) Complete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete([]byte(id))
+	})
+}
+
+// Retry makes the action with id due again at due
+func (s *Store) Retry(ctx context.Context, id string, due time.Time, err error) error {
+	return s.updateAction(id, func(a *store.Action) {
+		a.Due = due
+		a.Error = errorText(err)
+	})
+}
+
+// Fail gives up the action with id
+func (s *Store) Fail(ctx context.Context, id string, err error) error {
+	return s.updateAction(id, func(a *store.Action) {
+		a.Failed = true
+		a.Error = errorText(err)
+	})
+}
+
+// Failed returns the actions given up
+func (s *Store) Failed(ctx context.Context) ([]store.Action, error) {
+	var failed []store.Action
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(k, v []byte) error {
+			var a store.Action
+			if err := json.Unmarshal(v, &a); err != nil {
+				return err
+			}
+			if a.Failed {
+				failed = append(failed, a)
+			}
+			return nil
+		})
+	})
+	return failed, err
+}
+
+// AddFollowUp inserts f
+func (s *Store) AddFollowUp(ctx context.Context, f *travis.FollowUp) error {
+	body, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(followUpsBucket).Put([]byte(f.ID), body)
+	})
+}
+
+// DueFollowUps returns the follow-ups due at now, the earliest first, the
+// follow-ups are scanned
+func (s *Store) DueFollowUps(ctx context.Context, now time.Time) ([]*travis.FollowUp, error) {
+	var due []*travis.FollowUp
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(followUpsBucket).ForEach(func(k, v []byte) error {
+			f := new(travis.FollowUp)
+			if err := json.Unmarshal(v, f); err != nil {
+				return err
+			}
+			if !f.At.After(now) {
+				due = append(due, f)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].At.Before(due[j].At) })
+	return due, nil
+}
+
+// RemoveFollowUp deletes the follow-up with id
+func (s *Store) RemoveFollowUp(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(followUpsBucket).Delete([]byte(id))
+	})
+}
+
+// updateAction applies f to the action with id, if it is still in the outbox
+func (s *Store) updateAction(id string, f func(a *store.Action)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v := tx.Bucket(outboxBucket).Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		var a store.Action
+		if err := json.Unmarshal(v, &a); err != nil {
+			return err
+		}
+		f(&a)
+		return putAction(tx, &a)
+	})
+}
+
+func putAction(tx *bolt.Tx, a *store.Action) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(outboxBucket).Put([]byte(a.ID), body)
+}
+
+// Export writes every build to w in format f
+func (s *Store) Export(ctx context.Context, w io.Writer, f store.Format) error {
+	builds, err := s.Builds(ctx, store.Query{})
+	if err != nil {
+		return err
+	}
+	return store.WriteBuilds(w, f, builds)
+}
+
+// Compact writes a compacted copy of the store to path, bbolt files never
+// shrink by themselves after builds are pruned
+func (s *Store) Compact(path string) error {
+	dst, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, s.db, 64<<20); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Close closes the file
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func deleteBuild(tx *bolt.Tx, p *travis.Payload) error {
+	id := itob(p.ID)
+	if err := tx.Bucket(buildsBucket).Delete(id); err != nil {
+		return err
+	}
+	if err := tx.Bucket(repoBucket).Delete(join(p.Slug(), id)); err != nil {
+		return err
+	}
+	latest := tx.Bucket(latestBucket)
+	key := join(p.Slug(), []byte(p.Branch))
+	if bytes.Equal(latest.Get(key), id) {
+		return latest.Delete(key)
+	}
+	return nil
+}
+
+func matches(p *travis.Payload, q store.Query) bool {
+	if q.Repo != "" && p.Slug() != q.Repo {
+		return false
+	}
+	if q.Branch != "" && p.Branch != q.Branch {
+		return false
+	}
+	if q.Type != "" && p.Type != q.Type {
+		return false
+	}
+	t := store.BuildTime(p)
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func decode(body []byte) (*travis.Payload, error) {
+	p := new(travis.Payload)
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// itob encodes id so that keys sort like ids, build ids are positive
+func itob(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func join(prefix string, suffix []byte) []byte {
+	k := make([]byte, 0, len(prefix)+1+len(suffix))
+	k = append(k, prefix...)
+	k = append(k, 0)
+	return append(k, suffix...)
+}
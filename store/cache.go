@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jacksgt/travis"
+)
+
+// Cache is a Store keeping the latest build of every branch in memory in
+// front of another Store, so Latest doesn't hit the database. Save the
+// payloads through the Cache, e.g. with Sink(cache), to keep it up to date.
+type Cache struct {
+	Store
+
+	mu     sync.RWMutex
+	latest map[branchKey]*travis.Payload
+}
+
+type branchKey struct {
+	repo, branch string
+}
+
+// Cached returns a Cache in front of s
+func Cached(s Store) *Cache {
+	return &Cache{Store: s, latest: make(map[branchKey]*travis.Payload)}
+}
+
+// Save saves p to the underlying store and updates the cache
+func (c *Cache) Save(ctx context.Context, p *travis.Payload) error {
+	if err := c.Store.Save(ctx, p); err != nil {
+		return err
+	}
+	if !p.IsPullRequest() {
+		c.update(p)
+	}
+	return nil
+}
+
+// Latest returns the latest push, cron or api build of branch in repo,
+// loading it from the underlying store if it isn't cached yet
+func (c *Cache) Latest(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+	c.mu.RLock()
+	p, ok := c.latest[branchKey{repo, branch}]
+	c.mu.RUnlock()
+	if ok {
+		return p, nil
+	}
+
+	p, err := c.Store.Latest(ctx, repo, branch)
+	if err != nil {
+		return nil, err
+	}
+	c.update(p)
+	return p, nil
+}
+
+// update caches p unless a newer build of its branch is cached
+func (c *Cache) update(p *travis.Payload) {
+	k := branchKey{p.Slug(), p.Branch}
+	c.mu.Lock()
+	if cur, ok := c.latest[k]; !ok || cur.ID <= p.ID {
+		c.latest[k] = p
+	}
+	c.mu.Unlock()
+}
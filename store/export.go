@@ -0,0 +1,107 @@
+package store
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format of exported build records
+type Format int
+
+const (
+	// CSV writes a header line then one line per build
+	CSV Format = iota
+	// Parquet writes a parquet file with the Record schema
+	Parquet
+)
+
+// Record is the flat row of an exported build. Times are in unix
+// microseconds so every analytics tool reads them the same way.
+type Record struct {
+	ID          int64  `parquet:"id"`
+	Repo        string `parquet:"repo,dict"`
+	Branch      string `parquet:"branch,dict"`
+	Number      string `parquet:"number"`
+	Type        string `parquet:"type,dict"`
+	State       string `parquet:"state,dict"`
+	Commit      string `parquet:"commit"`
+	AuthorEmail string `parquet:"author_email,dict"`
+	PullRequest int64  `parquet:"pull_request_number"`
+	Tag         string `parquet:"tag"`
+	StartedAt   int64  `parquet:"started_at,timestamp(microsecond)"`
+	FinishedAt  int64  `parquet:"finished_at,timestamp(microsecond)"`
+	Duration    int64  `parquet:"duration_seconds"`
+	BuildURL    string `parquet:"build_url"`
+}
+
+// NewRecord returns the exported row of p
+func NewRecord(p *travis.Payload) Record {
+	return Record{
+		ID:          p.ID,
+		Repo:        p.Slug(),
+		Branch:      p.Branch,
+		Number:      p.Number,
+		Type:        p.Type,
+		State:       travis.Outcome(p),
+		Commit:      p.Commit,
+		AuthorEmail: p.AuthorEmail,
+		PullRequest: int64(p.PullRequestNumber),
+		Tag:         p.Tag,
+		StartedAt:   micros(p.StartedAt),
+		FinishedAt:  micros(p.FinishedAt),
+		Duration:    int64(p.Duration),
+		BuildURL:    p.BuildURL,
+	}
+}
+
+var csvHeader = []string{
+	"id", "repo", "branch", "number", "type", "state", "commit", "author_email",
+	"pull_request_number", "tag", "started_at", "finished_at", "duration_seconds", "build_url",
+}
+
+// WriteBuilds writes builds to w in format f
+func WriteBuilds(w io.Writer, f Format, builds []*travis.Payload) error {
+	records := make([]Record, len(builds))
+	for i, p := range builds {
+		records[i] = NewRecord(p)
+	}
+
+	switch f {
+	case CSV:
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		for _, r := range records {
+			cw.Write([]string{
+				strconv.FormatInt(r.ID, 10), r.Repo, r.Branch, r.Number, r.Type, r.State,
+				r.Commit, r.AuthorEmail, strconv.FormatInt(r.PullRequest, 10), r.Tag,
+				formatMicros(r.StartedAt), formatMicros(r.FinishedAt),
+				strconv.FormatInt(r.Duration, 10), r.BuildURL,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	case Parquet:
+		return parquet.Write(w, records)
+	}
+	return fmt.Errorf("unknown export format %d", f)
+}
+
+func micros(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMicro()
+}
+
+func formatMicros(us int64) string {
+	if us == 0 {
+		return ""
+	}
+	return time.UnixMicro(us).UTC().Format(time.RFC3339)
+}
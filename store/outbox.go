@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+const (
+	// DefaultRelayInterval is how often a Relay polls its Outbox if
+	// Interval isn't set
+	DefaultRelayInterval = 5 * time.Second
+	// DefaultRelayLease is how long a Relay holds the actions it claims if
+	// Lease isn't set
+	DefaultRelayLease = 5 * time.Minute
+	// DefaultRelayBackoff is the delay before the first retry of an action
+	// if Backoff isn't set
+	DefaultRelayBackoff = 10 * time.Second
+	// DefaultRelayAttempts is the number of attempts of an action if
+	// MaxAttempts isn't set
+	DefaultRelayAttempts = 10
+	// DefaultRelayBatch is the number of actions claimed at once if Batch
+	// isn't set
+	DefaultRelayBatch = 10
+)
+
+// maxRelayBackoff caps the doubling of the backoff of a Relay
+const maxRelayBackoff = time.Hour
+
+// Action is a side effect of a build recorded in an Outbox: sending
+// Payload to the sink of a Relay named Sink
+type Action struct {
+	// ID identifies the action, sinks can use it as an idempotency key, see
+	// ActionFromContext
+	ID      string          `json:"id"`
+	Sink    string          `json:"sink"`
+	Payload *travis.Payload `json:"payload"`
+	// Attempts is the number of times the action was claimed
+	Attempts int `json:"attempts"`
+	// Due is when the action runs next, at once if zero
+	Due time.Time `json:"due"`
+	// Error is the error of the last attempt
+	Error string `json:"error,omitempty"`
+	// Failed is set once the action was given up
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Outbox is a Store recording the actions on a build in the transaction
+// saving it, so that none is lost when the receiver stops between
+// receiving a webhook and acting on it. SQL and boltstore.Store are
+// outboxes.
+type Outbox interface {
+	Store
+	// Record saves p and inserts actions, all or none
+	Record(ctx context.Context, p *travis.Payload, actions []Action) error
+	// Claim returns up to n actions due at now, oldest first, after
+	// counting the attempt and moving Due to now+lease so they aren't
+	// claimed again before the lease expires
+	Claim(ctx context.Context, now time.Time, n int, lease time.Duration) ([]Action, error)
+	// Complete deletes the action with id once it ran
+	Complete(ctx context.Context, id string) error
+	// Retry makes the action with id due again at due, after it failed with err
+	Retry(ctx context.Context, id string, due time.Time, err error) error
+	// Fail gives up the action with id after it failed with err, it is
+	// kept for Failed
+	Fail(ctx context.Context, id string, err error) error
+	// Failed returns the actions given up
+	Failed(ctx context.Context) ([]Action, error)
+}
+
+// Relay runs the side effects of builds from an Outbox. The Sink of the
+// Relay records each payload with an action per sink of Sinks, instead of
+// sending it, and Run sends the payloads of the due actions, retrying the
+// failures with a backoff doubled after each attempt.
+//
+// An action is completed after its sink returns, so it runs at least once:
+// it runs again if the receiver stops in between. Sinks with side effects
+// that must not repeat can skip the actions they already ran by their ID,
+// see ActionFromContext.
+type Relay struct {
+	Outbox Outbox
+	// Sinks run the actions by name
+	Sinks map[string]travis.Sink
+
+	// Interval is how often the outbox is polled, DefaultRelayInterval if
+	// zero
+	Interval time.Duration
+	// Lease is how long an action may run before it is claimed again,
+	// DefaultRelayLease if zero
+	Lease time.Duration
+	// Backoff is the delay before the first retry of an action,
+	// DefaultRelayBackoff if zero, doubled up to an hour after each attempt
+	Backoff time.Duration
+	// MaxAttempts is the number of attempts before an action is given up,
+	// DefaultRelayAttempts if zero
+	MaxAttempts int
+	// Batch is the number of actions claimed at once, DefaultRelayBatch
+	// if zero
+	Batch int
+
+	// OnError, if set, is called with the errors of the actions and of the
+	// outbox, a is nil for the latter
+	OnError func(a *Action, err error)
+
+	// Clock tells the time, travis.SystemClock if nil
+	Clock travis.Clock
+}
+
+type actionKey struct{}
+
+// ActionFromContext returns the action a sink is run for by a Relay
+func ActionFromContext(ctx context.Context) (*Action, bool) {
+	a, ok := ctx.Value(actionKey{}).(*Action)
+	return a, ok
+}
+
+// Sink returns a travis.Sink recording each payload in the outbox with an
+// action per sink of Sinks
+func (r *Relay) Sink() travis.Sink {
+	return relaySink{r}
+}
+
+type relaySink struct {
+	r *Relay
+}
+
+func (s relaySink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+func (s relaySink) SendContext(ctx context.Context, p *travis.Payload) error {
+	names := make([]string, 0, len(s.r.Sinks))
+	for name := range s.r.Sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	actions := make([]Action, len(names))
+	for i, name := range names {
+		actions[i] = Action{ID: travis.NewID(), Sink: name, Payload: p}
+	}
+	return s.r.Outbox.Record(ctx, p, actions)
+}
+
+// Run polls the outbox every Interval and runs the due actions until ctx
+// is done
+func (r *Relay) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultRelayInterval
+	}
+	ticker := r.clock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			// Poll already reported the sink errors
+			n, err := r.Poll(ctx)
+			if err != nil {
+				r.report(nil, err)
+			}
+			if err != nil || n < r.batch() {
+				break
+			}
+		}
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Poll claims a batch of due actions and runs them, it returns the number
+// of actions claimed and the errors of the outbox. The errors of the sinks
+// aren't returned, they are recorded with the actions and reported to
+// OnError.
+func (r *Relay) Poll(ctx context.Context) (int, error) {
+	lease := r.Lease
+	if lease <= 0 {
+		lease = DefaultRelayLease
+	}
+	actions, err := r.Outbox.Claim(ctx, r.clock().Now(), r.batch(), lease)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	for i := range actions {
+		if err := r.run(ctx, &actions[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(actions), errors.Join(errs...)
+}
+
+// run sends the payload of a and records the outcome in the outbox, it
+// reports the error of the sink and returns the one of the outbox
+func (r *Relay) run(ctx context.Context, a *Action) error {
+	s, ok := r.Sinks[a.Sink]
+	if !ok {
+		err := fmt.Errorf("outbox: no sink %q", a.Sink)
+		r.report(a, err)
+		return r.Outbox.Fail(ctx, a.ID, err)
+	}
+	err := travis.SendContext(context.WithValue(ctx, actionKey{}, a), s, a.Payload)
+	if err == nil {
+		return r.Outbox.Complete(ctx, a.ID)
+	}
+	r.report(a, err)
+	max := r.MaxAttempts
+	if max <= 0 {
+		max = DefaultRelayAttempts
+	}
+	if a.Attempts >= max {
+		return r.Outbox.Fail(ctx, a.ID, err)
+	}
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRelayBackoff
+	}
+	for i := 1; i < a.Attempts && backoff < maxRelayBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRelayBackoff {
+		backoff = maxRelayBackoff
+	}
+	return r.Outbox.Retry(ctx, a.ID, r.clock().Now().Add(backoff), err)
+}
+
+func (r *Relay) report(a *Action, err error) {
+	if r.OnError != nil {
+		r.OnError(a, err)
+	}
+}
+
+func (r *Relay) batch() int {
+	if r.Batch <= 0 {
+		return DefaultRelayBatch
+	}
+	return r.Batch
+}
+
+func (r *Relay) clock() travis.Clock {
+	if r.Clock == nil {
+		return travis.SystemClock
+	}
+	return r.Clock
+}
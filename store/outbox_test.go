@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// memOutbox is an Outbox of one action, its Store methods are unused
+type memOutbox struct {
+	Store
+	action  *Action
+	retried int
+	failed  bool
+	err     error
+}
+
+func (o *memOutbox) Record(ctx context.Context, p *travis.Payload, actions []Action) error {
+	return nil
+}
+
+func (o *memOutbox) Claim(ctx context.Context, now time.Time, n int, lease time.Duration) ([]Action, error) {
+	if o.action == nil || o.failed || o.action.Due.After(now) {
+		return nil, o.err
+	}
+	o.action.Attempts++
+	return []Action{*o.action}, o.err
+}
+
+func (o *memOutbox) Complete(ctx context.Context, id string) error {
+	o.action = nil
+	return o.err
+}
+
+func (o *memOutbox) Retry(ctx context.Context, id string, due time.Time, err error) error {
+	o.retried++
+	o.action.Due = due
+	return o.err
+}
+
+func (o *memOutbox) Fail(ctx context.Context, id string, err error) error {
+	o.failed = true
+	return o.err
+}
+
+func (o *memOutbox) Failed(ctx context.Context) ([]Action, error) {
+	return nil, nil
+}
+
+func TestRelayPoll(t *testing.T) {
+	sinkErr := errors.New("sink down")
+	outboxErr := errors.New("outbox down")
+	tests := []struct {
+		name      string
+		sink      error
+		outbox    error
+		attempts  int
+		wantErr   error
+		reports   int
+		retried   int
+		failed    bool
+		completed bool
+	}{
+		{"sent", nil, nil, 0, nil, 0, 0, false, true},
+		{"sink error retried", sinkErr, nil, 0, nil, 1, 1, false, false},
+		{"sink error given up", sinkErr, nil, DefaultRelayAttempts - 1, nil, 1, 0, true, false},
+		{"outbox error", sinkErr, outboxErr, 0, outboxErr, 1, 1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &memOutbox{action: &Action{ID: "a", Sink: "s", Attempts: tt.attempts}}
+			var reports []*Action
+			r := &Relay{
+				Outbox: o,
+				Sinks: map[string]travis.Sink{"s": travis.SinkFunc(func(p *travis.Payload) error {
+					o.err = tt.outbox
+					return tt.sink
+				})},
+				OnError: func(a *Action, err error) {
+					if a == nil || err != tt.sink {
+						t.Errorf("OnError(%v, %v), want the action and its sink error", a, err)
+					}
+					reports = append(reports, a)
+				},
+			}
+			n, err := r.Poll(context.Background())
+			if n != 1 || !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("Poll() = %d, %v, want 1, %v", n, err, tt.wantErr)
+			}
+			if len(reports) != tt.reports || o.retried != tt.retried || o.failed != tt.failed || (o.action == nil) != tt.completed {
+				t.Errorf("reports %d, retried %d, failed %v, completed %v", len(reports), o.retried, o.failed, o.action == nil)
+			}
+		})
+	}
+}
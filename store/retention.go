@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// Retention is the policy deciding which builds Prune deletes. A build is
+// deleted when it is older than MaxAge or not among the MaxPerRepo latest
+// builds of its repository, unless it is among the KeepPerBranch latest
+// builds of its branch. Zero fields don't apply.
+type Retention struct {
+	MaxAge        time.Duration
+	MaxPerRepo    int
+	KeepPerBranch int
+}
+
+// IsZero returns true if r never deletes anything
+func (r Retention) IsZero() bool {
+	return r.MaxAge <= 0 && r.MaxPerRepo <= 0
+}
+
+// Expired returns the builds r deletes, it is meant for stores that can't
+// select them in their query language
+func (r Retention) Expired(builds []*travis.Payload, now time.Time) []*travis.Payload {
+	if r.IsZero() {
+		return nil
+	}
+	sorted := append([]*travis.Payload(nil), builds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID > sorted[j].ID })
+
+	cutoff := now.Add(-r.MaxAge)
+	perRepo := make(map[string]int)
+	perBranch := make(map[branchKey]int)
+	var expired []*travis.Payload
+	for _, p := range sorted {
+		perRepo[p.Slug()]++
+		k := branchKey{p.Slug(), p.Branch}
+		perBranch[k]++
+		if r.KeepPerBranch > 0 && perBranch[k] <= r.KeepPerBranch {
+			continue
+		}
+		t := BuildTime(p)
+		old := r.MaxAge > 0 && !t.IsZero() && t.Before(cutoff)
+		over := r.MaxPerRepo > 0 && perRepo[p.Slug()] > r.MaxPerRepo
+		if old || over {
+			expired = append(expired, p)
+		}
+	}
+	return expired
+}
+
+// Pruner prunes a store periodically
+type Pruner struct {
+	Store    Store
+	Interval time.Duration
+	// OnError, if set, is called with the errors of Prune
+	OnError func(err error)
+}
+
+// Run prunes the store every Interval until ctx is done
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Store.Prune(ctx); err != nil && p.OnError != nil {
+				p.OnError(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
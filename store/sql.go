@@ -0,0 +1,454 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// SQL is a Store and an Outbox backed by a database/sql database, SQLite or
+// Postgres. The driver has to be imported by the program. Times are stored
+// as unix seconds so queries behave the same with every driver.
+type SQL struct {
+	DB *sql.DB
+	// Table is the name of the table, "travis_builds" if empty. The actions
+	// of the outbox are in the table of the same name suffixed with _outbox,
+	// and the follow-ups of a travis.Scheduler suffixed with _followups.
+	Table string
+	// Postgres switches the query placeholders from ? to $n
+	Postgres bool
+	// Retention is the policy of Prune
+	Retention Retention
+}
+
+var (
+	_ Outbox               = (*SQL)(nil)
+	_ travis.FollowUpStore = (*SQL)(nil)
+)
+
+func (s *SQL) table() string {
+	if s.Table == "" {
+		return "travis_builds"
+	}
+	return s.Table
+}
+
+// placeholder returns the placeholder of the nth argument, starting at 1
+func (s *SQL) placeholder(n int) string {
+	if s.Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (s *SQL) outboxTable() string {
+	return s.table() + "_outbox"
+}
+
+func (s *SQL) followUpsTable() string {
+	return s.table() + "_followups"
+}
+
+// CreateTable creates the tables and their indexes if they don't exist
+func (s *SQL) CreateTable(ctx context.Context) error {
+	t := s.table()
+	o := s.outboxTable()
+	f := s.followUpsTable()
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS ` + t + ` (
+	id BIGINT PRIMARY KEY,
+	repo TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	type TEXT NOT NULL,
+	state TEXT NOT NULL,
+	build_time BIGINT NOT NULL,
+	payload TEXT NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + t + `_repo_branch ON ` + t + ` (repo, branch, id)`,
+		`CREATE INDEX IF NOT EXISTS ` + t + `_build_time ON ` + t + ` (build_time)`,
+		`CREATE TABLE IF NOT EXISTS ` + o + ` (
+	id TEXT PRIMARY KEY,
+	sink TEXT NOT NULL,
+	build_id BIGINT NOT NULL,
+	payload TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	due BIGINT NOT NULL,
+	error TEXT NOT NULL,
+	failed INTEGER NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + o + `_due ON ` + o + ` (failed, due)`,
+		`CREATE TABLE IF NOT EXISTS ` + f + ` (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	due BIGINT NOT NULL,
+	payload TEXT NOT NULL
+)`,
+		`CREATE INDEX IF NOT EXISTS ` + f + `_due ON ` + f + ` (due)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := s.DB.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save inserts or replaces p
+func (s *SQL) Save(ctx context.Context, p *travis.Payload) error {
+	return s.save(ctx, s.DB, p)
+}
+
+// execer is a *sql.DB or a *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (s *SQL) save(ctx context.Context, db execer, p *travis.Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var ph []interface{}
+	for i := 1; i <= 7; i++ {
+		ph = append(ph, s.placeholder(i))
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (id, repo, branch, type, state, build_time, payload) VALUES (%s, %s, %s, %s, %s, %s, %s)
+ON CONFLICT (id) DO UPDATE SET repo = excluded.repo, branch = excluded.branch, type = excluded.type,
+state = excluded.state, build_time = excluded.build_time, payload = excluded.payload`,
+		append([]interface{}{s.table()}, ph...)...)
+	_, err = db.ExecContext(ctx, query, p.ID, p.Slug(), p.Branch, p.Type, travis.Outcome(p), unix(BuildTime(p)), string(body))
+	return err
+}
+
+// Build returns the build with id
+func (s *SQL) Build(ctx context.Context, id int64) (*travis.Payload, error) {
+	query := fmt.Sprintf("SELECT payload FROM %s WHERE id = %s", s.table(), s.placeholder(1))
+	var body string
+	err := s.DB.QueryRowContext(ctx, query, id).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(body)
+}
+
+// Builds returns the builds matching q, newest first
+func (s *SQL) Builds(ctx context.Context, q Query) ([]*travis.Payload, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, cond+" "+s.placeholder(len(args)))
+	}
+	if q.Repo != "" {
+		add("repo =", q.Repo)
+	}
+	if q.Branch != "" {
+		add("branch =", q.Branch)
+	}
+	if q.Type != "" {
+		add("type =", q.Type)
+	}
+	if !q.Since.IsZero() {
+		add("build_time >=", unix(q.Since))
+	}
+	if !q.Until.IsZero() {
+		add("build_time <", unix(q.Until))
+	}
+
+	query := "SELECT payload FROM " + s.table()
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	return s.query(ctx, query, args...)
+}
+
+// LatestPerBranch returns the latest push, cron or api build of each branch of repo
+func (s *SQL) LatestPerBranch(ctx context.Context, repo string) ([]*travis.Payload, error) {
+	t := s.table()
+	query := fmt.Sprintf(`SELECT payload FROM %s WHERE id IN (
+	SELECT MAX(id) FROM %s WHERE repo = %s AND type <> 'pull_request' GROUP BY branch
+) ORDER BY branch`, t, t, s.placeholder(1))
+	return s.query(ctx, query, repo)
+}
+
+// Latest returns the latest push, cron or api build of branch in repo
+func (s *SQL) Latest(ctx context.Context, repo, branch string) (*travis.Payload, error) {
+	query := fmt.Sprintf(`SELECT payload FROM %s WHERE repo = %s AND branch = %s AND type <> 'pull_request'
+ORDER BY id DESC LIMIT 1`, s.table(), s.placeholder(1), s.placeholder(2))
+	var body string
+	err := s.DB.QueryRowContext(ctx, query, repo, branch).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(body)
+}
+
+// Prune deletes the builds expired according to Retention, the database
+// must support window functions (SQLite 3.25 or later)
+func (s *SQL) Prune(ctx context.Context) error {
+	r := s.Retention
+	if r.IsZero() {
+		return nil
+	}
+
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return s.placeholder(len(args))
+	}
+	var expired []string
+	if r.MaxAge > 0 {
+		expired = append(expired, "(build_time > 0 AND build_time < "+arg(time.Now().Add(-r.MaxAge).Unix())+")")
+	}
+	if r.MaxPerRepo > 0 {
+		expired = append(expired, "repo_rank > "+arg(r.MaxPerRepo))
+	}
+	where := "(" + strings.Join(expired, " OR ") + ")"
+	if r.KeepPerBranch > 0 {
+		where += " AND branch_rank > " + arg(r.KeepPerBranch)
+	}
+
+	t := s.table()
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (
+	SELECT id FROM (
+		SELECT id, build_time,
+			ROW_NUMBER() OVER (PARTITION BY repo ORDER BY id DESC) AS repo_rank,
+			ROW_NUMBER() OVER (PARTITION BY repo, branch ORDER BY id DESC) AS branch_rank
+		FROM %s
+	) ranked WHERE %s
+)`, t, t, where)
+	_, err := s.DB.ExecContext(ctx, query, args...)
+	return err
+}
+
+// Export writes every build to w in format f
+func (s *SQL) Export(ctx context.Context, w io.Writer, f Format) error {
+	builds, err := s.Builds(ctx, Query{})
+	if err != nil {
+		return err
+	}
+	return WriteBuilds(w, f, builds)
+}
+
+// Close closes the database
+func (s *SQL) Close() error {
+	return s.DB.Close()
+}
+
+// Record saves p and inserts actions in a transaction
+func (s *SQL) Record(ctx context.Context, p *travis.Payload, actions []Action) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.save(ctx, tx, p); err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, sink, build_id, payload, attempts, due, error, failed) VALUES (%s, %s, %s, %s, %s, %s, %s, 0)",
+		s.outboxTable(), s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4), s.placeholder(5), s.placeholder(6), s.placeholder(7))
+	for _, a := range actions {
+		body, err := json.Marshal(a.Payload)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query, a.ID, a.Sink, a.Payload.ID, string(body), a.Attempts, unix(a.Due), a.Error); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Claim returns up to n actions due at now. Each is claimed by an update
+// conditioned on its attempts and due time, so that of concurrent claims
+// of an action only one succeeds.
+func (s *SQL) Claim(ctx context.Context, now time.Time, n int, lease time.Duration) ([]Action, error) {
+	query := fmt.Sprintf("SELECT id, sink, payload, attempts, due, error FROM %s WHERE failed = 0 AND due <= %s ORDER BY due, id LIMIT %d",
+		s.outboxTable(), s.placeholder(1), n)
+	due, err := s.actions(ctx, query, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	update := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, due = %s WHERE id = %s AND attempts = %s AND due = %s",
+		s.outboxTable(), s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4))
+	next := now.Add(lease)
+	var claimed []Action
+	for _, a := range due {
+		res, err := s.DB.ExecContext(ctx, update, next.Unix(), a.ID, a.Attempts, unix(a.Due))
+		if err != nil {
+			return claimed, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n != 1 {
+			continue
+		}
+		a.Attempts++
+		a.Due = time.Unix(next.Unix(), 0)
+		claimed = append(claimed, a)
+	}
+	return claimed, nil
+}
+
+// Complete deletes the action with id
+func (s *SQL) Complete(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.outboxTable(), s.placeholder(1))
+	_, err := s.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// Retry makes the action with id due again at due
+func (s *SQL) Retry(ctx context.Context, id string, due time.Time, err error) error {
+	query := fmt.Sprintf("UPDATE %s SET due = %s, error = %s WHERE id = %s",
+		s.outboxTable(), s.placeholder(1), s.placeholder(2), s.placeholder(3))
+	_, err = s.DB.ExecContext(ctx, query, unix(due), errorText(err), id)
+	return err
+}
+
+// Fail gives up the action with id
+func (s *SQL) Fail(ctx context.Context, id string, err error) error {
+	query := fmt.Sprintf("UPDATE %s SET failed = 1, error = %s WHERE id = %s",
+		s.outboxTable(), s.placeholder(1), s.placeholder(2))
+	_, err = s.DB.ExecContext(ctx, query, errorText(err), id)
+	return err
+}
+
+// Failed returns the actions given up, oldest first
+func (s *SQL) Failed(ctx context.Context) ([]Action, error) {
+	query := fmt.Sprintf("SELECT id, sink, payload, attempts, due, error FROM %s WHERE failed = 1 ORDER BY due, id", s.outboxTable())
+	actions, err := s.actions(ctx, query)
+	for i := range actions {
+		actions[i].Failed = true
+	}
+	return actions, err
+}
+
+// AddFollowUp inserts f, its time is rounded to the second
+func (s *SQL) AddFollowUp(ctx context.Context, f *travis.FollowUp) error {
+	body, err := json.Marshal(f.Payload)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (id, name, due, payload) VALUES (%s, %s, %s, %s)",
+		s.followUpsTable(), s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4))
+	_, err = s.DB.ExecContext(ctx, query, f.ID, f.Name, unix(f.At), string(body))
+	return err
+}
+
+// DueFollowUps returns the follow-ups due at now, the earliest first
+func (s *SQL) DueFollowUps(ctx context.Context, now time.Time) ([]*travis.FollowUp, error) {
+	query := fmt.Sprintf("SELECT id, name, due, payload FROM %s WHERE due <= %s ORDER BY due, id",
+		s.followUpsTable(), s.placeholder(1))
+	rows, err := s.DB.QueryContext(ctx, query, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*travis.FollowUp
+	for rows.Next() {
+		f := new(travis.FollowUp)
+		var at int64
+		var body string
+		if err := rows.Scan(&f.ID, &f.Name, &at, &body); err != nil {
+			return nil, err
+		}
+		if f.Payload, err = decode(body); err != nil {
+			return nil, err
+		}
+		f.At = time.Unix(at, 0)
+		due = append(due, f)
+	}
+	return due, rows.Err()
+}
+
+// RemoveFollowUp deletes the follow-up with id
+func (s *SQL) RemoveFollowUp(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.followUpsTable(), s.placeholder(1))
+	_, err := s.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *SQL) actions(ctx context.Context, query string, args ...interface{}) ([]Action, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []Action
+	for rows.Next() {
+		var a Action
+		var body string
+		var due int64
+		if err := rows.Scan(&a.ID, &a.Sink, &body, &a.Attempts, &due, &a.Error); err != nil {
+			return nil, err
+		}
+		if a.Payload, err = decode(body); err != nil {
+			return nil, err
+		}
+		if due > 0 {
+			a.Due = time.Unix(due, 0)
+		}
+		actions = append(actions, a)
+	}
+	return actions, rows.Err()
+}
+
+func (s *SQL) query(ctx context.Context, query string, args ...interface{}) ([]*travis.Payload, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var builds []*travis.Payload
+	for rows.Next() {
+		var body string
+		if err := rows.Scan(&body); err != nil {
+			return nil, err
+		}
+		p, err := decode(body)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, p)
+	}
+	return builds, rows.Err()
+}
+
+func decode(body string) (*travis.Payload, error) {
+	p := new(travis.Payload)
+	if err := json.Unmarshal([]byte(body), p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
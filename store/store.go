@@ -0,0 +1,80 @@
+// Package store persists travis payloads so build history outlives the
+// retention of Travis itself.
+//
+//	db, _ := sql.Open("sqlite3", "builds.db")
+//	s := &store.SQL{DB: db}
+//	s.CreateTable(ctx)
+//	h := &travis.Handler{Sinks: []travis.Sink{store.Sink(s)}}
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// ErrNotFound is returned when a build isn't in the store
+var ErrNotFound = errors.New("build not found")
+
+// Query selects builds, zero fields don't filter
+type Query struct {
+	Repo   string
+	Branch string
+	// Type is the event type, e.g. push or pull_request
+	Type string
+	// Since and Until delimit the time of the builds, which is when they
+	// finished, or started if they are running
+	Since, Until time.Time
+	// Limit is the maximum number of builds returned, newest first
+	Limit int
+}
+
+// Store persists payloads. Saving a build again, e.g. when its finished
+// webhook follows the started one, replaces it.
+type Store interface {
+	Save(ctx context.Context, p *travis.Payload) error
+	// Build returns the build with id or ErrNotFound
+	Build(ctx context.Context, id int64) (*travis.Payload, error)
+	// Builds returns the builds matching q, newest first
+	Builds(ctx context.Context, q Query) ([]*travis.Payload, error)
+	// LatestPerBranch returns the latest push, cron or api build of each
+	// branch of repo
+	LatestPerBranch(ctx context.Context, repo string) ([]*travis.Payload, error)
+	// Latest returns the latest push, cron or api build of branch in repo
+	// or ErrNotFound
+	Latest(ctx context.Context, repo, branch string) (*travis.Payload, error)
+	// Prune deletes the builds expired according to the Retention of the store
+	Prune(ctx context.Context) error
+	// Export writes every build to w in format f
+	Export(ctx context.Context, w io.Writer, f Format) error
+	Close() error
+}
+
+// Sink returns a travis.Sink saving payloads to s, with the context of the
+// dispatch when there is one
+func Sink(s Store) travis.Sink {
+	return sink{s}
+}
+
+type sink struct {
+	s Store
+}
+
+func (s sink) Send(p *travis.Payload) error {
+	return s.s.Save(context.Background(), p)
+}
+
+func (s sink) SendContext(ctx context.Context, p *travis.Payload) error {
+	return s.s.Save(ctx, p)
+}
+
+// BuildTime is the time of p used by queries, when it finished or started
+func BuildTime(p *travis.Payload) time.Time {
+	if !p.FinishedAt.IsZero() {
+		return p.FinishedAt
+	}
+	return p.StartedAt
+}
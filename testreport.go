@@ -0,0 +1,206 @@
+package travis
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TestReport is a summary of the tests run by jobs, parsed from their logs
+type TestReport struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+	// Failures are the names of the failed tests, in the order of the logs
+	Failures []string `json:"failures,omitempty"`
+}
+
+var (
+	// ansiEscape matches the color codes of logs
+	ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	// goTestResult matches the result lines of go test -v, including the
+	// indented ones of subtests
+	goTestResult = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+)`)
+	// goPackageFailure matches the packages go test couldn't build
+	goPackageFailure = regexp.MustCompile(`^FAIL\s+(\S+)\s+\[(build failed|setup failed)\]`)
+	// junitStart matches the start of a JUnit XML document echoed to a log
+	junitStart = regexp.MustCompile(`<testsuites?[\s>]`)
+)
+
+// ParseTestReport extracts the tests of a job log: the JUnit XML documents
+// echoed to it, else the output of go test -v. The packages go test failed
+// to build count as failed tests.
+func ParseTestReport(log string) *TestReport {
+	log = ansiEscape.ReplaceAllString(log, "")
+	log = strings.ReplaceAll(log, "\r\n", "\n")
+	if r := parseJUnit(log); r != nil {
+		return r
+	}
+	return parseGoTest(log)
+}
+
+func parseGoTest(log string) *TestReport {
+	r := new(TestReport)
+	for _, line := range strings.Split(log, "\n") {
+		if m := goPackageFailure.FindStringSubmatch(line); m != nil {
+			r.Failed++
+			r.Failures = append(r.Failures, m[1]+" ["+m[2]+"]")
+			continue
+		}
+		m := goTestResult.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		switch m[1] {
+		case "PASS":
+			r.Passed++
+		case "SKIP":
+			r.Skipped++
+		case "FAIL":
+			r.Failed++
+			r.Failures = append(r.Failures, m[2])
+		}
+	}
+	r.Failures = leafTests(r.Failures)
+	r.Failed = len(r.Failures)
+	return r
+}
+
+// leafTests drops the tests failing only because one of their subtests
+// did, e.g. TestA when TestA/case failed
+func leafTests(names []string) []string {
+	var leaves []string
+	for _, n := range names {
+		parent := false
+		for _, other := range names {
+			if strings.HasPrefix(other, n+"/") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			leaves = append(leaves, n)
+		}
+	}
+	return leaves
+}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+	junitSuite
+}
+
+type junitSuite struct {
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitCase struct {
+	Name      string    `xml:"name,attr"`
+	Classname string    `xml:"classname,attr"`
+	Failure   *struct{} `xml:"failure"`
+	Error     *struct{} `xml:"error"`
+	Skipped   *struct{} `xml:"skipped"`
+}
+
+// parseJUnit returns the report of the JUnit documents of log, nil if
+// there is none
+func parseJUnit(log string) *TestReport {
+	var r *TestReport
+	for {
+		loc := junitStart.FindStringIndex(log)
+		if loc == nil {
+			return r
+		}
+		log = log[loc[0]:]
+		d := xml.NewDecoder(strings.NewReader(log))
+		d.Strict = false
+		var doc junitSuites
+		if err := d.Decode(&doc); err != nil {
+			// skip the broken document
+			log = log[loc[1]-loc[0]:]
+			continue
+		}
+		log = log[d.InputOffset():]
+		if r == nil {
+			r = new(TestReport)
+		}
+		r.addSuite(doc.junitSuite)
+		for _, s := range doc.Suites {
+			r.addSuite(s)
+		}
+	}
+}
+
+func (r *TestReport) addSuite(s junitSuite) {
+	for _, c := range s.Cases {
+		switch {
+		case c.Failure != nil || c.Error != nil:
+			r.Failed++
+			name := c.Name
+			if c.Classname != "" {
+				name = c.Classname + "." + c.Name
+			}
+			r.Failures = append(r.Failures, name)
+		case c.Skipped != nil:
+			r.Skipped++
+		default:
+			r.Passed++
+		}
+	}
+	for _, nested := range s.Suites {
+		r.addSuite(nested)
+	}
+}
+
+// Merge adds the tests of other to r
+func (r *TestReport) Merge(other *TestReport) {
+	r.Passed += other.Passed
+	r.Failed += other.Failed
+	r.Skipped += other.Skipped
+	r.Failures = append(r.Failures, other.Failures...)
+}
+
+// Summary describes r for a notification, e.g. "3 tests failed: TestX,
+// TestY, TestZ", naming up to five failures
+func (r *TestReport) Summary() string {
+	if r.Failed == 0 {
+		return fmt.Sprintf("%d tests passed", r.Passed)
+	}
+	names := r.Failures
+	more := ""
+	if len(names) > 5 {
+		names, more = names[:5], "…"
+	}
+	noun := "tests"
+	if r.Failed == 1 {
+		noun = "test"
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%d %s failed", r.Failed, noun)
+	}
+	return fmt.Sprintf("%d %s failed: %s%s", r.Failed, noun, strings.Join(names, ", "), more)
+}
+
+// TestReport returns the tests of the failed and errored jobs of the build
+// with id, parsed from their logs
+func (c *Client) TestReport(ctx context.Context, buildID int64) (*TestReport, error) {
+	jobs, err := c.Jobs(ctx, buildID)
+	if err != nil {
+		return nil, err
+	}
+	report := new(TestReport)
+	for _, j := range jobs {
+		if j.State != OutcomeFailed && j.State != OutcomeErrored {
+			continue
+		}
+		log, err := c.Log(ctx, j.ID)
+		if err != nil {
+			return nil, fmt.Errorf("log of job %s: %w", j.Number, err)
+		}
+		report.Merge(ParseTestReport(log))
+	}
+	return report, nil
+}
@@ -0,0 +1,19 @@
+package travis
+
+import "context"
+
+// Tracer starts spans around the steps of handling a webhook: verification
+// (including the public key fetch), payload decoding and each sink. The
+// returned func ends the span, p is the decoded payload if known and err
+// the outcome of the step.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, func(p *Payload, err error))
+}
+
+// startSpan is t.Start accepting a nil Tracer
+func startSpan(t Tracer, ctx context.Context, name string) (context.Context, func(p *Payload, err error)) {
+	if t == nil {
+		return ctx, func(*Payload, error) {}
+	}
+	return t.Start(ctx, name)
+}
@@ -0,0 +1,101 @@
+// Package tracing traces the travis webhook Handler and Travis API requests
+// with OpenTelemetry.
+//
+//	tp := otel.GetTracerProvider()
+//	h := &travis.Handler{
+//		Tracer: tracing.New(tp),
+//		Client: &http.Client{Transport: tracing.Transport(tp, nil)},
+//	}
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/jacksgt/travis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/jacksgt/travis"
+
+// Tracer is a travis.Tracer creating OpenTelemetry spans
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New returns a Tracer using tp
+func New(tp trace.TracerProvider) *Tracer {
+	return &Tracer{tracer: tp.Tracer(instrumentation)}
+}
+
+// Start starts a span, the build ID and repository of the payload are added
+// as attributes when it ends
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, func(p *travis.Payload, err error)) {
+	ctx, span := t.tracer.Start(ctx, name)
+	return ctx, func(p *travis.Payload, err error) {
+		if p != nil {
+			span.SetAttributes(Attributes(p)...)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// Attributes returns the span attributes describing p
+func Attributes(p *travis.Payload) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64("travis.build.id", p.ID),
+		attribute.String("travis.build.number", p.Number),
+		attribute.String("travis.repo.slug", p.Slug()),
+		attribute.String("travis.branch", p.Branch),
+		attribute.String("travis.state", travis.Outcome(p)),
+	}
+}
+
+// Transport wraps next, http.DefaultTransport if nil, to create a client span
+// per request and propagate it to the server
+func Transport(tp trace.TracerProvider, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{tracer: tp.Tracer(instrumentation), next: next}
+}
+
+type transport struct {
+	tracer trace.Tracer
+	next   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), "HTTP "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("url.full", r.URL.String()),
+			attribute.String("server.address", r.URL.Host),
+		),
+	)
+	defer span.End()
+
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
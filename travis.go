@@ -1,9 +1,9 @@
 package travis
 
 import (
+	"context"
 	"crypto"
 	"crypto/rsa"
-	"crypto/sha1"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -11,7 +11,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -29,6 +34,17 @@ const (
 	Cancel = 10329501
 )
 
+// discardLogger is used when no logger is configured
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// ErrUnauthorized is returned when the signature of a payload doesn't match
+var ErrUnauthorized = errors.New("unauthorized payload")
+
+// Hex returns the color in the #RRGGBB notation
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%06X", int(c))
+}
+
 // Payload for travis
 type Payload struct {
 	ID                int64       `json:"id,omitempty"`
@@ -61,13 +77,57 @@ type Payload struct {
 	PullRequestTitle  string      `json:"pull_request_title,omitempty"`
 	Tag               string      `json:"tag,omitempty"`
 	Repository        *Repository `json:"repository,omitempty"`
+	Matrix            []*Job      `json:"matrix,omitempty"`
+
+	// raw is the JSON the payload was decoded from, see Raw
+	raw []byte
 }
 
-// Config field of the payload
+// Config field of the payload, the build configuration of .travis.yml
 type Config struct {
-	Sudo     bool   `json:"sudo,omitempty"`
-	Dist     string `json:"dist,omitempty"`
-	Language string `json:"language,omitempty"`
+	Sudo     bool    `json:"sudo,omitempty"`
+	Dist     string  `json:"dist,omitempty"`
+	Language string  `json:"language,omitempty"`
+	OS       Strings `json:"os,omitempty"`
+	Arch     Strings `json:"arch,omitempty"`
+	Env      *Env    `json:"env,omitempty"`
+	// Versions are the language versions and the other matrix expansion
+	// keys, e.g. go, python or compiler, by key
+	Versions map[string]Strings `json:"-"`
+	// Jobs is the jobs key, or the matrix key of older configs
+	Jobs *Jobs `json:"jobs,omitempty"`
+	// Stages is the order and conditions of the stages of the jobs
+	Stages   []StageConfig `json:"stages,omitempty"`
+	Services Strings       `json:"services,omitempty"`
+	Cache    *Cache        `json:"cache,omitempty"`
+	Group    string        `json:"group,omitempty"`
+
+	BeforeInstall Strings `json:"before_install,omitempty"`
+	Install       Strings `json:"install,omitempty"`
+	BeforeScript  Strings `json:"before_script,omitempty"`
+	Script        Strings `json:"script,omitempty"`
+	AfterSuccess  Strings `json:"after_success,omitempty"`
+	AfterFailure  Strings `json:"after_failure,omitempty"`
+	AfterScript   Strings `json:"after_script,omitempty"`
+
+	// Name and Stage are set on the jobs of the matrix
+	Name  string `json:"name,omitempty"`
+	Stage string `json:"stage,omitempty"`
+}
+
+// Job is an entry of the matrix field of the payload
+type Job struct {
+	ID           int64     `json:"id,omitempty"`
+	RepositoryID int64     `json:"repository_id,omitempty"`
+	ParentID     int64     `json:"parent_id,omitempty"`
+	Number       string    `json:"number,omitempty"`
+	State        string    `json:"state,omitempty"`
+	Status       int       `json:"status,omitempty"`
+	Result       int       `json:"result,omitempty"`
+	StartedAt    time.Time `json:"started_at,omitempty"`
+	FinishedAt   time.Time `json:"finished_at,omitempty"`
+	AllowFailure bool      `json:"allow_failure,omitempty"`
+	Config       *Config   `json:"config,omitempty"`
 }
 
 // Repository field of the payload
@@ -78,6 +138,50 @@ type Repository struct {
 	URL       string `json:"url,omitempty"`
 }
 
+// UnmarshalJSON decodes a payload, accepting the boolean pull_request field
+// Travis sends as well as a number, and the committed_at, committer_name and
+// committer_email spellings of Travis as well as the ones of the tags
+func (p *Payload) UnmarshalJSON(b []byte) error {
+	type plain Payload
+	aux := struct {
+		*plain
+		PullRequest    json.RawMessage `json:"pull_request,omitempty"`
+		CommittedAt    time.Time       `json:"committed_at"`
+		CommitterName  string          `json:"committer_name"`
+		CommitterEmail string          `json:"committer_email"`
+	}{plain: (*plain)(p)}
+	if err := decodeJSON(b, &aux); err != nil {
+		return err
+	}
+	p.raw = append(p.raw[:0], b...)
+	if p.CommitedAt.IsZero() {
+		p.CommitedAt = aux.CommittedAt
+	}
+	if p.CommiterName == "" {
+		p.CommiterName = aux.CommitterName
+	}
+	if p.CommiterEmail == "" {
+		p.CommiterEmail = aux.CommitterEmail
+	}
+	// drop the null entries of the matrix so its jobs are never nil
+	jobs := p.Matrix[:0]
+	for _, j := range p.Matrix {
+		if j != nil {
+			jobs = append(jobs, j)
+		}
+	}
+	p.Matrix = jobs
+	switch string(aux.PullRequest) {
+	case "", "null", "false":
+		p.PullRequest = 0
+	case "true":
+		p.PullRequest = 1
+	default:
+		return decodeJSON(aux.PullRequest, &p.PullRequest)
+	}
+	return nil
+}
+
 // GetPayload will parse the payload inside r
 func GetPayload(r io.Reader) (*Payload, error) {
 	if r == nil {
@@ -91,42 +195,238 @@ func GetPayload(r io.Reader) (*Payload, error) {
 	return p, nil
 }
 
+// DefaultConfigURL is the Travis API endpoint serving the public key webhooks
+// are signed with
+const DefaultConfigURL = "https://api.travis-ci.org/config"
+
 // GetPayloadFromRequest will verify the integrity of the request and then
 // parse the payload inside the body
 func GetPayloadFromRequest(r *http.Request) (*Payload, error) {
+	v := &verifier{client: http.DefaultClient, log: discardLogger, keyTTL: DefaultKeyTTL}
+	payload, err := v.verify(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodePayload(payload)
+}
 
-	if r.Method != "POST" {
-		return nil, fmt.Errorf("wrong request method %q instead of POST", r.Method)
+// verifier checks the signature of webhook requests
+type verifier struct {
+	client *http.Client
+	log    *slog.Logger
+	// configURL serves the public key, DefaultConfigURL if empty
+	configURL string
+	// keyTTL is how long the public key is cached, it is fetched for every
+	// request if zero
+	keyTTL time.Duration
+	// clock measures keyTTL, SystemClock if nil
+	clock Clock
+	// scheme, if set, verifies the signatures instead of the public key
+	scheme SignatureScheme
+	// cache, if set, shares the public key with other processes
+	cache CacheBackend
+
+	mu        sync.Mutex
+	key       *rsa.PublicKey
+	keyLoaded time.Time
+	// keyRefreshed is the last time the key was refreshed after a signature
+	// didn't match it
+	keyRefreshed time.Time
+	// fetch is the load of the key in progress, if any
+	fetch *keyFetch
+
+	// lastKeyFetch is the last time the public key was fetched, in unix nanoseconds
+	lastKeyFetch atomic.Int64
+}
+
+// keyRefreshInterval is the minimum time between the refreshes of a
+// cached public key after a signature doesn't match it, so forged requests
+// can't make the verifier fetch the key for each of them
+const keyRefreshInterval = time.Minute
+
+// keyFetch is a load of the public key in progress, shared by the requests
+// waiting for it
+type keyFetch struct {
+	done   chan struct{}
+	key    *rsa.PublicKey
+	cached bool
+	err    error
+}
+
+// cachedKey returns the cached public key, fetching it if it is missing,
+// expired or refresh is true. cached reports whether it wasn't just fetched.
+// Refreshes are ignored within keyRefreshInterval of the previous one or of
+// loading the key, and a single load runs at a time, outside of the lock.
+func (v *verifier) cachedKey(ctx context.Context, refresh bool) (key *rsa.PublicKey, cached bool, err error) {
+	v.mu.Lock()
+	now := clockOr(v.clock).Now()
+	if v.key != nil {
+		fresh := !refresh && now.Sub(v.keyLoaded) < v.keyTTL
+		throttled := refresh && (now.Sub(v.keyRefreshed) < keyRefreshInterval || now.Sub(v.keyLoaded) < keyRefreshInterval)
+		if fresh || throttled {
+			key := v.key
+			v.mu.Unlock()
+			return key, true, nil
+		}
+	}
+	if f := v.fetch; f != nil {
+		v.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.key, f.cached, f.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	f := &keyFetch{done: make(chan struct{})}
+	v.fetch = f
+	if refresh {
+		v.keyRefreshed = now
 	}
+	v.mu.Unlock()
 
-	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
-		return nil, fmt.Errorf("wrong Content-Type header, got %s != want application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+	var loaded time.Time
+	f.key, loaded, f.cached, f.err = v.loadKey(ctx, refresh, now)
+	v.mu.Lock()
+	if f.err == nil {
+		v.key, v.keyLoaded = f.key, loaded
 	}
+	v.fetch = nil
+	v.mu.Unlock()
+	close(f.done)
+	return f.key, f.cached, f.err
+}
 
-	key, err := travisPublicKey()
+// loadKey returns the public key from the caches shared with other
+// handlers, unless refresh is true, or fetches it
+func (v *verifier) loadKey(ctx context.Context, refresh bool, now time.Time) (key *rsa.PublicKey, loaded time.Time, cached bool, err error) {
+	url := v.url()
+	if !refresh {
+		if key, loaded, ok := lookupSharedKey(url, now, v.keyTTL); ok {
+			return key, loaded, true, nil
+		}
+		if key, loaded, ok := v.lookupCachedKey(ctx, url, now); ok {
+			storeSharedKey(url, key, loaded)
+			return key, loaded, true, nil
+		}
+	}
+	key, err = v.publicKey(ctx)
 	if err != nil {
-		return nil, err
+		return nil, time.Time{}, false, err
 	}
+	loaded = clockOr(v.clock).Now()
+	storeSharedKey(url, key, loaded)
+	v.storeCachedKey(ctx, url, key, loaded)
+	return key, loaded, false, nil
+}
 
-	signature, err := parsePayloadSignature(r)
+// lookupCachedKey returns the key of url in the cache of v, if any, loaded
+// less than keyTTL before now
+func (v *verifier) lookupCachedKey(ctx context.Context, url string, now time.Time) (*rsa.PublicKey, time.Time, bool) {
+	if v.cache == nil {
+		return nil, time.Time{}, false
+	}
+	b, ok, err := v.cache.Get(ctx, keyCacheKey(url))
 	if err != nil {
-		return nil, err
+		v.log.Warn("reading the public key cache", "error", err)
+	}
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	nanos, pemKey, _ := strings.Cut(string(b), "\n")
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	loaded := time.Unix(0, n)
+	key, err := parsePublicKey(pemKey)
+	if err != nil || now.Sub(loaded) >= v.keyTTL {
+		return nil, time.Time{}, false
 	}
+	return key, loaded, true
+}
 
-	payload := payloadDigest(r.FormValue("payload"))
+// storeCachedKey saves the key of url in the cache of v, if any, with the
+// time it was loaded
+func (v *verifier) storeCachedKey(ctx context.Context, url string, key *rsa.PublicKey, loaded time.Time) {
+	if v.cache == nil || v.keyTTL <= 0 {
+		return
+	}
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return
+	}
+	b := strconv.AppendInt(nil, loaded.UnixNano(), 10)
+	b = append(b, '\n')
+	b = append(b, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	if err := v.cache.Set(ctx, keyCacheKey(url), b, v.keyTTL); err != nil {
+		v.log.Warn("writing the public key cache", "error", err)
+	}
+}
+
+// keyCacheKey is the CacheBackend key of the public key served at url
+func keyCacheKey(url string) string {
+	return "travis:key:" + url
+}
+
+// keyAge returns whether a public key is cached and how old it is
+func (v *verifier) keyAge() (bool, time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return false, 0
+	}
+	return true, clockOr(v.clock).Now().Sub(v.keyLoaded)
+}
+
+// verify checks the signature of r and returns the raw payload
+func (v *verifier) verify(r *http.Request) (string, error) {
+	log := v.log
+
+	if err := checkRequest(r); err != nil {
+		return "", err
+	}
 
-	err = rsa.VerifyPKCS1v15(key, crypto.SHA1, payload, signature)
+	if v.scheme != nil {
+		payload := r.FormValue("payload")
+		if err := v.scheme.Verify(r, payload); err != nil {
+			log.Debug("rejected webhook with wrong signature", "error", err)
+			return "", err
+		}
+		log.Debug("verified webhook signature", "size", len(payload))
+		return payload, nil
+	}
+
+	key, cached, err := v.cachedKey(r.Context(), false)
 	if err != nil {
-		return nil, errors.New("unauthorized payload")
+		return "", err
 	}
 
-	p := new(Payload)
-	err = json.Unmarshal([]byte(r.FormValue("payload")), p)
+	signature, err := parsePayloadSignature(r)
 	if err != nil {
-		return nil, errors.New("cannot decode payload")
+		log.Debug("rejected webhook without valid signature", "error", err)
+		return "", err
 	}
 
-	return p, nil
+	payload := r.FormValue("payload")
+
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA1, payloadDigest(payload), signature)
+	if err != nil && cached {
+		// the key may have been rotated since it was cached
+		log.Debug("retrying webhook verification with a fresh public key", "error", err)
+		key, _, err = v.cachedKey(r.Context(), true)
+		if err != nil {
+			return "", err
+		}
+		err = rsa.VerifyPKCS1v15(key, crypto.SHA1, payloadDigest(payload), signature)
+	}
+	if err != nil {
+		log.Debug("rejected webhook with wrong signature", "error", err)
+		return "", ErrUnauthorized
+	}
+	log.Debug("verified webhook signature", "size", len(payload))
+
+	return payload, nil
 }
 
 type configKey struct {
@@ -151,10 +451,26 @@ type configKey struct {
 	} `json:"config"`
 }
 
-func travisPublicKey() (*rsa.PublicKey, error) {
-	response, err := http.Get("https://api.travis-ci.org/config")
+// url returns the config URL of v
+func (v *verifier) url() string {
+	if v.configURL == "" {
+		return DefaultConfigURL
+	}
+	return v.configURL
+}
+
+func (v *verifier) publicKey(ctx context.Context) (*rsa.PublicKey, error) {
+	log := v.log
+	url := v.url()
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	response, err := v.client.Do(request.WithContext(ctx))
 
 	if err != nil {
+		log.Debug("cannot fetch travis public key", "url", url, "error", err)
 		return nil, errors.New("cannot fetch travis public key")
 	}
 	defer response.Body.Close()
@@ -163,18 +479,25 @@ func travisPublicKey() (*rsa.PublicKey, error) {
 	var t configKey
 	err = decoder.Decode(&t)
 	if err != nil {
+		log.Debug("cannot decode travis public key", "url", url, "status", response.StatusCode, "error", err)
 		return nil, errors.New("cannot decode travis public key")
 	}
 
 	key, err := parsePublicKey(t.Config.Notifications.Webhook.PublicKey)
 	if err != nil {
+		log.Debug("invalid travis public key", "url", url, "error", err)
 		return nil, err
 	}
+	log.Debug("fetched travis public key", "url", url, "duration", time.Since(start))
+	v.lastKeyFetch.Store(clockOr(v.clock).Now().UnixNano())
 
 	return key, nil
 }
 
 func parsePublicKey(key string) (*rsa.PublicKey, error) {
+	if parsed := lookupParsedKey(key); parsed != nil {
+		return parsed, nil
+	}
 
 	// https://golang.org/pkg/encoding/pem/#Block
 	block, _ := pem.Decode([]byte(key))
@@ -188,8 +511,24 @@ func parsePublicKey(key string) (*rsa.PublicKey, error) {
 		return nil, errors.New("invalid public key")
 	}
 
-	return publicKey.(*rsa.PublicKey), nil
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid public key")
+	}
+	storeParsedKey(key, rsaKey)
+	return rsaKey, nil
+
+}
 
+// checkRequest checks the method and content type of a webhook request
+func checkRequest(r *http.Request) error {
+	if r.Method != "POST" {
+		return fmt.Errorf("wrong request method %q instead of POST", r.Method)
+	}
+	if r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		return fmt.Errorf("wrong Content-Type header, got %s != want application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+	}
+	return nil
 }
 
 func parsePayloadSignature(r *http.Request) ([]byte, error) {
@@ -204,12 +543,6 @@ func parsePayloadSignature(r *http.Request) ([]byte, error) {
 	return b64, nil
 }
 
-func payloadDigest(payload string) []byte {
-	hash := sha1.New()
-	hash.Write([]byte(payload))
-	return hash.Sum(nil)
-}
-
 // Pending returns true if a build has been requested
 func (p *Payload) Pending() bool {
 	return p.StatusMessage == "Pending" || p.ResultMessage == "Pending"
@@ -269,3 +602,11 @@ func (p *Payload) IsCron() bool {
 func (p *Payload) IsAPI() bool {
 	return p.Type == "api"
 }
+
+// Slug returns the "owner/name" of the repository, or "" if unknown
+func (p *Payload) Slug() string {
+	if p.Repository == nil {
+		return ""
+	}
+	return p.Repository.OwnerName + "/" + p.Repository.Name
+}
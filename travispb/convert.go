@@ -0,0 +1,177 @@
+// Package travispb is the protobuf encoding of the webhook payloads, for
+// forwarding them over gRPC or storing them in columnar systems.
+//
+//	b, err := proto.Marshal(travispb.ToProto(p))
+//
+// The schema is travis.proto. The build configs are kept as the JSON Travis
+// sends, rather than mirrored field by field, so that decoding a BuildEvent
+// written today still works after Config gains fields.
+package travispb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative travis.proto
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/jacksgt/travis"
+)
+
+// ToProto returns p as a BuildEvent
+func ToProto(p *travis.Payload) *BuildEvent {
+	e := &BuildEvent{
+		Id:                p.ID,
+		Number:            p.Number,
+		Type:              p.Type,
+		State:             p.State,
+		Status:            int32(p.Status),
+		Result:            int32(p.Result),
+		StatusMessage:     p.StatusMessage,
+		ResultMessage:     p.ResultMessage,
+		StartedAt:         timestamp(p.StartedAt),
+		FinishedAt:        timestamp(p.FinishedAt),
+		Duration:          int32(p.Duration),
+		BuildUrl:          p.BuildURL,
+		CommitId:          int64(p.CommitID),
+		Commit:            p.Commit,
+		BaseCommit:        p.BaseCommit,
+		HeadCommit:        p.HeadCommit,
+		Branch:            p.Branch,
+		Message:           p.Message,
+		CompareUrl:        p.CompareURL,
+		CommittedAt:       timestamp(p.CommitedAt),
+		AuthorName:        p.AuthorName,
+		AuthorEmail:       p.AuthorEmail,
+		CommitterName:     p.CommiterName,
+		CommitterEmail:    p.CommiterEmail,
+		PullRequest:       p.PullRequest != 0,
+		PullRequestNumber: int32(p.PullRequestNumber),
+		PullRequestTitle:  p.PullRequestTitle,
+		Tag:               p.Tag,
+		Config:            configJSON(p.Config),
+		Outcome:           travis.Outcome(p),
+		Source:            p.SourceID(),
+	}
+	if r := p.Repository; r != nil {
+		e.Repository = &Repository{Id: r.ID, Name: r.Name, OwnerName: r.OwnerName, Url: r.URL}
+	}
+	for _, j := range p.Matrix {
+		e.Jobs = append(e.Jobs, &Job{
+			Id:           j.ID,
+			RepositoryId: j.RepositoryID,
+			ParentId:     j.ParentID,
+			Number:       j.Number,
+			State:        j.State,
+			Status:       int32(j.Status),
+			Result:       int32(j.Result),
+			StartedAt:    timestamp(j.StartedAt),
+			FinishedAt:   timestamp(j.FinishedAt),
+			AllowFailure: j.AllowFailure,
+			Config:       configJSON(j.Config),
+		})
+	}
+	return e
+}
+
+// FromProto returns the payload of e. The derived Outcome and Source of e
+// are ignored.
+func FromProto(e *BuildEvent) (*travis.Payload, error) {
+	p := &travis.Payload{
+		ID:                e.GetId(),
+		Number:            e.GetNumber(),
+		Type:              e.GetType(),
+		State:             e.GetState(),
+		Status:            int(e.GetStatus()),
+		Result:            int(e.GetResult()),
+		StatusMessage:     e.GetStatusMessage(),
+		ResultMessage:     e.GetResultMessage(),
+		StartedAt:         fromTimestamp(e.GetStartedAt()),
+		FinishedAt:        fromTimestamp(e.GetFinishedAt()),
+		Duration:          int(e.GetDuration()),
+		BuildURL:          e.GetBuildUrl(),
+		CommitID:          int(e.GetCommitId()),
+		Commit:            e.GetCommit(),
+		BaseCommit:        e.GetBaseCommit(),
+		HeadCommit:        e.GetHeadCommit(),
+		Branch:            e.GetBranch(),
+		Message:           e.GetMessage(),
+		CompareURL:        e.GetCompareUrl(),
+		CommitedAt:        fromTimestamp(e.GetCommittedAt()),
+		AuthorName:        e.GetAuthorName(),
+		AuthorEmail:       e.GetAuthorEmail(),
+		CommiterName:      e.GetCommitterName(),
+		CommiterEmail:     e.GetCommitterEmail(),
+		PullRequestNumber: int(e.GetPullRequestNumber()),
+		PullRequestTitle:  e.GetPullRequestTitle(),
+		Tag:               e.GetTag(),
+	}
+	if e.GetPullRequest() {
+		p.PullRequest = 1
+	}
+	var err error
+	if p.Config, err = parseConfig(e.GetConfig()); err != nil {
+		return nil, err
+	}
+	if r := e.GetRepository(); r != nil {
+		p.Repository = &travis.Repository{ID: r.GetId(), Name: r.GetName(), OwnerName: r.GetOwnerName(), URL: r.GetUrl()}
+	}
+	for _, j := range e.GetJobs() {
+		job := &travis.Job{
+			ID:           j.GetId(),
+			RepositoryID: j.GetRepositoryId(),
+			ParentID:     j.GetParentId(),
+			Number:       j.GetNumber(),
+			State:        j.GetState(),
+			Status:       int(j.GetStatus()),
+			Result:       int(j.GetResult()),
+			StartedAt:    fromTimestamp(j.GetStartedAt()),
+			FinishedAt:   fromTimestamp(j.GetFinishedAt()),
+			AllowFailure: j.GetAllowFailure(),
+		}
+		if job.Config, err = parseConfig(j.GetConfig()); err != nil {
+			return nil, err
+		}
+		p.Matrix = append(p.Matrix, job)
+	}
+	return p, nil
+}
+
+// timestamp returns t as a Timestamp, nil if it is zero
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// fromTimestamp returns ts as a time, zero if it is nil
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// configJSON returns c as JSON, nil if it is nil
+func configJSON(c *travis.Config) []byte {
+	if c == nil {
+		return nil
+	}
+	// a Config always marshals
+	b, _ := json.Marshal(c)
+	return b
+}
+
+// parseConfig decodes the JSON of a config, nil if it is empty
+func parseConfig(b []byte) (*travis.Config, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	c := new(travis.Config)
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
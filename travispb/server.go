@@ -0,0 +1,111 @@
+package travispb
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/jacksgt/travis"
+)
+
+// Server is a Sink serving the payloads it receives to the gRPC clients of
+// the Builds service, the gRPC counterpart of travis.Broadcaster:
+//
+//	srv := &travispb.Server{}
+//	dispatcher.Sinks = append(dispatcher.Sinks, srv)
+//	g := grpc.NewServer()
+//	travispb.RegisterBuildsServer(g, srv)
+//	go g.Serve(lis)
+//
+// A subscriber whose buffer is full is ended with ResourceExhausted rather
+// than slowing down the other subscribers.
+type Server struct {
+	UnimplementedBuildsServer
+
+	// Buffer is the number of events buffered per subscriber, 16 if zero
+	Buffer int
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	filter  *BuildFilter
+	events  chan *BuildEvent
+	dropped chan struct{}
+}
+
+// Send streams p to the subscribers it matches
+func (s *Server) Send(p *travis.Payload) error {
+	var e *BuildEvent
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		if !match(sub.filter, p) {
+			continue
+		}
+		if e == nil {
+			e = ToProto(p)
+		}
+		select {
+		case sub.events <- e:
+		default:
+			delete(s.subs, sub)
+			close(sub.dropped)
+		}
+	}
+	return nil
+}
+
+// Subscribers returns the number of connected subscribers
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+// SubscribeBuilds streams the events matching f until the client cancels
+func (s *Server) SubscribeBuilds(f *BuildFilter, stream Builds_SubscribeBuildsServer) error {
+	size := s.Buffer
+	if size <= 0 {
+		size = 16
+	}
+	sub := &subscriber{
+		filter:  f,
+		events:  make(chan *BuildEvent, size),
+		dropped: make(chan struct{}),
+	}
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[*subscriber]struct{})
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case e := <-sub.events:
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		case <-sub.dropped:
+			return status.Error(codes.ResourceExhausted, "subscriber too slow, events dropped")
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// match returns true if p matches every non-empty field of f
+func match(f *BuildFilter, p *travis.Payload) bool {
+	return travis.MatchAny(f.GetRepos(), p.Slug()) &&
+		travis.MatchAny(f.GetBranches(), p.Branch) &&
+		travis.MatchAny(f.GetOutcomes(), travis.Outcome(p)) &&
+		travis.MatchAny(f.GetTypes(), p.Type)
+}
@@ -0,0 +1,41 @@
+package travispb
+
+import (
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestMatch(t *testing.T) {
+	p := &travis.Payload{
+		Type:          "push",
+		Branch:        "release/1.2",
+		State:         "failed",
+		Status:        1,
+		StatusMessage: "Broken",
+		Repository:    &travis.Repository{OwnerName: "acme", Name: "api"},
+	}
+	tests := []struct {
+		name   string
+		filter *BuildFilter
+		want   bool
+	}{
+		{"empty", &BuildFilter{}, true},
+		{"repo", &BuildFilter{Repos: []string{"acme/api"}}, true},
+		{"other repo", &BuildFilter{Repos: []string{"acme/web"}}, false},
+		{"repo pattern", &BuildFilter{Repos: []string{"acme/*"}}, true},
+		{"branch pattern", &BuildFilter{Branches: []string{"main", "release/*"}}, true},
+		{"branch pattern mismatch", &BuildFilter{Branches: []string{"feature/*"}}, false},
+		{"outcome", &BuildFilter{Outcomes: []string{"failed", "errored"}}, true},
+		{"outcome mismatch", &BuildFilter{Outcomes: []string{"passed"}}, false},
+		{"type pattern", &BuildFilter{Types: []string{"pu*"}}, true},
+		{"every field", &BuildFilter{Repos: []string{"acme/*"}, Branches: []string{"release/*"}, Outcomes: []string{"failed"}, Types: []string{"pull_request"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := match(tt.filter, p); got != tt.want {
+				t.Errorf("match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
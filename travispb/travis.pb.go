@@ -0,0 +1,744 @@
+// The build events of Travis CI webhooks, for forwarding them over gRPC or
+// storing them in columnar systems.
+//
+// Field numbers are stable: fields are only ever added, and the numbers of
+// removed ones are reserved.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: travis.proto
+
+package travispb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// BuildFilter selects build events, every non-empty field must match: one
+// of its path.Match patterns matches the build
+type BuildFilter struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// repos are repository slugs, owner/name
+	Repos    []string `protobuf:"bytes,1,rep,name=repos,proto3" json:"repos,omitempty"`
+	Branches []string `protobuf:"bytes,2,rep,name=branches,proto3" json:"branches,omitempty"`
+	// outcomes are passed, failed, errored, canceled or pending
+	Outcomes []string `protobuf:"bytes,3,rep,name=outcomes,proto3" json:"outcomes,omitempty"`
+	// types are push, pull_request, cron or api
+	Types         []string `protobuf:"bytes,4,rep,name=types,proto3" json:"types,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildFilter) Reset() {
+	*x = BuildFilter{}
+	mi := &file_travis_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildFilter) ProtoMessage() {}
+
+func (x *BuildFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_travis_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildFilter.ProtoReflect.Descriptor instead.
+func (*BuildFilter) Descriptor() ([]byte, []int) {
+	return file_travis_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BuildFilter) GetRepos() []string {
+	if x != nil {
+		return x.Repos
+	}
+	return nil
+}
+
+func (x *BuildFilter) GetBranches() []string {
+	if x != nil {
+		return x.Branches
+	}
+	return nil
+}
+
+func (x *BuildFilter) GetOutcomes() []string {
+	if x != nil {
+		return x.Outcomes
+	}
+	return nil
+}
+
+func (x *BuildFilter) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+// BuildEvent is a webhook payload of a build
+type BuildEvent struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Number string                 `protobuf:"bytes,2,opt,name=number,proto3" json:"number,omitempty"`
+	// type is push, pull_request, cron or api
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	Status        int32                  `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	Result        int32                  `protobuf:"varint,6,opt,name=result,proto3" json:"result,omitempty"`
+	StatusMessage string                 `protobuf:"bytes,7,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
+	ResultMessage string                 `protobuf:"bytes,8,opt,name=result_message,json=resultMessage,proto3" json:"result_message,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	// duration is in seconds
+	Duration          int32                  `protobuf:"varint,11,opt,name=duration,proto3" json:"duration,omitempty"`
+	BuildUrl          string                 `protobuf:"bytes,12,opt,name=build_url,json=buildUrl,proto3" json:"build_url,omitempty"`
+	CommitId          int64                  `protobuf:"varint,13,opt,name=commit_id,json=commitId,proto3" json:"commit_id,omitempty"`
+	Commit            string                 `protobuf:"bytes,14,opt,name=commit,proto3" json:"commit,omitempty"`
+	BaseCommit        string                 `protobuf:"bytes,15,opt,name=base_commit,json=baseCommit,proto3" json:"base_commit,omitempty"`
+	HeadCommit        string                 `protobuf:"bytes,16,opt,name=head_commit,json=headCommit,proto3" json:"head_commit,omitempty"`
+	Branch            string                 `protobuf:"bytes,17,opt,name=branch,proto3" json:"branch,omitempty"`
+	Message           string                 `protobuf:"bytes,18,opt,name=message,proto3" json:"message,omitempty"`
+	CompareUrl        string                 `protobuf:"bytes,19,opt,name=compare_url,json=compareUrl,proto3" json:"compare_url,omitempty"`
+	CommittedAt       *timestamppb.Timestamp `protobuf:"bytes,20,opt,name=committed_at,json=committedAt,proto3" json:"committed_at,omitempty"`
+	AuthorName        string                 `protobuf:"bytes,21,opt,name=author_name,json=authorName,proto3" json:"author_name,omitempty"`
+	AuthorEmail       string                 `protobuf:"bytes,22,opt,name=author_email,json=authorEmail,proto3" json:"author_email,omitempty"`
+	CommitterName     string                 `protobuf:"bytes,23,opt,name=committer_name,json=committerName,proto3" json:"committer_name,omitempty"`
+	CommitterEmail    string                 `protobuf:"bytes,24,opt,name=committer_email,json=committerEmail,proto3" json:"committer_email,omitempty"`
+	PullRequest       bool                   `protobuf:"varint,25,opt,name=pull_request,json=pullRequest,proto3" json:"pull_request,omitempty"`
+	PullRequestNumber int32                  `protobuf:"varint,26,opt,name=pull_request_number,json=pullRequestNumber,proto3" json:"pull_request_number,omitempty"`
+	PullRequestTitle  string                 `protobuf:"bytes,27,opt,name=pull_request_title,json=pullRequestTitle,proto3" json:"pull_request_title,omitempty"`
+	Tag               string                 `protobuf:"bytes,28,opt,name=tag,proto3" json:"tag,omitempty"`
+	Repository        *Repository            `protobuf:"bytes,29,opt,name=repository,proto3" json:"repository,omitempty"`
+	Jobs              []*Job                 `protobuf:"bytes,30,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	// config is the build config as the JSON Travis sends
+	Config []byte `protobuf:"bytes,31,opt,name=config,proto3" json:"config,omitempty"`
+	// outcome is passed, failed, errored, canceled or pending, as the
+	// payload's status is read by the Go package
+	Outcome string `protobuf:"bytes,32,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	// source is the Travis instance of the build, e.g. travis-ci.com/123
+	Source        string `protobuf:"bytes,33,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildEvent) Reset() {
+	*x = BuildEvent{}
+	mi := &file_travis_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildEvent) ProtoMessage() {}
+
+func (x *BuildEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_travis_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildEvent.ProtoReflect.Descriptor instead.
+func (*BuildEvent) Descriptor() ([]byte, []int) {
+	return file_travis_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BuildEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetResult() int32 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetResultMessage() string {
+	if x != nil {
+		return x.ResultMessage
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetDuration() int32 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetBuildUrl() string {
+	if x != nil {
+		return x.BuildUrl
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetCommitId() int64 {
+	if x != nil {
+		return x.CommitId
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetBaseCommit() string {
+	if x != nil {
+		return x.BaseCommit
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetHeadCommit() string {
+	if x != nil {
+		return x.HeadCommit
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetCompareUrl() string {
+	if x != nil {
+		return x.CompareUrl
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetCommittedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CommittedAt
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetAuthorName() string {
+	if x != nil {
+		return x.AuthorName
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetAuthorEmail() string {
+	if x != nil {
+		return x.AuthorEmail
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetCommitterName() string {
+	if x != nil {
+		return x.CommitterName
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetCommitterEmail() string {
+	if x != nil {
+		return x.CommitterEmail
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetPullRequest() bool {
+	if x != nil {
+		return x.PullRequest
+	}
+	return false
+}
+
+func (x *BuildEvent) GetPullRequestNumber() int32 {
+	if x != nil {
+		return x.PullRequestNumber
+	}
+	return 0
+}
+
+func (x *BuildEvent) GetPullRequestTitle() string {
+	if x != nil {
+		return x.PullRequestTitle
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetRepository() *Repository {
+	if x != nil {
+		return x.Repository
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *BuildEvent) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *BuildEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+// Repository is the repository of a build
+type Repository struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	OwnerName     string                 `protobuf:"bytes,3,opt,name=owner_name,json=ownerName,proto3" json:"owner_name,omitempty"`
+	Url           string                 `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Repository) Reset() {
+	*x = Repository{}
+	mi := &file_travis_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Repository) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Repository) ProtoMessage() {}
+
+func (x *Repository) ProtoReflect() protoreflect.Message {
+	mi := &file_travis_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Repository.ProtoReflect.Descriptor instead.
+func (*Repository) Descriptor() ([]byte, []int) {
+	return file_travis_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Repository) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Repository) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Repository) GetOwnerName() string {
+	if x != nil {
+		return x.OwnerName
+	}
+	return ""
+}
+
+func (x *Repository) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+// Job is a job of the build matrix
+type Job struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	RepositoryId int64                  `protobuf:"varint,2,opt,name=repository_id,json=repositoryId,proto3" json:"repository_id,omitempty"`
+	ParentId     int64                  `protobuf:"varint,3,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	Number       string                 `protobuf:"bytes,4,opt,name=number,proto3" json:"number,omitempty"`
+	State        string                 `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	Status       int32                  `protobuf:"varint,6,opt,name=status,proto3" json:"status,omitempty"`
+	Result       int32                  `protobuf:"varint,7,opt,name=result,proto3" json:"result,omitempty"`
+	StartedAt    *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt   *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	AllowFailure bool                   `protobuf:"varint,10,opt,name=allow_failure,json=allowFailure,proto3" json:"allow_failure,omitempty"`
+	// config is the job config as the JSON Travis sends
+	Config        []byte `protobuf:"bytes,11,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_travis_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_travis_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_travis_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Job) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Job) GetRepositoryId() int64 {
+	if x != nil {
+		return x.RepositoryId
+	}
+	return 0
+}
+
+func (x *Job) GetParentId() int64 {
+	if x != nil {
+		return x.ParentId
+	}
+	return 0
+}
+
+func (x *Job) GetNumber() string {
+	if x != nil {
+		return x.Number
+	}
+	return ""
+}
+
+func (x *Job) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *Job) GetResult() int32 {
+	if x != nil {
+		return x.Result
+	}
+	return 0
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Job) GetAllowFailure() bool {
+	if x != nil {
+		return x.AllowFailure
+	}
+	return false
+}
+
+func (x *Job) GetConfig() []byte {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+var File_travis_proto protoreflect.FileDescriptor
+
+const file_travis_proto_rawDesc = "" +
+	"\n" +
+	"\ftravis.proto\x12\ttravis.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"q\n" +
+	"\vBuildFilter\x12\x14\n" +
+	"\x05repos\x18\x01 \x03(\tR\x05repos\x12\x1a\n" +
+	"\bbranches\x18\x02 \x03(\tR\bbranches\x12\x1a\n" +
+	"\boutcomes\x18\x03 \x03(\tR\boutcomes\x12\x14\n" +
+	"\x05types\x18\x04 \x03(\tR\x05types\"\xe2\b\n" +
+	"\n" +
+	"BuildEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x16\n" +
+	"\x06number\x18\x02 \x01(\tR\x06number\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x14\n" +
+	"\x05state\x18\x04 \x01(\tR\x05state\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\x05R\x06status\x12\x16\n" +
+	"\x06result\x18\x06 \x01(\x05R\x06result\x12%\n" +
+	"\x0estatus_message\x18\a \x01(\tR\rstatusMessage\x12%\n" +
+	"\x0eresult_message\x18\b \x01(\tR\rresultMessage\x129\n" +
+	"\n" +
+	"started_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12\x1a\n" +
+	"\bduration\x18\v \x01(\x05R\bduration\x12\x1b\n" +
+	"\tbuild_url\x18\f \x01(\tR\bbuildUrl\x12\x1b\n" +
+	"\tcommit_id\x18\r \x01(\x03R\bcommitId\x12\x16\n" +
+	"\x06commit\x18\x0e \x01(\tR\x06commit\x12\x1f\n" +
+	"\vbase_commit\x18\x0f \x01(\tR\n" +
+	"baseCommit\x12\x1f\n" +
+	"\vhead_commit\x18\x10 \x01(\tR\n" +
+	"headCommit\x12\x16\n" +
+	"\x06branch\x18\x11 \x01(\tR\x06branch\x12\x18\n" +
+	"\amessage\x18\x12 \x01(\tR\amessage\x12\x1f\n" +
+	"\vcompare_url\x18\x13 \x01(\tR\n" +
+	"compareUrl\x12=\n" +
+	"\fcommitted_at\x18\x14 \x01(\v2\x1a.google.protobuf.TimestampR\vcommittedAt\x12\x1f\n" +
+	"\vauthor_name\x18\x15 \x01(\tR\n" +
+	"authorName\x12!\n" +
+	"\fauthor_email\x18\x16 \x01(\tR\vauthorEmail\x12%\n" +
+	"\x0ecommitter_name\x18\x17 \x01(\tR\rcommitterName\x12'\n" +
+	"\x0fcommitter_email\x18\x18 \x01(\tR\x0ecommitterEmail\x12!\n" +
+	"\fpull_request\x18\x19 \x01(\bR\vpullRequest\x12.\n" +
+	"\x13pull_request_number\x18\x1a \x01(\x05R\x11pullRequestNumber\x12,\n" +
+	"\x12pull_request_title\x18\x1b \x01(\tR\x10pullRequestTitle\x12\x10\n" +
+	"\x03tag\x18\x1c \x01(\tR\x03tag\x125\n" +
+	"\n" +
+	"repository\x18\x1d \x01(\v2\x15.travis.v1.RepositoryR\n" +
+	"repository\x12\"\n" +
+	"\x04jobs\x18\x1e \x03(\v2\x0e.travis.v1.JobR\x04jobs\x12\x16\n" +
+	"\x06config\x18\x1f \x01(\fR\x06config\x12\x18\n" +
+	"\aoutcome\x18  \x01(\tR\aoutcome\x12\x16\n" +
+	"\x06source\x18! \x01(\tR\x06source\"a\n" +
+	"\n" +
+	"Repository\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"owner_name\x18\x03 \x01(\tR\townerName\x12\x10\n" +
+	"\x03url\x18\x04 \x01(\tR\x03url\"\xea\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12#\n" +
+	"\rrepository_id\x18\x02 \x01(\x03R\frepositoryId\x12\x1b\n" +
+	"\tparent_id\x18\x03 \x01(\x03R\bparentId\x12\x16\n" +
+	"\x06number\x18\x04 \x01(\tR\x06number\x12\x14\n" +
+	"\x05state\x18\x05 \x01(\tR\x05state\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\x05R\x06status\x12\x16\n" +
+	"\x06result\x18\a \x01(\x05R\x06result\x129\n" +
+	"\n" +
+	"started_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x12#\n" +
+	"\rallow_failure\x18\n" +
+	" \x01(\bR\fallowFailure\x12\x16\n" +
+	"\x06config\x18\v \x01(\fR\x06config2L\n" +
+	"\x06Builds\x12B\n" +
+	"\x0fSubscribeBuilds\x12\x16.travis.v1.BuildFilter\x1a\x15.travis.v1.BuildEvent0\x01B$Z\"github.com/jacksgt/travis/travispbb\x06proto3"
+
+var (
+	file_travis_proto_rawDescOnce sync.Once
+	file_travis_proto_rawDescData []byte
+)
+
+func file_travis_proto_rawDescGZIP() []byte {
+	file_travis_proto_rawDescOnce.Do(func() {
+		file_travis_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_travis_proto_rawDesc), len(file_travis_proto_rawDesc)))
+	})
+	return file_travis_proto_rawDescData
+}
+
+var file_travis_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_travis_proto_goTypes = []any{
+	(*BuildFilter)(nil),           // 0: travis.v1.BuildFilter
+	(*BuildEvent)(nil),            // 1: travis.v1.BuildEvent
+	(*Repository)(nil),            // 2: travis.v1.Repository
+	(*Job)(nil),                   // 3: travis.v1.Job
+	(*timestamppb.Timestamp)(nil), // 4: google.protobuf.Timestamp
+}
+var file_travis_proto_depIdxs = []int32{
+	4, // 0: travis.v1.BuildEvent.started_at:type_name -> google.protobuf.Timestamp
+	4, // 1: travis.v1.BuildEvent.finished_at:type_name -> google.protobuf.Timestamp
+	4, // 2: travis.v1.BuildEvent.committed_at:type_name -> google.protobuf.Timestamp
+	2, // 3: travis.v1.BuildEvent.repository:type_name -> travis.v1.Repository
+	3, // 4: travis.v1.BuildEvent.jobs:type_name -> travis.v1.Job
+	4, // 5: travis.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	4, // 6: travis.v1.Job.finished_at:type_name -> google.protobuf.Timestamp
+	0, // 7: travis.v1.Builds.SubscribeBuilds:input_type -> travis.v1.BuildFilter
+	1, // 8: travis.v1.Builds.SubscribeBuilds:output_type -> travis.v1.BuildEvent
+	8, // [8:9] is the sub-list for method output_type
+	7, // [7:8] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_travis_proto_init() }
+func file_travis_proto_init() {
+	if File_travis_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_travis_proto_rawDesc), len(file_travis_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_travis_proto_goTypes,
+		DependencyIndexes: file_travis_proto_depIdxs,
+		MessageInfos:      file_travis_proto_msgTypes,
+	}.Build()
+	File_travis_proto = out.File
+	file_travis_proto_goTypes = nil
+	file_travis_proto_depIdxs = nil
+}
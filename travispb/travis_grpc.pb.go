@@ -0,0 +1,138 @@
+// The build events of Travis CI webhooks, for forwarding them over gRPC or
+// storing them in columnar systems.
+//
+// Field numbers are stable: fields are only ever added, and the numbers of
+// removed ones are reserved.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: travis.proto
+
+package travispb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Builds_SubscribeBuilds_FullMethodName = "/travis.v1.Builds/SubscribeBuilds"
+)
+
+// BuildsClient is the client API for Builds service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Builds streams the build events received by a webhook receiver
+type BuildsClient interface {
+	// SubscribeBuilds streams the verified build events matching the filter
+	// as they are received, until the client cancels
+	SubscribeBuilds(ctx context.Context, in *BuildFilter, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BuildEvent], error)
+}
+
+type buildsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBuildsClient(cc grpc.ClientConnInterface) BuildsClient {
+	return &buildsClient{cc}
+}
+
+func (c *buildsClient) SubscribeBuilds(ctx context.Context, in *BuildFilter, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BuildEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Builds_ServiceDesc.Streams[0], Builds_SubscribeBuilds_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BuildFilter, BuildEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Builds_SubscribeBuildsClient = grpc.ServerStreamingClient[BuildEvent]
+
+// BuildsServer is the server API for Builds service.
+// All implementations must embed UnimplementedBuildsServer
+// for forward compatibility.
+//
+// Builds streams the build events received by a webhook receiver
+type BuildsServer interface {
+	// SubscribeBuilds streams the verified build events matching the filter
+	// as they are received, until the client cancels
+	SubscribeBuilds(*BuildFilter, grpc.ServerStreamingServer[BuildEvent]) error
+	mustEmbedUnimplementedBuildsServer()
+}
+
+// UnimplementedBuildsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBuildsServer struct{}
+
+func (UnimplementedBuildsServer) SubscribeBuilds(*BuildFilter, grpc.ServerStreamingServer[BuildEvent]) error {
+	return status.Error(codes.Unimplemented, "method SubscribeBuilds not implemented")
+}
+func (UnimplementedBuildsServer) mustEmbedUnimplementedBuildsServer() {}
+func (UnimplementedBuildsServer) testEmbeddedByValue()                {}
+
+// UnsafeBuildsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BuildsServer will
+// result in compilation errors.
+type UnsafeBuildsServer interface {
+	mustEmbedUnimplementedBuildsServer()
+}
+
+func RegisterBuildsServer(s grpc.ServiceRegistrar, srv BuildsServer) {
+	// If the following call panics, it indicates UnimplementedBuildsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Builds_ServiceDesc, srv)
+}
+
+func _Builds_SubscribeBuilds_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BuildFilter)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BuildsServer).SubscribeBuilds(m, &grpc.GenericServerStream[BuildFilter, BuildEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Builds_SubscribeBuildsServer = grpc.ServerStreamingServer[BuildEvent]
+
+// Builds_ServiceDesc is the grpc.ServiceDesc for Builds service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Builds_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "travis.v1.Builds",
+	HandlerType: (*BuildsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeBuilds",
+			Handler:       _Builds_SubscribeBuilds_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "travis.proto",
+}
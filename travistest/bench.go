@@ -0,0 +1,231 @@
+package travistest
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+// BenchmarkHandler measures h receiving the webhook of p signed with key,
+// reporting its allocations, e.g. to compare Handler.PoolPayloads:
+//
+//	func BenchmarkHandler(b *testing.B) {
+//		s := travistest.NewServer()
+//		defer s.Close()
+//		p := travistest.NewPayload().Jobs(8).Build()
+//		for _, pool := range []bool{false, true} {
+//			b.Run(fmt.Sprint("pool=", pool), func(b *testing.B) {
+//				h := &travis.Handler{ConfigURL: s.ConfigURL(), PoolPayloads: pool}
+//				travistest.BenchmarkHandler(b, h, p, s.Key)
+//			})
+//		}
+//	}
+//
+// The request and response are reused across iterations, so the
+// allocations reported are those of h. It fails b unless h responds 2xx.
+func BenchmarkHandler(b *testing.B, h http.Handler, p *travis.Payload, key *rsa.PrivateKey) {
+	b.Helper()
+	raw := marshal(b, p)
+	body := url.Values{"payload": {raw}}.Encode()
+	r := NewRawRequest(raw, Sign(raw, key))
+	reader := &requestBody{}
+	w := &benchmarkWriter{header: make(http.Header)}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		r.Body, r.Form, r.PostForm = reader, nil, nil
+		w.code = 0
+		h.ServeHTTP(w, r)
+		if w.code < 200 || w.code > 299 {
+			b.Fatalf("got %d, want 2xx", w.code)
+		}
+	}
+}
+
+// requestBody is a request body that can be reset
+type requestBody struct {
+	strings.Reader
+}
+
+func (*requestBody) Close() error { return nil }
+
+// benchmarkWriter is a ResponseWriter discarding the response
+type benchmarkWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *benchmarkWriter) Header() http.Header { return w.header }
+
+func (w *benchmarkWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (w *benchmarkWriter) WriteHeader(code int) { w.code = code }
+
+// BenchmarkSizes are the numbers of jobs of the payloads of BenchmarkSuite,
+// from a single job build to a large matrix
+var BenchmarkSizes = []int{1, 8, 64}
+
+// Benchmark is a benchmark of BenchmarkSuite
+type Benchmark struct {
+	// Name is the step and the number of jobs of the payload, e.g.
+	// verify/jobs=8
+	Name string
+	F    func(b *testing.B)
+}
+
+// BenchmarkSuite returns the benchmarks of the steps of receiving a webhook
+// (form parsing, signature verification, peeking, decoding and
+// dispatching) and of the whole Handler, for payloads of each of
+// BenchmarkSizes. Run them from a test:
+//
+//	func BenchmarkTravis(b *testing.B) {
+//		for _, bm := range travistest.BenchmarkSuite() {
+//			b.Run(bm.Name, bm.F)
+//		}
+//	}
+//
+// or with testing.Benchmark, as cmd/travis-bench does.
+func BenchmarkSuite() []Benchmark {
+	key, _ := KeyPair()
+	var suite []Benchmark
+	for _, jobs := range BenchmarkSizes {
+		p := NewPayload().Jobs(jobs).Build()
+		suffix := fmt.Sprintf("/jobs=%d", jobs)
+		suite = append(suite,
+			Benchmark{"read" + suffix, func(b *testing.B) { BenchmarkReadWebhook(b, p, key) }},
+			Benchmark{"verify" + suffix, func(b *testing.B) { BenchmarkVerify(b, p, key) }},
+			Benchmark{"peek" + suffix, func(b *testing.B) { BenchmarkPeek(b, p) }},
+			Benchmark{"decode" + suffix, func(b *testing.B) { BenchmarkDecode(b, p) }},
+			Benchmark{"dispatch" + suffix, func(b *testing.B) {
+				BenchmarkDispatch(b, &travis.Dispatcher{Sinks: nopSinks(4)}, p)
+			}},
+			Benchmark{"dispatch-async" + suffix, func(b *testing.B) {
+				BenchmarkDispatch(b, &travis.Dispatcher{Sinks: nopSinks(4), Workers: 4}, p)
+			}},
+			Benchmark{"handler" + suffix, func(b *testing.B) {
+				s := NewServer()
+				defer s.Close()
+				BenchmarkHandler(b, &travis.Handler{ConfigURL: s.ConfigURL()}, p, s.Key)
+			}},
+		)
+	}
+	return suite
+}
+
+func nopSinks(n int) []travis.Sink {
+	sinks := make([]travis.Sink, n)
+	for i := range sinks {
+		sinks[i] = travis.SinkFunc(func(*travis.Payload) error { return nil })
+	}
+	return sinks
+}
+
+// marshal returns the JSON of p
+func marshal(b *testing.B, p *travis.Payload) string {
+	b.Helper()
+	raw, err := json.Marshal(p)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return string(raw)
+}
+
+// BenchmarkReadWebhook measures travis.ReadWebhook parsing the form of the
+// webhook of p signed with key
+func BenchmarkReadWebhook(b *testing.B, p *travis.Payload, key *rsa.PrivateKey) {
+	b.Helper()
+	raw := marshal(b, p)
+	body := url.Values{"payload": {raw}}.Encode()
+	r := NewRawRequest(raw, Sign(raw, key))
+	reader := &requestBody{}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(body)
+		r.Body, r.Form, r.PostForm = reader, nil, nil
+		if _, _, err := travis.ReadWebhook(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkVerify measures travis.VerifySignature checking the signature of
+// p with the public key of key
+func BenchmarkVerify(b *testing.B, p *travis.Payload, key *rsa.PrivateKey) {
+	b.Helper()
+	raw := marshal(b, p)
+	signature := Sign(raw, key)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := travis.VerifySignature(raw, signature, &key.PublicKey); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPeek measures travis.PeekPayload reading the JSON of p
+func BenchmarkPeek(b *testing.B, p *travis.Payload) {
+	b.Helper()
+	raw := marshal(b, p)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := travis.PeekPayload(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecode measures travis.DecodePayloadInto decoding the JSON of p
+// into a new payload, as a Handler does without PoolPayloads
+func BenchmarkDecode(b *testing.B, p *travis.Payload) {
+	b.Helper()
+	raw := marshal(b, p)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := travis.DecodePayloadInto(raw, new(travis.Payload)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDispatch measures d dispatching p, including the sending of the
+// queued payloads if d is asynchronous. d is closed at the end.
+func BenchmarkDispatch(b *testing.B, d *travis.Dispatcher, p *travis.Payload) {
+	b.Helper()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := d.Dispatch(ctx, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+	d.Close()
+}
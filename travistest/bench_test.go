@@ -0,0 +1,9 @@
+package travistest
+
+import "testing"
+
+func BenchmarkTravis(b *testing.B) {
+	for _, bm := range BenchmarkSuite() {
+		b.Run(bm.Name, bm.F)
+	}
+}
@@ -0,0 +1,239 @@
+package travistest
+
+import (
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// Status is the status message of a payload
+type Status string
+
+// The status messages Travis sends
+const (
+	Pending      Status = "Pending"
+	Passed       Status = "Passed"
+	Fixed        Status = "Fixed"
+	Broken       Status = "Broken"
+	Failed       Status = "Failed"
+	StillFailing Status = "Still Failing"
+	Canceled     Status = "Canceled"
+	Errored      Status = "Errored"
+)
+
+// Statuses lists every Status
+var Statuses = []Status{Pending, Passed, Fixed, Broken, Failed, StillFailing, Canceled, Errored}
+
+// Events lists the build types Travis sends
+var Events = []string{"push", "pull_request", "cron", "api"}
+
+// DefaultStart is the start time of the builds of NewPayload
+var DefaultStart = time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// PayloadBuilder builds consistent payloads, its methods return the builder
+// so calls can be chained:
+//
+//	p := travistest.NewPayload().Repo("a/b").Branch("main").State(travistest.Broken).PR(42).Build()
+type PayloadBuilder struct {
+	id       int64
+	number   string
+	event    string
+	status   Status
+	slug     string
+	branch   string
+	tag      string
+	pr       int
+	prTitle  string
+	commit   string
+	message  string
+	author   string
+	email    string
+	start    time.Time
+	duration time.Duration
+	jobs     int
+}
+
+// NewPayload returns a builder of a passed push build of master in
+// octocat/hello-world with one job, started at DefaultStart
+func NewPayload() *PayloadBuilder {
+	return &PayloadBuilder{
+		id:       1,
+		event:    "push",
+		status:   Passed,
+		slug:     "octocat/hello-world",
+		branch:   "master",
+		message:  "Update README",
+		author:   "The Octocat",
+		email:    "octocat@example.com",
+		start:    DefaultStart,
+		duration: 3 * time.Minute,
+		jobs:     1,
+	}
+}
+
+// ID sets the build id, the number defaults to it
+func (b *PayloadBuilder) ID(id int64) *PayloadBuilder { b.id = id; return b }
+
+// Number sets the build number
+func (b *PayloadBuilder) Number(n string) *PayloadBuilder { b.number = n; return b }
+
+// Repo sets the repository slug ("owner/name")
+func (b *PayloadBuilder) Repo(slug string) *PayloadBuilder { b.slug = slug; return b }
+
+// Branch sets the branch, or the target branch of a pull request
+func (b *PayloadBuilder) Branch(name string) *PayloadBuilder { b.branch = name; return b }
+
+// State sets the status message and the state, status and result matching it
+func (b *PayloadBuilder) State(s Status) *PayloadBuilder { b.status = s; return b }
+
+// Event sets the build type: push, pull_request, cron or api
+func (b *PayloadBuilder) Event(event string) *PayloadBuilder { b.event = event; return b }
+
+// PR makes the build a pull_request build of pull request n
+func (b *PayloadBuilder) PR(n int) *PayloadBuilder {
+	b.event, b.pr = "pull_request", n
+	if b.prTitle == "" {
+		b.prTitle = fmt.Sprintf("Pull request %d", n)
+	}
+	return b
+}
+
+// PRTitle sets the title of the pull request
+func (b *PayloadBuilder) PRTitle(title string) *PayloadBuilder { b.prTitle = title; return b }
+
+// Cron makes the build a cron build
+func (b *PayloadBuilder) Cron() *PayloadBuilder { return b.Event("cron") }
+
+// API makes the build an api build
+func (b *PayloadBuilder) API() *PayloadBuilder { return b.Event("api") }
+
+// Tag makes the build a push build of tag name
+func (b *PayloadBuilder) Tag(name string) *PayloadBuilder {
+	b.event, b.tag, b.branch = "push", name, name
+	return b
+}
+
+// Commit sets the commit SHA, derived from the build id by default
+func (b *PayloadBuilder) Commit(sha string) *PayloadBuilder { b.commit = sha; return b }
+
+// Message sets the commit message
+func (b *PayloadBuilder) Message(msg string) *PayloadBuilder { b.message = msg; return b }
+
+// Author sets the author and committer of the commit
+func (b *PayloadBuilder) Author(name, email string) *PayloadBuilder {
+	b.author, b.email = name, email
+	return b
+}
+
+// StartedAt sets the start time of the build
+func (b *PayloadBuilder) StartedAt(t time.Time) *PayloadBuilder { b.start = t; return b }
+
+// Duration sets how long the build ran, it is ignored for pending builds
+func (b *PayloadBuilder) Duration(d time.Duration) *PayloadBuilder { b.duration = d; return b }
+
+// Jobs sets the number of jobs in the matrix, they all share the state of the build
+func (b *PayloadBuilder) Jobs(n int) *PayloadBuilder { b.jobs = n; return b }
+
+// Build returns a new payload
+func (b *PayloadBuilder) Build() *travis.Payload {
+	owner, name := b.slug, ""
+	if i := strings.Index(b.slug, "/"); i >= 0 {
+		owner, name = b.slug[:i], b.slug[i+1:]
+	}
+	number := b.number
+	if number == "" {
+		number = fmt.Sprint(b.id)
+	}
+	commit := b.commit
+	if commit == "" {
+		sum := sha1.Sum([]byte(fmt.Sprint(b.slug, b.id)))
+		commit = hex.EncodeToString(sum[:])
+	}
+	state, code := stateOf(b.status)
+	repoID := int64(len(b.slug)) * 1000
+
+	p := &travis.Payload{
+		ID:            b.id,
+		Number:        number,
+		Type:          b.event,
+		State:         state,
+		StatusMessage: string(b.status),
+		ResultMessage: string(b.status),
+		Status:        code,
+		Result:        code,
+		StartedAt:     b.start,
+		BuildURL:      fmt.Sprintf("https://travis-ci.org/%s/builds/%d", b.slug, b.id),
+		CommitID:      int(b.id),
+		Commit:        commit,
+		Branch:        b.branch,
+		Message:       b.message,
+		CompareURL:    fmt.Sprintf("https://github.com/%s/commit/%s", b.slug, commit),
+		CommitedAt:    b.start.Add(-time.Minute),
+		AuthorName:    b.author,
+		AuthorEmail:   b.email,
+		CommiterName:  b.author,
+		CommiterEmail: b.email,
+		Tag:           b.tag,
+		Config:        &travis.Config{Language: "go", Dist: "focal"},
+		Repository: &travis.Repository{
+			ID:        repoID,
+			Name:      name,
+			OwnerName: owner,
+			URL:       "https://github.com/" + b.slug,
+		},
+	}
+	if b.event == "pull_request" {
+		p.PullRequest = 1
+		p.PullRequestNumber = b.pr
+		p.PullRequestTitle = b.prTitle
+		p.CompareURL = fmt.Sprintf("https://github.com/%s/pull/%d", b.slug, b.pr)
+		p.HeadCommit = commit
+		sum := sha1.Sum([]byte(b.branch))
+		p.BaseCommit = hex.EncodeToString(sum[:])
+	}
+	if b.status != Pending {
+		p.FinishedAt = b.start.Add(b.duration)
+		p.Duration = int(b.duration.Seconds())
+	}
+	for i := 1; i <= b.jobs; i++ {
+		p.Matrix = append(p.Matrix, &travis.Job{
+			ID:           b.id*100 + int64(i),
+			RepositoryID: repoID,
+			ParentID:     b.id,
+			Number:       fmt.Sprintf("%s.%d", number, i),
+			State:        state,
+			Status:       code,
+			Result:       code,
+			StartedAt:    p.StartedAt,
+			FinishedAt:   p.FinishedAt,
+			Config:       p.Config,
+		})
+	}
+	return p
+}
+
+// Request returns a webhook request for the payload signed with key
+func (b *PayloadBuilder) Request(key *rsa.PrivateKey) *http.Request {
+	return NewSignedRequest(b.Build(), key)
+}
+
+// stateOf returns the state and status code of a build with status s
+func stateOf(s Status) (string, int) {
+	switch s {
+	case Passed, Fixed:
+		return "passed", 0
+	case Broken, Failed, StillFailing:
+		return "failed", 1
+	case Errored:
+		return "errored", 1
+	case Canceled:
+		return "canceled", 1
+	}
+	return "started", 0
+}
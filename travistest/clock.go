@@ -0,0 +1,96 @@
+package travistest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// FakeClock is a travis.Clock that only moves when told to
+//
+//	clock := travistest.NewFakeClock(travistest.DefaultStart)
+//	d := travis.NewDigest(n, time.Hour, travis.WithClock(clock))
+//	clock.Advance(time.Hour) // sends the digest
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+var _ travis.Clock = (*FakeClock)(nil)
+
+// NewFakeClock returns a clock set to now
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t, firing the tickers due
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+	for _, t := range c.tickers {
+		t.fire(c.now)
+	}
+}
+
+// Advance moves the clock forward by d, firing the tickers due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// NewTicker returns a ticker firing every d of clock time. Like a
+// time.Ticker it drops the ticks a slow receiver misses.
+func (c *FakeClock) NewTicker(d time.Duration) travis.Ticker {
+	if d <= 0 {
+		panic("travistest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{clock: c, interval: d, next: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+type fakeTicker struct {
+	clock    *FakeClock
+	interval time.Duration
+	next     time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.tickers {
+		if other == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			return
+		}
+	}
+}
+
+// fire sends a tick if the ticker is due at now, called with the clock locked
+func (t *fakeTicker) fire(now time.Time) {
+	if now.Before(t.next) {
+		return
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.interval)
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,71 @@
+package travistest
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// StateMatrix is a contract test sending a handler one signed payload per
+// combination of Events and Statuses
+type StateMatrix struct {
+	// Key signs the payloads, the key of KeyPair if nil
+	Key *rsa.PrivateKey
+	// Want returns the expected response code for payload p, any 2xx code
+	// is accepted if nil
+	Want func(status Status, event string) int
+	// Build, if set, customizes the payload builder of each combination
+	Build func(b *PayloadBuilder)
+}
+
+// RunStateMatrix checks that h accepts a payload of every state and event,
+// signed with the key of KeyPair (see NewServer to verify them):
+//
+//	s := travistest.NewServer()
+//	defer s.Close()
+//	travistest.RunStateMatrix(t, &travis.Handler{ConfigURL: s.ConfigURL(), Sinks: sinks})
+func RunStateMatrix(t *testing.T, h http.Handler) {
+	t.Helper()
+	(&StateMatrix{}).Run(t, h)
+}
+
+// Run sends the payloads to h, each in a subtest named like "cron/Canceled"
+func (m *StateMatrix) Run(t *testing.T, h http.Handler) {
+	t.Helper()
+	key := m.Key
+	if key == nil {
+		key, _ = KeyPair()
+	}
+	for i, event := range Events {
+		for j, status := range Statuses {
+			name := event + "/" + strings.ReplaceAll(string(status), " ", "")
+			id := int64(1000 + i*len(Statuses) + j)
+			t.Run(name, func(t *testing.T) {
+				b := NewPayload().ID(id).Event(event).State(status)
+				if event == "pull_request" {
+					b.PR(int(id))
+				}
+				if m.Build != nil {
+					m.Build(b)
+				}
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, b.Request(key))
+
+				if m.Want != nil {
+					if want := m.Want(status, event); w.Code != want {
+						t.Errorf("%s %s build: got %d, want %d: %s", status, event, w.Code, want, body(w))
+					}
+				} else if w.Code < 200 || w.Code > 299 {
+					t.Errorf("%s %s build: got %d, want 2xx: %s", status, event, w.Code, body(w))
+				}
+			})
+		}
+	}
+}
+
+func body(w *httptest.ResponseRecorder) string {
+	return fmt.Sprintf("%q", strings.TrimSpace(w.Body.String()))
+}
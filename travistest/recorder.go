@@ -0,0 +1,145 @@
+package travistest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Recording is a webhook request captured by a Recorder
+type Recording struct {
+	Received time.Time   `json:"received"`
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header"`
+	Body     string      `json:"body"`
+	// Status is the response of the recorded handler
+	Status int `json:"status"`
+}
+
+// Request returns a new request equal to the recorded one
+func (rec *Recording) Request() *http.Request {
+	r := httptest.NewRequest(rec.Method, rec.URL, strings.NewReader(rec.Body))
+	r.Header = rec.Header.Clone()
+	return r
+}
+
+// Recorder is a middleware writing every request its Handler verified,
+// i.e. didn't answer 400 or 401, as a JSON file in Dir. It is meant to
+// capture production deliveries to reproduce them with Replay.
+//
+//	http.Handle("/travis", &travistest.Recorder{Handler: h, Dir: "/var/lib/travis/recordings"})
+type Recorder struct {
+	Handler http.Handler
+	Dir     string
+	// OnError, if set, is called with the errors writing recordings
+	OnError func(err error)
+
+	seq atomic.Int64
+}
+
+// ServeHTTP serves r with the Handler and records it
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	received := time.Now()
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, "cannot read body", http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	rec.Handler.ServeHTTP(sw, r)
+	if sw.status == http.StatusBadRequest || sw.status == http.StatusUnauthorized {
+		return
+	}
+
+	err = rec.write(&Recording{
+		Received: received.UTC(),
+		Method:   r.Method,
+		URL:      r.URL.RequestURI(),
+		Header:   r.Header.Clone(),
+		Body:     string(body),
+		Status:   sw.status,
+	})
+	if err != nil && rec.OnError != nil {
+		rec.OnError(err)
+	}
+}
+
+func (rec *Recorder) write(recording *Recording) error {
+	body, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d-%d.json", recording.Received.UnixNano(), rec.seq.Add(1))
+	return os.WriteFile(filepath.Join(rec.Dir, name), body, 0600)
+}
+
+// Recordings reads the recordings in dir, oldest first
+func Recordings(dir string) ([]*Recording, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	recordings := make([]*Recording, 0, len(names))
+	for _, name := range names {
+		body, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		rec := new(Recording)
+		if err := json.Unmarshal(body, rec); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		recordings = append(recordings, rec)
+	}
+	return recordings, nil
+}
+
+// Replay serves the recordings in dir with h, oldest first, and returns the
+// responses. The recorded signatures are the ones of Travis, h verifies
+// them against the real Travis public key.
+func Replay(dir string, h http.Handler) ([]*httptest.ResponseRecorder, error) {
+	recordings, err := Recordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*httptest.ResponseRecorder, len(recordings))
+	for i, rec := range recordings {
+		responses[i] = httptest.NewRecorder()
+		h.ServeHTTP(responses[i], rec.Request())
+	}
+	return responses, nil
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,107 @@
+package travistest
+
+import (
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jacksgt/travis"
+)
+
+//go:embed samples/*.json
+var samplesFS embed.FS
+
+// Sample is an anonymized payload as sent by Travis, with the values a
+// decoder must find in it
+type Sample struct {
+	// Name is e.g. "pull-request-broken-com": event, status and the
+	// travis-ci.org or travis-ci.com variant
+	Name string
+	// Raw is the payload form value, it is signed by nobody
+	Raw string
+
+	Type    string
+	Outcome string
+	Status  Status
+	Repo    string
+	Branch  string
+}
+
+// samples describes the files in samples/
+var samples = []Sample{
+	{Name: "api-canceled-com", Type: "api", Outcome: travis.OutcomeCanceled, Status: Canceled, Repo: "example-corp/api", Branch: "main"},
+	{Name: "cron-passed-org", Type: "cron", Outcome: travis.OutcomePassed, Status: Passed, Repo: "example/widget", Branch: "master"},
+	{Name: "pull-request-broken-com", Type: "pull_request", Outcome: travis.OutcomeFailed, Status: Broken, Repo: "example-corp/api", Branch: "main"},
+	{Name: "pull-request-still-failing-org", Type: "pull_request", Outcome: travis.OutcomeFailed, Status: StillFailing, Repo: "example/widget", Branch: "master"},
+	{Name: "push-errored-org", Type: "push", Outcome: travis.OutcomeErrored, Status: Errored, Repo: "example/widget", Branch: "feature/login"},
+	{Name: "push-fixed-com", Type: "push", Outcome: travis.OutcomePassed, Status: Fixed, Repo: "example-corp/api", Branch: "main"},
+	{Name: "push-passed-org", Type: "push", Outcome: travis.OutcomePassed, Status: Passed, Repo: "example/widget", Branch: "master"},
+	{Name: "push-pending-com", Type: "push", Outcome: travis.OutcomePending, Status: Pending, Repo: "example-corp/api", Branch: "main"},
+	{Name: "tag-passed-com", Type: "push", Outcome: travis.OutcomePassed, Status: Passed, Repo: "example-corp/api", Branch: "v1.4.0"},
+}
+
+// Samples returns the corpus of real-world payloads sorted by name, e.g. to
+// run a handler against each of them:
+//
+//	for _, s := range travistest.Samples() {
+//		h.ServeHTTP(w, travistest.NewRawRequest(s.Raw, travistest.Sign(s.Raw, key)))
+//	}
+func Samples() []Sample {
+	out := make([]Sample, len(samples))
+	for i, s := range samples {
+		raw, err := samplesFS.ReadFile("samples/" + s.Name + ".json")
+		if err != nil {
+			panic("travistest: missing sample " + s.Name)
+		}
+		s.Raw = string(raw)
+		out[i] = s
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Payload decodes the sample with travis.GetPayload
+func (s Sample) Payload() (*travis.Payload, error) {
+	return travis.GetPayload(strings.NewReader(s.Raw))
+}
+
+// source returns the source of the sample from the suffix of its name
+func (s Sample) source() string {
+	if strings.HasSuffix(s.Name, "-org") {
+		return travis.SourceOrg
+	}
+	return travis.SourceCom
+}
+
+// Check decodes the sample and returns an error if the payload doesn't
+// hold the expected values
+func (s Sample) Check() error {
+	p, err := s.Payload()
+	if err != nil {
+		return fmt.Errorf("%s: %v", s.Name, err)
+	}
+	checks := []struct {
+		field     string
+		got, want string
+	}{
+		{"type", p.Type, s.Type},
+		{"outcome", travis.Outcome(p), s.Outcome},
+		{"status message", p.StatusMessage, string(s.Status)},
+		{"repo", p.Slug(), s.Repo},
+		{"branch", p.Branch, s.Branch},
+		{"source", p.Source(), s.source()},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			return fmt.Errorf("%s: %s is %q, want %q", s.Name, c.field, c.got, c.want)
+		}
+	}
+	if p.CommitedAt.IsZero() || p.CommiterEmail == "" {
+		return fmt.Errorf("%s: commit time or committer is missing", s.Name)
+	}
+	if s.Type == "pull_request" && (!p.IsPullRequest() || p.PullRequestNumber == 0) {
+		return fmt.Errorf("%s: pull request number is missing", s.Name)
+	}
+	return nil
+}
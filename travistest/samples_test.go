@@ -0,0 +1,49 @@
+package travistest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSamples(t *testing.T) {
+	all := Samples()
+	if len(all) == 0 {
+		t.Fatal("no samples")
+	}
+	for _, s := range all {
+		t.Run(s.Name, func(t *testing.T) {
+			if err := s.Check(); err != nil {
+				t.Fatal(err)
+			}
+			p, err := s.Payload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.ID == 0 || p.Number == "" || p.Commit == "" {
+				t.Errorf("id %d, number %q or commit %q missing", p.ID, p.Number, p.Commit)
+			}
+			if strings.HasPrefix(s.Name, "tag-") && p.Tag != s.Branch {
+				t.Errorf("tag is %q, want %q", p.Tag, s.Branch)
+			}
+		})
+	}
+}
+
+func TestSamplesDescribed(t *testing.T) {
+	files, err := samplesFS.ReadDir("samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	described := make(map[string]bool)
+	for _, s := range samples {
+		described[s.Name+".json"] = true
+	}
+	for _, f := range files {
+		if !described[f.Name()] {
+			t.Errorf("samples/%s isn't described in samples", f.Name())
+		}
+	}
+	if len(files) != len(samples) {
+		t.Errorf("%d sample files, %d described", len(files), len(samples))
+	}
+}
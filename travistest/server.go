@@ -0,0 +1,92 @@
+package travistest
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/jacksgt/travis"
+)
+
+// Server is a fake Travis API serving /config with the public key of Key,
+// and JSON fixtures for the v3 API
+//
+//	s := travistest.NewServer()
+//	defer s.Close()
+//	h := &travis.Handler{ConfigURL: s.ConfigURL()}
+//	h.ServeHTTP(w, s.NewSignedRequest(payload))
+//	c := &travis.Client{BaseURL: s.URL}
+type Server struct {
+	*httptest.Server
+	Key *rsa.PrivateKey
+
+	mu       sync.Mutex
+	fixtures map[string]interface{}
+	requests []string
+}
+
+// NewServer starts a Server signing with the key of KeyPair
+func NewServer() *Server {
+	key, _ := KeyPair()
+	s := &Server{Key: key, fixtures: make(map[string]interface{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// ConfigURL returns the URL of the config endpoint, for Handler.ConfigURL
+func (s *Server) ConfigURL() string {
+	return s.URL + "/config"
+}
+
+// Fixture makes the server respond to requests for path, escaped and
+// without the query (e.g. "/repo/owner%2Fname/builds"), with v encoded as JSON
+func (s *Server) Fixture(path string, v interface{}) {
+	s.mu.Lock()
+	s.fixtures[path] = v
+	s.mu.Unlock()
+}
+
+// Requests returns the method and URI of the requests received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// NewSignedRequest returns a webhook request for payload signed with Key
+func (s *Server) NewSignedRequest(payload *travis.Payload) *http.Request {
+	return NewSignedRequest(payload, s.Key)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.RequestURI())
+	v, ok := s.fixtures[r.URL.EscapedPath()]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.URL.Path == "/config" && !ok {
+		v, ok = configResponse(&s.Key.PublicKey), true
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{
+			"@type":         "error",
+			"error_type":    "not_found",
+			"error_message": "resource not found (travistest has no fixture for " + r.URL.Path + ")",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+func configResponse(pub *rsa.PublicKey) interface{} {
+	webhook := map[string]string{"public_key": PublicKeyPEM(pub)}
+	return map[string]interface{}{
+		"config": map[string]interface{}{
+			"notifications": map[string]interface{}{"webhook": webhook},
+		},
+	}
+}
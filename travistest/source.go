@@ -0,0 +1,61 @@
+package travistest
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/jacksgt/travis"
+)
+
+// ErrNoPayload is returned by a Source whose payloads were all returned
+var ErrNoPayload = errors.New("travistest: no more payloads")
+
+// Source is a travis.PayloadSource returning canned payloads in order,
+// whatever the request, which may be nil
+type Source struct {
+	mu       sync.Mutex
+	payloads []*travis.Payload
+	errs     []error
+	// Requests are the requests given to Payload
+	Requests []*http.Request
+}
+
+var _ travis.PayloadSource = (*Source)(nil)
+
+// NewSource returns a source returning payloads then ErrNoPayload
+func NewSource(payloads ...*travis.Payload) *Source {
+	return &Source{payloads: payloads, errs: make([]error, len(payloads))}
+}
+
+// Fail queues err to be returned after the payloads queued so far, e.g.
+// travis.ErrUnauthorized
+func (s *Source) Fail(err error) *Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = append(s.payloads, nil)
+	s.errs = append(s.errs, err)
+	return s
+}
+
+// Add queues payloads after the ones queued so far
+func (s *Source) Add(payloads ...*travis.Payload) *Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = append(s.payloads, payloads...)
+	s.errs = append(s.errs, make([]error, len(payloads))...)
+	return s
+}
+
+// Payload returns the next payload or error
+func (s *Source) Payload(r *http.Request) (*travis.Payload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Requests = append(s.Requests, r)
+	if len(s.payloads) == 0 {
+		return nil, ErrNoPayload
+	}
+	p, err := s.payloads[0], s.errs[0]
+	s.payloads, s.errs = s.payloads[1:], s.errs[1:]
+	return p, err
+}
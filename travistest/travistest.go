@@ -0,0 +1,83 @@
+// Package travistest provides utilities to test code receiving Travis
+// webhooks without a real Travis delivery.
+//
+//	key, _ := travistest.KeyPair()
+//	r := travistest.NewSignedRequest(&travis.Payload{ID: 1, State: "passed"}, key)
+//
+// The signature only verifies against the public key of the pair.
+package travistest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jacksgt/travis"
+)
+
+var (
+	keyOnce sync.Once
+	key     *rsa.PrivateKey
+	keyPEM  string
+)
+
+// KeyPair returns a private key and its public key in the PEM format of the
+// Travis config endpoint. The key is generated once per process as
+// generating RSA keys is slow.
+func KeyPair() (*rsa.PrivateKey, string) {
+	keyOnce.Do(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic("travistest: generating key: " + err.Error())
+		}
+		keyPEM = PublicKeyPEM(&key.PublicKey)
+	})
+	return key, keyPEM
+}
+
+// PublicKeyPEM encodes pub like the Travis config endpoint
+func PublicKeyPEM(pub *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		panic("travistest: encoding public key: " + err.Error())
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// Sign returns the Signature header of the raw payload, see
+// travis.SignPayload
+func Sign(payload string, key *rsa.PrivateKey) string {
+	sig, err := travis.SignPayload([]byte(payload), key)
+	if err != nil {
+		panic("travistest: signing payload: " + err.Error())
+	}
+	return sig
+}
+
+// NewSignedRequest returns a webhook request for payload signed with key,
+// suitable for passing to an http.Handler
+func NewSignedRequest(payload *travis.Payload, key *rsa.PrivateKey) *http.Request {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		panic("travistest: encoding payload: " + err.Error())
+	}
+	return NewRawRequest(string(body), Sign(string(body), key))
+}
+
+// NewRawRequest returns a webhook request for the raw payload with the
+// given Signature header, e.g. to test tampered payloads
+func NewRawRequest(payload, signature string) *http.Request {
+	form := url.Values{"payload": {payload}}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Signature", signature)
+	return r
+}
@@ -0,0 +1,95 @@
+package travis
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ComConfigURL serves the public key of travis-ci.com webhooks,
+// DefaultConfigURL the one of travis-ci.org
+const ComConfigURL = "https://api.travis-ci.com/config"
+
+// VerifySignature checks the base64 Signature header of the raw payload
+// against key. It returns ErrUnauthorized if they don't match.
+func VerifySignature(payload, signature string, key *rsa.PublicKey) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("cannot decode signature")
+	}
+	if rsa.VerifyPKCS1v15(key, crypto.SHA1, payloadDigest(payload), sig) != nil {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// SignPayload returns the base64 Signature header of the raw payload signed
+// with key like Travis signs webhooks, for senders of Travis compatible
+// webhooks
+func SignPayload(payload []byte, key *rsa.PrivateKey) (string, error) {
+	digest := sha1.Sum(payload)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// NewWebhookRequest returns a webhook request posting the raw payload to
+// target as Travis does, in the payload form value with its Signature header
+// signed with key. The request isn't signed if key is nil, e.g. to sign it
+// with HMAC.SignRequest. Use its WithContext method to send it with a
+// context.
+func NewWebhookRequest(target string, payload []byte, key *rsa.PrivateKey) (*http.Request, error) {
+	form := url.Values{"payload": {string(payload)}}
+	r, err := http.NewRequest("POST", target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if key != nil {
+		signature, err := SignPayload(payload, key)
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set("Signature", signature)
+	}
+	return r, nil
+}
+
+// ReadWebhook returns the raw payload and the Signature header of the
+// webhook request r without verifying them. It is the first step of a
+// Handler, followed by VerifySignature, PeekPayload and DecodePayloadInto.
+func ReadWebhook(r *http.Request) (payload, signature string, err error) {
+	if err := checkRequest(r); err != nil {
+		return "", "", err
+	}
+	signature = r.Header.Get("Signature")
+	if signature == "" {
+		return "", "", errors.New("missing Signature header")
+	}
+	return r.PostFormValue("payload"), signature, nil
+}
+
+// ParsePublicKey parses a PEM encoded public key like the one of the Travis
+// config endpoint
+func ParsePublicKey(pem string) (*rsa.PublicKey, error) {
+	return parsePublicKey(pem)
+}
+
+// FetchPublicKey fetches the public key served at url, e.g. DefaultConfigURL
+// or ComConfigURL, with client or http.DefaultClient if nil
+func FetchPublicKey(ctx context.Context, client *http.Client, url string) (*rsa.PublicKey, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	v := &verifier{client: client, log: discardLogger, configURL: url}
+	return v.publicKey(ctx)
+}
@@ -0,0 +1,217 @@
+package webhookconfig
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jacksgt/travis"
+	"github.com/jacksgt/travis/rediscache"
+	"github.com/jacksgt/travis/store"
+	"github.com/jacksgt/travis/store/boltstore"
+	"github.com/redis/go-redis/v9"
+)
+
+// Logger returns the logger of LogLevel and LogFormat writing to w
+func (c *Config) Logger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	switch c.LogFormat {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, errors.New("log_format must be json or text")
+}
+
+// Sinks returns the sinks notifying the targets of the builds routed to
+// them, posting with client or http.DefaultClient if nil
+func (c *Config) Sinks(client *http.Client) []travis.Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	sinks := make([]travis.Sink, 0, len(c.Targets))
+	for _, t := range c.Targets {
+		s := &TargetSink{Target: t, Client: client}
+		if len(c.Routes) > 0 {
+			s.Routes = c.routesOf(t.Name)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks
+}
+
+// routesOf returns the routes naming target, a non-nil slice so that a
+// target without routes receives nothing
+func (c *Config) routesOf(target string) []Route {
+	routes := []Route{}
+	for _, r := range c.Routes {
+		for _, name := range r.Targets {
+			if name == target && target != "" {
+				routes = append(routes, r)
+				break
+			}
+		}
+	}
+	return routes
+}
+
+// Handler returns the handler verifying webhooks as configured and
+// dispatching them to sinks with the configured workers. The caller closes
+// its Dispatcher on shutdown.
+func (c *Config) Handler(sinks []travis.Sink, client *http.Client, log *slog.Logger) *travis.Handler {
+	h := &travis.Handler{
+		Dispatcher: &travis.Dispatcher{
+			Sinks:     sinks,
+			Workers:   c.Workers,
+			QueueSize: c.QueueSize,
+			FailFast:  c.FailFast,
+			Logger:    log,
+		},
+		Client:     client,
+		ConfigURL:  c.ConfigURL,
+		KeyTTL:     time.Duration(c.KeyTTL),
+		RetryAfter: time.Duration(c.RetryAfter),
+		Logger:     log,
+	}
+	if len(c.PriorityBranches) > 0 {
+		h.Dispatcher.Priority = travis.BranchPriority(c.PriorityBranches...)
+	}
+	if c.Dedupe {
+		h.Dedupe, h.DedupeTTL = true, time.Duration(c.DedupeTTL)
+	}
+	if r := c.Redis; r.Addr != "" {
+		client := redis.NewClient(&redis.Options{Addr: r.Addr, Username: r.Username, Password: r.Password, DB: r.DB})
+		h.Cache = &rediscache.Cache{Client: client, Prefix: r.Prefix}
+	}
+	if c.HMACSecret != "" {
+		h.Scheme = &travis.HMAC{Secret: []byte(c.HMACSecret), Header: c.HMACHeader}
+	}
+	return h
+}
+
+// OpenStore opens the configured store, nil if there is none. SQL stores
+// need their driver imported by the program, their table is created if
+// missing.
+func (c *Config) OpenStore(ctx context.Context) (store.Store, error) {
+	s := c.Store
+	retention := store.Retention{
+		MaxAge:        time.Duration(s.MaxAge),
+		MaxPerRepo:    s.MaxPerRepo,
+		KeepPerBranch: s.KeepPerBranch,
+	}
+	switch s.Driver {
+	case "":
+		return nil, nil
+	case "bolt":
+		return boltstore.Open(s.DSN, &boltstore.Options{Retention: retention, Timeout: 10 * time.Second})
+	}
+	db, err := sql.Open(s.Driver, s.DSN)
+	if err != nil {
+		return nil, err
+	}
+	st := &store.SQL{
+		DB:        db,
+		Table:     s.Table,
+		Postgres:  s.Driver == "postgres" || s.Driver == "pgx",
+		Retention: retention,
+	}
+	if err := st.CreateTable(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// Match returns true if p matches every non-empty field of r
+func (r *Route) Match(p *travis.Payload) bool {
+	return r.When.Match(p) &&
+		travis.MatchAny(r.Repos, p.Slug()) &&
+		travis.MatchAny(r.Branches, p.Branch) &&
+		travis.MatchAny(r.Outcomes, travis.Outcome(p)) &&
+		travis.MatchAny(r.Types, p.Type)
+}
+
+// TargetSink posts the payloads matching a target and one of its routes to
+// its URL
+type TargetSink struct {
+	Target
+	// Routes, if not nil, are the routes of the target, a payload must
+	// match one of them
+	Routes []Route
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Send posts p if it matches the target
+func (s *TargetSink) Send(p *travis.Payload) error {
+	return s.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (s *TargetSink) SendContext(ctx context.Context, p *travis.Payload) error {
+	if !s.Match(p) {
+		return nil
+	}
+
+	var body interface{} = p
+	if s.Format != FormatPayload {
+		body = map[string]string{"text": SlackText(p)}
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", s.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// Match returns true if p passes the filters of the target and matches one
+// of its routes
+func (s *TargetSink) Match(p *travis.Payload) bool {
+	if !travis.MatchAny(s.Outcomes, travis.Outcome(p)) || !travis.MatchAny(s.Branches, p.Branch) || !s.When.Match(p) {
+		return false
+	}
+	if s.Routes == nil {
+		return true
+	}
+	for i := range s.Routes {
+		if s.Routes[i].Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// SlackText is the message of FormatSlack targets
+func SlackText(p *travis.Payload) string {
+	return fmt.Sprintf("%s <%s|%s #%s> (%s) %s by %s",
+		travis.StateEmoji(p), p.BuildURL, p.Slug(), p.Number, p.Branch, travis.StageStatus(p), p.AuthorName)
+}
@@ -0,0 +1,64 @@
+package webhookconfig
+
+import (
+	"testing"
+
+	"github.com/jacksgt/travis"
+)
+
+func TestMatch(t *testing.T) {
+	p := &travis.Payload{
+		Type:          "push",
+		Branch:        "release/1.2",
+		State:         "failed",
+		Status:        1,
+		StatusMessage: "Broken",
+		Repository:    &travis.Repository{OwnerName: "acme", Name: "api"},
+	}
+	tests := []struct {
+		name   string
+		route  Route
+		target Target
+		want   bool
+	}{
+		{"empty", Route{}, Target{}, true},
+		{"repo pattern", Route{Repos: []string{"acme/*"}}, Target{}, true},
+		{"other repo", Route{Repos: []string{"acme/web"}}, Target{}, false},
+		{"branch pattern", Route{Branches: []string{"main", "release/*"}}, Target{}, true},
+		{"outcome pattern", Route{Outcomes: []string{"fail*"}}, Target{}, true},
+		{"outcome mismatch", Route{Outcomes: []string{"passed"}}, Target{}, false},
+		{"type pattern", Route{Types: []string{"pu*"}}, Target{}, true},
+		{"type mismatch", Route{Types: []string{"pull_request"}}, Target{}, false},
+		{"target branch pattern", Route{}, Target{Branches: []string{"release/*"}}, true},
+		{"target branch mismatch", Route{}, Target{Branches: []string{"feature/*"}}, false},
+		{"target outcome pattern", Route{}, Target{Outcomes: []string{"*ed"}}, true},
+		{"target outcome mismatch", Route{}, Target{Outcomes: []string{"errored"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &TargetSink{Target: tt.target, Routes: []Route{tt.route}}
+			if got := s.Match(p); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidOutcomes(t *testing.T) {
+	tests := []struct {
+		outcomes []string
+		ok       bool
+	}{
+		{nil, true},
+		{[]string{"failed", "errored"}, true},
+		{[]string{"fail*"}, true},
+		{[]string{"broken"}, false},
+		{[]string{"x*"}, false},
+		{[]string{"[fail"}, false},
+	}
+	for _, tt := range tests {
+		if err := validOutcomes(tt.outcomes); (err == nil) != tt.ok {
+			t.Errorf("validOutcomes(%q) = %v", tt.outcomes, err)
+		}
+	}
+}
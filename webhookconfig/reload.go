@@ -0,0 +1,143 @@
+package webhookconfig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jacksgt/travis"
+)
+
+// DefaultWatchInterval is how often Watch polls a file if interval isn't
+// positive
+const DefaultWatchInterval = 10 * time.Second
+
+// Router is a travis.Sink sending payloads to the targets of a
+// configuration along its routes. Reload swaps the targets and routes
+// while the receiver runs, without dropping deliveries.
+type Router struct {
+	client *http.Client
+	gen    atomic.Pointer[generation]
+}
+
+// generation is the sinks of a configuration, mu is read locked while they
+// send a payload
+type generation struct {
+	config *Config
+	sinks  []travis.Sink
+	mu     sync.RWMutex
+}
+
+// NewRouter returns the router of the targets and routes of c, posting
+// with client or http.DefaultClient if nil
+func NewRouter(c *Config, client *http.Client) *Router {
+	r := &Router{client: client}
+	r.gen.Store(&generation{config: c, sinks: c.Sinks(client)})
+	return r
+}
+
+// Send sends p to the targets routed to and returns their errors
+func (r *Router) Send(p *travis.Payload) error {
+	return r.SendContext(context.Background(), p)
+}
+
+// SendContext is Send with ctx
+func (r *Router) SendContext(ctx context.Context, p *travis.Payload) error {
+	g := r.gen.Load()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var errs []error
+	for _, s := range g.sinks {
+		if err := travis.SendContext(ctx, s, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Config returns the configuration of the targets and routes in use
+func (r *Router) Config() *Config {
+	return r.gen.Load().config
+}
+
+// Reload validates c and atomically replaces the targets and routes with
+// its ones. Payloads dispatched afterwards go to the new targets; Reload
+// returns once the ones being sent to the previous targets are sent, or
+// with the error of ctx. The other fields of c only apply on restart, see
+// RestartRequired.
+func (r *Router) Reload(ctx context.Context, c *Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	old := r.gen.Swap(&generation{config: c, sinks: c.Sinks(r.client)})
+
+	done := make(chan struct{})
+	go func() {
+		old.mu.Lock()
+		old.mu.Unlock()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RestartRequired returns the keys whose change from old to c Reload
+// doesn't apply
+func RestartRequired(old, c *Config) []string {
+	a, b := *old, *c
+	a.Targets, a.Routes, b.Targets, b.Routes = nil, nil, nil, nil
+	var keys []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			keys = append(keys, t.Field(i).Tag.Get("yaml"))
+		}
+	}
+	return keys
+}
+
+// Watch polls the file at path every interval, DefaultWatchInterval if it
+// isn't positive, and calls onChange with the configuration or the error
+// of loading it when it changed, until ctx is done. Changes are noticed by
+// modification time and size, not file system events, so a file rewritten
+// with the same size within the resolution of its modification time is
+// missed.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func(c *Config, err error)) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	stat := func() (time.Time, int64) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+	mtime, size := stat()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		m, s := stat()
+		if m.Equal(mtime) && s == size {
+			continue
+		}
+		mtime, size = m, s
+		onChange(Load(path))
+	}
+}
@@ -0,0 +1,46 @@
+package webhookconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhookd.yaml")
+	if err := os.WriteFile(path, []byte("addr: :8080\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *Config, 1)
+	go Watch(ctx, path, 5*time.Millisecond, func(c *Config, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		changes <- c
+	})
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("addr: :9090\nlog_level: debug\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		if c.Addr != ":9090" {
+			t.Errorf("Addr = %q, want :9090", c.Addr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change not noticed")
+	}
+}
+
+func TestWatchDefaultInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// returns rather than panicking in time.NewTicker
+	Watch(ctx, filepath.Join(t.TempDir(), "webhookd.yaml"), 0, func(c *Config, err error) {
+		t.Error("no change expected")
+	})
+}
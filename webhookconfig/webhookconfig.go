@@ -0,0 +1,441 @@
+// Package webhookconfig is the configuration of a webhook receiver: listen
+// addresses, verification, queueing, notification targets with their
+// routing rules and the build store. It is the configuration of
+// travis-webhookd, and lets programs embedding the package share its schema.
+//
+//	c, err := webhookconfig.Load("/etc/travis-webhookd.yml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	h := c.Handler(c.Sinks(nil), nil, logger)
+//
+// The configuration is YAML, or JSON which is a subset of it:
+//
+//	addr: ":8080"
+//	path: /travis
+//	config_url: https://api.travis-ci.com/config
+//	key_ttl: 1h
+//	workers: 4
+//	fail_fast: true
+//	priority_branches: [main]
+//	targets:
+//	  - name: team
+//	    url: https://hooks.slack.com/services/...
+//	    outcomes: [failed, errored]
+//	  - name: deploy
+//	    url: https://deploy.example.com/hook
+//	    format: payload
+//	routes:
+//	  - repos: [acme/*]
+//	    targets: [team]
+//	  - repos: [acme/api]
+//	    branches: [main]
+//	    outcomes: [passed]
+//	    targets: [deploy]
+//	  - when: 'branch matches "release/*" && state in ["failed", "errored"]'
+//	    targets: [team]
+//	store:
+//	  driver: bolt
+//	  dsn: /var/lib/travis/builds.db
+//	  max_age: 2160h
+//
+// Load overrides the fields with the TRAVIS_WEBHOOKD_* environment
+// variables named in their comments.
+package webhookconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jacksgt/travis"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by Load
+const DefaultEnvPrefix = "TRAVIS_WEBHOOKD_"
+
+// The formats of targets
+const (
+	// FormatSlack posts a {"text": ...} message accepted by Slack,
+	// Mattermost or Rocket.Chat incoming webhooks
+	FormatSlack = "slack"
+	// FormatPayload posts the payload as JSON
+	FormatPayload = "payload"
+)
+
+// Config is the configuration of a webhook receiver. Every field can be
+// overridden by the environment variable in its comment, after the prefix
+// given to ApplyEnv.
+type Config struct {
+	// Addr is the listen address, ADDR
+	Addr string `yaml:"addr"`
+	// GRPCAddr, if set, is the listen address of the gRPC Builds service
+	// streaming the builds to subscribers, GRPC_ADDR
+	GRPCAddr string `yaml:"grpc_addr"`
+	// Path receives the webhooks, PATH
+	Path string `yaml:"path"`
+
+	// ConfigURL serves the public key webhooks are verified with, e.g. the
+	// one of travis-ci.com or of an enterprise host, CONFIG_URL
+	ConfigURL string `yaml:"config_url"`
+	// KeyTTL is how long the public key is cached, KEY_TTL
+	KeyTTL Duration `yaml:"key_ttl"`
+	// HMACSecret, if set, verifies webhooks with this shared secret instead
+	// of the public key, the HMAC-SHA256 of their payload being in the
+	// HMACHeader header, travis.DefaultHMACHeader if empty, HMAC_SECRET and
+	// HMAC_HEADER
+	HMACSecret string `yaml:"hmac_secret"`
+	HMACHeader string `yaml:"hmac_header"`
+
+	// Dedupe drops the deliveries of payloads handled in the last
+	// DedupeTTL, see travis.Handler, DEDUPE and DEDUPE_TTL
+	Dedupe    bool     `yaml:"dedupe"`
+	DedupeTTL Duration `yaml:"dedupe_ttl"`
+	// Redis, if its address is set, keeps the public key and the deliveries
+	// of Dedupe for every replica of the receiver
+	Redis Redis `yaml:"redis"`
+
+	// Workers, if not zero, answer webhooks right away and notify in the
+	// background, WORKERS
+	Workers int `yaml:"workers"`
+	// QueueSize is the capacity of the queue of the workers, QUEUE_SIZE
+	QueueSize int `yaml:"queue_size"`
+	// FailFast answers 503 with a Retry-After of RetryAfter to webhooks
+	// received while the queue is full, FAIL_FAST and RETRY_AFTER
+	FailFast   bool     `yaml:"fail_fast"`
+	RetryAfter Duration `yaml:"retry_after"`
+	// PriorityBranches, if set, makes the workers handle the queued
+	// failures of these branches first, see travis.BranchPriority,
+	// PRIORITY_BRANCHES is a comma separated list
+	PriorityBranches []string `yaml:"priority_branches"`
+
+	// LogLevel is debug, info, warn or error, LOG_LEVEL
+	LogLevel string `yaml:"log_level"`
+	// LogFormat is json or text, LOG_FORMAT
+	LogFormat string `yaml:"log_format"`
+	// ShutdownTimeout is how long in-flight requests are waited for on
+	// shutdown, SHUTDOWN_TIMEOUT
+	ShutdownTimeout Duration `yaml:"shutdown_timeout"`
+
+	// Targets are notified of builds, TARGETS is a comma separated list of
+	// URLs receiving the slack format
+	Targets []Target `yaml:"targets"`
+	// Routes, if any, select the targets of each build: a target is only
+	// notified of the builds matching a route naming it
+	Routes []Route `yaml:"routes"`
+
+	// Store, if its driver is set, saves every build
+	Store Store `yaml:"store"`
+
+	// ReloadInterval, if set, is how often the configuration file is
+	// checked for changes, to reload its targets and routes, RELOAD_INTERVAL
+	ReloadInterval Duration `yaml:"reload_interval"`
+}
+
+// Target is a URL notified of builds
+type Target struct {
+	// Name identifies the target in routes
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Format is FormatSlack, the default, or FormatPayload
+	Format string `yaml:"format"`
+	// Outcomes restricts the notifications to the outcomes (passed,
+	// failed, errored, canceled, pending) matching these path.Match
+	// patterns, all if empty
+	Outcomes []string `yaml:"outcomes"`
+	// Branches restricts the notifications to the branches matching these
+	// path.Match patterns, all if empty
+	Branches []string `yaml:"branches"`
+	// When, if set, is a travis.Filter expression the builds notified
+	// must match
+	When *travis.Filter `yaml:"when"`
+}
+
+// Route sends the builds it matches to targets. Its lists are path.Match
+// patterns, e.g. acme/*, and empty fields match every build.
+type Route struct {
+	Repos    []string `yaml:"repos"`
+	Branches []string `yaml:"branches"`
+	Outcomes []string `yaml:"outcomes"`
+	// Types are event types, e.g. push or pull_request
+	Types []string `yaml:"types"`
+	// When, if set, is a travis.Filter expression the builds must match
+	// as well, e.g. `state in ["failed", "errored"] && !pull_request`
+	When *travis.Filter `yaml:"when"`
+	// Targets are the names of the targets notified
+	Targets []string `yaml:"targets"`
+}
+
+// Redis is the cache shared by replicas, see rediscache.Cache, REDIS_ADDR
+// and REDIS_PASSWORD
+type Redis struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+	// Prefix is prepended to the keys, to share a database
+	Prefix string `yaml:"prefix"`
+}
+
+// Store is the build store, STORE_DRIVER and STORE_DSN
+type Store struct {
+	// Driver is bolt, or the name of a database/sql driver imported by the
+	// program, e.g. sqlite3 or postgres
+	Driver string `yaml:"driver"`
+	// DSN is the file of bolt or the data source name of the SQL driver
+	DSN string `yaml:"dsn"`
+	// Table is the table of SQL stores, travis_builds if empty
+	Table string `yaml:"table"`
+	// MaxAge, MaxPerRepo and KeepPerBranch are the retention policy of
+	// the store, see store.Retention
+	MaxAge        Duration `yaml:"max_age"`
+	MaxPerRepo    int      `yaml:"max_per_repo"`
+	KeepPerBranch int      `yaml:"keep_per_branch"`
+}
+
+// Duration is a time.Duration written like "1h30m"
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (d *Duration) UnmarshalYAML(n *yaml.Node) error {
+	var s string
+	if err := n.Decode(&s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	*d = Duration(v)
+	return err
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Default returns the configuration of an empty file
+func Default() *Config {
+	return &Config{
+		Addr:            ":8080",
+		Path:            "/",
+		LogLevel:        "info",
+		LogFormat:       "json",
+		ShutdownTimeout: Duration(10 * time.Second),
+	}
+}
+
+// Load reads the file at path, if any, over the defaults, then the
+// environment variables prefixed with DefaultEnvPrefix, and validates the
+// result
+func Load(path string) (*Config, error) {
+	c := Default()
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Decode(bytes.NewReader(b)); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if err := c.ApplyEnv(DefaultEnvPrefix); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Decode reads the YAML of r into c, rejecting unknown keys
+func (c *Config) Decode(r io.Reader) error {
+	d := yaml.NewDecoder(r)
+	d.KnownFields(true)
+	if err := d.Decode(c); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// ApplyEnv overrides the fields of c with the environment variables named
+// prefix and the name in their comment
+func (c *Config) ApplyEnv(prefix string) error {
+	env := func(name string, f func(v string) error) error {
+		v, ok := os.LookupEnv(prefix + name)
+		if !ok {
+			return nil
+		}
+		if err := f(v); err != nil {
+			return fmt.Errorf("%s%s: %v", prefix, name, err)
+		}
+		return nil
+	}
+	str := func(dst *string) func(string) error {
+		return func(v string) error { *dst = v; return nil }
+	}
+	num := func(dst *int) func(string) error {
+		return func(v string) (err error) {
+			*dst, err = strconv.Atoi(v)
+			return err
+		}
+	}
+	dur := func(dst *Duration) func(string) error {
+		return func(v string) error {
+			d, err := time.ParseDuration(v)
+			*dst = Duration(d)
+			return err
+		}
+	}
+	errs := []error{
+		env("ADDR", str(&c.Addr)),
+		env("GRPC_ADDR", str(&c.GRPCAddr)),
+		env("PATH", str(&c.Path)),
+		env("CONFIG_URL", str(&c.ConfigURL)),
+		env("KEY_TTL", dur(&c.KeyTTL)),
+		env("HMAC_SECRET", str(&c.HMACSecret)),
+		env("HMAC_HEADER", str(&c.HMACHeader)),
+		env("DEDUPE", func(v string) (err error) {
+			c.Dedupe, err = strconv.ParseBool(v)
+			return err
+		}),
+		env("DEDUPE_TTL", dur(&c.DedupeTTL)),
+		env("REDIS_ADDR", str(&c.Redis.Addr)),
+		env("REDIS_PASSWORD", str(&c.Redis.Password)),
+		env("WORKERS", num(&c.Workers)),
+		env("QUEUE_SIZE", num(&c.QueueSize)),
+		env("FAIL_FAST", func(v string) (err error) {
+			c.FailFast, err = strconv.ParseBool(v)
+			return err
+		}),
+		env("RETRY_AFTER", dur(&c.RetryAfter)),
+		env("PRIORITY_BRANCHES", func(v string) error {
+			c.PriorityBranches = strings.Split(v, ",")
+			return nil
+		}),
+		env("LOG_LEVEL", str(&c.LogLevel)),
+		env("LOG_FORMAT", str(&c.LogFormat)),
+		env("SHUTDOWN_TIMEOUT", dur(&c.ShutdownTimeout)),
+		env("TARGETS", func(v string) error {
+			c.Targets = nil
+			for _, u := range strings.Split(v, ",") {
+				if u = strings.TrimSpace(u); u != "" {
+					c.Targets = append(c.Targets, Target{URL: u})
+				}
+			}
+			return nil
+		}),
+		env("STORE_DRIVER", str(&c.Store.Driver)),
+		env("STORE_DSN", str(&c.Store.DSN)),
+		env("RELOAD_INTERVAL", dur(&c.ReloadInterval)),
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks c and fills in the default format of targets
+func (c *Config) Validate() error {
+	var errs []error
+	if !strings.HasPrefix(c.Path, "/") {
+		errs = append(errs, fmt.Errorf("path %q must start with /", c.Path))
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level must be debug, info, warn or error, not %q", c.LogLevel))
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		errs = append(errs, errors.New("log_format must be json or text"))
+	}
+	if c.Workers < 0 || c.QueueSize < 0 {
+		errs = append(errs, errors.New("workers and queue_size must not be negative"))
+	}
+	if c.FailFast && c.Workers == 0 {
+		errs = append(errs, errors.New("fail_fast requires workers"))
+	}
+	if len(c.PriorityBranches) > 0 && c.Workers == 0 {
+		errs = append(errs, errors.New("priority_branches requires workers"))
+	}
+
+	names := make(map[string]bool)
+	for i, t := range c.Targets {
+		name := t.Name
+		if name == "" {
+			name = t.URL
+		}
+		switch t.Format {
+		case "":
+			c.Targets[i].Format = FormatSlack
+		case FormatSlack, FormatPayload:
+		default:
+			errs = append(errs, fmt.Errorf("target %s: unknown format %q", name, t.Format))
+		}
+		if t.URL == "" {
+			errs = append(errs, fmt.Errorf("target %d: missing url", i+1))
+		}
+		if err := validOutcomes(t.Outcomes); err != nil {
+			errs = append(errs, fmt.Errorf("target %s: %v", name, err))
+		}
+		for _, p := range t.Branches {
+			if _, err := path.Match(p, ""); err != nil {
+				errs = append(errs, fmt.Errorf("target %s: bad pattern %q", name, p))
+			}
+		}
+		if t.Name != "" {
+			if names[t.Name] {
+				errs = append(errs, fmt.Errorf("target %s: duplicate name", t.Name))
+			}
+			names[t.Name] = true
+		}
+	}
+	for i, r := range c.Routes {
+		if len(r.Targets) == 0 {
+			errs = append(errs, fmt.Errorf("route %d: no targets", i+1))
+		}
+		for _, name := range r.Targets {
+			if !names[name] {
+				errs = append(errs, fmt.Errorf("route %d: unknown target %q", i+1, name))
+			}
+		}
+		if err := validOutcomes(r.Outcomes); err != nil {
+			errs = append(errs, fmt.Errorf("route %d: %v", i+1, err))
+		}
+		for _, patterns := range [][]string{r.Repos, r.Branches, r.Types} {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					errs = append(errs, fmt.Errorf("route %d: bad pattern %q", i+1, p))
+				}
+			}
+		}
+	}
+
+	if c.Store.Driver != "" && c.Store.DSN == "" {
+		errs = append(errs, errors.New("store: missing dsn"))
+	}
+	return errors.Join(errs...)
+}
+
+var knownOutcomes = []string{travis.OutcomePassed, travis.OutcomeFailed, travis.OutcomeErrored, travis.OutcomeCanceled, travis.OutcomePending}
+
+// validOutcomes returns an error if one of the outcome patterns is
+// malformed or matches no outcome
+func validOutcomes(outcomes []string) error {
+	for _, o := range outcomes {
+		if _, err := path.Match(o, ""); err != nil {
+			return fmt.Errorf("bad pattern %q", o)
+		}
+		known := false
+		for _, k := range knownOutcomes {
+			known = known || travis.MatchAny([]string{o}, k)
+		}
+		if !known {
+			return fmt.Errorf("unknown outcome %q", o)
+		}
+	}
+	return nil
+}